@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -35,6 +36,8 @@ import (
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/storage"
+	"github.com/livekit/livekit-server/pkg/streaming"
 )
 
 func generateKeys(_ context.Context, _ *cli.Command) error {
@@ -45,6 +48,39 @@ func generateKeys(_ context.Context, _ *cli.Command) error {
 	return nil
 }
 
+func generateVAPIDKeys(_ context.Context, _ *cli.Command) error {
+	keys, err := streaming.GenerateVAPIDKeys()
+	if err != nil {
+		return err
+	}
+	fmt.Println("VAPID Public Key: ", keys.PublicKey)
+	fmt.Println("VAPID Private Key: ", keys.PrivateKey)
+	return nil
+}
+
+func grantRole(ctx context.Context, c *cli.Command) error {
+	role := c.String("role")
+	if !slices.Contains(storage.AllRoles, role) {
+		return fmt.Errorf("unknown role %q, expected one of %s", role, strings.Join(storage.AllRoles, ", "))
+	}
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	users := storage.NewUserRepository(db)
+	user, err := users.GetByEmail(ctx, c.String("email"))
+	if err != nil {
+		return fmt.Errorf("no account for %s: %w", c.String("email"), err)
+	}
+	if err = users.GrantRole(ctx, user.ID, role); err != nil {
+		return err
+	}
+	fmt.Printf("granted %s to %s (%s)\n", role, user.Email, user.ID)
+	return nil
+}
+
 func printPorts(_ context.Context, c *cli.Command) error {
 	conf, err := getConfig(c)
 	if err != nil {
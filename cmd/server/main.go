@@ -17,7 +17,7 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/json"
+	"database/sql"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -28,7 +28,6 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
@@ -37,6 +36,7 @@ import (
 	appauth "github.com/livekit/livekit-server/pkg/auth"
 	apphandler "github.com/livekit/livekit-server/pkg/handler"
 	"github.com/livekit/livekit-server/pkg/storage"
+	"github.com/livekit/livekit-server/pkg/streaming"
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
@@ -45,6 +45,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/logger"
+	redisLiveKit "github.com/livekit/protocol/redis"
 )
 
 var baseFlags = []cli.Flag{
@@ -152,6 +153,11 @@ func main() {
 				Usage:  "generates an API key and secret pair",
 				Action: generateKeys,
 			},
+			{
+				Name:   "generate-vapid-keys",
+				Usage:  "generates a VAPID key pair for web push notifications",
+				Action: generateVAPIDKeys,
+			},
 			{
 				Name:   "ports",
 				Usage:  "print ports that server is configured to use",
@@ -181,6 +187,23 @@ func main() {
 					},
 				},
 			},
+			{
+				Name:   "grant-role",
+				Usage:  "grants a role to the account of an email, e.g. to set up the first admin",
+				Action: grantRole,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "email",
+						Usage:    "email of the account",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "role",
+						Usage: "one of viewer, streamer, moderator and admin",
+						Value: "admin",
+					},
+				},
+			},
 			{
 				Name:   "list-nodes",
 				Usage:  "list all nodes",
@@ -266,18 +289,7 @@ func startServer(_ context.Context, c *cli.Command) error {
 		return err
 	}
 
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		// Try to load from local env file as a fallback for local development
-		if err := loadEnvFromFile("config/local.env"); err == nil {
-			dbURL = os.Getenv("DATABASE_URL")
-		}
-	}
-	if dbURL == "" {
-		return errors.New("DATABASE_URL not set")
-	}
-
-	db, err := storage.NewDB(dbURL)
+	db, err := openDatabase()
 	if err != nil {
 		return err
 	}
@@ -286,22 +298,40 @@ func startServer(_ context.Context, c *cli.Command) error {
 	userRepo := storage.NewUserRepository(db)
 	authService := appauth.NewService(userRepo)
 
-	issuer := "livekit-local"
-	secret := conf.Keys["key1"]
-	if secret == "" {
-		// fall back to any available secret
-		for _, s := range conf.Keys {
-			secret = s
-			break
-		}
+	signingKeys, generated, err := loadSigningKeys(conf.AppAuth, conf.Development)
+	if err != nil {
+		return err
 	}
-	if secret == "" {
-		return errors.New("no API key secret configured")
+	if generated && conf.AppAuth.RotationInterval > 0 {
+		rotateCtx, stopRotation := context.WithCancel(context.Background())
+		defer stopRotation()
+		go signingKeys.RotateEvery(rotateCtx, conf.AppAuth.RotationInterval, conf.AppAuth.RotationOverlap)
 	}
-	tokenGenerator := appauth.NewTokenGenerator(issuer, secret)
+	tokenGenerator := appauth.NewTokenGenerator(conf.AppAuth.Issuer, signingKeys)
+
+	sessionManager := appauth.NewSessionManager(storage.NewSessionRepository(db), tokenGenerator)
+	authService.SetSessions(sessionManager)
+	authService.SetTokenGenerator(tokenGenerator)
+	authService.SetMFAPolicy(conf.AppAuth.MFA.Issuer, conf.AppAuth.MFA.RequiredRoles)
 
-	authHandler := apphandler.NewAuthHandler(authService, tokenGenerator)
+	loginGuard, err := newLoginGuard(conf)
+	if err != nil {
+		return err
+	}
+	authHandler := apphandler.NewAuthHandler(authService, sessionManager)
+	authHandler.SetLoginGuard(loginGuard)
+	accountHandler := apphandler.NewAccountHandler(authService, userRepo)
+	accountHandler.SetLoginGuard(loginGuard)
+	sessionHandler := apphandler.NewSessionHandler(sessionManager)
 	authMiddleware := apphandler.NewAuthMiddleware(tokenGenerator)
+	authMiddleware.RequireSessions(sessionManager)
+	authMiddleware.SetPermissions(authService)
+	adminHandler := apphandler.NewAdminHandler(authService)
+	oidcProviders, err := newOIDCProviders(conf.AppAuth)
+	if err != nil {
+		return err
+	}
+	oidcHandler := apphandler.NewOIDCHandler(authService, sessionManager, tokenGenerator, oidcProviders...)
 
 	if cpuProfile := c.String("cpuprofile"); cpuProfile != "" {
 		if f, err := os.Create(cpuProfile); err != nil {
@@ -345,14 +375,50 @@ func startServer(_ context.Context, c *cli.Command) error {
 		return err
 	}
 
+	server.StreamingAPI().SetAuthMiddleware(authMiddleware)
+	server.StreamingAPI().SetUserRepository(userRepo)
+	server.StreamingAPI().SetAccountPolicy(authService)
+	authService.OnAccountDeleted(server.StreamingAPI().DeleteUserData)
+	authService.OnAccountSuspended(server.StreamingAPI().EndUserStreams)
+	if mailer := server.StreamingAPI().EmailNotifier(); mailer != nil {
+		authService.SetMailer(mailer, conf.Streaming.Email.BaseURL)
+	}
+	// every node serves the thumbnails captured on the others
+	if conf.Streaming.Thumbnails.Enabled && conf.Redis.IsConfigured() {
+		rc, err := redisLiveKit.GetRedisClient(&conf.Redis)
+		if err != nil {
+			return err
+		}
+		server.StreamingAPI().SetThumbnailStore(streaming.NewRedisThumbnailStore(rc))
+	}
+
 	server.RegisterHTTPHandler("/api/register", http.HandlerFunc(authHandler.Register))
 	server.RegisterHTTPHandler("/api/login", http.HandlerFunc(authHandler.Login))
-	server.RegisterHTTPHandler("/api/profile", authMiddleware.Authorize(http.HandlerFunc(handleProfile)))
-
-	// Stream Registry API
-	server.RegisterHTTPHandler("/api/streaming/list", http.HandlerFunc(handleListStreams))
-	server.RegisterHTTPHandler("/api/streaming/register", http.HandlerFunc(handleRegisterStream))
-	server.RegisterHTTPHandler("/api/streaming/unregister", http.HandlerFunc(handleUnregisterStream))
+	server.RegisterHTTPHandler("/api/login/mfa", http.HandlerFunc(authHandler.LoginMFA))
+	server.RegisterHTTPHandler("/api/token/refresh", http.HandlerFunc(sessionHandler.Refresh))
+	server.RegisterHTTPHandler("/api/logout", authMiddleware.Authorize(http.HandlerFunc(sessionHandler.Logout)))
+	server.RegisterHTTPHandler("/api/sessions", authMiddleware.Authorize(http.HandlerFunc(sessionHandler.Sessions)))
+	server.RegisterHTTPHandler("/api/profile", authMiddleware.Authorize(http.HandlerFunc(accountHandler.Profile)))
+	server.RegisterHTTPHandler("/api/account", authMiddleware.Authorize(http.HandlerFunc(accountHandler.DeleteAccount)))
+	server.RegisterHTTPHandler("/api/account/password", authMiddleware.Authorize(http.HandlerFunc(accountHandler.ChangePassword)))
+	server.RegisterHTTPHandler("/api/account/email", authMiddleware.Authorize(http.HandlerFunc(accountHandler.ChangeEmail)))
+	server.RegisterHTTPHandler(appauth.EmailConfirmPath, http.HandlerFunc(accountHandler.ConfirmEmail))
+	server.RegisterHTTPHandler(appauth.EmailVerifyPath, http.HandlerFunc(accountHandler.VerifyEmail))
+	server.RegisterHTTPHandler("/api/account/verify/resend", authMiddleware.Authorize(http.HandlerFunc(accountHandler.ResendVerification)))
+	server.RegisterHTTPHandler("/api/account/mfa", authMiddleware.Authorize(http.HandlerFunc(accountHandler.MFA)))
+	server.RegisterHTTPHandler("/api/account/mfa/totp", authMiddleware.Authorize(http.HandlerFunc(accountHandler.EnrollTOTP)))
+	server.RegisterHTTPHandler("/api/account/mfa/totp/confirm", authMiddleware.Authorize(http.HandlerFunc(accountHandler.ConfirmTOTP)))
+	server.RegisterHTTPHandler("/api/account/mfa/recovery-codes", authMiddleware.Authorize(http.HandlerFunc(accountHandler.RegenerateRecoveryCodes)))
+	server.RegisterHTTPHandler("/api/account/mfa/disable", authMiddleware.Authorize(http.HandlerFunc(accountHandler.DisableMFA)))
+	server.RegisterHTTPHandler("/api/password/reset", http.HandlerFunc(accountHandler.RequestPasswordReset))
+	server.RegisterHTTPHandler("/api/password/reset/confirm", http.HandlerFunc(accountHandler.ResetPassword))
+	server.RegisterHTTPHandler(apphandler.JWKSPath, apphandler.NewJWKSHandler(signingKeys))
+	server.RegisterHTTPHandler(apphandler.OIDCPath, oidcHandler)
+	server.RegisterHTTPHandler("/api/admin/users", authMiddleware.RequirePermission(storage.PermissionListUsers, http.HandlerFunc(adminHandler.Users)))
+	server.RegisterHTTPHandler("/api/admin/users/suspend", authMiddleware.RequirePermission(storage.PermissionSuspend, http.HandlerFunc(adminHandler.Suspend)))
+	server.RegisterHTTPHandler("/api/admin/users/reinstate", authMiddleware.RequirePermission(storage.PermissionSuspend, http.HandlerFunc(adminHandler.Reinstate)))
+	server.RegisterHTTPHandler("/api/admin/users/roles/grant", authMiddleware.RequirePermission(storage.PermissionAssignRole, http.HandlerFunc(adminHandler.GrantRole)))
+	server.RegisterHTTPHandler("/api/admin/users/roles/revoke", authMiddleware.RequirePermission(storage.PermissionAssignRole, http.HandlerFunc(adminHandler.RevokeRole)))
 
 	// Serve static files from examples directory
 	fs := http.FileServer(http.Dir("examples"))
@@ -375,103 +441,92 @@ func startServer(_ context.Context, c *cli.Command) error {
 	return server.Start()
 }
 
-// --- Stream Registry ---
-
-type StreamInfo struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Streamer  string `json:"streamer"`
-	Avatar    string `json:"avatar"`
-	Viewers   int    `json:"viewers"`
-	StartTime int64  `json:"startTime"`
-}
-
-var (
-	streamMutex   sync.RWMutex
-	activeStreams = make(map[string]StreamInfo)
-)
-
-func handleListStreams(w http.ResponseWriter, r *http.Request) {
-	enableCORS(w)
-	if r.Method == "OPTIONS" {
-		return
+// openDatabase opens the app database at DATABASE_URL, which config/local.env may set for local development
+func openDatabase() (*sql.DB, error) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		// Try to load from local env file as a fallback for local development
+		if err := loadEnvFromFile("config/local.env"); err == nil {
+			dbURL = os.Getenv("DATABASE_URL")
+		}
 	}
-
-	streamMutex.RLock()
-	defer streamMutex.RUnlock()
-
-	streams := make([]StreamInfo, 0, len(activeStreams))
-	for _, s := range activeStreams {
-		streams = append(streams, s)
+	if dbURL == "" {
+		return nil, errors.New("DATABASE_URL not set")
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(streams)
+	return storage.NewDB(dbURL)
 }
 
-func handleRegisterStream(w http.ResponseWriter, r *http.Request) {
-	enableCORS(w)
-	if r.Method == "OPTIONS" {
-		return
-	}
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var info StreamInfo
-	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+// loadSigningKeys reads the configured app token signing keys. Only in development, a key is generated when none
+// are configured: it is lost on restart and not shared with other nodes, which would reject the tokens it signs.
+func loadSigningKeys(conf config.AppAuthConfig, development bool) (*appauth.KeySet, bool, error) {
+	if len(conf.SigningKeys) == 0 {
+		if !development {
+			return nil, false, errors.New("app_auth.signing_keys is required outside of development mode")
+		}
+		key, err := appauth.GenerateSigningKey(conf.Algorithm)
+		if err != nil {
+			return nil, false, err
+		}
+		logger.Warnw("no app token signing keys configured, using a generated key", nil,
+			"kid", key.ID, "algorithm", key.Algorithm)
+		return appauth.NewKeySet(key), true, nil
 	}
 
-	if info.ID == "" {
-		http.Error(w, "Missing stream ID", http.StatusBadRequest)
-		return
+	keys := make([]*appauth.SigningKey, 0, len(conf.SigningKeys))
+	for _, kc := range conf.SigningKeys {
+		key, err := appauth.LoadSigningKey(kc.ID, kc.KeyFile)
+		if err != nil {
+			return nil, false, fmt.Errorf("app token signing key %s: %w", kc.ID, err)
+		}
+		if !kc.RetireAt.IsZero() {
+			key.Retire(kc.RetireAt)
+		}
+		keys = append(keys, key)
 	}
-
-	streamMutex.Lock()
-	activeStreams[info.ID] = info
-	streamMutex.Unlock()
-
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(`{"status":"registered"}`))
+	return appauth.NewKeySet(keys[0], keys[1:]...), false, nil
 }
 
-func handleUnregisterStream(w http.ResponseWriter, r *http.Request) {
-	enableCORS(w)
-	if r.Method == "OPTIONS" {
-		return
-	}
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req struct {
-		ID string `json:"id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	streamMutex.Lock()
-	delete(activeStreams, req.ID)
-	streamMutex.Unlock()
-
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(`{"status":"unregistered"}`))
+// newLoginGuard limits login attempts per node, or across nodes when Redis is configured
+func newLoginGuard(conf *config.Config) (*appauth.LoginGuard, error) {
+	var store appauth.AttemptStore = appauth.NewMemoryAttemptStore()
+	if conf.Redis.IsConfigured() {
+		rc, err := redisLiveKit.GetRedisClient(&conf.Redis)
+		if err != nil {
+			return nil, err
+		}
+		store = appauth.NewRedisAttemptStore(rc)
+	}
+	limits := conf.AppAuth.Login
+	return appauth.NewLoginGuard(store, appauth.LoginLimits{
+		IPAttempts:    limits.IPAttempts,
+		IPWindow:      limits.IPWindow,
+		MaxFailures:   limits.MaxFailures,
+		FailureWindow: limits.FailureWindow,
+		LockoutBase:   limits.LockoutBase,
+		LockoutMax:    limits.LockoutMax,
+	}), nil
 }
 
-func enableCORS(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+// newOIDCProviders creates the configured OpenID Connect providers, which are only contacted on the first login
+func newOIDCProviders(conf config.AppAuthConfig) ([]*appauth.OIDCProvider, error) {
+	providers := make([]*appauth.OIDCProvider, 0, len(conf.OIDCProviders))
+	for _, pc := range conf.OIDCProviders {
+		provider, err := appauth.NewOIDCProvider(pc.ID, appauth.OIDCConfig{
+			Name:         pc.Name,
+			IssuerURL:    pc.IssuerURL,
+			ClientID:     pc.ClientID,
+			ClientSecret: pc.ClientSecret,
+			RedirectURL:  pc.RedirectURL,
+			Scopes:       pc.Scopes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("oidc provider %q: %w", pc.ID, err)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
 }
 
-// --- End Stream Registry ---
-
 // loadEnvFromFile loads KEY=VALUE lines from a .env-like file (no export, no quotes)
 func loadEnvFromFile(path string) error {
 	f, err := os.Open(path)
@@ -494,20 +549,6 @@ func loadEnvFromFile(path string) error {
 	return scanner.Err()
 }
 
-func handleProfile(w http.ResponseWriter, r *http.Request) {
-	userID, ok := apphandler.UserIDFromContext(r.Context())
-	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	resp := map[string]string{
-		"userId": userID,
-	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(resp)
-}
-
 func getConfigString(configFile string, inConfigBody string) (string, error) {
 	if inConfigBody != "" || configFile == "" {
 		return inConfigBody, nil
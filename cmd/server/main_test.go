@@ -19,6 +19,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
 )
 
 type testStruct struct {
@@ -48,6 +50,16 @@ func TestGetConfigString(t *testing.T) {
 	}
 }
 
+func TestLoadSigningKeys(t *testing.T) {
+	_, _, err := loadSigningKeys(config.AppAuthConfig{}, false)
+	require.Error(t, err)
+
+	keys, generated, err := loadSigningKeys(config.AppAuthConfig{}, true)
+	require.NoError(t, err)
+	require.True(t, generated)
+	require.NotNil(t, keys)
+}
+
 func TestShouldReturnErrorIfConfigFileDoesNotExist(t *testing.T) {
 	configBody, err := getConfigString("notExistingFile", "")
 	require.Error(t, err)
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxAvatarURLLength   = 2048
+	minPasswordLength    = 8
+
+	emailChangeTTL = 24 * time.Hour
+	// path of the link sent to confirm an email change
+	EmailConfirmPath = "/api/account/email/confirm"
+)
+
+var (
+	ErrInvalidProfile = errors.New("invalid profile")
+	ErrInvalidEmail   = errors.New("invalid email")
+	ErrWeakPassword   = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	ErrInvalidToken   = errors.New("invalid or expired token")
+
+	channelSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,30}[a-z0-9]$`)
+)
+
+// ProfileUpdate holds the profile fields to change, nil fields are left as they are and empty ones are cleared
+type ProfileUpdate struct {
+	DisplayName *string `json:"displayName"`
+	AvatarURL   *string `json:"avatarUrl"`
+	Bio         *string `json:"bio"`
+	ChannelSlug *string `json:"channelSlug"`
+}
+
+// UpdateProfile validates and saves changes to the profile of a user
+func (s *Service) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) (*storage.User, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.DisplayName != nil {
+		name := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return nil, fmt.Errorf("%w: display name is longer than %d characters", ErrInvalidProfile, maxDisplayNameLength)
+		}
+		user.DisplayName = nullString(name)
+	}
+	if update.AvatarURL != nil {
+		avatar := strings.TrimSpace(*update.AvatarURL)
+		if avatar != "" && !isWebURL(avatar) {
+			return nil, fmt.Errorf("%w: avatar must be an http or https URL", ErrInvalidProfile)
+		}
+		user.AvatarURL = nullString(avatar)
+	}
+	if update.Bio != nil {
+		bio := strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return nil, fmt.Errorf("%w: bio is longer than %d characters", ErrInvalidProfile, maxBioLength)
+		}
+		user.Bio = nullString(bio)
+	}
+	if update.ChannelSlug != nil {
+		slug := strings.ToLower(strings.TrimSpace(*update.ChannelSlug))
+		if slug != "" && !channelSlugPattern.MatchString(slug) {
+			return nil, fmt.Errorf("%w: channel slug must be 3 to 32 letters, digits, dashes or underscores", ErrInvalidProfile)
+		}
+		user.ChannelSlug = nullString(slug)
+	}
+
+	if err = s.users.UpdateProfile(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password of a user after checking their current one,
+// ending their sessions on other devices
+func (s *Service) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	user, err := s.checkPassword(ctx, userID, currentPassword)
+	if err != nil {
+		return err
+	}
+	if len(newPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	hash, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err = s.users.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return err
+	}
+	// whoever else knew the old password or holds a token of another device is logged out
+	if s.sessions != nil {
+		return s.sessions.RevokeOthers(ctx, user.ID)
+	}
+	return nil
+}
+
+// RequestEmailChange sends a confirmation link to the new address, the email of the user
+// changes once the link is followed
+func (s *Service) RequestEmailChange(ctx context.Context, userID, password, newEmail string) error {
+	user, err := s.checkPassword(ctx, userID, password)
+	if err != nil {
+		return err
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if !validEmail(newEmail) {
+		return ErrInvalidEmail
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return fmt.Errorf("%w: this is already the email of the account", ErrInvalidEmail)
+	}
+	if _, err = s.users.GetByEmail(ctx, newEmail); err == nil {
+		return storage.ErrEmailTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	token, hash, err := newAccountToken()
+	if err != nil {
+		return err
+	}
+	if err = s.users.SetPendingEmail(ctx, user.ID, newEmail, hash, time.Now().Add(emailChangeTTL)); err != nil {
+		return err
+	}
+
+	link := s.baseURL + EmailConfirmPath + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Confirm the new email address of your account by opening this link within %s:\n\n%s\n\n"+
+		"If you did not ask for this change, you can ignore this email.", emailChangeTTL, link)
+	return s.mailer.SendMail(ctx, newEmail, "Confirm your new email address", body)
+}
+
+// ConfirmEmailChange applies the email change of a confirmation token
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) (*storage.User, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.users.ConfirmEmailChange(ctx, hashAccountToken(token))
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, storage.ErrTokenExpired) {
+		return nil, ErrInvalidToken
+	}
+	return user, err
+}
+
+// DeleteAccount removes a user after checking their password, along with the data registered callbacks hold
+func (s *Service) DeleteAccount(ctx context.Context, userID, password string) error {
+	user, err := s.checkPassword(ctx, userID, password)
+	if err != nil {
+		return err
+	}
+	if s.sessions != nil {
+		if err = s.sessions.DeleteUserSessions(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+	if err = s.users.DeleteUser(ctx, user.ID); err != nil {
+		return err
+	}
+	for _, f := range s.onDelete {
+		f(ctx, user.ID)
+	}
+	return nil
+}
+
+func (s *Service) checkPassword(ctx context.Context, userID, password string) (*storage.User, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckPassword(user.PasswordHash, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// newAccountToken returns a random token to send to a user and the hash to store
+func newAccountToken() (string, []byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashAccountToken(token), nil
+}
+
+func hashAccountToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// validEmail accepts bare addresses, without display names
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func isWebURL(raw string) bool {
+	if len(raw) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
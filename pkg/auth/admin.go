@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+// admin audit event types
+const (
+	AuditAccountSuspended  = "account_suspended"
+	AuditAccountReinstated = "account_reinstated"
+	AuditRoleGranted       = "role_granted"
+	AuditRoleRevoked       = "role_revoked"
+)
+
+var (
+	ErrAccountSuspended = errors.New("account suspended")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUnknownRole      = errors.New("unknown role")
+	// admins cannot suspend themselves or take away their own admin role and lock everyone out
+	ErrSelfAdministration = errors.New("cannot apply this to your own account")
+)
+
+// UserWithRoles is a user as admins see it
+type UserWithRoles struct {
+	*storage.User
+	Roles []string
+}
+
+// SetAuditor sets where admin actions are reported
+func (s *Service) SetAuditor(auditor Auditor) {
+	s.auditor = auditor
+}
+
+// OnAccountSuspended registers a callback run after an account is suspended, e.g. to end its streams
+func (s *Service) OnAccountSuspended(f func(ctx context.Context, userID string)) {
+	s.onSuspend = append(s.onSuspend, f)
+}
+
+// HasPermission reports whether the roles of a user grant a permission, suspended accounts have none
+func (s *Service) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if user.SuspendedAt.Valid {
+		return false, nil
+	}
+	return s.users.HasPermission(ctx, userID, permission)
+}
+
+// ListUsers returns the users matching a filter along with their roles
+func (s *Service) ListUsers(ctx context.Context, filter storage.UserFilter) ([]*UserWithRoles, error) {
+	users, err := s.users.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*UserWithRoles, 0, len(users))
+	for _, user := range users {
+		roles, err := s.users.Roles(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &UserWithRoles{User: user, Roles: roles})
+	}
+	return result, nil
+}
+
+// SuspendUser blocks an account from logging in and ends its sessions, the OnAccountSuspended callbacks
+// take down what it has running
+func (s *Service) SuspendUser(ctx context.Context, adminID, userID, reason string) error {
+	if adminID == userID {
+		return ErrSelfAdministration
+	}
+	if err := s.users.Suspend(ctx, userID, reason); err != nil {
+		return err
+	}
+	if s.sessions != nil {
+		if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+			return err
+		}
+	}
+	audit(ctx, s.auditor, AuditEvent{Type: AuditAccountSuspended, UserID: userID, Details: map[string]any{"by": adminID, "reason": reason}})
+	for _, f := range s.onSuspend {
+		f(ctx, userID)
+	}
+	return nil
+}
+
+// ReinstateUser lifts the suspension of an account
+func (s *Service) ReinstateUser(ctx context.Context, adminID, userID string) error {
+	if err := s.users.Reinstate(ctx, userID); err != nil {
+		return err
+	}
+	audit(ctx, s.auditor, AuditEvent{Type: AuditAccountReinstated, UserID: userID, Details: map[string]any{"by": adminID}})
+	return nil
+}
+
+// GrantRole gives a user one of storage.AllRoles
+func (s *Service) GrantRole(ctx context.Context, adminID, userID, role string) error {
+	if !slices.Contains(storage.AllRoles, role) {
+		return ErrUnknownRole
+	}
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.users.GrantRole(ctx, userID, role); err != nil {
+		return err
+	}
+	audit(ctx, s.auditor, AuditEvent{Type: AuditRoleGranted, UserID: userID, Details: map[string]any{"by": adminID, "role": role}})
+	return nil
+}
+
+// RevokeRole takes a role from a user
+func (s *Service) RevokeRole(ctx context.Context, adminID, userID, role string) error {
+	if !slices.Contains(storage.AllRoles, role) {
+		return ErrUnknownRole
+	}
+	if adminID == userID && role == storage.RoleAdmin {
+		return ErrSelfAdministration
+	}
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.users.RevokeRole(ctx, userID, role); err != nil {
+		return err
+	}
+	audit(ctx, s.auditor, AuditEvent{Type: AuditRoleRevoked, UserID: userID, Details: map[string]any{"by": adminID, "role": role}})
+	return nil
+}
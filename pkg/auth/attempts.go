@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AttemptStore keeps the times of recent attempts per key for sliding window limits
+type AttemptStore interface {
+	// Record adds an attempt at now, attempts older than window may be dropped
+	Record(ctx context.Context, key string, now time.Time, window time.Duration) error
+	// Attempts returns the times of the attempts within window before now, oldest first
+	Attempts(ctx context.Context, key string, now time.Time, window time.Duration) ([]time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// MemoryAttemptStore keeps attempts of a single node in memory
+type MemoryAttemptStore struct {
+	mu        sync.Mutex
+	attempts  map[string]*attemptLog
+	lastSweep time.Time
+}
+
+type attemptLog struct {
+	times     []time.Time
+	expiresAt time.Time
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{attempts: make(map[string]*attemptLog)}
+}
+
+func (s *MemoryAttemptStore) Record(_ context.Context, key string, now time.Time, window time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweepLocked(now)
+	l, ok := s.attempts[key]
+	if !ok {
+		l = &attemptLog{}
+		s.attempts[key] = l
+	}
+	l.times = append(pruneAttempts(l.times, now.Add(-window)), now)
+	l.expiresAt = now.Add(window)
+	return nil
+}
+
+func (s *MemoryAttemptStore) Attempts(_ context.Context, key string, now time.Time, window time.Duration) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	l.times = pruneAttempts(l.times, now.Add(-window))
+	return append([]time.Time(nil), l.times...), nil
+}
+
+func (s *MemoryAttemptStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// sweepLocked drops keys without recent attempts, at most once a minute
+func (s *MemoryAttemptStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, l := range s.attempts {
+		if now.After(l.expiresAt) {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+func pruneAttempts(times []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(since) {
+		i++
+	}
+	return times[i:]
+}
+
+// RedisAttemptStore shares attempts between nodes, each key is a sorted set of attempt times
+type RedisAttemptStore struct {
+	rc     redis.UniversalClient
+	prefix string
+}
+
+func NewRedisAttemptStore(rc redis.UniversalClient) *RedisAttemptStore {
+	return &RedisAttemptStore{rc: rc, prefix: "auth_attempts:"}
+}
+
+func (s *RedisAttemptStore) Record(ctx context.Context, key string, now time.Time, window time.Duration) error {
+	key = s.prefix + key
+	score := float64(now.UnixNano())
+	_, err := s.rc.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+		// attempts at the same time on different nodes must not collapse into one member
+		member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+		p.ZAdd(ctx, key, redis.Z{Score: score, Member: member})
+		p.PExpire(ctx, key, window)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisAttemptStore) Attempts(ctx context.Context, key string, now time.Time, window time.Duration) ([]time.Time, error) {
+	scores, err := s.rc.ZRangeByScoreWithScores(ctx, s.prefix+key, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(now.Add(-window).UnixNano(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, len(scores))
+	for _, z := range scores {
+		times = append(times, time.Unix(0, int64(z.Score)))
+	}
+	return times, nil
+}
+
+func (s *RedisAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.rc.Del(ctx, s.prefix+key).Err()
+}
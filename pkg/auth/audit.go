@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// audit event types
+const (
+	AuditLoginFailed        = "login_failed"
+	AuditAccountLocked      = "account_locked"
+	AuditLoginThrottled     = "login_throttled"
+	AuditIPRateLimited      = "ip_rate_limited"
+	AuditRefreshTokenReused = "refresh_token_reused"
+)
+
+// AuditEvent records security relevant activity on accounts
+type AuditEvent struct {
+	Type   string
+	UserID string
+	// the email an attempt was made for, which may not belong to any account
+	Email   string
+	IP      string
+	Time    time.Time
+	Details map[string]any
+}
+
+// Auditor receives audit events, implementations must not block
+type Auditor interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// LogAuditor writes audit events to the server log as warnings
+type LogAuditor struct{}
+
+func (LogAuditor) Audit(_ context.Context, event AuditEvent) {
+	values := []any{"event", event.Type, "time", event.Time}
+	if event.UserID != "" {
+		values = append(values, "userID", event.UserID)
+	}
+	if event.Email != "" {
+		values = append(values, "email", event.Email)
+	}
+	if event.IP != "" {
+		values = append(values, "ip", event.IP)
+	}
+	for k, v := range event.Details {
+		values = append(values, k, v)
+	}
+	logger.Warnw("auth audit", nil, values...)
+}
+
+func audit(ctx context.Context, auditor Auditor, event AuditEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	auditor.Audit(ctx, event)
+}
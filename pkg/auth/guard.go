@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+)
+
+var ErrTooManyAttempts = errors.New("too many attempts, try again later")
+
+// ThrottledError is returned for attempts over a limit, RetryAfter tells when the next one may succeed
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginLimits configures LoginGuard, zero values disable the respective limit
+type LoginLimits struct {
+	// login and signup attempts allowed per IP address within IPWindow
+	IPAttempts int
+	IPWindow   time.Duration
+	// failed logins of an account within FailureWindow before it is locked
+	MaxFailures   int
+	FailureWindow time.Duration
+	// the first lockout lasts LockoutBase, it doubles with every further failure up to LockoutMax.
+	// Lockouts do not grow without LockoutMax.
+	LockoutBase time.Duration
+	LockoutMax  time.Duration
+}
+
+var DefaultLoginLimits = LoginLimits{
+	IPAttempts:    30,
+	IPWindow:      time.Minute,
+	MaxFailures:   5,
+	FailureWindow: time.Hour,
+	LockoutBase:   30 * time.Second,
+	LockoutMax:    15 * time.Minute,
+}
+
+// LoginGuard throttles password guessing with sliding window limits per IP address and per account.
+// Accounts are keyed by the email attempted, so unknown emails are locked the same way as existing ones.
+type LoginGuard struct {
+	store   AttemptStore
+	limits  LoginLimits
+	auditor Auditor
+}
+
+func NewLoginGuard(store AttemptStore, limits LoginLimits) *LoginGuard {
+	return &LoginGuard{store: store, limits: limits, auditor: LogAuditor{}}
+}
+
+// SetAuditor sets where failed and throttled attempts are reported
+func (g *LoginGuard) SetAuditor(auditor Auditor) {
+	g.auditor = auditor
+}
+
+// Allow checks the limits of ip and, when email is set, of its account before an attempt,
+// counting the attempt against ip. Limits fail open when the attempt store is unavailable.
+func (g *LoginGuard) Allow(ctx context.Context, ip, email string) error {
+	now := time.Now()
+	if err := g.allowIP(ctx, ip, email, now); err != nil {
+		return err
+	}
+
+	if g.limits.MaxFailures > 0 && email != "" {
+		failures, err := g.store.Attempts(ctx, accountKey(email), now, g.limits.FailureWindow)
+		if err != nil {
+			logger.Warnw("could not check failed logins", err)
+			return nil
+		}
+		if lockedUntil, locked := g.lockedUntil(failures); locked && now.Before(lockedUntil) {
+			audit(ctx, g.auditor, AuditEvent{Type: AuditLoginThrottled, IP: ip, Email: email, Time: now})
+			return &ThrottledError{RetryAfter: lockedUntil.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// AllowPasswordReset checks the limits of ip like Allow before a password reset email is sent, and allows as many
+// reset emails per account as failed logins. They are counted apart from failed logins, so asking for reset emails
+// does not lock the account and locked accounts can still reset their password.
+func (g *LoginGuard) AllowPasswordReset(ctx context.Context, ip, email string) error {
+	now := time.Now()
+	if err := g.allowIP(ctx, ip, email, now); err != nil {
+		return err
+	}
+
+	if g.limits.MaxFailures > 0 && email != "" {
+		key := resetKey(email)
+		requests, err := g.store.Attempts(ctx, key, now, g.limits.FailureWindow)
+		if err != nil {
+			logger.Warnw("could not check password reset requests", err)
+			return nil
+		}
+		if len(requests) >= g.limits.MaxFailures {
+			audit(ctx, g.auditor, AuditEvent{Type: AuditLoginThrottled, IP: ip, Email: email, Time: now})
+			return &ThrottledError{RetryAfter: requests[0].Add(g.limits.FailureWindow).Sub(now)}
+		}
+		if err = g.store.Record(ctx, key, now, g.limits.FailureWindow); err != nil {
+			logger.Warnw("could not record password reset request", err)
+		}
+	}
+	return nil
+}
+
+// allowIP checks the limit of ip and counts the attempt against it
+func (g *LoginGuard) allowIP(ctx context.Context, ip, email string, now time.Time) error {
+	if g.limits.IPAttempts <= 0 || ip == "" {
+		return nil
+	}
+	attempts, err := g.store.Attempts(ctx, ipKey(ip), now, g.limits.IPWindow)
+	if err != nil {
+		logger.Warnw("could not check login attempts", err)
+		return nil
+	}
+	if len(attempts) >= g.limits.IPAttempts {
+		audit(ctx, g.auditor, AuditEvent{Type: AuditIPRateLimited, IP: ip, Email: email, Time: now})
+		return &ThrottledError{RetryAfter: attempts[0].Add(g.limits.IPWindow).Sub(now)}
+	}
+	if err = g.store.Record(ctx, ipKey(ip), now, g.limits.IPWindow); err != nil {
+		logger.Warnw("could not record login attempt", err)
+	}
+	return nil
+}
+
+// Failed counts a failed login against the account of email
+func (g *LoginGuard) Failed(ctx context.Context, ip, email string) {
+	now := time.Now()
+	audit(ctx, g.auditor, AuditEvent{Type: AuditLoginFailed, IP: ip, Email: email, Time: now})
+	if g.limits.MaxFailures <= 0 || email == "" {
+		return
+	}
+
+	key := accountKey(email)
+	if err := g.store.Record(ctx, key, now, g.limits.FailureWindow); err != nil {
+		logger.Warnw("could not record failed login", err)
+		return
+	}
+	failures, err := g.store.Attempts(ctx, key, now, g.limits.FailureWindow)
+	if err != nil {
+		logger.Warnw("could not check failed logins", err)
+		return
+	}
+	if lockedUntil, locked := g.lockedUntil(failures); locked {
+		audit(ctx, g.auditor, AuditEvent{
+			Type:  AuditAccountLocked,
+			IP:    ip,
+			Email: email,
+			Time:  now,
+			Details: map[string]any{
+				"failures":    len(failures),
+				"lockedUntil": lockedUntil,
+			},
+		})
+	}
+}
+
+// Succeeded clears the failed logins of the account of email
+func (g *LoginGuard) Succeeded(ctx context.Context, email string) {
+	if g.limits.MaxFailures <= 0 || email == "" {
+		return
+	}
+	if err := g.store.Reset(ctx, accountKey(email)); err != nil {
+		logger.Warnw("could not reset failed logins", err)
+	}
+}
+
+// lockedUntil returns the end of the lockout the failures lead to, if they reach the limit
+func (g *LoginGuard) lockedUntil(failures []time.Time) (time.Time, bool) {
+	if len(failures) < g.limits.MaxFailures {
+		return time.Time{}, false
+	}
+	lockout := g.limits.LockoutBase
+	for i := g.limits.MaxFailures; i < len(failures) && lockout < g.limits.LockoutMax; i++ {
+		lockout *= 2
+	}
+	if g.limits.LockoutMax > 0 {
+		lockout = min(lockout, g.limits.LockoutMax)
+	}
+	return failures[len(failures)-1].Add(lockout), true
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func accountKey(email string) string {
+	return "account:" + hashEmail(email)
+}
+
+func resetKey(email string) string {
+	return "reset:" + hashEmail(email)
+}
+
+// hashEmail keys accounts without revealing the email to whoever can read the attempt store
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,16 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyPasswordHash is compared against when there is no account, so that takes as long as a wrong password
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := HashPassword("not the password of any account")
+	return hash
+})
 
 func HashPassword(plain string) ([]byte, error) {
 	return bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+// OIDCUser returns the user a finished OIDC login was handed over to, unless they were suspended meanwhile
+func (s *Service) OIDCUser(ctx context.Context, userID string) (*storage.User, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt.Valid {
+		return nil, ErrAccountSuspended
+	}
+	return user, nil
+}
+
+// LoginWithOIDC returns the user a provider account belongs to. Accounts are linked on first login
+// to the user with the same email, or a new user is created, which requires the provider to have verified the email.
+func (s *Service) LoginWithOIDC(ctx context.Context, identity *OIDCIdentity) (*storage.User, error) {
+	user, err := s.users.GetByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		if user.SuspendedAt.Valid {
+			return nil, ErrAccountSuspended
+		}
+		return user, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(identity.Email)
+	if !identity.EmailVerified || !validEmail(email) {
+		return nil, ErrEmailNotVerified
+	}
+
+	user, err = s.users.GetByEmail(ctx, email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// without a password hash only the provider or a password reset can log in
+		user = &storage.User{
+			Email:        email,
+			PasswordHash: []byte{},
+			DisplayName:  nullString(identity.Name),
+		}
+		if err = s.users.CreateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case user.SuspendedAt.Valid:
+		return nil, ErrAccountSuspended
+	case !user.EmailVerifiedAt.Valid:
+		// whoever signed up with the address never proved owning it, so they lose the password they set
+		if err = s.users.UpdatePassword(ctx, user.ID, []byte{}); err != nil {
+			return nil, err
+		}
+		if s.sessions != nil {
+			if err = s.sessions.RevokeAll(ctx, user.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !user.EmailVerifiedAt.Valid {
+		if err = s.users.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	err = s.users.LinkIdentity(ctx, &storage.Identity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UserID:   user.ID,
+		Email:    email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.users.GetByID(ctx, user.ID)
+}
@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+
+	rsaKeyBits = 2048
+)
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// SigningKey is a key app tokens are signed with, identified by the kid header of the tokens
+type SigningKey struct {
+	ID        string
+	Algorithm string
+
+	private crypto.Signer
+	// after this the key no longer verifies tokens, zero while it has not been rotated out
+	notAfter time.Time
+}
+
+// NewSigningKey wraps an Ed25519 or RSA private key, the algorithm follows from the type of the key
+func NewSigningKey(id string, key crypto.Signer) (*SigningKey, error) {
+	if id == "" {
+		return nil, errors.New("signing key needs an id")
+	}
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: id, Algorithm: AlgorithmEdDSA, private: k}, nil
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < rsaKeyBits {
+			return nil, fmt.Errorf("RSA signing key %s has fewer than %d bits", id, rsaKeyBits)
+		}
+		return &SigningKey{ID: id, Algorithm: AlgorithmRS256, private: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T, use Ed25519 or RSA", key)
+	}
+}
+
+// GenerateSigningKey creates a new random key for the algorithm, its id is derived from the current time
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var key crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmEdDSA, "":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case AlgorithmRS256:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	suffix, _, err := newAccountToken()
+	if err != nil {
+		return nil, err
+	}
+	return NewSigningKey(time.Now().UTC().Format("20060102T150405")+"-"+suffix[:8], key)
+}
+
+// LoadSigningKey reads a PEM encoded PKCS#8 or PKCS#1 private key file
+func LoadSigningKey(id, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, key)
+	}
+	return NewSigningKey(id, signer)
+}
+
+// Retire limits how long a previous key keeps verifying tokens
+func (k *SigningKey) Retire(at time.Time) {
+	k.notAfter = at
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+func (k *SigningKey) publicKey() crypto.PublicKey {
+	return k.private.Public()
+}
+
+// JWK is the public part of a signing key as published in the JWKS
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// Ed25519 keys, and EC keys of identity providers which also have Y
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set other services can verify app tokens with
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *SigningKey) jwk() JWK {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+	switch pub := k.publicKey().(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	}
+	return jwk
+}
+
+// KeySet holds the key new tokens are signed with and the keys rotated out of signing,
+// which keep verifying tokens until their overlap window ends
+type KeySet struct {
+	mu       sync.RWMutex
+	active   *SigningKey
+	previous []*SigningKey
+}
+
+// NewKeySet signs with active and verifies with active and previous. Previous keys without
+// a retirement time set by Retire verify tokens until they are removed from the set.
+func NewKeySet(active *SigningKey, previous ...*SigningKey) *KeySet {
+	return &KeySet{active: active, previous: previous}
+}
+
+// Rotate makes next the signing key. The replaced key keeps verifying tokens for overlap,
+// which should be at least the lifetime of access tokens.
+func (ks *KeySet) Rotate(next *SigningKey, overlap time.Duration) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.active != nil {
+		ks.active.Retire(time.Now().Add(overlap))
+		ks.previous = append(ks.previous, ks.active)
+	}
+	ks.active = next
+	ks.pruneLocked(time.Now())
+	logger.Infow("rotated app token signing key", "kid", next.ID, "algorithm", next.Algorithm)
+}
+
+// RotateEvery generates a new key with the algorithm of the signing key at every interval until ctx is done.
+// Generated keys only live in memory, deployments with several nodes should rotate configured key files instead.
+func (ks *KeySet) RotateEvery(ctx context.Context, interval, overlap time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			next, err := GenerateSigningKey(ks.Signing().Algorithm)
+			if err != nil {
+				logger.Errorw("could not generate app token signing key", err)
+				continue
+			}
+			ks.Rotate(next, overlap)
+		}
+	}
+}
+
+// Signing returns the key new tokens are signed with
+func (ks *KeySet) Signing() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active
+}
+
+// Lookup returns the key with the given id if it still verifies tokens
+func (ks *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if ks.active != nil && ks.active.ID == kid {
+		return ks.active, true
+	}
+	now := time.Now()
+	for _, key := range ks.previous {
+		if key.ID == kid && (key.notAfter.IsZero() || now.Before(key.notAfter)) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS returns the public keys that currently verify tokens
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.pruneLocked(time.Now())
+	set := JWKS{Keys: make([]JWK, 0, len(ks.previous)+1)}
+	if ks.active != nil {
+		set.Keys = append(set.Keys, ks.active.jwk())
+	}
+	for _, key := range ks.previous {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	return set
+}
+
+func (ks *KeySet) pruneLocked(now time.Time) {
+	kept := ks.previous[:0]
+	for _, key := range ks.previous {
+		if key.notAfter.IsZero() || now.Before(key.notAfter) {
+			kept = append(kept, key)
+		}
+	}
+	clear(ks.previous[len(kept):])
+	ks.previous = kept
+}
+
+// keyFunc selects the verification key of a token by its kid header
+func (ks *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("%w: token has no kid", ErrUnknownSigningKey)
+	}
+	key, ok := ks.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSigningKey, kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.publicKey(), nil
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// Mailer delivers account emails such as email change confirmations
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
+
+// logMailer is used until a mailer is configured, it logs messages so links can be followed in development
+type logMailer struct{}
+
+func (logMailer) SendMail(_ context.Context, to, subject, body string) error {
+	logger.Infow("no mailer configured, account email not sent", "to", to, "subject", subject, "body", body)
+	return nil
+}
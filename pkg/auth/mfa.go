@@ -0,0 +1,294 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+const (
+	mfaChallengeTTL      = 5 * time.Minute
+	purposeMFAChallenge  = "mfa_challenge"
+	recoveryCodeCount    = 10
+	defaultMFAIssuerName = "LiveKit"
+)
+
+var (
+	ErrMFARequired       = errors.New("two-factor authentication required")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication not enabled")
+)
+
+// TOTPEnrollment is a new TOTP secret, it is enabled by ConfirmTOTP with a first code from the authenticator app
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	// otpauth URI to show as a QR code
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// MFAChallenge is issued instead of tokens when a password login needs a second factor
+type MFAChallenge struct {
+	Token     string    `json:"mfaToken"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recoveryCodesLeft"`
+	// whether the roles of the user require a second factor to go live
+	Required bool `json:"required"`
+}
+
+// SetMFAPolicy sets the issuer shown in authenticator apps and the roles that need a second factor to go live
+func (s *Service) SetMFAPolicy(issuer string, requiredRoles []string) {
+	s.mfaIssuer = issuer
+	s.mfaRoles = requiredRoles
+}
+
+// EnrollTOTP creates a TOTP secret for a user, replacing an unconfirmed one
+func (s *Service) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.users.SetPendingTOTP(ctx, userID, secret); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFAAlreadyEnabled
+	} else if err != nil {
+		return nil, err
+	}
+	issuer := s.mfaIssuer
+	if issuer == "" {
+		issuer = defaultMFAIssuerName
+	}
+	return &TOTPEnrollment{Secret: secret, ProvisioningURI: totpURI(issuer, user.Email, secret)}, nil
+}
+
+// ConfirmTOTP enables a pending enrollment with a code of the authenticator app, returning the recovery codes
+func (s *Service) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	m, err := s.users.GetMFA(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFANotEnabled
+	} else if err != nil {
+		return nil, err
+	}
+	if m.EnabledAt.Valid {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	counter, ok := validateTOTP(m.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	codes, hashes := newRecoveryCodes()
+	if err = s.users.EnableMFA(ctx, userID, counter, hashes); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFAAlreadyEnabled
+	} else if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user after checking their second factor
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.VerifyMFA(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes := newRecoveryCodes()
+	if err := s.users.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableMFA removes the second factor of a user after checking it
+func (s *Service) DisableMFA(ctx context.Context, userID, code string) error {
+	if err := s.VerifyMFA(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.users.DeleteMFA(ctx, userID)
+}
+
+func (s *Service) MFAStatus(ctx context.Context, userID string) (*MFAStatus, error) {
+	status := &MFAStatus{}
+	m, err := s.users.GetMFA(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if m != nil && m.EnabledAt.Valid {
+		status.Enabled = true
+		if status.RecoveryCodesLeft, err = s.users.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	if status.Required, err = s.mfaRequired(ctx, userID); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// VerifyMFA checks a TOTP code or an unused recovery code of a user, either works only once
+func (s *Service) VerifyMFA(ctx context.Context, userID, code string) error {
+	m, err := s.users.GetMFA(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMFANotEnabled
+	} else if err != nil {
+		return err
+	}
+	if !m.EnabledAt.Valid {
+		return ErrMFANotEnabled
+	}
+
+	if counter, ok := validateTOTP(m.TOTPSecret, code, time.Now()); ok {
+		err = s.users.UseTOTPCounter(ctx, userID, counter)
+	} else {
+		err = s.users.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidMFACode
+	}
+	return err
+}
+
+// MFAChallenge returns a challenge for the second login step, or nil when the user has no second factor
+func (s *Service) MFAChallenge(ctx context.Context, user *storage.User) (*MFAChallenge, error) {
+	enabled, err := s.mfaEnabled(ctx, user.ID)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	if s.tokens == nil {
+		return nil, errors.New("no token generator configured for MFA challenges")
+	}
+	id, _, err := newAccountToken()
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.tokens.GenerateLink(user.ID, purposeMFAChallenge, id, mfaChallengeTTL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{Token: token, ExpiresAt: time.Now().Add(mfaChallengeTTL)}, nil
+}
+
+// ParseMFAChallenge returns the user a challenge was issued to, the login completes once VerifyMFA accepts their code
+// and RedeemMFAChallenge the challenge. Redeemed challenges are refused.
+func (s *Service) ParseMFAChallenge(ctx context.Context, token string) (*storage.User, error) {
+	if s.tokens == nil || token == "" {
+		return nil, ErrInvalidToken
+	}
+	claims, err := s.tokens.ParseLink(token, purposeMFAChallenge)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if jti, _ := claims["jti"].(string); jti == "" || s.challengeRedeemed(jti) {
+		return nil, ErrInvalidToken
+	}
+	sub, _ := claims["sub"].(string)
+	user, err := s.users.GetByID(ctx, sub)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt.Valid {
+		return nil, ErrAccountSuspended
+	}
+	return user, nil
+}
+
+// RedeemMFAChallenge marks a challenge used once its login completed, returning ErrInvalidToken when it was
+// redeemed already
+func (s *Service) RedeemMFAChallenge(token string) error {
+	if s.tokens == nil {
+		return ErrInvalidToken
+	}
+	claims, err := s.tokens.ParseLink(token, purposeMFAChallenge)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return ErrInvalidToken
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, expiresAt := range s.redeemedChallenges {
+		if now.After(expiresAt) {
+			delete(s.redeemedChallenges, id)
+		}
+	}
+	if _, ok := s.redeemedChallenges[jti]; ok {
+		return ErrInvalidToken
+	}
+	s.redeemedChallenges[jti] = now.Add(mfaChallengeTTL)
+	return nil
+}
+
+func (s *Service) challengeRedeemed(jti string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.redeemedChallenges[jti]
+	return ok
+}
+
+// mfaRequired reports whether a user holds a role that needs a second factor
+func (s *Service) mfaRequired(ctx context.Context, userID string) (bool, error) {
+	if len(s.mfaRoles) == 0 {
+		return false, nil
+	}
+	roles, err := s.users.Roles(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		if slices.Contains(s.mfaRoles, role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mfaEnabled reports whether a user has confirmed a second factor
+func (s *Service) mfaEnabled(ctx context.Context, userID string) (bool, error) {
+	m, err := s.users.GetMFA(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return m.EnabledAt.Valid, nil
+}
+
+// newRecoveryCodes returns codes formatted like abcde-fghij and their hashes
+func newRecoveryCodes() ([]string, [][]byte) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([][]byte, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		text := strings.ToLower(rand.Text())
+		code := text[:5] + "-" + text[5:10]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, which users may type differently
+func hashRecoveryCode(code string) []byte {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return sum[:]
+}
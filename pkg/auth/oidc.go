@@ -0,0 +1,466 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	// keys of a provider are fetched again for an unknown kid at most this often
+	oidcKeysMinRefresh = time.Minute
+	oidcLoginTTL       = 10 * time.Minute
+	purposeOIDCLogin   = "oidc_login"
+	// OIDCCodeTTL bounds how long the page a login returns to has to exchange its code
+	OIDCCodeTTL     = time.Minute
+	purposeOIDCCode = "oidc_code"
+)
+
+var (
+	// the provider refused the login or its ID token was not valid
+	ErrOIDCLoginFailed = errors.New("identity provider login failed")
+	// the provider could not be reached or answered with an unexpected response
+	ErrOIDCProviderUnavailable = errors.New("identity provider unavailable")
+)
+
+// OIDCConfig configures an OpenID Connect provider users can log in with
+type OIDCConfig struct {
+	// shown on the login button
+	Name string
+	// the issuer of the ID tokens, the discovery document is read from below it
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// the callback URL registered with the provider
+	RedirectURL string
+	// openid is always requested, email and profile when unset
+	Scopes []string
+}
+
+// OIDCIdentity is the provider account an ID token was issued for
+type OIDCIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCProvider logs users in with the authorization code flow and PKCE.
+// The discovery document and the keys of the provider are fetched on first use and cached.
+type OIDCProvider struct {
+	id     string
+	config OIDCConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+func NewOIDCProvider(id string, config OIDCConfig) (*OIDCProvider, error) {
+	if id == "" || config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC provider needs an id, issuer URL, client id and redirect URL")
+	}
+	if config.Name == "" {
+		config.Name = id
+	}
+	return &OIDCProvider{
+		id:     id,
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *OIDCProvider) ID() string {
+	return p.id
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the URL of the provider to send the user to for login
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, login *OIDCLogin) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := []string{"openid", "email", "profile"}
+	if len(p.config.Scopes) > 0 {
+		scopes = append([]string{"openid"}, p.config.Scopes...)
+	}
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {login.State},
+		"nonce":                 {login.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange redeems the code the provider redirected back with and verifies the ID token it returns
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, login *OIDCLogin) (*OIDCIdentity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {login.Verifier},
+	}
+	// client_secret_basic is the default when the provider does not list its methods
+	basic := len(d.TokenAuthMethods) == 0
+	for _, m := range d.TokenAuthMethods {
+		basic = basic || m == "client_secret_basic"
+	}
+	if !basic || p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+		if p.config.ClientSecret != "" {
+			form.Set("client_secret", p.config.ClientSecret)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic && p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCProviderUnavailable, err)
+	}
+	defer res.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: token response: %v", ErrOIDCProviderUnavailable, err)
+	}
+	if res.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: token endpoint returned %d", ErrOIDCProviderUnavailable, res.StatusCode)
+	}
+	if res.StatusCode != http.StatusOK || body.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrOIDCLoginFailed, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%w: no ID token", ErrOIDCLoginFailed)
+	}
+	return p.verifyIDToken(ctx, d, body.IDToken, login.Nonce)
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, d *oidcDiscovery, idToken, nonce string) (*OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(
+		idToken,
+		claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.key(ctx, d, kid)
+		},
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", AlgorithmEdDSA}),
+	)
+	if err != nil {
+		if errors.Is(err, ErrOIDCProviderUnavailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	// the nonce ties the token to the login it was requested for, so it cannot be replayed
+	got, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCLoginFailed)
+	}
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.config.ClientID {
+			return nil, fmt.Errorf("%w: token authorized for another party", ErrOIDCLoginFailed)
+		}
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrOIDCLoginFailed)
+	}
+
+	identity := &OIDCIdentity{Provider: p.id, Subject: sub}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	// some providers send the flag as a string
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = v
+	case string:
+		identity.EmailVerified = v == "true"
+	}
+	return identity, nil
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	d := &oidcDiscovery{}
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.config.IssuerURL, "/")+"/.well-known/openid-configuration", d); err != nil {
+		return nil, err
+	}
+	// the issuer must be the one configured, or tokens of another issuer would be accepted
+	if d.Issuer != p.config.IssuerURL {
+		return nil, fmt.Errorf("%w: discovery document of issuer %q", ErrOIDCProviderUnavailable, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrOIDCProviderUnavailable)
+	}
+	p.discovery = d
+	return d, nil
+}
+
+// key returns the public key of the provider with the given kid, fetching the keys again when it is unknown
+func (p *OIDCProvider) key(ctx context.Context, d *oidcDiscovery, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKeyLocked(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcKeysMinRefresh {
+		return nil, ErrUnknownSigningKey
+	}
+
+	var set JWKS
+	if err := p.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	p.keys = make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			logger.Debugw("skipping key of identity provider", "provider", p.id, "kid", jwk.KeyID, "error", err)
+			continue
+		}
+		p.keys[jwk.KeyID] = key
+	}
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKeyLocked(kid); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownSigningKey
+}
+
+// lookupKeyLocked finds a key by kid, tokens without a kid are accepted when the provider has a single key
+func (p *OIDCProvider) lookupKeyLocked(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOIDCProviderUnavailable, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %d", ErrOIDCProviderUnavailable, u, res.StatusCode)
+	}
+	if err = json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrOIDCProviderUnavailable, u, err)
+	}
+	return nil
+}
+
+// publicKey decodes the RSA, EC or Ed25519 key of a JWK
+func (k JWK) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) ([]byte, error) {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// OIDCLogin is a login in progress. It is kept signed in a cookie while the user is at the provider,
+// binding the callback to the browser that started the login.
+type OIDCLogin struct {
+	Provider string
+	State    string
+	Nonce    string
+	// the PKCE code verifier, the provider only saw its hash
+	Verifier string
+	// the path to send the user to after logging in
+	ReturnTo string
+}
+
+func NewOIDCLogin(provider, returnTo string) (*OIDCLogin, error) {
+	login := &OIDCLogin{Provider: provider, ReturnTo: returnTo}
+	for _, v := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		token, _, err := newAccountToken()
+		if err != nil {
+			return nil, err
+		}
+		*v = token
+	}
+	return login, nil
+}
+
+// Seal signs the login for the cookie, OpenOIDCLogin accepts it for 10 minutes
+func (l *OIDCLogin) Seal(tokens *TokenGenerator) (string, error) {
+	return tokens.GenerateLink("", purposeOIDCLogin, l.State, oidcLoginTTL, jwt.MapClaims{
+		"provider": l.Provider,
+		"nonce":    l.Nonce,
+		"verifier": l.Verifier,
+		"returnTo": l.ReturnTo,
+	})
+}
+
+func OpenOIDCLogin(tokens *TokenGenerator, sealed string) (*OIDCLogin, error) {
+	claims, err := tokens.ParseLink(sealed, purposeOIDCLogin)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	login := &OIDCLogin{}
+	login.State, _ = claims["jti"].(string)
+	login.Provider, _ = claims["provider"].(string)
+	login.Nonce, _ = claims["nonce"].(string)
+	login.Verifier, _ = claims["verifier"].(string)
+	login.ReturnTo, _ = claims["returnTo"].(string)
+	return login, nil
+}
+
+// SealOIDCCode creates the one-time code handing a finished login over to the page it returns to. The code goes in
+// the URL, sealed goes in a cookie binding it to the browser and the user, so that no token is ever put in a URL.
+func SealOIDCCode(tokens *TokenGenerator, userID string) (code, sealed string, err error) {
+	code, _, err = newAccountToken()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err = tokens.GenerateLink(userID, purposeOIDCCode, code, OIDCCodeTTL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	return code, sealed, nil
+}
+
+// OpenOIDCCode returns the user a code was sealed for, checking it is the code of the cookie
+func OpenOIDCCode(tokens *TokenGenerator, sealed, code string) (string, error) {
+	claims, err := tokens.ParseLink(sealed, purposeOIDCCode)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	jti, _ := claims["jti"].(string)
+	sub, _ := claims["sub"].(string)
+	if code == "" || jti != code || sub == "" {
+		return "", ErrInvalidToken
+	}
+	return sub, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+var (
+	ErrEmailNotVerified = errors.New("email address not verified")
+	ErrUnknownAccount   = errors.New("unknown account")
+)
+
+// Action is something an account does that a Policy may forbid
+type Action string
+
+const (
+	ActionGoLive Action = "go_live"
+	ActionChat   Action = "chat"
+	// ActionModerate is muting and banning in the chat of a room the account does not own
+	ActionModerate Action = "moderate"
+	// ActionRevokeKeys is listing and revoking the stream keys of another account
+	ActionRevokeKeys Action = "revoke_keys"
+)
+
+// Policy decides whether an account may take an action, returning the reason when it may not
+type Policy interface {
+	Authorize(ctx context.Context, userID string, action Action) error
+}
+
+// actionPermissions are the permissions a role needs for an action
+var actionPermissions = map[Action]string{
+	ActionGoLive:     storage.PermissionGoLive,
+	ActionChat:       storage.PermissionChat,
+	ActionModerate:   storage.PermissionModerate,
+	ActionRevokeKeys: storage.PermissionRevokeKeys,
+}
+
+// Authorize implements Policy, accounts have to verify their email before going live or chatting
+// and hold a role with the permission for it. Suspended accounts may do neither.
+// Accounts whose roles require it also need a second factor to go live.
+func (s *Service) Authorize(ctx context.Context, userID string, action Action) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUnknownAccount
+	} else if err != nil {
+		return err
+	}
+	if user.SuspendedAt.Valid {
+		return ErrAccountSuspended
+	}
+	switch action {
+	case ActionGoLive, ActionChat:
+		if !user.EmailVerifiedAt.Valid {
+			return ErrEmailNotVerified
+		}
+	}
+	if permission, ok := actionPermissions[action]; ok {
+		allowed, err := s.users.HasPermission(ctx, userID, permission)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrPermissionDenied
+		}
+	}
+	if action == ActionGoLive {
+		return s.CheckMFA(ctx, userID)
+	}
+	return nil
+}
+
+// CheckMFA returns ErrMFARequired when the roles of a user require a second factor they have not set up
+func (s *Service) CheckMFA(ctx context.Context, userID string) error {
+	required, err := s.mfaRequired(ctx, userID)
+	if err != nil || !required {
+		return err
+	}
+	enabled, err := s.mfaEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrMFARequired
+	}
+	return nil
+}
@@ -4,6 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
 	"github.com/livekit/livekit-server/pkg/storage"
 )
 
@@ -11,13 +17,65 @@ var ErrInvalidCredentials = errors.New("invalid credentials")
 
 type Service struct {
 	users *storage.UserRepository
+
+	mailer   Mailer
+	baseURL  string
+	tokens   *TokenGenerator
+	sessions *SessionManager
+	// shown in authenticator apps
+	mfaIssuer string
+	// roles that need a second factor to go live
+	mfaRoles []string
+	auditor  Auditor
+	// called after an account is deleted to remove data kept outside the user store
+	onDelete []func(ctx context.Context, userID string)
+	// called after an account is suspended
+	onSuspend []func(ctx context.Context, userID string)
+
+	// MFA challenges completed already, until they expire
+	mu                 sync.Mutex
+	redeemedChallenges map[string]time.Time
 }
 
 func NewService(users *storage.UserRepository) *Service {
-	return &Service{users: users}
+	return &Service{
+		users:              users,
+		mailer:             logMailer{},
+		auditor:            LogAuditor{},
+		redeemedChallenges: make(map[string]time.Time),
+	}
 }
 
+// SetMailer sets the mailer for account emails, baseURL is prepended to the links they contain
+func (s *Service) SetMailer(mailer Mailer, baseURL string) {
+	s.mailer = mailer
+	s.baseURL = baseURL
+}
+
+// SetTokenGenerator sets the generator signing the email verification links
+func (s *Service) SetTokenGenerator(tokens *TokenGenerator) {
+	s.tokens = tokens
+}
+
+// SetSessions sets the login sessions to end when a password changes or an account is deleted
+func (s *Service) SetSessions(sessions *SessionManager) {
+	s.sessions = sessions
+}
+
+// OnAccountDeleted registers a callback run after an account is deleted
+func (s *Service) OnAccountDeleted(f func(ctx context.Context, userID string)) {
+	s.onDelete = append(s.onDelete, f)
+}
+
+// Register creates an unverified account and emails it a verification link
 func (s *Service) Register(ctx context.Context, email, password, displayName string) (*storage.User, error) {
+	email = strings.TrimSpace(email)
+	if !validEmail(email) {
+		return nil, ErrInvalidEmail
+	}
+	if len(password) < minPasswordLength {
+		return nil, ErrWeakPassword
+	}
 	hash, err := HashPassword(password)
 	if err != nil {
 		return nil, err
@@ -30,16 +88,29 @@ func (s *Service) Register(ctx context.Context, email, password, displayName str
 	if err := s.users.CreateUser(ctx, user); err != nil {
 		return nil, err
 	}
+	// the account is usable without verification, the link can be sent again
+	if err = s.sendVerification(ctx, user); err != nil {
+		logger.Errorw("could not send verification email", err, "userID", user.ID)
+	}
 	return user, nil
 }
 
+// Login checks the credentials of a user. Unknown emails take as long as wrong passwords
+// and return ErrInvalidCredentials too, so responses do not tell which emails have accounts.
+// Suspended accounts get ErrAccountSuspended once their password is right.
 func (s *Service) Login(ctx context.Context, email, password string) (*storage.User, error) {
 	user, err := s.users.GetByEmail(ctx, email)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = CheckPassword(dummyPasswordHash(), password)
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
 		return nil, err
 	}
 	if err := CheckPassword(user.PasswordHash, password); err != nil {
 		return nil, ErrInvalidCredentials
 	}
+	if user.SuspendedAt.Valid {
+		return nil, ErrAccountSuspended
+	}
 	return user, nil
 }
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// ErrRefreshTokenReused is returned when a refresh token that was already rotated is presented again,
+// which means it leaked; the session is revoked
+var ErrRefreshTokenReused = errors.New("refresh token reused, session revoked")
+
+type sessionIDContextKey struct{}
+
+// WithSessionID returns a context carrying the login session of the request
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDContextKey{}, sessionID)
+}
+
+// SessionIDFromContext returns the login session of the request, if its access token is bound to one
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(sessionIDContextKey{}).(string)
+	return sessionID, ok && sessionID != ""
+}
+
+// Device describes where a session was started or refreshed from
+type Device struct {
+	UserAgent string
+	IPAddress string
+}
+
+// TokenPair is a short-lived access token and the refresh token to get the next one
+type TokenPair struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// SessionManager issues access tokens for login sessions kept alive with rotating refresh tokens.
+// Refresh tokens are "<session id>.<secret>", only their hash is stored.
+type SessionManager struct {
+	sessions   *storage.SessionRepository
+	tokens     *TokenGenerator
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	auditor    Auditor
+}
+
+func NewSessionManager(sessions *storage.SessionRepository, tokens *TokenGenerator) *SessionManager {
+	return &SessionManager{
+		sessions:   sessions,
+		tokens:     tokens,
+		accessTTL:  DefaultAccessTokenTTL,
+		refreshTTL: DefaultRefreshTokenTTL,
+		auditor:    LogAuditor{},
+	}
+}
+
+// SetTTLs overrides the lifetimes of access tokens and of idle sessions
+func (m *SessionManager) SetTTLs(accessTTL, refreshTTL time.Duration) {
+	m.accessTTL = accessTTL
+	m.refreshTTL = refreshTTL
+}
+
+// SetAuditor sets where reuse of rotated refresh tokens is reported
+func (m *SessionManager) SetAuditor(auditor Auditor) {
+	m.auditor = auditor
+}
+
+// Start opens a session for a user who just logged in
+func (m *SessionManager) Start(ctx context.Context, userID string, device Device) (*TokenPair, error) {
+	session := &storage.Session{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		UserAgent: device.UserAgent,
+		IPAddress: device.IPAddress,
+		ExpiresAt: time.Now().Add(m.refreshTTL),
+	}
+	refreshToken, hash, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshTokenHash = hash
+	if err = m.sessions.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return m.issue(userID, session.ID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair, the presented refresh token stops being valid
+func (m *SessionManager) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	sessionID, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || sessionID == "" {
+		return nil, ErrInvalidToken
+	}
+	session, err := m.sessions.GetSession(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	if !session.Active(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+
+	hash := hashAccountToken(refreshToken)
+	if len(session.PreviousTokenHash) > 0 && subtle.ConstantTimeCompare(hash, session.PreviousTokenHash) == 1 {
+		audit(ctx, m.auditor, AuditEvent{
+			Type:    AuditRefreshTokenReused,
+			UserID:  session.UserID,
+			Details: map[string]any{"sessionID": session.ID},
+		})
+		if err = m.sessions.RevokeSession(ctx, session.UserID, session.ID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if subtle.ConstantTimeCompare(hash, session.RefreshTokenHash) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	next, nextHash, err := newRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = m.sessions.RotateSession(ctx, session.ID, hash, nextHash, time.Now().Add(m.refreshTTL))
+	if errors.Is(err, sql.ErrNoRows) {
+		// revoked or refreshed concurrently
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	return m.issue(session.UserID, session.ID, next)
+}
+
+// List returns the active sessions of a user
+func (m *SessionManager) List(ctx context.Context, userID string) ([]*storage.Session, error) {
+	return m.sessions.ListSessions(ctx, userID)
+}
+
+// Revoke ends a session of a user, access tokens issued for it are rejected from then on
+func (m *SessionManager) Revoke(ctx context.Context, userID, sessionID string) error {
+	return m.sessions.RevokeSession(ctx, userID, sessionID)
+}
+
+// RevokeOthers ends all sessions of a user except the one of the request, if any
+func (m *SessionManager) RevokeOthers(ctx context.Context, userID string) error {
+	current, _ := SessionIDFromContext(ctx)
+	_, err := m.sessions.RevokeUserSessions(ctx, userID, current)
+	return err
+}
+
+// RevokeAll ends all sessions of a user
+func (m *SessionManager) RevokeAll(ctx context.Context, userID string) error {
+	_, err := m.sessions.RevokeUserSessions(ctx, userID, "")
+	return err
+}
+
+// Active reports whether a session can still be used, it is checked on every authorized request
+func (m *SessionManager) Active(ctx context.Context, sessionID string) (bool, error) {
+	session, err := m.sessions.GetSession(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return session.Active(time.Now()), nil
+}
+
+// DeleteUserSessions removes all sessions of an account
+func (m *SessionManager) DeleteUserSessions(ctx context.Context, userID string) error {
+	return m.sessions.DeleteUserSessions(ctx, userID)
+}
+
+func (m *SessionManager) issue(userID, sessionID, refreshToken string) (*TokenPair, error) {
+	expiresAt := time.Now().Add(m.accessTTL)
+	accessToken, err := m.tokens.GenerateForSession(userID, sessionID, m.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		SessionID:    sessionID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// newRefreshToken returns a refresh token of a session and the hash to store
+func newRefreshToken(sessionID string) (string, []byte, error) {
+	secret, _, err := newAccountToken()
+	if err != nil {
+		return "", nil, err
+	}
+	token := sessionID + "." + secret
+	return token, hashAccountToken(token), nil
+}
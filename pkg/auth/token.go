@@ -1,50 +1,106 @@
 package auth
 
 import (
-    "fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type TokenGenerator struct {
-	issuer     string
-	signingKey []byte
+	issuer string
+	keys   *KeySet
 }
 
-func NewTokenGenerator(issuer, key string) *TokenGenerator {
-	return &TokenGenerator{issuer: issuer, signingKey: []byte(key)}
+// NewTokenGenerator signs tokens with the signing key of keys, tokens carry its id in the kid header
+func NewTokenGenerator(issuer string, keys *KeySet) *TokenGenerator {
+	return &TokenGenerator{issuer: issuer, keys: keys}
+}
+
+// Keys returns the key set tokens are signed and verified with
+func (t *TokenGenerator) Keys() *KeySet {
+	return t.keys
 }
 
 func (t *TokenGenerator) Generate(userID string, ttl time.Duration) (string, error) {
+	return t.GenerateForSession(userID, "", ttl)
+}
+
+// GenerateForSession issues an access token bound to a login session, it stops being accepted once the session is revoked
+func (t *TokenGenerator) GenerateForSession(userID, sessionID string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": userID,
 		"iss": t.issuer,
 		"exp": time.Now().Add(ttl).Unix(),
 		"iat": time.Now().Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(t.signingKey)
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+	return t.sign(claims)
+}
+
+func (t *TokenGenerator) sign(claims jwt.MapClaims) (string, error) {
+	key := t.keys.Signing()
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
 }
 
 // Parse validates a token string and returns its claims when valid.
+// The key is selected by the kid header, tokens of a rotated key are accepted until it is retired.
 func (t *TokenGenerator) Parse(tokenString string) (jwt.MapClaims, error) {
     claims := jwt.MapClaims{}
     _, err := jwt.ParseWithClaims(
         tokenString,
         claims,
-        func(token *jwt.Token) (interface{}, error) {
-            if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-                return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-            }
-            return t.signingKey, nil
-        },
+        t.keys.keyFunc,
         jwt.WithIssuer(t.issuer),
         jwt.WithLeeway(30*time.Second),
-        jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+        jwt.WithValidMethods([]string{AlgorithmEdDSA, AlgorithmRS256}),
     )
     if err != nil {
         return nil, err
     }
+    // link tokens are not access tokens
+    if _, ok := claims["aud"]; ok {
+        return nil, ErrInvalidToken
+    }
     return claims, nil
 }
+
+// GenerateLink issues a token for a link emailed to a user, only ParseLink with the same purpose accepts it.
+// id identifies the token so it can be used once.
+func (t *TokenGenerator) GenerateLink(userID, purpose, id string, ttl time.Duration, extra jwt.MapClaims) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"iss": t.issuer,
+		"aud": purpose,
+		"jti": id,
+		"exp": time.Now().Add(ttl).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	for k, v := range extra {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+	return t.sign(claims)
+}
+
+// ParseLink validates a link token issued for purpose and returns its claims
+func (t *TokenGenerator) ParseLink(tokenString, purpose string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		t.keys.keyFunc,
+		jwt.WithIssuer(t.issuer),
+		jwt.WithAudience(purpose),
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods([]string{AlgorithmEdDSA, AlgorithmRS256}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+const (
+	verificationTTL  = 48 * time.Hour
+	passwordResetTTL = time.Hour
+
+	// path of the link sent to verify the email of a new account
+	EmailVerifyPath = "/api/account/verify"
+	// page the password reset link opens, it posts the token and the new password to the reset API
+	PasswordResetPagePath = "/auth/auth.html"
+)
+
+var ErrAlreadyVerified = errors.New("email already verified")
+
+// sendVerification emails a signed single use link to verify the email of a user, replacing earlier links
+func (s *Service) sendVerification(ctx context.Context, user *storage.User) error {
+	if s.tokens == nil {
+		logger.Warnw("no token generator configured, verification email not sent", nil, "userID", user.ID)
+		return nil
+	}
+
+	id, hash, err := newAccountToken()
+	if err != nil {
+		return err
+	}
+	if err = s.users.DeleteAccountTokens(ctx, user.ID, storage.TokenPurposeVerifyEmail); err != nil {
+		return err
+	}
+	if err = s.users.CreateAccountToken(ctx, user.ID, storage.TokenPurposeVerifyEmail, hash, time.Now().Add(verificationTTL)); err != nil {
+		return err
+	}
+	// bound to the address, so the link stops working when the email changes
+	token, err := s.tokens.GenerateLink(user.ID, storage.TokenPurposeVerifyEmail, id, verificationTTL, jwt.MapClaims{"email": user.Email})
+	if err != nil {
+		return err
+	}
+
+	link := s.baseURL + EmailVerifyPath + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Welcome! Verify your email address by opening this link within %s:\n\n%s\n\n"+
+		"Until then you cannot go live or chat. If you did not sign up, you can ignore this email.", verificationTTL, link)
+	return s.mailer.SendMail(ctx, user.Email, "Verify your email address", body)
+}
+
+// ResendVerification sends a new verification link to a user who has not verified their email yet
+func (s *Service) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt.Valid {
+		return ErrAlreadyVerified
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// VerifyEmail marks the email of the user a verification link was sent to as verified, each link works once
+func (s *Service) VerifyEmail(ctx context.Context, token string) (*storage.User, error) {
+	if s.tokens == nil || token == "" {
+		return nil, ErrInvalidToken
+	}
+	claims, err := s.tokens.ParseLink(token, storage.TokenPurposeVerifyEmail)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	id, _ := claims["jti"].(string)
+	sub, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+
+	userID, err := s.users.ConsumeAccountToken(ctx, storage.TokenPurposeVerifyEmail, hashAccountToken(id))
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, storage.ErrTokenExpired) {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	if userID != sub {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, email) {
+		return nil, ErrInvalidToken
+	}
+	if !user.EmailVerifiedAt.Valid {
+		if err = s.users.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return user, nil
+}
+
+// RequestPasswordReset emails a password reset link if email belongs to an account.
+// It succeeds either way, so it does not reveal which emails have accounts.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	token, hash, err := newAccountToken()
+	if err != nil {
+		return err
+	}
+	if err = s.users.DeleteAccountTokens(ctx, user.ID, storage.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+	if err = s.users.CreateAccountToken(ctx, user.ID, storage.TokenPurposePasswordReset, hash, time.Now().Add(passwordResetTTL)); err != nil {
+		return err
+	}
+
+	link := s.baseURL + PasswordResetPagePath + "?reset_token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Choose a new password for your account by opening this link within %s:\n\n%s\n\n"+
+		"If you did not ask to reset your password, you can ignore this email.", passwordResetTTL, link)
+	return s.mailer.SendMail(ctx, user.Email, "Reset your password", body)
+}
+
+// ResetPassword sets a new password with a reset token and ends all sessions of the account, returning the account
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) (*storage.User, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+	if len(newPassword) < minPasswordLength {
+		return nil, ErrWeakPassword
+	}
+	userID, err := s.users.ConsumeAccountToken(ctx, storage.TokenPurposePasswordReset, hashAccountToken(token))
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, storage.ErrTokenExpired) {
+		return nil, ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.users.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return nil, err
+	}
+	// the link reached the inbox, which proves the address as well as a verification link does
+	if !user.EmailVerifiedAt.Valid {
+		if err = s.users.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	if s.sessions != nil {
+		if err = s.sessions.RevokeAll(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/mime"
 	"github.com/livekit/livekit-server/pkg/sfu/pacer"
 	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
+	"github.com/livekit/livekit-server/pkg/streaming"
 	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
@@ -83,6 +84,9 @@ type Config struct {
 	Metric metric.MetricConfig `yaml:"metric,omitempty"`
 
 	NodeStats NodeStatsConfig `yaml:"node_stats,omitempty"`
+
+	Streaming streaming.Config `yaml:"streaming,omitempty"`
+	AppAuth   AppAuthConfig    `yaml:"app_auth,omitempty"`
 }
 
 type RTCConfig struct {
@@ -125,6 +129,10 @@ type RTCConfig struct {
 	DatachannelSlowThreshold int `yaml:"datachannel_slow_threshold,omitempty"`
 
 	ForwardStats ForwardStatsConfig `yaml:"forward_stats,omitempty"`
+
+	// addresses or CIDR ranges of proxies whose X-Forwarded-For header is trusted for client addresses,
+	// when empty forwarding headers are taken as they are
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
 }
 
 type TURNServer struct {
@@ -299,6 +307,70 @@ type PrometheusConfig struct {
 	Password string `yaml:"password,omitempty"`
 }
 
+// AppAuthConfig configures the tokens of app user accounts, which are signed with their own keys
+// rather than the API keys of rooms
+type AppAuthConfig struct {
+	Issuer string `yaml:"issuer,omitempty"`
+	// the first key signs new tokens, the others only verify tokens issued before it was rotated in
+	SigningKeys []SigningKeyConfig `yaml:"signing_keys,omitempty"`
+	// without signing keys, a key of this algorithm (EdDSA or RS256) is generated at startup in development mode
+	// and rotated every RotationInterval. Generated keys do not survive restarts. Required otherwise.
+	Algorithm        string        `yaml:"algorithm,omitempty"`
+	RotationInterval time.Duration `yaml:"rotation_interval,omitempty"`
+	// how long a generated key keeps verifying tokens after it was rotated out
+	RotationOverlap time.Duration `yaml:"rotation_overlap,omitempty"`
+	// throttling of password guessing, shared between nodes through Redis when it is configured
+	Login LoginLimitConfig `yaml:"login,omitempty"`
+	// OpenID Connect providers users can log in with instead of a password
+	OIDCProviders []OIDCProviderConfig `yaml:"oidc_providers,omitempty"`
+	// TOTP two-factor authentication
+	MFA MFAConfig `yaml:"mfa,omitempty"`
+}
+
+type MFAConfig struct {
+	// the issuer authenticator apps list the account under
+	Issuer string `yaml:"issuer,omitempty"`
+	// users holding any of these roles cannot go live without a second factor
+	RequiredRoles []string `yaml:"required_roles,omitempty"`
+}
+
+type OIDCProviderConfig struct {
+	// used in the login and callback paths, /api/auth/oidc/<id>/login
+	ID string `yaml:"id,omitempty"`
+	// shown on the login button, defaults to the id
+	Name      string `yaml:"name,omitempty"`
+	IssuerURL string `yaml:"issuer_url,omitempty"`
+	ClientID  string `yaml:"client_id,omitempty"`
+	// may be empty for public clients, PKCE protects the code either way
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	// the callback URL registered with the provider, https://<host>/api/auth/oidc/<id>/callback
+	RedirectURL string `yaml:"redirect_url,omitempty"`
+	// requested besides openid, defaults to email and profile
+	Scopes []string `yaml:"scopes,omitempty"`
+}
+
+// LoginLimitConfig limits login attempts, a zero count disables its limit
+type LoginLimitConfig struct {
+	// login and signup attempts per IP address within IPWindow
+	IPAttempts int           `yaml:"ip_attempts,omitempty"`
+	IPWindow   time.Duration `yaml:"ip_window,omitempty"`
+	// failed logins of an account within FailureWindow before it is locked
+	MaxFailures   int           `yaml:"max_failures,omitempty"`
+	FailureWindow time.Duration `yaml:"failure_window,omitempty"`
+	// the first lockout, doubled with every further failure up to LockoutMax
+	LockoutBase time.Duration `yaml:"lockout_base,omitempty"`
+	LockoutMax  time.Duration `yaml:"lockout_max,omitempty"`
+}
+
+type SigningKeyConfig struct {
+	// sent as the kid header of tokens
+	ID string `yaml:"id,omitempty"`
+	// PEM encoded Ed25519 or RSA private key
+	KeyFile string `yaml:"key_file,omitempty"`
+	// a key that no longer signs verifies tokens until then, or as long as it is configured when unset
+	RetireAt time.Time `yaml:"retire_at,omitempty"`
+}
+
 type ForwardStatsConfig struct {
 	SummaryInterval time.Duration `yaml:"summary_interval,omitempty"`
 	ReportInterval  time.Duration `yaml:"report_interval,omitempty"`
@@ -410,6 +482,22 @@ var DefaultConfig = Config{
 	Metric:    metric.DefaultMetricConfig,
 	WebHook:   webhook.DefaultWebHookConfig,
 	NodeStats: DefaultNodeStatsConfig,
+	AppAuth: AppAuthConfig{
+		Issuer:          "livekit-local",
+		Algorithm:       "EdDSA",
+		RotationOverlap: time.Hour,
+		Login: LoginLimitConfig{
+			IPAttempts:    30,
+			IPWindow:      time.Minute,
+			MaxFailures:   5,
+			FailureWindow: time.Hour,
+			LockoutBase:   30 * time.Second,
+			LockoutMax:    15 * time.Minute,
+		},
+		MFA: MFAConfig{
+			Issuer: "LiveKit",
+		},
+	},
 }
 
 func NewConfig(confString string, strictMode bool, c *cli.Command, baseFlags []cli.Flag) (*Config, error) {
@@ -442,6 +530,9 @@ func NewConfig(confString string, strictMode bool, c *cli.Command, baseFlags []c
 	if err := conf.RTC.Validate(conf.Development); err != nil {
 		return nil, fmt.Errorf("could not validate RTC config: %v", err)
 	}
+	if _, err := streaming.ParseTrustedProxies(conf.RTC.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("could not validate RTC config: %v", err)
+	}
 
 	// expand env vars in filenames
 	file, err := homedir.Expand(os.ExpandEnv(conf.KeyFile))
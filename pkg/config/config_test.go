@@ -48,6 +48,17 @@ room:
 	require.Error(t, err)
 }
 
+func TestConfig_TrustedProxies(t *testing.T) {
+	conf, err := NewConfig(`rtc:
+  trusted_proxies: [10.0.0.0/8, 192.168.1.10]`, true, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.0/8", "192.168.1.10"}, conf.RTC.TrustedProxies)
+
+	_, err = NewConfig(`rtc:
+  trusted_proxies: [proxy.local]`, true, nil, nil)
+	require.Error(t, err)
+}
+
 func TestGeneratedFlags(t *testing.T) {
 	generatedFlags, err := GenerateCLIFlags(nil, false)
 	require.NoError(t, err)
@@ -0,0 +1,317 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+const maxAccountRequestSize = 16 << 10
+
+type profileResponse struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PendingEmail string    `json:"pendingEmail,omitempty"`
+	Verified     bool      `json:"emailVerified"`
+	DisplayName  string    `json:"displayName"`
+	AvatarURL    string    `json:"avatarUrl"`
+	Bio          string    `json:"bio"`
+	ChannelSlug  string    `json:"channelSlug"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// AccountHandler serves the profile and account management endpoints of the logged in user
+type AccountHandler struct {
+	service *auth.Service
+	users   *storage.UserRepository
+	guard   *auth.LoginGuard
+}
+
+// NewAccountHandler throttles password resets with the default limits kept in memory until SetLoginGuard is called
+func NewAccountHandler(service *auth.Service, users *storage.UserRepository) *AccountHandler {
+	return &AccountHandler{
+		service: service,
+		users:   users,
+		guard:   auth.NewLoginGuard(auth.NewMemoryAttemptStore(), auth.DefaultLoginLimits),
+	}
+}
+
+// SetLoginGuard replaces the limits on password resets, usually with the guard of the login endpoints
+func (h *AccountHandler) SetLoginGuard(guard *auth.LoginGuard) {
+	h.guard = guard
+}
+
+// Profile returns the profile of the user on GET and updates it on PATCH
+func (h *AccountHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		user, err := h.users.GetByID(r.Context(), userID)
+		if err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		writeProfile(w, user)
+
+	case http.MethodPatch, http.MethodPut:
+		var update auth.ProfileUpdate
+		if !decodeAccountRequest(w, r, &update) {
+			return
+		}
+		user, err := h.service.UpdateProfile(r.Context(), userID, update)
+		if err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		writeProfile(w, user)
+
+	default:
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ChangePassword replaces the password of the user, the current one is required
+func (h *AccountHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedPost(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	if err := h.service.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeEmail sends a confirmation link to the new address, the email changes once it is followed
+func (h *AccountHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedPost(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+		Email    string `json:"email"`
+	}
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	if err := h.service.RequestEmailChange(r.Context(), userID, req.Password, req.Email); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmEmail applies an email change. It is the target of the emailed link, so it needs no login.
+func (h *AccountHandler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, err := h.service.ConfirmEmailChange(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeProfile(w, user)
+}
+
+// VerifyEmail verifies the email of a new account. It is the target of the emailed link, so it needs no login.
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, err := h.service.VerifyEmail(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeProfile(w, user)
+}
+
+// ResendVerification sends a new verification link to the user
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedPost(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.ResendVerification(r.Context(), userID); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// RequestPasswordReset emails a reset link. The response is the same whether or not the email has an account.
+func (h *AccountHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Email string `json:"email"`
+	}
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	if err := h.guard.AllowPasswordReset(r.Context(), remoteIP(r), req.Email); err != nil {
+		writeThrottled(w, err)
+		return
+	}
+	if err := h.service.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with the token of a reset link. Wrong tokens count against the IP address,
+// the account is only known once the token is used, which clears its failed logins.
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	ip := remoteIP(r)
+	if err := h.guard.Allow(r.Context(), ip, ""); err != nil {
+		writeThrottled(w, err)
+		return
+	}
+	user, err := h.service.ResetPassword(r.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			h.guard.Failed(r.Context(), ip, "")
+		}
+		writeAccountError(w, err)
+		return
+	}
+	h.guard.Succeeded(r.Context(), user.Email)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAccount removes the account of the user after checking their password
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+	}
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	if err := h.service.DeleteAccount(r.Context(), userID, req.Password); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AccountHandler) authorizedPost(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, "unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return "", false
+	}
+	return userID, true
+}
+
+func decodeAccountRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAccountRequestSize)).Decode(v); err != nil {
+		writeError(w, "invalid body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		writeError(w, "invalid password", http.StatusForbidden)
+	case errors.Is(err, auth.ErrInvalidProfile),
+		errors.Is(err, auth.ErrInvalidEmail),
+		errors.Is(err, auth.ErrWeakPassword),
+		errors.Is(err, auth.ErrInvalidToken):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, auth.ErrInvalidMFACode),
+		errors.Is(err, auth.ErrAccountSuspended),
+		errors.Is(err, auth.ErrPermissionDenied):
+		writeError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, auth.ErrUnknownRole), errors.Is(err, auth.ErrSelfAdministration):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, auth.ErrAlreadyVerified),
+		errors.Is(err, auth.ErrMFAAlreadyEnabled),
+		errors.Is(err, auth.ErrMFANotEnabled):
+		writeError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, storage.ErrEmailTaken), errors.Is(err, storage.ErrChannelSlugTaken):
+		writeError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, "user not found", http.StatusNotFound)
+	default:
+		logger.Errorw("account request failed", err)
+		writeError(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func writeProfile(w http.ResponseWriter, user *storage.User) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(profileResponse{
+		ID:           user.ID,
+		Email:        user.Email,
+		PendingEmail: user.PendingEmail.String,
+		Verified:     user.EmailVerifiedAt.Valid,
+		DisplayName:  user.DisplayName.String,
+		AvatarURL:    user.AvatarURL.String,
+		Bio:          user.Bio.String,
+		ChannelSlug:  user.ChannelSlug.String,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+	})
+}
+
+func writeError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+	})
+}
@@ -0,0 +1,195 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+type testMailer struct {
+	to, body string
+}
+
+func (m *testMailer) SendMail(_ context.Context, to, _, body string) error {
+	m.to, m.body = to, body
+	return nil
+}
+
+func TestAccountAPI(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.db")
+
+	// a database created before profiles existed is migrated when opened
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE users (
+  id TEXT PRIMARY KEY,
+  email TEXT NOT NULL UNIQUE,
+  password_hash BLOB NOT NULL,
+  display_name TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := storage.NewDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	users := storage.NewUserRepository(db)
+	service := auth.NewService(users)
+	mailer := &testMailer{}
+	service.SetMailer(mailer, "https://example.com")
+	var deleted []string
+	service.OnAccountDeleted(func(_ context.Context, userID string) { deleted = append(deleted, userID) })
+
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	tokens := auth.NewTokenGenerator("test", auth.NewKeySet(key))
+	middleware := handler.NewAuthMiddleware(tokens)
+	accounts := handler.NewAccountHandler(service, users)
+	mux := http.NewServeMux()
+	mux.Handle("/api/profile", middleware.Authorize(http.HandlerFunc(accounts.Profile)))
+	mux.Handle("/api/account", middleware.Authorize(http.HandlerFunc(accounts.DeleteAccount)))
+	mux.Handle("/api/account/password", middleware.Authorize(http.HandlerFunc(accounts.ChangePassword)))
+	mux.Handle("/api/account/email", middleware.Authorize(http.HandlerFunc(accounts.ChangeEmail)))
+	mux.HandleFunc(auth.EmailConfirmPath, accounts.ConfirmEmail)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	alice, err := service.Register(ctx, "alice@example.com", "password1", "Alice")
+	require.NoError(t, err)
+	bob, err := service.Register(ctx, "bob@example.com", "password2", "Bob")
+	require.NoError(t, err)
+
+	do := func(method, path string, user *storage.User, body any) *http.Response {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &payload)
+		require.NoError(t, err)
+		if user != nil {
+			token, err := tokens.Generate(user.ID, time.Hour)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+	profile := func(res *http.Response) map[string]any {
+		var p map[string]any
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&p))
+		return p
+	}
+
+	t.Run("profile", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/profile", nil, nil).StatusCode)
+
+		res := do(http.MethodPatch, "/api/profile", alice, map[string]string{
+			"bio":         "  streaming every night  ",
+			"avatarUrl":   "https://cdn.example.com/alice.png",
+			"channelSlug": "Alice_Live",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		p := profile(res)
+		require.Equal(t, "Alice", p["displayName"])
+		require.Equal(t, "streaming every night", p["bio"])
+		require.Equal(t, "alice_live", p["channelSlug"])
+
+		// untouched fields keep their values
+		res = do(http.MethodPatch, "/api/profile", alice, map[string]string{"displayName": "Alice L."})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		p = profile(do(http.MethodGet, "/api/profile", alice, nil))
+		require.Equal(t, "Alice L.", p["displayName"])
+		require.Equal(t, "https://cdn.example.com/alice.png", p["avatarUrl"])
+
+		require.Equal(t, http.StatusConflict, do(http.MethodPatch, "/api/profile", bob, map[string]string{"channelSlug": "alice_live"}).StatusCode)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPatch, "/api/profile", bob, map[string]string{"channelSlug": "a b"}).StatusCode)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPatch, "/api/profile", bob, map[string]string{"avatarUrl": "javascript:alert(1)"}).StatusCode)
+
+		owner, err := users.GetByChannelSlug(ctx, "alice_live")
+		require.NoError(t, err)
+		require.Equal(t, alice.ID, owner.ID)
+	})
+
+	t.Run("password", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, do(http.MethodPost, "/api/account/password", alice, map[string]string{
+			"currentPassword": "wrong", "newPassword": "password3",
+		}).StatusCode)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/account/password", alice, map[string]string{
+			"currentPassword": "password1", "newPassword": "short",
+		}).StatusCode)
+		require.Equal(t, http.StatusNoContent, do(http.MethodPost, "/api/account/password", alice, map[string]string{
+			"currentPassword": "password1", "newPassword": "password3",
+		}).StatusCode)
+
+		_, err := service.Login(ctx, "alice@example.com", "password1")
+		require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		_, err = service.Login(ctx, "alice@example.com", "password3")
+		require.NoError(t, err)
+	})
+
+	t.Run("email", func(t *testing.T) {
+		require.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/account/email", alice, map[string]string{
+			"password": "password3", "email": "bob@example.com",
+		}).StatusCode)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/account/email", alice, map[string]string{
+			"password": "password3", "email": "not an email",
+		}).StatusCode)
+		require.Equal(t, http.StatusAccepted, do(http.MethodPost, "/api/account/email", alice, map[string]string{
+			"password": "password3", "email": "alice@example.org",
+		}).StatusCode)
+
+		// the email only changes once the link is followed
+		require.Equal(t, "alice@example.org", mailer.to)
+		p := profile(do(http.MethodGet, "/api/profile", alice, nil))
+		require.Equal(t, "alice@example.com", p["email"])
+		require.Equal(t, "alice@example.org", p["pendingEmail"])
+
+		link := regexp.MustCompile(`https://example\.com\S+`).FindString(mailer.body)
+		require.NotEmpty(t, link)
+		parsed, err := url.Parse(link)
+		require.NoError(t, err)
+		require.Equal(t, auth.EmailConfirmPath, parsed.Path)
+
+		res := do(http.MethodGet, parsed.RequestURI(), nil, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		p = profile(res)
+		require.Equal(t, "alice@example.org", p["email"])
+		require.Nil(t, p["pendingEmail"])
+
+		// links are single use
+		require.Equal(t, http.StatusBadRequest, do(http.MethodGet, parsed.RequestURI(), nil, nil).StatusCode)
+		_, err = service.Login(ctx, "alice@example.org", "password3")
+		require.NoError(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/api/account", bob, map[string]string{"password": "wrong"}).StatusCode)
+		require.Empty(t, deleted)
+
+		require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/account", bob, map[string]string{"password": "password2"}).StatusCode)
+		require.Equal(t, []string{bob.ID}, deleted)
+		_, err := users.GetByID(ctx, bob.ID)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/profile", bob, nil).StatusCode)
+	})
+}
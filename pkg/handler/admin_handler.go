@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+type adminUserResponse struct {
+	ID               string     `json:"id"`
+	Email            string     `json:"email"`
+	DisplayName      string     `json:"displayName"`
+	ChannelSlug      string     `json:"channelSlug"`
+	Verified         bool       `json:"emailVerified"`
+	Roles            []string   `json:"roles"`
+	SuspendedAt      *time.Time `json:"suspendedAt,omitempty"`
+	SuspensionReason string     `json:"suspensionReason,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+type adminUserRequest struct {
+	UserID string `json:"userId"`
+	// the role to grant or revoke
+	Role string `json:"role,omitempty"`
+	// shown to other admins, not to the user
+	Reason string `json:"reason,omitempty"`
+}
+
+// AdminHandler serves the user administration endpoints, each needs a permission checked by
+// AuthMiddleware.RequirePermission when registering it
+type AdminHandler struct {
+	service *auth.Service
+}
+
+func NewAdminHandler(service *auth.Service) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+// Users lists users, ?q= searches email, display name and channel slug, ?role= and ?suspended=true filter them.
+// ?limit= and ?offset= page through the results.
+func (h *AdminHandler) Users(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	filter := storage.UserFilter{
+		Query:         query.Get("q"),
+		Role:          query.Get("role"),
+		SuspendedOnly: query.Get("suspended") == "true",
+	}
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			writeError(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	users, err := h.service.ListUsers(r.Context(), filter)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	resp := make([]adminUserResponse, 0, len(users))
+	for _, u := range users {
+		user := adminUserResponse{
+			ID:               u.ID,
+			Email:            u.Email,
+			DisplayName:      u.DisplayName.String,
+			ChannelSlug:      u.ChannelSlug.String,
+			Verified:         u.EmailVerifiedAt.Valid,
+			Roles:            append([]string{storage.RoleViewer}, u.Roles...),
+			SuspensionReason: u.SuspensionReason.String,
+			CreatedAt:        u.CreatedAt,
+		}
+		if u.SuspendedAt.Valid {
+			user.SuspendedAt = &u.SuspendedAt.Time
+		}
+		resp = append(resp, user)
+	}
+	writeJSON(w, resp)
+}
+
+// Suspend suspends an account, logging it out everywhere and ending its streams
+func (h *AdminHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r, func(adminID string, req *adminUserRequest) error {
+		return h.service.SuspendUser(r.Context(), adminID, req.UserID, req.Reason)
+	})
+}
+
+// Reinstate lifts the suspension of an account
+func (h *AdminHandler) Reinstate(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r, func(adminID string, req *adminUserRequest) error {
+		return h.service.ReinstateUser(r.Context(), adminID, req.UserID)
+	})
+}
+
+// GrantRole gives a user a role
+func (h *AdminHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r, func(adminID string, req *adminUserRequest) error {
+		return h.service.GrantRole(r.Context(), adminID, req.UserID, req.Role)
+	})
+}
+
+// RevokeRole takes a role from a user
+func (h *AdminHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r, func(adminID string, req *adminUserRequest) error {
+		return h.service.RevokeRole(r.Context(), adminID, req.UserID, req.Role)
+	})
+}
+
+// handle decodes the POST body of an admin action on a user and runs it, responding 204 on success
+func (h *AdminHandler) handle(w http.ResponseWriter, r *http.Request, action func(adminID string, req *adminUserRequest) error) {
+	adminID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req adminUserRequest
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, "userId required", http.StatusBadRequest)
+		return
+	}
+	if err := action(adminID, &req); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
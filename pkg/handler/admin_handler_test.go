@@ -0,0 +1,191 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	users := storage.NewUserRepository(db)
+	service := auth.NewService(users)
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	generator := auth.NewTokenGenerator("test", auth.NewKeySet(key))
+	sessions := auth.NewSessionManager(storage.NewSessionRepository(db), generator)
+	service.SetSessions(sessions)
+	var suspended []string
+	service.OnAccountSuspended(func(_ context.Context, userID string) {
+		suspended = append(suspended, userID)
+	})
+
+	middleware := handler.NewAuthMiddleware(generator)
+	middleware.RequireSessions(sessions)
+	middleware.SetPermissions(service)
+	authHandler := handler.NewAuthHandler(service, sessions)
+	admin := handler.NewAdminHandler(service)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/login", authHandler.Login)
+	mux.Handle("/api/admin/users", middleware.RequirePermission(storage.PermissionListUsers, http.HandlerFunc(admin.Users)))
+	mux.Handle("/api/admin/users/suspend", middleware.RequirePermission(storage.PermissionSuspend, http.HandlerFunc(admin.Suspend)))
+	mux.Handle("/api/admin/users/reinstate", middleware.RequirePermission(storage.PermissionSuspend, http.HandlerFunc(admin.Reinstate)))
+	mux.Handle("/api/admin/users/roles/grant", middleware.RequirePermission(storage.PermissionAssignRole, http.HandlerFunc(admin.GrantRole)))
+	mux.Handle("/api/admin/users/roles/revoke", middleware.RequirePermission(storage.PermissionAssignRole, http.HandlerFunc(admin.RevokeRole)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	register := func(email, name string) *storage.User {
+		user, err := service.Register(ctx, email, "password1", name)
+		require.NoError(t, err)
+		require.NoError(t, users.MarkEmailVerified(ctx, user.ID))
+		return user
+	}
+	root := register("root@example.com", "Root")
+	bob := register("bob@example.com", "Bob")
+	carol := register("carol@example.com", "Carol")
+	require.NoError(t, users.GrantRole(ctx, root.ID, storage.RoleAdmin))
+
+	do := func(method, path, token string, body any) *http.Response {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &payload)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+		return res
+	}
+	login := func(email string) (*http.Response, string) {
+		res := do(http.MethodPost, "/api/login", "", map[string]string{"email": email, "password": "password1"})
+		var v struct {
+			Token string `json:"token"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&v)
+		return res, v.Token
+	}
+	type listedUser struct {
+		ID          string   `json:"id"`
+		Email       string   `json:"email"`
+		Roles       []string `json:"roles"`
+		SuspendedAt *string  `json:"suspendedAt"`
+		Reason      string   `json:"suspensionReason"`
+	}
+	list := func(token, query string) []listedUser {
+		res := do(http.MethodGet, "/api/admin/users"+query, token, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var v []listedUser
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&v))
+		return v
+	}
+
+	_, rootToken := login("root@example.com")
+	_, bobToken := login("bob@example.com")
+	_, carolToken := login("carol@example.com")
+
+	t.Run("admin endpoints need permissions", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/admin/users", "", nil).StatusCode)
+		require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/admin/users", bobToken, nil).StatusCode)
+		res := do(http.MethodPost, "/api/admin/users/suspend", bobToken, map[string]string{"userId": carol.ID})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("list and search users", func(t *testing.T) {
+		all := list(rootToken, "")
+		require.Len(t, all, 3)
+
+		found := list(rootToken, "?q=BOB")
+		require.Len(t, found, 1)
+		require.Equal(t, bob.ID, found[0].ID)
+		require.Equal(t, []string{storage.RoleViewer}, found[0].Roles)
+
+		admins := list(rootToken, "?role=admin")
+		require.Len(t, admins, 1)
+		require.Equal(t, []string{storage.RoleViewer, storage.RoleAdmin}, admins[0].Roles)
+
+		require.Len(t, list(rootToken, "?limit=2"), 2)
+		require.Len(t, list(rootToken, "?limit=2&offset=2"), 1)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/api/admin/users?limit=x", rootToken, nil).StatusCode)
+	})
+
+	t.Run("roles", func(t *testing.T) {
+		grant := func(userID, role string) int {
+			return do(http.MethodPost, "/api/admin/users/roles/grant", rootToken, map[string]string{"userId": userID, "role": role}).StatusCode
+		}
+		require.Equal(t, http.StatusBadRequest, grant(carol.ID, "owner"))
+		require.Equal(t, http.StatusNotFound, grant("unknown", storage.RoleModerator))
+		require.Equal(t, http.StatusNoContent, grant(carol.ID, storage.RoleModerator))
+
+		// moderators may look users up but not suspend them
+		require.Len(t, list(carolToken, "?role=moderator"), 1)
+		res := do(http.MethodPost, "/api/admin/users/suspend", carolToken, map[string]string{"userId": bob.ID})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res = do(http.MethodPost, "/api/admin/users/roles/revoke", rootToken, map[string]string{"userId": root.ID, "role": storage.RoleAdmin})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res = do(http.MethodPost, "/api/admin/users/roles/revoke", rootToken, map[string]string{"userId": carol.ID, "role": storage.RoleModerator})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/admin/users", carolToken, nil).StatusCode)
+	})
+
+	t.Run("suspend and reinstate", func(t *testing.T) {
+		res := do(http.MethodPost, "/api/admin/users/suspend", rootToken, map[string]string{"userId": root.ID})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res = do(http.MethodPost, "/api/admin/users/suspend", rootToken, map[string]string{"userId": "unknown"})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res = do(http.MethodPost, "/api/admin/users/suspend", rootToken, map[string]string{"userId": bob.ID, "reason": "spam"})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		require.Equal(t, []string{bob.ID}, suspended)
+
+		// logged out everywhere and locked out
+		require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/admin/users", bobToken, nil).StatusCode)
+		res, _ = login("bob@example.com")
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.ErrorIs(t, service.Authorize(ctx, bob.ID, auth.ActionChat), auth.ErrAccountSuspended)
+
+		listed := list(rootToken, "?suspended=true")
+		require.Len(t, listed, 1)
+		require.Equal(t, bob.ID, listed[0].ID)
+		require.NotNil(t, listed[0].SuspendedAt)
+		require.Equal(t, "spam", listed[0].Reason)
+
+		res = do(http.MethodPost, "/api/admin/users/reinstate", rootToken, map[string]string{"userId": bob.ID})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		res, _ = login("bob@example.com")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Empty(t, list(rootToken, "?suspended=true"))
+	})
+
+	t.Run("permissions of roles come from the database", func(t *testing.T) {
+		require.NoError(t, service.Authorize(ctx, carol.ID, auth.ActionGoLive))
+
+		// only streamers go live once viewers lose the permission
+		_, err := db.Exec(`DELETE FROM role_permissions WHERE role = $1 AND permission = $2`, storage.RoleViewer, storage.PermissionGoLive)
+		require.NoError(t, err)
+		require.ErrorIs(t, service.Authorize(ctx, carol.ID, auth.ActionGoLive), auth.ErrPermissionDenied)
+		require.NoError(t, service.Authorize(ctx, carol.ID, auth.ActionChat))
+
+		require.NoError(t, users.GrantRole(ctx, carol.ID, storage.RoleStreamer))
+		require.NoError(t, service.Authorize(ctx, carol.ID, auth.ActionGoLive))
+	})
+}
@@ -3,16 +3,18 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/livekit/protocol/logger"
+
 	"github.com/livekit/livekit-server/pkg/auth"
 	"github.com/livekit/livekit-server/pkg/storage"
 )
 
-const defaultTokenTTL = 24 * time.Hour
-
 type authRequest struct {
 	Email       string `json:"email"`
 	Password    string `json:"password"`
@@ -20,23 +22,42 @@ type authRequest struct {
 }
 
 type authUserResponse struct {
-	ID          string `json:"id"`
-	Email       string `json:"email"`
-	DisplayName string `json:"displayName"`
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	DisplayName   string `json:"displayName"`
+	EmailVerified bool   `json:"emailVerified"`
 }
 
 type authResponse struct {
-	Token string           `json:"token"`
-	User  authUserResponse `json:"user"`
+	Token        string           `json:"token"`
+	RefreshToken string           `json:"refreshToken"`
+	ExpiresAt    time.Time        `json:"expiresAt"`
+	User         authUserResponse `json:"user"`
+}
+
+type mfaChallengeResponse struct {
+	MFARequired bool `json:"mfaRequired"`
+	*auth.MFAChallenge
 }
 
 type AuthHandler struct {
-	service *auth.Service
-	tokens  *auth.TokenGenerator
+	service  *auth.Service
+	sessions *auth.SessionManager
+	guard    *auth.LoginGuard
 }
 
-func NewAuthHandler(service *auth.Service, tokens *auth.TokenGenerator) *AuthHandler {
-	return &AuthHandler{service: service, tokens: tokens}
+// NewAuthHandler throttles attempts with the default limits kept in memory until SetLoginGuard is called
+func NewAuthHandler(service *auth.Service, sessions *auth.SessionManager) *AuthHandler {
+	return &AuthHandler{
+		service:  service,
+		sessions: sessions,
+		guard:    auth.NewLoginGuard(auth.NewMemoryAttemptStore(), auth.DefaultLoginLimits),
+	}
+}
+
+// SetLoginGuard replaces the limits on login and signup attempts
+func (h *AuthHandler) SetLoginGuard(guard *auth.LoginGuard) {
+	h.guard = guard
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -46,7 +67,16 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.guard.Allow(r.Context(), remoteIP(r), ""); err != nil {
+		writeThrottled(w, err)
+		return
+	}
+
 	user, err := h.service.Register(r.Context(), req.Email, req.Password, req.DisplayName)
+	if errors.Is(err, auth.ErrInvalidEmail) || errors.Is(err, auth.ErrWeakPassword) {
+		h.writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		// Check for duplicate user error (UNIQUE constraint violation)
 		errMsg := strings.ToLower(err.Error())
@@ -57,11 +87,12 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 			h.writeError(w, "email already registered", http.StatusConflict)
 			return
 		}
-		h.writeError(w, "failed to register: "+err.Error(), http.StatusInternalServerError)
+		logger.Errorw("could not register user", err)
+		h.writeError(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 
-	h.writeAuthResponse(w, user)
+	h.writeAuthResponse(w, r, user)
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -71,40 +102,117 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := remoteIP(r)
+	if err := h.guard.Allow(r.Context(), ip, req.Email); err != nil {
+		writeThrottled(w, err)
+		return
+	}
+
 	user, err := h.service.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidCredentials) {
+			h.guard.Failed(r.Context(), ip, req.Email)
 			h.writeError(w, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
-		h.writeError(w, "failed to login: "+err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, auth.ErrAccountSuspended) {
+			h.writeError(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		logger.Errorw("could not log in user", err)
+		h.writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// accounts with a second factor get a challenge for LoginMFA instead of tokens. Their failed logins are only
+	// cleared once the code is accepted, or guessing codes would never lock the account for longer.
+	challenge, err := h.service.MFAChallenge(r.Context(), user)
+	if err != nil {
+		logger.Errorw("could not create MFA challenge", err, "userID", user.ID)
+		h.writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if challenge != nil {
+		writeJSON(w, mfaChallengeResponse{MFARequired: true, MFAChallenge: challenge})
 		return
 	}
+	h.guard.Succeeded(r.Context(), req.Email)
 
-	h.writeAuthResponse(w, user)
+	h.writeAuthResponse(w, r, user)
+}
+
+// LoginMFA completes a login with the challenge token and a TOTP or recovery code.
+// Wrong codes count as failed logins of the account.
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		MFAToken string `json:"mfaToken"`
+		Code     string `json:"code"`
+	}
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.ParseMFAChallenge(r.Context(), req.MFAToken)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	ip := remoteIP(r)
+	if err = h.guard.Allow(r.Context(), ip, user.Email); err != nil {
+		writeThrottled(w, err)
+		return
+	}
+	if err = h.service.VerifyMFA(r.Context(), user.ID, req.Code); err != nil {
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			h.guard.Failed(r.Context(), ip, user.Email)
+			h.writeError(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		writeAccountError(w, err)
+		return
+	}
+	// a challenge completes one login
+	if err = h.service.RedeemMFAChallenge(req.MFAToken); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	h.guard.Succeeded(r.Context(), user.Email)
+
+	h.writeAuthResponse(w, r, user)
+}
+
+func writeThrottled(w http.ResponseWriter, err error) {
+	var throttled *auth.ThrottledError
+	if errors.As(err, &throttled) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	}
+	writeError(w, auth.ErrTooManyAttempts.Error(), http.StatusTooManyRequests)
 }
 
 func (h *AuthHandler) writeError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
+	writeError(w, message, statusCode)
 }
 
-func (h *AuthHandler) writeAuthResponse(w http.ResponseWriter, user *storage.User) {
-	token, err := h.tokens.Generate(user.ID, defaultTokenTTL)
+func (h *AuthHandler) writeAuthResponse(w http.ResponseWriter, r *http.Request, user *storage.User) {
+	tokens, err := h.sessions.Start(r.Context(), user.ID, deviceOf(r))
 	if err != nil {
 		h.writeError(w, "failed to issue token", http.StatusInternalServerError)
 		return
 	}
 
 	resp := authResponse{
-		Token: token,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
 		User: authUserResponse{
-			ID:          user.ID,
-			Email:       user.Email,
-			DisplayName: user.DisplayName.String,
+			ID:            user.ID,
+			Email:         user.Email,
+			DisplayName:   user.DisplayName.String,
+			EmailVerified: user.EmailVerifiedAt.Valid,
 		},
 	}
 
@@ -0,0 +1,152 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+type testAuditor struct {
+	mu     sync.Mutex
+	events []auth.AuditEvent
+}
+
+func (a *testAuditor) Audit(_ context.Context, event auth.AuditEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, event)
+}
+
+func (a *testAuditor) count(eventType string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, e := range a.events {
+		if e.Type == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLoginThrottling(t *testing.T) {
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	service := auth.NewService(storage.NewUserRepository(db))
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	sessions := auth.NewSessionManager(storage.NewSessionRepository(db), auth.NewTokenGenerator("test", auth.NewKeySet(key)))
+	_, err = service.Register(context.Background(), "alice@example.com", "password1", "Alice")
+	require.NoError(t, err)
+
+	newServer := func(limits auth.LoginLimits) (*httptest.Server, *testAuditor) {
+		auditor := &testAuditor{}
+		guard := auth.NewLoginGuard(auth.NewMemoryAttemptStore(), limits)
+		guard.SetAuditor(auditor)
+		h := handler.NewAuthHandler(service, sessions)
+		h.SetLoginGuard(guard)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/register", h.Register)
+		mux.HandleFunc("/api/login", h.Login)
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+		return server, auditor
+	}
+	post := func(server *httptest.Server, path, email, password string) (*http.Response, string) {
+		body, err := json.Marshal(map[string]string{"email": email, "password": password})
+		require.NoError(t, err)
+		res, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(data)
+	}
+
+	t.Run("unknown emails look like wrong passwords", func(t *testing.T) {
+		server, _ := newServer(auth.DefaultLoginLimits)
+		wrongPassword, wrongPasswordBody := post(server, "/api/login", "alice@example.com", "wrong")
+		unknownEmail, unknownEmailBody := post(server, "/api/login", "nobody@example.com", "wrong")
+		require.Equal(t, http.StatusUnauthorized, wrongPassword.StatusCode)
+		require.Equal(t, wrongPassword.StatusCode, unknownEmail.StatusCode)
+		require.Equal(t, wrongPasswordBody, unknownEmailBody)
+
+		res, body := post(server, "/api/register", "alice@example.com", "password1")
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.NotContains(t, body, "UNIQUE")
+	})
+
+	t.Run("accounts lock after repeated failures", func(t *testing.T) {
+		server, auditor := newServer(auth.LoginLimits{
+			MaxFailures:   3,
+			FailureWindow: time.Hour,
+			LockoutBase:   time.Hour,
+			LockoutMax:    4 * time.Hour,
+		})
+
+		// a successful login clears earlier failures
+		for range 2 {
+			res, _ := post(server, "/api/login", "alice@example.com", "wrong")
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		}
+		res, _ := post(server, "/api/login", "alice@example.com", "password1")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		for range 3 {
+			res, _ := post(server, "/api/login", "alice@example.com", "wrong")
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		}
+		require.Equal(t, 5, auditor.count(auth.AuditLoginFailed))
+		require.Equal(t, 1, auditor.count(auth.AuditAccountLocked))
+
+		// even the right password is refused while locked
+		res, _ = post(server, "/api/login", "alice@example.com", "password1")
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+		require.NoError(t, err)
+		require.InDelta(t, time.Hour.Seconds(), retryAfter, 5)
+		require.Equal(t, 1, auditor.count(auth.AuditLoginThrottled))
+
+		// unknown emails lock the same way, other accounts are not affected
+		for range 3 {
+			res, _ := post(server, "/api/login", "nobody@example.com", "wrong")
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		}
+		res, _ = post(server, "/api/login", "NOBODY@example.com", "wrong")
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	})
+
+	t.Run("addresses are rate limited", func(t *testing.T) {
+		server, auditor := newServer(auth.LoginLimits{IPAttempts: 3, IPWindow: time.Hour})
+
+		res, _ := post(server, "/api/login", "alice@example.com", "password1")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		res, _ = post(server, "/api/login", "alice@example.com", "wrong")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		res, _ = post(server, "/api/register", "bob@example.com", "password1")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, body := post(server, "/api/login", "alice@example.com", "password1")
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		require.Contains(t, body, auth.ErrTooManyAttempts.Error())
+		require.NotEmpty(t, res.Header.Get("Retry-After"))
+		require.Equal(t, 1, auditor.count(auth.AuditIPRateLimited))
+	})
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+)
+
+// JWKSPath is where the public keys of app tokens are published
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWKSHandler publishes the keys that verify app tokens, so other services can check them without a shared secret
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+func (h *JWKSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// short enough for verifiers to pick up a rotated key well within its overlap window
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.keys.JWKS())
+}
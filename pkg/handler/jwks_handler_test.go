@@ -0,0 +1,116 @@
+package handler_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/handler"
+)
+
+func TestSigningKeyRotation(t *testing.T) {
+	first, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	keys := auth.NewKeySet(first)
+	tokens := auth.NewTokenGenerator("test", keys)
+	server := httptest.NewServer(handler.NewJWKSHandler(keys))
+	defer server.Close()
+
+	fetchJWKS := func() auth.JWKS {
+		res, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var set auth.JWKS
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&set))
+		return set
+	}
+	// verifies like a service that only knows the published keys
+	verifyWithJWKS := func(token string) error {
+		set := fetchJWKS()
+		_, err := jwt.Parse(token, func(token *jwt.Token) (any, error) {
+			for _, k := range set.Keys {
+				if k.KeyID != token.Header["kid"] {
+					continue
+				}
+				switch k.KeyType {
+				case "OKP":
+					x, err := base64.RawURLEncoding.DecodeString(k.X)
+					return ed25519.PublicKey(x), err
+				case "RSA":
+					n, err := base64.RawURLEncoding.DecodeString(k.N)
+					if err != nil {
+						return nil, err
+					}
+					e, err := base64.RawURLEncoding.DecodeString(k.E)
+					if err != nil {
+						return nil, err
+					}
+					return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+				}
+			}
+			return nil, auth.ErrUnknownSigningKey
+		}, jwt.WithValidMethods([]string{auth.AlgorithmEdDSA, auth.AlgorithmRS256}))
+		return err
+	}
+
+	oldToken, err := tokens.Generate("user-1", time.Minute)
+	require.NoError(t, err)
+	claims, err := tokens.Parse(oldToken)
+	require.NoError(t, err)
+	require.Equal(t, "user-1", claims["sub"])
+	require.NoError(t, verifyWithJWKS(oldToken))
+
+	second, err := auth.GenerateSigningKey(auth.AlgorithmRS256)
+	require.NoError(t, err)
+	keys.Rotate(second, time.Hour)
+
+	newToken, err := tokens.Generate("user-2", time.Minute)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	require.NoError(t, err)
+	require.Equal(t, second.ID, parsed.Header["kid"])
+	require.Equal(t, auth.AlgorithmRS256, parsed.Method.Alg())
+
+	// both keys verify during the overlap
+	require.Len(t, fetchJWKS().Keys, 2)
+	_, err = tokens.Parse(oldToken)
+	require.NoError(t, err)
+	_, err = tokens.Parse(newToken)
+	require.NoError(t, err)
+	require.NoError(t, verifyWithJWKS(newToken))
+
+	// a key rotated out without overlap stops verifying right away
+	third, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	keys.Rotate(third, 0)
+	_, err = tokens.Parse(newToken)
+	require.ErrorIs(t, err, auth.ErrUnknownSigningKey)
+	_, err = tokens.Parse(oldToken)
+	require.NoError(t, err)
+	require.Len(t, fetchJWKS().Keys, 2)
+
+	// tokens signed with a shared secret or an unknown key are rejected
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user-1", "iss": "test"}).
+		SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = tokens.Parse(hmacToken)
+	require.Error(t, err)
+
+	other, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	otherToken, err := auth.NewTokenGenerator("test", auth.NewKeySet(other)).Generate("user-1", time.Minute)
+	require.NoError(t, err)
+	_, err = tokens.Parse(otherToken)
+	require.ErrorIs(t, err, auth.ErrUnknownSigningKey)
+}
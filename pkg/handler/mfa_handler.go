@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type mfaCodeRequest struct {
+	// a TOTP code, or a recovery code where the endpoint checks the second factor
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	// shown once, only their hashes are stored
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MFA returns whether the user has a second factor, how many recovery codes are left and whether their roles require one
+func (h *AccountHandler) MFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	status, err := h.service.MFAStatus(r.Context(), userID)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeJSON(w, status)
+}
+
+// EnrollTOTP starts a TOTP enrollment, returning the secret and the provisioning URI to show as a QR code
+func (h *AccountHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedPost(w, r)
+	if !ok {
+		return
+	}
+	enrollment, err := h.service.EnrollTOTP(r.Context(), userID)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeJSON(w, enrollment)
+}
+
+// ConfirmTOTP enables the enrolled secret with a first code, returning the recovery codes
+func (h *AccountHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedPost(w, r)
+	if !ok {
+		return
+	}
+	var req mfaCodeRequest
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	codes, err := h.service.ConfirmTOTP(r.Context(), userID, req.Code)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeJSON(w, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, the current ones stop working
+func (h *AccountHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedPost(w, r)
+	if !ok {
+		return
+	}
+	var req mfaCodeRequest
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	codes, err := h.service.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeJSON(w, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA removes the second factor of the user
+func (h *AccountHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authorizedPost(w, r)
+	if !ok {
+		return
+	}
+	var req mfaCodeRequest
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+	if err := h.service.DisableMFA(r.Context(), userID, req.Code); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
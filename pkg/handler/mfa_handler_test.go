@@ -0,0 +1,252 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+// totp computes the code an authenticator app shows at t
+func totp(t *testing.T, secret string, at time.Time) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1_000_000)
+}
+
+func TestMFA(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	users := storage.NewUserRepository(db)
+	service := auth.NewService(users)
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	generator := auth.NewTokenGenerator("test", auth.NewKeySet(key))
+	sessions := auth.NewSessionManager(storage.NewSessionRepository(db), generator)
+	service.SetSessions(sessions)
+	service.SetTokenGenerator(generator)
+	service.SetMFAPolicy("StreamG8", []string{storage.RoleStreamer, storage.RoleAdmin})
+
+	middleware := handler.NewAuthMiddleware(generator)
+	middleware.RequireSessions(sessions)
+	middleware.SetPermissions(service)
+	authHandler := handler.NewAuthHandler(service, sessions)
+	accounts := handler.NewAccountHandler(service, users)
+	admin := handler.NewAdminHandler(service)
+	mux := http.NewServeMux()
+	mux.Handle("/api/admin/users", middleware.RequirePermission(storage.PermissionListUsers, http.HandlerFunc(admin.Users)))
+	mux.HandleFunc("/api/login", authHandler.Login)
+	mux.HandleFunc("/api/login/mfa", authHandler.LoginMFA)
+	mux.Handle("/api/account/mfa", middleware.Authorize(http.HandlerFunc(accounts.MFA)))
+	mux.Handle("/api/account/mfa/totp", middleware.Authorize(http.HandlerFunc(accounts.EnrollTOTP)))
+	mux.Handle("/api/account/mfa/totp/confirm", middleware.Authorize(http.HandlerFunc(accounts.ConfirmTOTP)))
+	mux.Handle("/api/account/mfa/recovery-codes", middleware.Authorize(http.HandlerFunc(accounts.RegenerateRecoveryCodes)))
+	mux.Handle("/api/account/mfa/disable", middleware.Authorize(http.HandlerFunc(accounts.DisableMFA)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	alice, err := service.Register(ctx, "alice@example.com", "password1", "Alice")
+	require.NoError(t, err)
+	require.NoError(t, users.MarkEmailVerified(ctx, alice.ID))
+
+	do := func(method, path, token string, body any) (*http.Response, map[string]any) {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &payload)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var v map[string]any
+		_ = json.NewDecoder(res.Body).Decode(&v)
+		return res, v
+	}
+	login := func() map[string]any {
+		res, v := do(http.MethodPost, "/api/login", "", map[string]string{"email": "alice@example.com", "password": "password1"})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		return v
+	}
+	recoveryCodes := func(v map[string]any) []string {
+		var codes []string
+		for _, c := range v["recoveryCodes"].([]any) {
+			codes = append(codes, c.(string))
+		}
+		return codes
+	}
+
+	token := login()["token"].(string)
+	var secret string
+	var codes []string
+	t.Run("streamers need a second factor to go live", func(t *testing.T) {
+		require.NoError(t, service.Authorize(ctx, alice.ID, auth.ActionGoLive))
+		require.NoError(t, users.GrantRole(ctx, alice.ID, storage.RoleStreamer))
+		require.ErrorIs(t, service.Authorize(ctx, alice.ID, auth.ActionGoLive), auth.ErrMFARequired)
+		require.NoError(t, service.Authorize(ctx, alice.ID, auth.ActionChat))
+
+		_, status := do(http.MethodGet, "/api/account/mfa", token, nil)
+		require.Equal(t, map[string]any{"enabled": false, "required": true, "recoveryCodesLeft": float64(0)}, status)
+	})
+
+	t.Run("enroll", func(t *testing.T) {
+		res, v := do(http.MethodPost, "/api/account/mfa/totp/confirm", token, map[string]string{"code": "123456"})
+		require.Equal(t, http.StatusConflict, res.StatusCode, v)
+
+		res, v = do(http.MethodPost, "/api/account/mfa/totp", token, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		secret = v["secret"].(string)
+		uri := v["provisioningUri"].(string)
+		require.True(t, strings.HasPrefix(uri, "otpauth://totp/StreamG8:alice@example.com?"), uri)
+		require.Contains(t, uri, "secret="+secret)
+		require.Contains(t, uri, "issuer=StreamG8")
+
+		// until confirmed, logins need no code
+		require.NotEmpty(t, login()["token"])
+
+		res, _ = do(http.MethodPost, "/api/account/mfa/totp/confirm", token, map[string]string{"code": totp(t, secret, time.Now().Add(-time.Hour))})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		res, v = do(http.MethodPost, "/api/account/mfa/totp/confirm", token, map[string]string{"code": totp(t, secret, time.Now())})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		codes = recoveryCodes(v)
+		require.Len(t, codes, 10)
+
+		res, _ = do(http.MethodPost, "/api/account/mfa/totp", token, nil)
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.NoError(t, service.Authorize(ctx, alice.ID, auth.ActionGoLive))
+	})
+
+	t.Run("login needs the second factor", func(t *testing.T) {
+		challenge := login()
+		require.Equal(t, true, challenge["mfaRequired"])
+		require.Nil(t, challenge["token"])
+		mfaToken := challenge["mfaToken"].(string)
+
+		res, _ := do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": mfaToken, "code": "000000"})
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		res, _ = do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": "invalid", "code": "000000"})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		// challenge tokens are not access tokens
+		res, _ = do(http.MethodGet, "/api/account/mfa", mfaToken, nil)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		code := totp(t, secret, time.Now().Add(30*time.Second))
+		res, v := do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": mfaToken, "code": code})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotEmpty(t, v["token"])
+		require.NotEmpty(t, v["refreshToken"])
+
+		// a challenge completes one login
+		res, _ = do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": mfaToken, "code": totp(t, secret, time.Now())})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		// a code works once
+		res, _ = do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": login()["mfaToken"].(string), "code": code})
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("recovery codes", func(t *testing.T) {
+		mfaToken := login()["mfaToken"].(string)
+		res, _ := do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": mfaToken, "code": strings.ToUpper(codes[0])})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		res, _ = do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": login()["mfaToken"].(string), "code": codes[0]})
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		_, status := do(http.MethodGet, "/api/account/mfa", token, nil)
+		require.Equal(t, float64(9), status["recoveryCodesLeft"])
+
+		res, v := do(http.MethodPost, "/api/account/mfa/recovery-codes", token, map[string]string{"code": codes[1]})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		renewed := recoveryCodes(v)
+		res, _ = do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": login()["mfaToken"].(string), "code": codes[2]})
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		codes = renewed
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		res, _ := do(http.MethodPost, "/api/account/mfa/disable", token, map[string]string{"code": "wrong"})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		res, _ = do(http.MethodPost, "/api/account/mfa/disable", token, map[string]string{"code": codes[0]})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		require.NotEmpty(t, login()["token"])
+		require.ErrorIs(t, service.Authorize(ctx, alice.ID, auth.ActionGoLive), auth.ErrMFARequired)
+	})
+
+	t.Run("admins need a second factor for admin routes", func(t *testing.T) {
+		root, err := service.Register(ctx, "root@example.com", "password1", "Root")
+		require.NoError(t, err)
+		require.NoError(t, users.GrantRole(ctx, root.ID, storage.RoleAdmin))
+		res, v := do(http.MethodPost, "/api/login", "", map[string]string{"email": "root@example.com", "password": "password1"})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		rootToken := v["token"].(string)
+
+		res, _ = do(http.MethodGet, "/api/admin/users", rootToken, nil)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.ErrorIs(t, service.CheckMFA(ctx, root.ID), auth.ErrMFARequired)
+
+		_, v = do(http.MethodPost, "/api/account/mfa/totp", rootToken, nil)
+		res, _ = do(http.MethodPost, "/api/account/mfa/totp/confirm", rootToken, map[string]string{"code": totp(t, v["secret"].(string), time.Now())})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		res, _ = do(http.MethodGet, "/api/admin/users", rootToken, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("the password alone does not clear failed codes", func(t *testing.T) {
+		_, err := service.Register(ctx, "dave@example.com", "password1", "Dave")
+		require.NoError(t, err)
+		passwordLogin := func() map[string]any {
+			res, v := do(http.MethodPost, "/api/login", "", map[string]string{"email": "dave@example.com", "password": "password1"})
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			return v
+		}
+		daveToken := passwordLogin()["token"].(string)
+		_, v := do(http.MethodPost, "/api/account/mfa/totp", daveToken, nil)
+		daveSecret := v["secret"].(string)
+		res, _ := do(http.MethodPost, "/api/account/mfa/totp/confirm", daveToken, map[string]string{"code": totp(t, daveSecret, time.Now())})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		guess := func(mfaToken string) int {
+			res, _ := do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": mfaToken, "code": "000000"})
+			return res.StatusCode
+		}
+		mfaToken := passwordLogin()["mfaToken"].(string)
+		for range auth.DefaultLoginLimits.MaxFailures - 1 {
+			require.Equal(t, http.StatusUnauthorized, guess(mfaToken))
+		}
+		// a new challenge keeps the failures of the last one
+		mfaToken = passwordLogin()["mfaToken"].(string)
+		require.Equal(t, http.StatusUnauthorized, guess(mfaToken))
+
+		res, _ = do(http.MethodPost, "/api/login/mfa", "", map[string]string{"mfaToken": mfaToken, "code": totp(t, daveSecret, time.Now().Add(30*time.Second))})
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	})
+}
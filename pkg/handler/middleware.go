@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
 	"github.com/livekit/livekit-server/pkg/auth"
 )
 
@@ -14,29 +15,89 @@ type contextKey string
 
 const userIDContextKey contextKey = "auth.userID"
 
+// browsers cannot set headers on WebSocket handshakes, so sockets may pass the token as a query parameter
+const accessTokenParam = "access_token"
+
+// SessionChecker tells whether a login session is still active
+type SessionChecker interface {
+	Active(ctx context.Context, sessionID string) (bool, error)
+}
+
+// PermissionChecker tells whether the roles of a user grant a permission
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+}
+
+// MFAChecker is implemented by permission checkers whose roles may require a second factor,
+// it returns auth.ErrMFARequired when the user has not set one up
+type MFAChecker interface {
+	CheckMFA(ctx context.Context, userID string) error
+}
+
 type AuthMiddleware struct {
-	tokens *auth.TokenGenerator
+	tokens      *auth.TokenGenerator
+	sessions    SessionChecker
+	permissions PermissionChecker
 }
 
 func NewAuthMiddleware(tokens *auth.TokenGenerator) *AuthMiddleware {
 	return &AuthMiddleware{tokens: tokens}
 }
 
+// RequireSessions makes Authorize accept only access tokens bound to a session that is still active,
+// so logging out or revoking a session takes effect before its tokens expire
+func (m *AuthMiddleware) RequireSessions(sessions SessionChecker) {
+	m.sessions = sessions
+}
+
+// SetPermissions sets the checker RequirePermission asks
+func (m *AuthMiddleware) SetPermissions(permissions PermissionChecker) {
+	m.permissions = permissions
+}
+
+// RequirePermission authorizes the request like Authorize and then lets it through only
+// if the roles of the user grant the permission, and the user has a second factor when their roles require one
+func (m *AuthMiddleware) RequirePermission(permission string, next http.Handler) http.Handler {
+	return m.Authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.permissions == nil {
+			http.Error(w, "permissions not configured", http.StatusServiceUnavailable)
+			return
+		}
+		userID, _ := UserIDFromContext(r.Context())
+		allowed, err := m.permissions.HasPermission(r.Context(), userID, permission)
+		if err != nil {
+			http.Error(w, "could not check permissions", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		if mfa, ok := m.permissions.(MFAChecker); ok {
+			if err = mfa.CheckMFA(r.Context(), userID); errors.Is(err, auth.ErrMFARequired) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			} else if err != nil {
+				http.Error(w, "could not check permissions", http.StatusInternalServerError)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
 func (m *AuthMiddleware) Authorize(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		raw := r.Header.Get("Authorization")
-		if raw == "" {
+		tokenString, ok := bearerToken(r)
+		if !ok {
 			http.Error(w, "missing authorization header", http.StatusUnauthorized)
 			return
 		}
-
-		const prefix = "Bearer "
-		if !strings.HasPrefix(raw, prefix) {
+		if tokenString == "" {
 			http.Error(w, "invalid authorization header", http.StatusUnauthorized)
 			return
 		}
 
-		tokenString := strings.TrimSpace(strings.TrimPrefix(raw, prefix))
 		claims, err := m.tokens.Parse(tokenString)
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
@@ -53,11 +114,53 @@ func (m *AuthMiddleware) Authorize(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userIDContextKey, sub)
+		ctx := withUserID(r.Context(), sub)
+		sessionID, _ := claims["sid"].(string)
+		if m.sessions != nil {
+			if sessionID == "" {
+				http.Error(w, "token is not bound to a session", http.StatusUnauthorized)
+				return
+			}
+			active, err := m.sessions.Active(ctx, sessionID)
+			if err != nil {
+				http.Error(w, "could not check session", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				http.Error(w, "session revoked", http.StatusUnauthorized)
+				return
+			}
+		}
+		if sessionID != "" {
+			ctx = auth.WithSessionID(ctx, sessionID)
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+func bearerToken(r *http.Request) (string, bool) {
+	raw := r.Header.Get("Authorization")
+	if raw == "" {
+		if websocket.IsWebSocketUpgrade(r) {
+			if token := r.URL.Query().Get(accessTokenParam); token != "" {
+				return token, true
+			}
+		}
+		return "", false
+	}
+
+	const prefix = "Bearer "
+	if !strings.HasPrefix(raw, prefix) {
+		return "", true
+	}
+	return strings.TrimSpace(strings.TrimPrefix(raw, prefix)), true
+}
+
+func withUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDContextKey, userID)
+}
+
 func UserIDFromContext(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(userIDContextKey).(string)
 	return userID, ok && userID != ""
@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+)
+
+const (
+	// OIDCPath prefixes the login and callback URLs of the providers, /api/auth/oidc/<id>/login and /api/auth/oidc/<id>/callback
+	OIDCPath = "/api/auth/oidc/"
+
+	oidcLoginCookie = "oidc_login"
+	oidcCodeCookie  = "oidc_code"
+	// where users land after logging in without a return path
+	defaultReturnPath = "/examples/live-streams.html"
+)
+
+type oidcProviderResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	LoginURL string `json:"loginUrl"`
+}
+
+// OIDCHandler logs users in with OpenID Connect providers, issuing the same tokens as a password login
+type OIDCHandler struct {
+	service   *auth.Service
+	sessions  *auth.SessionManager
+	tokens    *auth.TokenGenerator
+	providers map[string]*auth.OIDCProvider
+	order     []string
+
+	// codes exchanged already, until they expire
+	mu        sync.Mutex
+	usedCodes map[string]time.Time
+}
+
+func NewOIDCHandler(service *auth.Service, sessions *auth.SessionManager, tokens *auth.TokenGenerator, providers ...*auth.OIDCProvider) *OIDCHandler {
+	h := &OIDCHandler{
+		service:   service,
+		sessions:  sessions,
+		tokens:    tokens,
+		providers: make(map[string]*auth.OIDCProvider, len(providers)),
+		usedCodes: make(map[string]time.Time),
+	}
+	for _, p := range providers {
+		h.providers[p.ID()] = p
+		h.order = append(h.order, p.ID())
+	}
+	return h
+}
+
+// ServeHTTP lists the providers at OIDCPath+"providers", exchanges the codes of finished logins at
+// OIDCPath+"token" and serves the login and callback URLs of the providers
+func (h *OIDCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, OIDCPath)
+	if rest == "token" {
+		h.exchange(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if rest == "providers" {
+		h.listProviders(w)
+		return
+	}
+	id, action, _ := strings.Cut(rest, "/")
+	provider, ok := h.providers[id]
+	if !ok {
+		writeError(w, "unknown identity provider", http.StatusNotFound)
+		return
+	}
+	switch action {
+	case "login":
+		h.login(w, r, provider)
+	case "callback":
+		h.callback(w, r, provider)
+	default:
+		writeError(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (h *OIDCHandler) listProviders(w http.ResponseWriter) {
+	resp := make([]oidcProviderResponse, 0, len(h.order))
+	for _, id := range h.order {
+		resp = append(resp, oidcProviderResponse{
+			ID:       id,
+			Name:     h.providers[id].Name(),
+			LoginURL: OIDCPath + url.PathEscape(id) + "/login",
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// login redirects to the provider, ?returnTo= sets the path to come back to
+func (h *OIDCHandler) login(w http.ResponseWriter, r *http.Request, provider *auth.OIDCProvider) {
+	login, err := auth.NewOIDCLogin(provider.ID(), returnPath(r.URL.Query().Get("returnTo")))
+	if err != nil {
+		logger.Errorw("could not start OIDC login", err)
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	authURL, err := provider.AuthCodeURL(r.Context(), login)
+	if err != nil {
+		writeOIDCError(w, provider, err)
+		return
+	}
+	sealed, err := login.Seal(h.tokens)
+	if err != nil {
+		logger.Errorw("could not seal OIDC login", err)
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    sealed,
+		Path:     OIDCPath,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// sent along with the top level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// callback finishes the login the provider redirected back from, sending the user to the return path with a
+// one-time code for exchange
+func (h *OIDCHandler) callback(w http.ResponseWriter, r *http.Request, provider *auth.OIDCProvider) {
+	cookie, err := r.Cookie(oidcLoginCookie)
+	if err != nil {
+		writeError(w, "no login in progress", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcLoginCookie, Path: OIDCPath, MaxAge: -1, HttpOnly: true})
+
+	query := r.URL.Query()
+	login, err := auth.OpenOIDCLogin(h.tokens, cookie.Value)
+	if err != nil || login.Provider != provider.ID() || login.State != query.Get("state") {
+		writeError(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		logger.Infow("OIDC login refused", "provider", provider.ID(), "error", errCode, "description", query.Get("error_description"))
+		writeError(w, auth.ErrOIDCLoginFailed.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), query.Get("code"), login)
+	if err != nil {
+		writeOIDCError(w, provider, err)
+		return
+	}
+	user, err := h.service.LoginWithOIDC(r.Context(), identity)
+	if err != nil {
+		writeOIDCError(w, provider, err)
+		return
+	}
+
+	// tokens in a URL end up in the history, logs and Referer headers, the page exchanges the code for them
+	code, sealed, err := auth.SealOIDCCode(h.tokens, user.ID)
+	if err != nil {
+		logger.Errorw("could not seal OIDC code", err, "userID", user.ID)
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCodeCookie,
+		Value:    sealed,
+		Path:     OIDCPath,
+		MaxAge:   int(auth.OIDCCodeTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	target, _ := url.Parse(login.ReturnTo)
+	params := target.Query()
+	params.Set("oidcCode", code)
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// exchange completes a login for the code the callback sent the browser back with. It responds like a password
+// login: with the tokens, or with an MFA challenge for AuthHandler.LoginMFA. Each code is accepted once.
+func (h *OIDCHandler) exchange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		writeError(w, "code required", http.StatusBadRequest)
+		return
+	}
+	cookie, err := r.Cookie(oidcCodeCookie)
+	if err != nil {
+		writeError(w, "no login to complete", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcCodeCookie, Path: OIDCPath, MaxAge: -1, HttpOnly: true})
+
+	userID, err := auth.OpenOIDCCode(h.tokens, cookie.Value, req.Code)
+	if err != nil || !h.useCode(req.Code) {
+		writeError(w, "invalid or expired code", http.StatusUnauthorized)
+		return
+	}
+	user, err := h.service.OIDCUser(r.Context(), userID)
+	if errors.Is(err, auth.ErrAccountSuspended) {
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	// the provider stands in for the password, accounts with a second factor still complete the login with it
+	challenge, err := h.service.MFAChallenge(r.Context(), user)
+	if err != nil {
+		logger.Errorw("could not create MFA challenge", err, "userID", user.ID)
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if challenge != nil {
+		writeJSON(w, mfaChallengeResponse{MFARequired: true, MFAChallenge: challenge})
+		return
+	}
+
+	tokens, err := h.sessions.Start(r.Context(), user.ID, deviceOf(r))
+	if err != nil {
+		logger.Errorw("could not start session", err, "userID", user.ID)
+		writeError(w, "failed to issue token", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, authResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User: authUserResponse{
+			ID:            user.ID,
+			Email:         user.Email,
+			DisplayName:   user.DisplayName.String,
+			EmailVerified: user.EmailVerifiedAt.Valid,
+		},
+	})
+}
+
+// useCode reports whether a code was not exchanged before, remembering it until it expires
+func (h *OIDCHandler) useCode(code string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	for c, expiresAt := range h.usedCodes {
+		if now.After(expiresAt) {
+			delete(h.usedCodes, c)
+		}
+	}
+	if _, ok := h.usedCodes[code]; ok {
+		return false
+	}
+	h.usedCodes[code] = now.Add(auth.OIDCCodeTTL)
+	return true
+}
+
+// returnPath only accepts paths on this server, so logins cannot be used to redirect elsewhere
+func returnPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.Contains(p, `\`) {
+		return defaultReturnPath
+	}
+	if u, err := url.Parse(p); err != nil || u.Host != "" || u.Scheme != "" {
+		return defaultReturnPath
+	}
+	return p
+}
+
+func writeOIDCError(w http.ResponseWriter, provider *auth.OIDCProvider, err error) {
+	switch {
+	case errors.Is(err, auth.ErrOIDCLoginFailed):
+		logger.Infow("OIDC login failed", "provider", provider.ID(), "error", err)
+		writeError(w, auth.ErrOIDCLoginFailed.Error(), http.StatusUnauthorized)
+	case errors.Is(err, auth.ErrEmailNotVerified):
+		writeError(w, "the identity provider has not verified your email address", http.StatusForbidden)
+	case errors.Is(err, auth.ErrAccountSuspended):
+		writeError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, auth.ErrOIDCProviderUnavailable):
+		logger.Warnw("OIDC provider unavailable", err, "provider", provider.ID())
+		writeError(w, auth.ErrOIDCProviderUnavailable.Error(), http.StatusBadGateway)
+	default:
+		logger.Errorw("OIDC login failed", err, "provider", provider.ID())
+		writeError(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,232 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/storage"
+	"github.com/livekit/livekit-server/pkg/testutils"
+)
+
+func TestOIDCLogin(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	users := storage.NewUserRepository(db)
+	service := auth.NewService(users)
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	generator := auth.NewTokenGenerator("test", auth.NewKeySet(key))
+	sessions := auth.NewSessionManager(storage.NewSessionRepository(db), generator)
+	service.SetSessions(sessions)
+
+	idp := testutils.NewOIDCStandIn(t, "livekit", "secret")
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider, err := auth.NewOIDCProvider("mock", auth.OIDCConfig{
+		Name:         "Mock",
+		IssuerURL:    idp.Issuer(),
+		ClientID:     "livekit",
+		ClientSecret: "secret",
+		RedirectURL:  server.URL + handler.OIDCPath + "mock/callback",
+	})
+	require.NoError(t, err)
+	middleware := handler.NewAuthMiddleware(generator)
+	middleware.RequireSessions(sessions)
+	authHandler := handler.NewAuthHandler(service, sessions)
+	accounts := handler.NewAccountHandler(service, users)
+	mux.Handle(handler.OIDCPath, handler.NewOIDCHandler(service, sessions, generator, provider))
+	mux.HandleFunc("/api/login", authHandler.Login)
+	mux.Handle("/api/profile", middleware.Authorize(http.HandlerFunc(accounts.Profile)))
+
+	// login follows the redirects through the provider like a browser, stopping at the page it returns to
+	login := func(returnTo string) *http.Response {
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		client := &http.Client{
+			Jar: jar,
+			CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+				if strings.HasPrefix(req.URL.String(), idp.Issuer()) || req.URL.Path == handler.OIDCPath+"mock/callback" {
+					return nil
+				}
+				return http.ErrUseLastResponse
+			},
+		}
+		res, err := client.Get(server.URL + handler.OIDCPath + "mock/login?returnTo=" + url.QueryEscape(returnTo))
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+	// exchange posts the one-time code of a finished login along with the cookie the callback set
+	exchange := func(res *http.Response, code string) (int, map[string]any) {
+		body, err := json.Marshal(map[string]string{"code": code})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, server.URL+handler.OIDCPath+"token", bytes.NewReader(body))
+		require.NoError(t, err)
+		for _, cookie := range res.Cookies() {
+			if cookie.Name == "oidc_code" {
+				req.AddCookie(cookie)
+			}
+		}
+		exchangeRes, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer exchangeRes.Body.Close()
+		var result map[string]any
+		if exchangeRes.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(exchangeRes.Body).Decode(&result))
+		}
+		return exchangeRes.StatusCode, result
+	}
+	loggedIn := func(res *http.Response) (url.Values, map[string]any) {
+		require.Equal(t, http.StatusFound, res.StatusCode)
+		location, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		params := location.Query()
+		// the tokens never travel in the URL
+		require.Empty(t, params.Get("token"))
+		require.Empty(t, params.Get("refreshToken"))
+		require.NotEmpty(t, params.Get("oidcCode"))
+
+		status, result := exchange(res, params.Get("oidcCode"))
+		require.Equal(t, http.StatusOK, status)
+		require.NotEmpty(t, result["refreshToken"])
+		token, _ := result["token"].(string)
+		require.NotEmpty(t, token)
+
+		// codes are exchanged once
+		status, _ = exchange(res, params.Get("oidcCode"))
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/profile", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		profileRes, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer profileRes.Body.Close()
+		require.Equal(t, http.StatusOK, profileRes.StatusCode)
+		var profile map[string]any
+		require.NoError(t, json.NewDecoder(profileRes.Body).Decode(&profile))
+		return params, profile
+	}
+	passwordLogin := func(email, password string) int {
+		body, err := json.Marshal(map[string]string{"email": email, "password": password})
+		require.NoError(t, err)
+		res, err := http.Post(server.URL+"/api/login", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("lists providers", func(t *testing.T) {
+		res, err := http.Get(server.URL + handler.OIDCPath + "providers")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var providers []map[string]string
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&providers))
+		require.Equal(t, []map[string]string{{"id": "mock", "name": "Mock", "loginUrl": handler.OIDCPath + "mock/login"}}, providers)
+	})
+
+	t.Run("creates accounts", func(t *testing.T) {
+		idp.SetUser(testutils.OIDCUser{Subject: "sub-1", Email: "carol@example.com", EmailVerified: true, Name: "Carol"})
+		res := login("/watch?room=1")
+		require.True(t, strings.HasPrefix(res.Header.Get("Location"), "/watch?"))
+		params, profile := loggedIn(res)
+		require.Equal(t, "1", params.Get("room"))
+		require.Equal(t, "carol@example.com", profile["email"])
+		require.Equal(t, "Carol", profile["displayName"])
+		require.Equal(t, true, profile["emailVerified"])
+
+		// later logins find the account by subject, even after the email changed at the provider
+		idp.SetUser(testutils.OIDCUser{Subject: "sub-1", Email: "carol@work.example.com", EmailVerified: true})
+		_, again := loggedIn(login("/watch"))
+		require.Equal(t, profile["id"], again["id"])
+
+		// the code only completes the login it was issued for
+		res = login("/watch")
+		status, _ := exchange(res, "guessed")
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		// there is no password to log in with
+		require.Equal(t, http.StatusUnauthorized, passwordLogin("carol@example.com", ""))
+	})
+
+	t.Run("links accounts by verified email", func(t *testing.T) {
+		dave, err := service.Register(ctx, "dave@example.com", "password1", "Dave")
+		require.NoError(t, err)
+		require.NoError(t, users.MarkEmailVerified(ctx, dave.ID))
+
+		idp.SetUser(testutils.OIDCUser{Subject: "sub-2", Email: "dave@example.com", EmailVerified: false})
+		require.Equal(t, http.StatusForbidden, login("/").StatusCode)
+
+		idp.SetUser(testutils.OIDCUser{Subject: "sub-2", Email: "dave@example.com", EmailVerified: true})
+		_, profile := loggedIn(login("/"))
+		require.Equal(t, dave.ID, profile["id"])
+		require.Equal(t, http.StatusOK, passwordLogin("dave@example.com", "password1"))
+	})
+
+	t.Run("unverified accounts lose their password when linked", func(t *testing.T) {
+		// someone else signed up with the address of the provider account
+		squatter, err := service.Register(ctx, "erin@example.com", "password1", "Erin")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, passwordLogin("erin@example.com", "password1"))
+
+		idp.SetUser(testutils.OIDCUser{Subject: "sub-3", Email: "erin@example.com", EmailVerified: true})
+		_, profile := loggedIn(login("/"))
+		require.Equal(t, squatter.ID, profile["id"])
+		require.Equal(t, true, profile["emailVerified"])
+		require.Equal(t, http.StatusUnauthorized, passwordLogin("erin@example.com", "password1"))
+	})
+
+	t.Run("rejects invalid ID tokens", func(t *testing.T) {
+		idp.SetUser(testutils.OIDCUser{Subject: "sub-1", Email: "carol@example.com", EmailVerified: true})
+		defer idp.Tamper(nil)
+
+		for name, tamper := range map[string]func(jwt.MapClaims){
+			"nonce":    func(c jwt.MapClaims) { c["nonce"] = "replayed" },
+			"audience": func(c jwt.MapClaims) { c["aud"] = "another-client" },
+			"issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+			"expired":  func(c jwt.MapClaims) { c["exp"] = 1 },
+		} {
+			idp.Tamper(tamper)
+			require.Equal(t, http.StatusUnauthorized, login("/").StatusCode, name)
+		}
+	})
+
+	t.Run("checks the login state", func(t *testing.T) {
+		res, err := http.Get(server.URL + handler.OIDCPath + "mock/callback?code=x&state=y")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		res, err = http.Get(server.URL + handler.OIDCPath + "unknown/login")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		// logins only return to paths of this server
+		idp.SetUser(testutils.OIDCUser{Subject: "sub-1", Email: "carol@example.com", EmailVerified: true})
+		res = login("https://evil.example.com/steal")
+		require.Equal(t, http.StatusFound, res.StatusCode)
+		location, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		require.Empty(t, location.Host)
+		require.Equal(t, "/examples/live-streams.html", location.Path)
+	})
+}
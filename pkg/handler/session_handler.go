@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+)
+
+const maxUserAgentLength = 512
+
+type tokenResponse struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// the session of the token used to list sessions
+	Current bool `json:"current"`
+}
+
+// SessionHandler serves token refresh, logout and the management of login sessions
+type SessionHandler struct {
+	sessions *auth.SessionManager
+}
+
+func NewSessionHandler(sessions *auth.SessionManager) *SessionHandler {
+	return &SessionHandler{sessions: sessions}
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token, it needs no access token
+func (h *SessionHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if !decodeAccountRequest(w, r, &req) {
+		return
+	}
+
+	tokens, err := h.sessions.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	})
+}
+
+// Logout ends the session of the access token, or every session of the user with ?all=true
+func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, hasSession := auth.SessionIDFromContext(r.Context())
+	var err error
+	switch {
+	case r.URL.Query().Get("all") == "true":
+		if err = h.sessions.RevokeOthers(r.Context(), userID); err == nil && hasSession {
+			err = h.sessions.Revoke(r.Context(), userID, sessionID)
+		}
+	case hasSession:
+		err = h.sessions.Revoke(r.Context(), userID, sessionID)
+	default:
+		writeError(w, "token is not bound to a session", http.StatusBadRequest)
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeSessionError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sessions lists the active sessions of the user on GET. DELETE revokes the session given by ?id=,
+// or all sessions but the current one without it.
+func (h *SessionHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		sessions, err := h.sessions.List(r.Context(), userID)
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		current, _ := auth.SessionIDFromContext(r.Context())
+		resp := make([]sessionResponse, 0, len(sessions))
+		for _, s := range sessions {
+			resp = append(resp, sessionResponse{
+				ID:         s.ID,
+				UserAgent:  s.UserAgent,
+				IPAddress:  s.IPAddress,
+				CreatedAt:  s.CreatedAt,
+				LastUsedAt: s.LastUsedAt,
+				ExpiresAt:  s.ExpiresAt,
+				Current:    s.ID == current,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+
+	case http.MethodDelete:
+		var err error
+		if id := r.URL.Query().Get("id"); id != "" {
+			err = h.sessions.Revoke(r.Context(), userID, id)
+		} else {
+			err = h.sessions.RevokeOthers(r.Context(), userID)
+		}
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrRefreshTokenReused):
+		writeError(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, "session not found", http.StatusNotFound)
+	default:
+		logger.Errorw("session request failed", err)
+		writeError(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// deviceOf describes the client of a request for its session
+func deviceOf(r *http.Request) auth.Device {
+	ip := remoteIP(r)
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return auth.Device{UserAgent: userAgent, IPAddress: ip}
+}
+
+// remoteIP is the address of the peer, forwarding headers are not trusted as clients can set them
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,166 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+type tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+func TestSessions(t *testing.T) {
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	users := storage.NewUserRepository(db)
+	service := auth.NewService(users)
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	generator := auth.NewTokenGenerator("test", auth.NewKeySet(key))
+	sessions := auth.NewSessionManager(storage.NewSessionRepository(db), generator)
+	service.SetSessions(sessions)
+
+	middleware := handler.NewAuthMiddleware(generator)
+	middleware.RequireSessions(sessions)
+	authHandler := handler.NewAuthHandler(service, sessions)
+	sessionHandler := handler.NewSessionHandler(sessions)
+	accounts := handler.NewAccountHandler(service, users)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/login", authHandler.Login)
+	mux.HandleFunc("/api/token/refresh", sessionHandler.Refresh)
+	mux.Handle("/api/logout", middleware.Authorize(http.HandlerFunc(sessionHandler.Logout)))
+	mux.Handle("/api/sessions", middleware.Authorize(http.HandlerFunc(sessionHandler.Sessions)))
+	mux.Handle("/api/profile", middleware.Authorize(http.HandlerFunc(accounts.Profile)))
+	mux.Handle("/api/account/password", middleware.Authorize(http.HandlerFunc(accounts.ChangePassword)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, err = service.Register(context.Background(), "alice@example.com", "password1", "Alice")
+	require.NoError(t, err)
+
+	do := func(method, path, token string, body any) *http.Response {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &payload)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", "test-agent")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+	login := func(password string) tokens {
+		res := do(http.MethodPost, "/api/login", "", map[string]string{"email": "alice@example.com", "password": password})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var tk tokens
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&tk))
+		require.NotEmpty(t, tk.Token)
+		require.NotEmpty(t, tk.RefreshToken)
+		return tk
+	}
+	refresh := func(refreshToken string) (*http.Response, tokens) {
+		res := do(http.MethodPost, "/api/token/refresh", "", map[string]string{"refreshToken": refreshToken})
+		var tk tokens
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&tk))
+		}
+		return res, tk
+	}
+	profileStatus := func(token string) int {
+		return do(http.MethodGet, "/api/profile", token, nil).StatusCode
+	}
+
+	t.Run("tokens without a session are rejected", func(t *testing.T) {
+		users, err := users.GetByEmail(context.Background(), "alice@example.com")
+		require.NoError(t, err)
+		token, err := generator.Generate(users.ID, auth.DefaultAccessTokenTTL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, profileStatus(token))
+	})
+
+	t.Run("list and revoke", func(t *testing.T) {
+		laptop := login("password1")
+		phone := login("password1")
+
+		res := do(http.MethodGet, "/api/sessions", laptop.Token, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var list []struct {
+			ID        string `json:"id"`
+			UserAgent string `json:"userAgent"`
+			Current   bool   `json:"current"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&list))
+		require.Len(t, list, 2)
+		require.Equal(t, "test-agent", list[0].UserAgent)
+
+		var phoneSession string
+		for _, s := range list {
+			if !s.Current {
+				phoneSession = s.ID
+			}
+		}
+		require.NotEmpty(t, phoneSession)
+		require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/sessions?id="+phoneSession, laptop.Token, nil).StatusCode)
+
+		// the revoked session can neither be used nor refreshed
+		require.Equal(t, http.StatusUnauthorized, profileStatus(phone.Token))
+		res, _ = refresh(phone.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		require.Equal(t, http.StatusOK, profileStatus(laptop.Token))
+
+		require.Equal(t, http.StatusNoContent, do(http.MethodPost, "/api/logout", laptop.Token, nil).StatusCode)
+		require.Equal(t, http.StatusUnauthorized, profileStatus(laptop.Token))
+	})
+
+	t.Run("refresh rotates tokens", func(t *testing.T) {
+		first := login("password1")
+
+		res, second := refresh(first.RefreshToken)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotEqual(t, first.RefreshToken, second.RefreshToken)
+		require.Equal(t, http.StatusOK, profileStatus(second.Token))
+
+		res, third := refresh(second.RefreshToken)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		// replaying a rotated token means it leaked, the whole session ends
+		res, _ = refresh(second.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		res, _ = refresh(third.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		require.Equal(t, http.StatusUnauthorized, profileStatus(third.Token))
+
+		res, _ = refresh("garbage")
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("password change ends other sessions", func(t *testing.T) {
+		current := login("password1")
+		other := login("password1")
+
+		require.Equal(t, http.StatusNoContent, do(http.MethodPost, "/api/account/password", current.Token, map[string]string{
+			"currentPassword": "password1", "newPassword": "password2",
+		}).StatusCode)
+		require.Equal(t, http.StatusOK, profileStatus(current.Token))
+		require.Equal(t, http.StatusUnauthorized, profileStatus(other.Token))
+	})
+}
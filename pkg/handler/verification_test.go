@@ -0,0 +1,212 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/storage"
+	"github.com/livekit/livekit-server/pkg/streaming"
+	"github.com/livekit/livekit-server/pkg/testutils"
+)
+
+func TestEmailVerification(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	users := storage.NewUserRepository(db)
+	service := auth.NewService(users)
+	key, err := auth.GenerateSigningKey(auth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	generator := auth.NewTokenGenerator("test", auth.NewKeySet(key))
+	sessions := auth.NewSessionManager(storage.NewSessionRepository(db), generator)
+	service.SetSessions(sessions)
+	service.SetTokenGenerator(generator)
+
+	middleware := handler.NewAuthMiddleware(generator)
+	middleware.RequireSessions(sessions)
+	authHandler := handler.NewAuthHandler(service, sessions)
+	accounts := handler.NewAccountHandler(service, users)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/register", authHandler.Register)
+	mux.HandleFunc("/api/login", authHandler.Login)
+	mux.Handle("/api/profile", middleware.Authorize(http.HandlerFunc(accounts.Profile)))
+	mux.HandleFunc(auth.EmailVerifyPath, accounts.VerifyEmail)
+	mux.Handle("/api/account/verify/resend", middleware.Authorize(http.HandlerFunc(accounts.ResendVerification)))
+	mux.HandleFunc("/api/password/reset", accounts.RequestPasswordReset)
+	mux.HandleFunc("/api/password/reset/confirm", accounts.ResetPassword)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	smtp := testutils.NewSMTPStandIn(t)
+	host, port := smtp.Addr()
+	mailer := streaming.NewEmailNotifier(streaming.EmailConfig{
+		Enabled:        true,
+		FromAddress:    "Live <noreply@example.com>",
+		SMTPServer:     host,
+		SMTPPort:       port,
+		DigestInterval: time.Hour,
+	}, nil)
+	defer mailer.Stop()
+	service.SetMailer(mailer, server.URL)
+
+	do := func(method, path, token string, body any) *http.Response {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &payload)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+	decode := func(res *http.Response) map[string]any {
+		var v map[string]any
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&v))
+		return v
+	}
+	linkPattern := regexp.MustCompile(regexp.QuoteMeta(server.URL) + `\S+`)
+	receiveLink := func(to, subject string) *url.URL {
+		msg := smtp.Receive(t)
+		require.Equal(t, []string{to}, msg.To)
+		gotSubject, text, _ := testutils.ParseEmail(t, msg.Data)
+		require.Equal(t, subject, gotSubject)
+		link, err := url.Parse(linkPattern.FindString(text))
+		require.NoError(t, err)
+		return link
+	}
+
+	var accessToken, userID string
+	var verifyLink *url.URL
+	t.Run("signup", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/register", "", map[string]string{
+			"email": "not an email", "password": "password1",
+		}).StatusCode)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/register", "", map[string]string{
+			"email": "alice@example.com", "password": "short",
+		}).StatusCode)
+		smtp.ExpectNone(t)
+
+		res := do(http.MethodPost, "/api/register", "", map[string]string{
+			"email": " alice@example.com ", "password": "password1",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		body := decode(res)
+		accessToken = body["token"].(string)
+		user := body["user"].(map[string]any)
+		userID = user["id"].(string)
+		require.Equal(t, "alice@example.com", user["email"])
+		require.Equal(t, false, user["emailVerified"])
+
+		verifyLink = receiveLink("alice@example.com", "Verify your email address")
+		require.Equal(t, auth.EmailVerifyPath, verifyLink.Path)
+
+		require.ErrorIs(t, service.Authorize(ctx, userID, auth.ActionGoLive), auth.ErrEmailNotVerified)
+		require.ErrorIs(t, service.Authorize(ctx, userID, auth.ActionChat), auth.ErrEmailNotVerified)
+		require.ErrorIs(t, service.Authorize(ctx, "nobody", auth.ActionChat), auth.ErrUnknownAccount)
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		// a resent link replaces the first one
+		require.Equal(t, http.StatusAccepted, do(http.MethodPost, "/api/account/verify/resend", accessToken, nil).StatusCode)
+		resent := receiveLink("alice@example.com", "Verify your email address")
+		require.Equal(t, http.StatusBadRequest, do(http.MethodGet, verifyLink.RequestURI(), "", nil).StatusCode)
+
+		tampered := resent.Query().Get("token") + "x"
+		require.Equal(t, http.StatusBadRequest, do(http.MethodGet, auth.EmailVerifyPath+"?token="+url.QueryEscape(tampered), "", nil).StatusCode)
+
+		res := do(http.MethodGet, resent.RequestURI(), "", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, true, decode(res)["emailVerified"])
+		require.Equal(t, true, decode(do(http.MethodGet, "/api/profile", accessToken, nil))["emailVerified"])
+
+		// links work once
+		require.Equal(t, http.StatusBadRequest, do(http.MethodGet, resent.RequestURI(), "", nil).StatusCode)
+		require.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/account/verify/resend", accessToken, nil).StatusCode)
+
+		require.NoError(t, service.Authorize(ctx, userID, auth.ActionGoLive))
+		require.NoError(t, service.Authorize(ctx, userID, auth.ActionChat))
+	})
+
+	t.Run("password reset", func(t *testing.T) {
+		require.Equal(t, http.StatusAccepted, do(http.MethodPost, "/api/password/reset", "", map[string]string{"email": "nobody@example.com"}).StatusCode)
+		smtp.ExpectNone(t)
+
+		require.Equal(t, http.StatusAccepted, do(http.MethodPost, "/api/password/reset", "", map[string]string{"email": "alice@example.com"}).StatusCode)
+		link := receiveLink("alice@example.com", "Reset your password")
+		require.Equal(t, auth.PasswordResetPagePath, link.Path)
+		token := link.Query().Get("reset_token")
+		require.NotEmpty(t, token)
+
+		// the token is only stored hashed
+		var stored []byte
+		require.NoError(t, db.QueryRow(`SELECT token_hash FROM account_tokens WHERE user_id = $1`, userID).Scan(&stored))
+		require.NotContains(t, string(stored), token)
+
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/password/reset/confirm", "", map[string]string{
+			"token": token, "newPassword": "short",
+		}).StatusCode)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/password/reset/confirm", "", map[string]string{
+			"token": "wrong", "newPassword": "password2",
+		}).StatusCode)
+		require.Equal(t, http.StatusNoContent, do(http.MethodPost, "/api/password/reset/confirm", "", map[string]string{
+			"token": token, "newPassword": "password2",
+		}).StatusCode)
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/password/reset/confirm", "", map[string]string{
+			"token": token, "newPassword": "password3",
+		}).StatusCode)
+
+		// sessions from before the reset are ended
+		require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/profile", accessToken, nil).StatusCode)
+		require.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/api/login", "", map[string]string{
+			"email": "alice@example.com", "password": "password1",
+		}).StatusCode)
+		require.Equal(t, http.StatusOK, do(http.MethodPost, "/api/login", "", map[string]string{
+			"email": "alice@example.com", "password": "password2",
+		}).StatusCode)
+	})
+
+	t.Run("password resets are throttled", func(t *testing.T) {
+		// per account, whether or not it exists
+		for range auth.DefaultLoginLimits.MaxFailures {
+			require.Equal(t, http.StatusAccepted, do(http.MethodPost, "/api/password/reset", "", map[string]string{"email": "carol@example.com"}).StatusCode)
+		}
+		res := do(http.MethodPost, "/api/password/reset", "", map[string]string{"email": "Carol@example.com"})
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		require.NotEmpty(t, res.Header.Get("Retry-After"))
+		// other accounts are not affected
+		require.Equal(t, http.StatusAccepted, do(http.MethodPost, "/api/password/reset", "", map[string]string{"email": "alice@example.com"}).StatusCode)
+		receiveLink("alice@example.com", "Reset your password")
+
+		// per IP address
+		throttled := handler.NewAccountHandler(service, users)
+		throttled.SetLoginGuard(auth.NewLoginGuard(auth.NewMemoryAttemptStore(), auth.LoginLimits{IPAttempts: 2, IPWindow: time.Minute}))
+		confirm := func() int {
+			w := httptest.NewRecorder()
+			body := bytes.NewBufferString(`{"token":"wrong","newPassword":"password2"}`)
+			throttled.ResetPassword(w, httptest.NewRequest(http.MethodPost, "/api/password/reset/confirm", body))
+			return w.Code
+		}
+		require.Equal(t, http.StatusBadRequest, confirm())
+		require.Equal(t, http.StatusBadRequest, confirm())
+		require.Equal(t, http.StatusTooManyRequests, confirm())
+	})
+}
@@ -135,6 +135,7 @@ type Room struct {
 	trailer []byte
 
 	onParticipantChanged func(p types.LocalParticipant)
+	onTrackPublishedFn   func(p types.LocalParticipant, track types.MediaTrack)
 	onRoomUpdated        func()
 	onClose              func()
 
@@ -878,6 +879,13 @@ func (r *Room) OnParticipantChanged(f func(participant types.LocalParticipant))
 	r.onParticipantChanged = f
 }
 
+// OnTrackPublished is called after a participant in the room has published a track
+func (r *Room) OnTrackPublished(f func(participant types.LocalParticipant, track types.MediaTrack)) {
+	r.lock.Lock()
+	r.onTrackPublishedFn = f
+	r.lock.Unlock()
+}
+
 func (r *Room) SendDataPacket(dp *livekit.DataPacket, kind livekit.DataPacket_Kind) {
 	r.onDataPacket(nil, kind, dp)
 }
@@ -1129,11 +1137,15 @@ func (r *Room) onTrackPublished(participant types.LocalParticipant, track types.
 		existingParticipant.SubscribeToTrack(track.ID(), false)
 	}
 	onParticipantChanged := r.onParticipantChanged
+	onTrackPublished := r.onTrackPublishedFn
 	r.lock.RUnlock()
 
 	if onParticipantChanged != nil {
 		onParticipantChanged(participant)
 	}
+	if onTrackPublished != nil {
+		onTrackPublished(participant, track)
+	}
 
 	r.trackManager.AddTrack(track, participant.Identity(), participant.ID())
 
@@ -30,6 +30,9 @@ const (
 	authorizationHeader = "Authorization"
 	bearerPrefix        = "Bearer "
 	accessTokenParam    = "access_token"
+
+	// application endpoints authenticate with app-issued tokens, see pkg/handler
+	appAPIPathPrefix = "/api/"
 )
 
 type grantsKey struct{}
@@ -61,6 +64,10 @@ func (m *APIKeyAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 	if r.URL != nil && r.URL.Path == "/rtc/validate" {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 	}
+	if r.URL != nil && strings.HasPrefix(r.URL.Path, appAPIPathPrefix) {
+		next.ServeHTTP(w, r)
+		return
+	}
 
 	authHeader := r.Header.Get(authorizationHeader)
 	var authToken string
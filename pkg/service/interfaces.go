@@ -19,6 +19,8 @@ import (
 	"time"
 
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -111,3 +113,12 @@ type AgentStore interface {
 	StoreAgentJob(ctx context.Context, job *livekit.Job) error
 	DeleteAgentJob(ctx context.Context, job *livekit.Job) error
 }
+
+// receives lifecycle events for rooms hosted on this node
+type RoomObserver interface {
+	OnRoomStarted(room *livekit.Room)
+	OnRoomClosed(room *livekit.Room)
+	OnParticipantJoined(room *livekit.Room, participant types.LocalParticipant)
+	OnParticipantLeft(room *livekit.Room, participant types.LocalParticipant)
+	OnTrackPublished(room *livekit.Room, participant types.LocalParticipant, track types.MediaTrack)
+}
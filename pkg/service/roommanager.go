@@ -84,6 +84,8 @@ type RoomManager struct {
 
 	rooms map[livekit.RoomName]*rtc.Room
 
+	observers []RoomObserver
+
 	roomServers                  utils.MultitonService[rpc.RoomTopic]
 	agentDispatchServers         utils.MultitonService[rpc.RoomTopic]
 	participantServers           utils.MultitonService[rpc.ParticipantTopic]
@@ -173,6 +175,19 @@ func NewLocalRoomManager(
 	return r, nil
 }
 
+// AddObserver registers an observer for lifecycle events of rooms hosted on this node
+func (r *RoomManager) AddObserver(observer RoomObserver) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.observers = append(r.observers, observer)
+}
+
+func (r *RoomManager) getObservers() []RoomObserver {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.observers
+}
+
 func (r *RoomManager) GetRoom(_ context.Context, roomName livekit.RoomName) *rtc.Room {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -556,6 +571,9 @@ func (r *RoomManager) StartSession(
 
 	clientMeta := &livekit.AnalyticsClientMeta{Region: r.currentNode.Region(), Node: string(r.currentNode.NodeID())}
 	r.telemetry.ParticipantJoined(ctx, protoRoom, participant.ToProto(), pi.Client, clientMeta, true, participant.TelemetryGuard())
+	for _, o := range r.getObservers() {
+		o.OnParticipantJoined(protoRoom, participant)
+	}
 	participant.AddOnClose(types.ParticipantCloseKeyNormal, func(p types.LocalParticipant) {
 		participantServerClosers.Close()
 
@@ -567,6 +585,9 @@ func (r *RoomManager) StartSession(
 		proto := room.ToProto()
 		persistRoomForParticipantCount(proto)
 		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto(), true, participant.TelemetryGuard())
+		for _, o := range r.getObservers() {
+			o.OnParticipantLeft(proto, p)
+		}
 	})
 	participant.OnClaimsChanged(func(participant types.LocalParticipant) {
 		pLogger.Debugw("refreshing client token after claims change")
@@ -648,6 +669,9 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		roomInfo := newRoom.ToProto()
 		r.telemetry.RoomEnded(ctx, roomInfo)
 		prometheus.RoomEnded(time.Unix(roomInfo.CreationTime, 0))
+		for _, o := range r.getObservers() {
+			o.OnRoomClosed(roomInfo)
+		}
 		if err := r.deleteRoom(ctx, roomName); err != nil {
 			newRoom.Logger().Errorw("could not delete room", err)
 		}
@@ -661,6 +685,13 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		}
 	})
 
+	newRoom.OnTrackPublished(func(p types.LocalParticipant, track types.MediaTrack) {
+		roomInfo := newRoom.ToProto()
+		for _, o := range r.getObservers() {
+			o.OnTrackPublished(roomInfo, p, track)
+		}
+	})
+
 	newRoom.OnParticipantChanged(func(p types.LocalParticipant) {
 		if !p.IsDisconnected() {
 			if err := r.roomStore.StoreParticipant(ctx, roomName, p.ToProto()); err != nil {
@@ -675,8 +706,12 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 
 	newRoom.Hold()
 
-	r.telemetry.RoomStarted(ctx, newRoom.ToProto())
+	roomInfo := newRoom.ToProto()
+	r.telemetry.RoomStarted(ctx, roomInfo)
 	prometheus.RoomStarted()
+	for _, o := range r.getObservers() {
+		o.OnRoomStarted(roomInfo)
+	}
 
 	if created && createRoom.GetEgress().GetRoom() != nil {
 		// ensure room name matches
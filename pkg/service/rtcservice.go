@@ -25,6 +25,7 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"net/netip"
 	"os"
 	"strconv"
 	"sync"
@@ -42,6 +43,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/streaming"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/pkg/utils"
@@ -55,6 +57,8 @@ type RTCService struct {
 	isDev         bool
 	limits        config.LimitConfig
 	telemetry     telemetry.TelemetryService
+	// proxies whose forwarded client address is trusted, see GetTrustedClientIP
+	trustedProxies []netip.Prefix
 
 	mu          sync.Mutex
 	connections map[*websocket.Conn]struct{}
@@ -76,6 +80,9 @@ func NewRTCService(
 		connections:   map[*websocket.Conn]struct{}{},
 	}
 
+	// the list is validated when the config is loaded
+	s.trustedProxies, _ = streaming.ParseTrustedProxies(conf.RTC.TrustedProxies)
+
 	s.upgrader = websocket.Upgrader{
 		EnableCompression: true,
 
@@ -241,6 +248,11 @@ func (s *RTCService) validateInternal(lgr logger.Logger, r *http.Request, strict
 		pi.ID = livekit.ParticipantID(joinRequest.ParticipantSid)
 	}
 
+	// with trusted proxies configured, forwarding headers only count when one of them set them
+	if len(s.trustedProxies) > 0 && pi.Client != nil {
+		pi.Client.Address = GetTrustedClientIP(r, s.trustedProxies)
+	}
+
 	return res.roomName, pi, code, err
 }
 
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/pion/turn/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 	"github.com/twitchtv/twirp"
@@ -51,6 +52,7 @@ type LivekitServer struct {
 	rtcService   *RTCService
 	whipService  *WHIPService
 	agentService *AgentService
+	streamingAPI *StreamingAPIService
 	httpMux      *http.ServeMux
 	httpServer   *http.Server
 	promServer   *http.Server
@@ -80,6 +82,7 @@ func NewLivekitServer(conf *config.Config,
 	signalServer *SignalServer,
 	turnServer *turn.Server,
 	currentNode routing.LocalNode,
+	statsTap *StreamingStatsTap,
 ) (s *LivekitServer, err error) {
 	s = &LivekitServer{
 		config:       conf,
@@ -150,8 +153,25 @@ func NewLivekitServer(conf *config.Config,
 	mux.Handle("/agent", agentService)
 
 	// Register Streaming API handlers
-	streamingAPI := NewStreamingAPIService(egressService)
-	streamingAPI.RegisterHTTPHandlers(mux)
+	s.streamingAPI = NewStreamingAPIService(&conf.Streaming, egressService)
+	s.streamingAPI.SetRoomService(roomService)
+	s.streamingAPI.RegisterHTTPHandlers(mux)
+	if roomManager != nil {
+		s.streamingAPI.SetRoomStore(roomManager.roomStore)
+		for _, observer := range s.streamingAPI.RoomObservers() {
+			roomManager.AddObserver(observer)
+		}
+	}
+	statsTap.OnStats(s.streamingAPI.OnAnalyticsStats)
+	streamingMetrics := prometheus.WrapRegistererWith(prometheus.Labels{
+		"node_id":   string(currentNode.NodeID()),
+		"node_type": currentNode.NodeType().String(),
+	}, prometheus.DefaultRegisterer)
+	for _, collector := range s.streamingAPI.Collectors() {
+		if err := streamingMetrics.Register(collector); err != nil {
+			logger.Warnw("could not register streaming metrics", err)
+		}
+	}
 
 	// Serve VOD recordings
 	mux.Handle("/videos/", http.StripPrefix("/videos/", http.FileServer(http.Dir("data/recordings"))))
@@ -192,6 +212,10 @@ func (s *LivekitServer) RegisterHTTPHandler(pattern string, handler http.Handler
 	}
 }
 
+func (s *LivekitServer) StreamingAPI() *StreamingAPIService {
+	return s.streamingAPI
+}
+
 func (s *LivekitServer) Node() *livekit.Node {
 	return s.currentNode.Clone()
 }
@@ -346,6 +370,7 @@ func (s *LivekitServer) Stop(force bool) {
 	}
 
 	s.router.Stop()
+	s.streamingAPI.Stop()
 	close(s.doneChan)
 
 	// wait for fully closed
@@ -0,0 +1,141 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+var errRoomServiceNotConfigured = errors.New("room service not configured")
+
+// SetRoomService sets the room service used to end streams and to bring guests on stage, neither works until it is set
+func (s *StreamingAPIService) SetRoomService(roomService livekit.RoomService) {
+	s.roomService = roomService
+}
+
+// registerAdminHandlers registers the endpoints of platform admins, each requiring a permission of their roles
+func (s *StreamingAPIService) registerAdminHandlers(mux *http.ServeMux) {
+	mux.Handle("/api/admin/streams/end", s.permitted(storage.PermissionEndStreams, s.handleEndStream))
+	mux.Handle("/api/admin/streams/keys/revoke", s.permitted(storage.PermissionRevokeKeys, s.handleAdminRevokeStreamKeys))
+}
+
+// permitted wraps handlers that need the logged-in user to hold a permission
+func (s *StreamingAPIService) permitted(permission string, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authMiddleware == nil {
+			http.Error(w, "authentication not configured", http.StatusServiceUnavailable)
+			return
+		}
+		s.authMiddleware.RequirePermission(permission, h).ServeHTTP(w, r)
+	})
+}
+
+// EndUserStreams revokes the stream keys of an account and ends the rooms it is live in, for suspended accounts
+func (s *StreamingAPIService) EndUserStreams(ctx context.Context, userID string) {
+	identity := livekit.ParticipantIdentity(userID)
+	s.streamKeyManager.RevokeStreamerKeys(ctx, identity)
+	for _, roomName := range s.lifecycle.roomsOf(identity) {
+		if err := s.endStream(ctx, roomName); err != nil {
+			s.logger.Errorw("could not end stream of suspended account", err, "room", roomName, "userID", userID)
+		}
+	}
+}
+
+// endStream deletes the room of a stream, disconnecting the streamer and every viewer
+func (s *StreamingAPIService) endStream(ctx context.Context, roomName livekit.RoomName) error {
+	if s.roomService == nil {
+		return errRoomServiceNotConfigured
+	}
+	// the room service checks the grants of API keys, admins act on behalf of the server
+	ctx = WithGrants(ctx, &auth.ClaimGrants{Video: &auth.VideoGrant{RoomCreate: true}}, "")
+	_, err := s.roomService.DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: string(roomName)})
+	return err
+}
+
+func (s *StreamingAPIService) handleEndStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	adminID, _ := currentUser(r)
+
+	var req struct {
+		RoomName string `json:"room_name"`
+		Reason   string `json:"reason,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+
+	err := s.endStream(r.Context(), livekit.RoomName(req.RoomName))
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrRoomNotFound):
+		http.Error(w, "room not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errRoomServiceNotConfigured):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+		s.logger.Errorw("could not end stream", err, "room", req.RoomName)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Infow("stream ended by admin", "room", req.RoomName, "adminID", adminID, "reason", req.Reason)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// handleAdminRevokeStreamKeys revokes one stream key, or every key of a streamer
+func (s *StreamingAPIService) handleAdminRevokeStreamKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	adminID, _ := currentUser(r)
+
+	var req struct {
+		Key        string `json:"key,omitempty"`
+		StreamerID string `json:"streamer_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Key == "") == (req.StreamerID == "") {
+		http.Error(w, "either key or streamer_id required", http.StatusBadRequest)
+		return
+	}
+
+	revoked := 1
+	if req.Key != "" {
+		if err := s.streamKeyManager.RevokeStreamKey(r.Context(), req.Key); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	} else {
+		revoked = s.streamKeyManager.RevokeStreamerKeys(r.Context(), livekit.ParticipantIdentity(req.StreamerID))
+	}
+
+	s.logger.Infow("stream keys revoked by admin", "adminID", adminID, "streamerID", req.StreamerID, "count", revoked)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+}
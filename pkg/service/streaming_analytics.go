@@ -0,0 +1,354 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/streaming"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// StreamingStatsTap wraps the telemetry analytics service so that the stats collected by
+// the telemetry stats workers also reach the streaming analytics.
+type StreamingStatsTap struct {
+	telemetry.AnalyticsService
+
+	mu      sync.RWMutex
+	onStats func(stats []*livekit.AnalyticsStat)
+}
+
+func NewStreamingStatsTap(conf *config.Config, currentNode routing.LocalNode) *StreamingStatsTap {
+	return &StreamingStatsTap{
+		AnalyticsService: telemetry.NewAnalyticsService(conf, currentNode),
+	}
+}
+
+// OnStats sets the callback receiving every batch of stats. It must not keep or modify the stats.
+func (t *StreamingStatsTap) OnStats(f func(stats []*livekit.AnalyticsStat)) {
+	t.mu.Lock()
+	t.onStats = f
+	t.mu.Unlock()
+}
+
+func (t *StreamingStatsTap) SendStats(ctx context.Context, stats []*livekit.AnalyticsStat) {
+	t.mu.RLock()
+	onStats := t.onStats
+	t.mu.RUnlock()
+
+	if onStats != nil {
+		onStats(stats)
+	}
+	t.AnalyticsService.SendStats(ctx, stats)
+}
+
+// ---------------------------------------------
+
+type feedParticipant struct {
+	id       livekit.ParticipantID
+	identity livekit.ParticipantIdentity
+	roomName livekit.RoomName
+	platform string
+	device   string
+	address  string
+
+	// start of the stream this participant was recorded as a viewer of
+	viewing time.Time
+}
+
+// analyticsFeed records viewers and their delivery stats into the stream analytics.
+// Participants other than the streamer count as viewers while the room is live,
+// those who joined before the stream started are counted once it goes live.
+type analyticsFeed struct {
+	analytics  *streaming.AnalyticsService
+	streamerOf func(roomName livekit.RoomName) (livekit.ParticipantIdentity, bool)
+	logger     logger.Logger
+
+	mu           sync.Mutex
+	participants map[livekit.ParticipantID]*feedParticipant
+	rooms        map[livekit.RoomName]map[livekit.ParticipantID]*feedParticipant
+}
+
+func newAnalyticsFeed(
+	analytics *streaming.AnalyticsService,
+	streamerOf func(roomName livekit.RoomName) (livekit.ParticipantIdentity, bool),
+) *analyticsFeed {
+	return &analyticsFeed{
+		analytics:    analytics,
+		streamerOf:   streamerOf,
+		logger:       logger.GetLogger(),
+		participants: make(map[livekit.ParticipantID]*feedParticipant),
+		rooms:        make(map[livekit.RoomName]map[livekit.ParticipantID]*feedParticipant),
+	}
+}
+
+func (f *analyticsFeed) OnRoomStarted(_ *livekit.Room) {}
+
+func (f *analyticsFeed) OnRoomClosed(room *livekit.Room) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	roomName := livekit.RoomName(room.Name)
+	for id := range f.rooms[roomName] {
+		delete(f.participants, id)
+	}
+	delete(f.rooms, roomName)
+}
+
+func (f *analyticsFeed) OnParticipantJoined(room *livekit.Room, participant types.LocalParticipant) {
+	if !isEndUser(participant) {
+		return
+	}
+
+	p := &feedParticipant{
+		id:       participant.ID(),
+		identity: participant.Identity(),
+		roomName: livekit.RoomName(room.Name),
+	}
+	p.platform, p.device = streaming.DescribeClient(participant.GetClientInfo())
+	p.address = participant.GetClientInfo().GetAddress()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	members := f.rooms[p.roomName]
+	if members == nil {
+		members = make(map[livekit.ParticipantID]*feedParticipant)
+		f.rooms[p.roomName] = members
+	}
+	// a reconnecting viewer may join again before the previous session has left,
+	// the viewer session carries over instead of being counted twice
+	for id, other := range members {
+		if other.identity == p.identity {
+			p.viewing = other.viewing
+			other.viewing = time.Time{}
+			delete(members, id)
+			delete(f.participants, id)
+		}
+	}
+	members[p.id] = p
+	f.participants[p.id] = p
+
+	f.startViewingLocked(p)
+}
+
+func (f *analyticsFeed) OnParticipantLeft(room *livekit.Room, participant types.LocalParticipant) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.participants[participant.ID()]
+	if !ok {
+		return
+	}
+	delete(f.participants, p.id)
+	delete(f.rooms[p.roomName], p.id)
+
+	if start, live := f.analytics.ActiveStreamStart(p.roomName); live && p.viewing.Equal(start) {
+		if err := f.analytics.RecordViewerLeave(context.Background(), p.roomName, p.identity); err != nil {
+			f.logger.Debugw("could not record viewer leave", "error", err, "room", p.roomName, "viewerID", p.identity)
+		}
+	}
+}
+
+func (f *analyticsFeed) OnTrackPublished(room *livekit.Room, participant types.LocalParticipant, track types.MediaTrack) {
+	if track.Kind() != livekit.TrackType_VIDEO {
+		return
+	}
+
+	// the room may just have gone live, count everyone already watching
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, p := range f.rooms[livekit.RoomName(room.Name)] {
+		f.startViewingLocked(p)
+	}
+}
+
+func (f *analyticsFeed) startViewingLocked(p *feedParticipant) {
+	streamerID, live := f.streamerOf(p.roomName)
+	if !live || streamerID == p.identity {
+		return
+	}
+	start, live := f.analytics.ActiveStreamStart(p.roomName)
+	if !live || p.viewing.Equal(start) {
+		return
+	}
+
+	if err := f.analytics.RecordViewerJoin(context.Background(), p.roomName, p.identity, p.platform, p.device, "", ""); err != nil {
+		f.logger.Debugw("could not record viewer join", "error", err, "room", p.roomName, "viewerID", p.identity)
+		return
+	}
+	p.viewing = start
+	f.analytics.LocateViewer(p.roomName, p.identity, p.address)
+}
+
+type feedDelivery struct {
+	bytes    uint64
+	duration time.Duration
+	scoreSum float32
+	scores   int
+	layer    int32
+}
+
+func (d *feedDelivery) add(stat *livekit.AnalyticsStat) {
+	var duration time.Duration
+	for _, stream := range stat.Streams {
+		d.bytes += stream.PrimaryBytes + stream.RetransmitBytes
+		if stream.StartTime != nil && stream.EndTime != nil {
+			duration = max(duration, stream.EndTime.AsTime().Sub(stream.StartTime.AsTime()))
+		}
+		for _, layer := range stream.VideoLayers {
+			d.layer = max(d.layer, layer.Layer)
+		}
+	}
+	// tracks of a participant are sampled over the same interval
+	d.duration = max(d.duration, duration)
+
+	if stat.Score > 0 {
+		d.scoreSum += stat.Score
+		d.scores++
+	}
+}
+
+// bitrate returns the bitrate in kbps
+func (d *feedDelivery) bitrate() int {
+	if d.duration <= 0 {
+		return 0
+	}
+	return int(float64(d.bytes*8) / d.duration.Seconds() / 1000)
+}
+
+func (d *feedDelivery) quality() string {
+	if d.scores == 0 {
+		return ""
+	}
+	return streaming.ConnectionQualityFromScore(d.scoreSum / float32(d.scores))
+}
+
+func (d *feedDelivery) qualityLevel() string {
+	if d.layer < 0 {
+		return ""
+	}
+	return strings.ToLower(livekit.VideoQuality(d.layer).String())
+}
+
+// OnStats takes a batch of telemetry stats: the upload of streamers makes up the stream bitrate,
+// what viewers receive makes up their session bitrate, connection quality and quality level.
+func (f *analyticsFeed) OnStats(stats []*livekit.AnalyticsStat) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	upstream := make(map[*feedParticipant]*feedDelivery)
+	downstream := make(map[*feedParticipant]*feedDelivery)
+	for _, stat := range stats {
+		p, ok := f.participants[livekit.ParticipantID(stat.ParticipantId)]
+		if !ok {
+			continue
+		}
+		deliveries := downstream
+		if stat.Kind == livekit.StreamType_UPSTREAM {
+			deliveries = upstream
+		}
+		d, ok := deliveries[p]
+		if !ok {
+			d = &feedDelivery{layer: -1}
+			deliveries[p] = d
+		}
+		d.add(stat)
+	}
+
+	ctx := context.Background()
+	for p, d := range upstream {
+		if streamerID, live := f.streamerOf(p.roomName); !live || streamerID != p.identity || d.duration <= 0 {
+			continue
+		}
+		if err := f.analytics.RecordBitrateUpdate(ctx, p.roomName, d.bitrate()); err != nil {
+			f.logger.Debugw("could not record stream bitrate", "error", err, "room", p.roomName)
+		}
+	}
+	for p, d := range downstream {
+		if start, live := f.analytics.ActiveStreamStart(p.roomName); !live || !p.viewing.Equal(start) {
+			continue
+		}
+		if err := f.analytics.RecordViewerStats(ctx, p.roomName, p.identity, d.bitrate(), d.quality(), d.qualityLevel()); err != nil {
+			f.logger.Debugw("could not record viewer stats", "error", err, "room", p.roomName, "viewerID", p.identity)
+		}
+	}
+}
+
+// ---------------------------------------------
+
+const (
+	analyticsSocketWriteWait  = 10 * time.Second
+	analyticsSocketPongWait   = 60 * time.Second
+	analyticsSocketPingPeriod = analyticsSocketPongWait * 9 / 10
+)
+
+// serveAnalyticsSocket pushes analytics snapshots to a dashboard until the stream ends or the client goes away
+func serveAnalyticsSocket(conn *websocket.Conn, updates <-chan *streaming.StreamAnalytics, unsubscribe func()) {
+	defer conn.Close()
+
+	// clients only send control messages, reading notices them going away
+	go func() {
+		defer unsubscribe()
+		conn.SetReadLimit(512)
+		_ = conn.SetReadDeadline(time.Now().Add(analyticsSocketPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(analyticsSocketPongWait))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(analyticsSocketPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case analytics, ok := <-updates:
+			if !ok {
+				_ = conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+					time.Now().Add(analyticsSocketWriteWait),
+				)
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(analyticsSocketWriteWait))
+			if err := conn.WriteJSON(analytics); err != nil {
+				unsubscribe()
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(analyticsSocketWriteWait)); err != nil {
+				unsubscribe()
+				return
+			}
+		}
+	}
+}
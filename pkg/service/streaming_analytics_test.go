@@ -0,0 +1,168 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func newFakeViewer(id string, identity string, info *livekit.ClientInfo) *typesfakes.FakeLocalParticipant {
+	p := newFakeStreamer(identity)
+	p.IDReturns(livekit.ParticipantID(id))
+	p.GetClientInfoReturns(info)
+	return p
+}
+
+// downstreamStat describes one second of delivery to a participant
+func downstreamStat(participantID string, kind livekit.StreamType, bytes uint64, score float32, layer int32) *livekit.AnalyticsStat {
+	start := time.Now().Add(-time.Second)
+	stream := &livekit.AnalyticsStream{
+		PrimaryBytes: bytes,
+		StartTime:    timestamppb.New(start),
+		EndTime:      timestamppb.New(start.Add(time.Second)),
+	}
+	if layer >= 0 {
+		stream.VideoLayers = []*livekit.AnalyticsVideoLayer{{Layer: layer, Bytes: bytes}}
+	}
+	return &livekit.AnalyticsStat{
+		Kind:          kind,
+		RoomName:      "room",
+		ParticipantId: participantID,
+		Score:         score,
+		Streams:       []*livekit.AnalyticsStream{stream},
+	}
+}
+
+func TestAnalyticsFeed(t *testing.T) {
+	room := &livekit.Room{Name: "room"}
+	ctx := context.Background()
+
+	setup := func() (*streamLifecycle, *analyticsFeed, *streaming.AnalyticsService) {
+		analytics := streaming.NewAnalyticsService(&streaming.AnalyticsConfig{MaxTimelinePoints: 10, RetentionDays: 1})
+		l := newStreamLifecycle(defaultStreamLifecycleParams, streaming.NewNotificationService(nil), analytics)
+		return l, newAnalyticsFeed(analytics, l.streamerOf), analytics
+	}
+	// observers run in registration order, the lifecycle first
+	join := func(l *streamLifecycle, f *analyticsFeed, p *typesfakes.FakeLocalParticipant) {
+		l.OnParticipantJoined(room, p)
+		f.OnParticipantJoined(room, p)
+	}
+	publish := func(l *streamLifecycle, f *analyticsFeed, p *typesfakes.FakeLocalParticipant) {
+		track := newFakeTrack(livekit.TrackType_VIDEO)
+		l.OnTrackPublished(room, p, track)
+		f.OnTrackPublished(room, p, track)
+	}
+	leave := func(l *streamLifecycle, f *analyticsFeed, p *typesfakes.FakeLocalParticipant) {
+		l.OnParticipantLeft(room, p)
+		f.OnParticipantLeft(room, p)
+	}
+	sessionOf := func(analytics *streaming.AnalyticsService, identity livekit.ParticipantIdentity) *streaming.ViewerSession {
+		sessions, _ := analytics.GetViewerSessions(ctx, "room")
+		for _, s := range sessions {
+			if s.ViewerID == identity {
+				return s
+			}
+		}
+		return nil
+	}
+
+	t.Run("records viewers of the live stream", func(t *testing.T) {
+		l, f, analytics := setup()
+		streamer := newFakeViewer("PA_streamer", "streamer", nil)
+		early := newFakeViewer("PA_early", "early", &livekit.ClientInfo{Sdk: livekit.ClientInfo_JS, Os: "macOS", Browser: "Chrome"})
+		late := newFakeViewer("PA_late", "late", &livekit.ClientInfo{Sdk: livekit.ClientInfo_SWIFT, Os: "iOS", DeviceModel: "iPhone15,2"})
+		recorder := newFakeViewer("PA_egress", "egress", nil)
+		recorder.KindReturns(livekit.ParticipantInfo_EGRESS)
+
+		join(l, f, streamer)
+		join(l, f, early)
+		join(l, f, recorder)
+		publish(l, f, streamer)
+		join(l, f, late)
+
+		stream, err := analytics.GetStreamAnalytics(ctx, "room")
+		require.NoError(t, err)
+		require.Equal(t, 2, stream.CurrentViewers)
+		require.Equal(t, map[string]int{"web": 1, "ios": 1}, stream.ViewersByPlatform)
+		require.Equal(t, map[string]int{"desktop": 1, "mobile": 1}, stream.ViewersByDevice)
+		require.Nil(t, sessionOf(analytics, "streamer"))
+
+		// publishing more video does not count viewers again
+		publish(l, f, streamer)
+		leave(l, f, early)
+		stream, _ = analytics.GetStreamAnalytics(ctx, "room")
+		require.Equal(t, 1, stream.CurrentViewers)
+		require.Equal(t, 2, stream.TotalViewers)
+		require.NotNil(t, sessionOf(analytics, "early").LeftAt)
+	})
+
+	t.Run("reconnecting viewer keeps the session", func(t *testing.T) {
+		l, f, analytics := setup()
+		streamer := newFakeViewer("PA_streamer", "streamer", nil)
+		publish(l, f, streamer)
+
+		join(l, f, newFakeViewer("PA_first", "viewer", nil))
+		join(l, f, newFakeViewer("PA_second", "viewer", nil))
+		leave(l, f, newFakeViewer("PA_first", "viewer", nil))
+
+		stream, _ := analytics.GetStreamAnalytics(ctx, "room")
+		require.Equal(t, 1, stream.CurrentViewers)
+		require.Equal(t, 1, stream.TotalViewers)
+		require.Nil(t, sessionOf(analytics, "viewer").LeftAt)
+	})
+
+	t.Run("records delivery stats", func(t *testing.T) {
+		l, f, analytics := setup()
+		streamer := newFakeViewer("PA_streamer", "streamer", nil)
+		viewer := newFakeViewer("PA_viewer", "viewer", nil)
+		join(l, f, streamer)
+		publish(l, f, streamer)
+		join(l, f, viewer)
+
+		f.OnStats([]*livekit.AnalyticsStat{
+			downstreamStat("PA_streamer", livekit.StreamType_UPSTREAM, 250_000, 4.4, 2),
+			downstreamStat("PA_viewer", livekit.StreamType_DOWNSTREAM, 100_000, 4.4, 2),
+			downstreamStat("PA_viewer", livekit.StreamType_DOWNSTREAM, 25_000, 4.2, -1),
+			downstreamStat("PA_unknown", livekit.StreamType_DOWNSTREAM, 25_000, 1, 0),
+		})
+		session := sessionOf(analytics, "viewer")
+		require.Equal(t, 1000, session.AvgBitrate)
+		require.Equal(t, "excellent", session.ConnectionQuality)
+		require.Equal(t, "high", session.QualityLevel)
+
+		f.OnStats([]*livekit.AnalyticsStat{
+			downstreamStat("PA_viewer", livekit.StreamType_DOWNSTREAM, 62_500, 2.5, 0),
+		})
+		session = sessionOf(analytics, "viewer")
+		require.Equal(t, 750, session.AvgBitrate)
+		require.Equal(t, "fair", session.ConnectionQuality)
+		require.Equal(t, "low", session.QualityLevel)
+
+		stream, _ := analytics.GetStreamAnalytics(ctx, "room")
+		require.Equal(t, 2000, stream.PeakBitrate)
+		require.Equal(t, 2000, stream.AverageBitrate)
+		require.Equal(t, 1, stream.QualityChanges)
+	})
+}
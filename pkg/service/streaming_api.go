@@ -15,28 +15,60 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
 
+	appauth "github.com/livekit/livekit-server/pkg/auth"
+	apphandler "github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/storage"
 	"github.com/livekit/livekit-server/pkg/streaming"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	defaultNotificationListLimit = 50
+	defaultAnalyticsRange        = 30 * 24 * time.Hour
+	maxQoEBeaconSize             = 64 << 10
 )
 
 // StreamingAPIService provides HTTP/WebSocket APIs for the streaming features
 type StreamingAPIService struct {
 	streamKeyManager    *streaming.StreamKeyManager
 	chatService         *streaming.ChatService
+	chatHub             *chatHub
 	reactionService     *streaming.ReactionService
 	vodService          *streaming.VODService
 	notificationService *streaming.NotificationService
 	analyticsService    *streaming.AnalyticsService
 	egressService       *EgressService
+	roomService         livekit.RoomService
+	notificationHub     *notificationHub
+	emailNotifier       *streaming.EmailNotifier
+	pushNotifier        *streaming.PushNotifier
+	lifecycle           *streamLifecycle
+	roomClaims          *roomClaims
+	directory           *liveDirectory
+	thumbnails          *thumbnailCapturer
+	schedules           *streaming.ScheduleManager
+	scheduler           *streamScheduler
+	stages              *streaming.StageManager
+	stageTracker        *stageTracker
+	raids               *raidTracker
+	analyticsFeed       *analyticsFeed
+	authMiddleware      *apphandler.AuthMiddleware
+	users               *storage.UserRepository
+	policy              appauth.Policy
 	logger              logger.Logger
 	upgrader            websocket.Upgrader
 	apiKey              string
@@ -44,14 +76,69 @@ type StreamingAPIService struct {
 }
 
 // NewStreamingAPIService creates a new streaming API service
-func NewStreamingAPIService(egressService *EgressService) *StreamingAPIService {
-	return &StreamingAPIService{
+func NewStreamingAPIService(conf *streaming.Config, egressService *EgressService) *StreamingAPIService {
+	if conf == nil {
+		conf = &streaming.Config{}
+	}
+
+	notificationConfig := streaming.DefaultNotificationConfig
+	notificationConfig.EnableEmail = conf.Email.Enabled
+	notificationConfig.EmailFromAddress = conf.Email.FromAddress
+	notificationConfig.SMTPServer = conf.Email.SMTPServer
+	notificationConfig.SMTPPort = conf.Email.SMTPPort
+
+	var pushNotifier *streaming.PushNotifier
+	if conf.Push.Enabled {
+		var err error
+		if pushNotifier, err = streaming.NewPushNotifier(conf.Push); err != nil {
+			logger.Errorw("could not set up push notifications", err)
+		}
+	}
+	notificationConfig.EnablePush = pushNotifier != nil
+
+	notificationService := streaming.NewNotificationService(&notificationConfig)
+	analyticsConfig := streaming.DefaultAnalyticsConfig
+	analyticsConfig.UpdateInterval = conf.Analytics.UpdateInterval
+	analyticsConfig.TimelineResolution = conf.Analytics.TimelineResolution
+	analyticsConfig.MaxTimelinePoints = conf.Analytics.MaxTimelinePoints
+	if conf.Analytics.RetentionDays > 0 {
+		analyticsConfig.RetentionDays = conf.Analytics.RetentionDays
+	}
+	analyticsService := streaming.NewAnalyticsService(&analyticsConfig)
+	if conf.GeoIP.Enabled {
+		if resolver, err := streaming.OpenMMDBResolver(conf.GeoIP.DatabasePath); err != nil {
+			logger.Errorw("could not set up geoip lookups", err)
+		} else {
+			analyticsService.SetGeoResolver(resolver)
+		}
+	}
+	lifecycle := newStreamLifecycle(defaultStreamLifecycleParams, notificationService, analyticsService)
+	thumbnails := newThumbnailCapturer(conf.Thumbnails, lifecycle.streamerOf)
+	schedules := streaming.NewScheduleManager(conf.Schedule)
+	scheduler := newStreamScheduler(schedules, notificationService)
+	lifecycle.onLive = scheduler.onLive
+	lifecycle.onEnded = scheduler.onEnded
+	stages := streaming.NewStageManager(conf.Stage)
+	s := &StreamingAPIService{
 		streamKeyManager:    streaming.NewStreamKeyManager(),
 		chatService:         streaming.NewChatService(),
+		chatHub:             newChatHub(),
 		reactionService:     streaming.NewReactionService(nil),
 		vodService:          streaming.NewVODService(nil),
-		notificationService: streaming.NewNotificationService(nil),
-		analyticsService:    streaming.NewAnalyticsService(nil),
+		notificationService: notificationService,
+		notificationHub:     newNotificationHub(notificationService),
+		pushNotifier:        pushNotifier,
+		analyticsService:    analyticsService,
+		lifecycle:           lifecycle,
+		roomClaims:          newRoomClaims(),
+		directory:           newLiveDirectory(lifecycle.streamerOf, thumbnails.thumbnailURL),
+		thumbnails:          thumbnails,
+		schedules:           schedules,
+		scheduler:           scheduler,
+		stages:              stages,
+		stageTracker:        &stageTracker{stages: stages},
+		raids:               newRaidTracker(),
+		analyticsFeed:       newAnalyticsFeed(analyticsService, lifecycle.streamerOf),
 		egressService:       egressService,
 		logger:              logger.GetLogger(),
 		apiKey:              "devkey", // Default dev key - should load from config
@@ -62,27 +149,152 @@ func NewStreamingAPIService(egressService *EgressService) *StreamingAPIService {
 			},
 		},
 	}
+
+	s.chatService.RegisterMessageHandler(s.onChatMessage)
+	s.chatService.RegisterMessageHandler(s.chatHub.onMessage)
+	s.stageTracker.onChange = s.publishStage
+	s.reactionService.RegisterReactionHandler(s.onReaction)
+
+	if conf.Email.Enabled {
+		s.emailNotifier = streaming.NewEmailNotifier(conf.Email, s.lookupEmail)
+		notificationService.RegisterNotificationHandler(streaming.ChannelEmail, s.emailNotifier.Handle)
+	}
+	if pushNotifier != nil {
+		notificationService.RegisterNotificationHandler(streaming.ChannelPush, pushNotifier.Handle)
+	}
+
+	return s
+}
+
+// Stop releases background workers
+func (s *StreamingAPIService) Stop() {
+	s.thumbnails.close()
+	s.scheduler.close()
+	s.raids.close()
+	if s.emailNotifier != nil {
+		s.emailNotifier.Stop()
+	}
+}
+
+// SetAuthMiddleware sets the application auth used by per-user endpoints.
+// Until it is set, those endpoints respond with 503.
+func (s *StreamingAPIService) SetAuthMiddleware(m *apphandler.AuthMiddleware) {
+	s.authMiddleware = m
+}
+
+// SetUserRepository sets the account store used to reach users outside the app, e.g. by email
+func (s *StreamingAPIService) SetUserRepository(users *storage.UserRepository) {
+	s.users = users
+}
+
+// SetAccountPolicy sets the policy deciding who may go live and chat, everyone may when unset
+func (s *StreamingAPIService) SetAccountPolicy(policy appauth.Policy) {
+	s.policy = policy
+}
+
+// authorizeAction checks the account policy for an action, writing the response when it is not allowed
+func (s *StreamingAPIService) authorizeAction(w http.ResponseWriter, r *http.Request, userID string, action appauth.Action) bool {
+	if s.policy == nil {
+		return true
+	}
+	err := s.policy.Authorize(r.Context(), userID, action)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, appauth.ErrEmailNotVerified), errors.Is(err, appauth.ErrUnknownAccount), errors.Is(err, appauth.ErrMFARequired),
+		errors.Is(err, appauth.ErrAccountSuspended), errors.Is(err, appauth.ErrPermissionDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		s.logger.Errorw("could not check account policy", err, "userID", userID, "action", action)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// isOwnerOr checks that a user is the owner of what they act on or that the account policy allows them the action,
+// writing the response when neither holds. Without a policy only owners are allowed.
+func (s *StreamingAPIService) isOwnerOr(w http.ResponseWriter, r *http.Request, owner, userID livekit.ParticipantIdentity, action appauth.Action) bool {
+	if owner != "" && owner == userID {
+		return true
+	}
+	if s.policy == nil {
+		http.Error(w, appauth.ErrPermissionDenied.Error(), http.StatusForbidden)
+		return false
+	}
+	return s.authorizeAction(w, r, string(userID), action)
+}
+
+// canModerate checks that a user owns a room or may moderate chats, writing the response when neither holds
+func (s *StreamingAPIService) canModerate(w http.ResponseWriter, r *http.Request, roomName livekit.RoomName, userID livekit.ParticipantIdentity) bool {
+	owner, _, err := s.roomOwner(r.Context(), roomName)
+	if err != nil {
+		s.logger.Errorw("could not look up room owner", err, "room", roomName)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return false
+	}
+	return s.isOwnerOr(w, r, owner, userID, appauth.ActionModerate)
+}
+
+// RoomObservers returns the observers that drive stream lifecycle events and analytics from rooms,
+// in the order they need to be registered
+func (s *StreamingAPIService) RoomObservers() []RoomObserver {
+	return []RoomObserver{s.lifecycle, s.analyticsFeed, s.thumbnails, s.directory, s.stageTracker}
+}
+
+// OnAnalyticsStats feeds telemetry stats into the stream analytics
+func (s *StreamingAPIService) OnAnalyticsStats(stats []*livekit.AnalyticsStat) {
+	s.analyticsFeed.OnStats(stats)
+}
+
+// ChatService returns the chat service backing the API
+func (s *StreamingAPIService) ChatService() *streaming.ChatService {
+	return s.chatService
+}
+
+// NotificationService returns the notification service backing the API
+func (s *StreamingAPIService) NotificationService() *streaming.NotificationService {
+	return s.notificationService
+}
+
+// AnalyticsService returns the analytics service backing the API
+func (s *StreamingAPIService) AnalyticsService() *streaming.AnalyticsService {
+	return s.analyticsService
 }
 
 // RegisterHTTPHandlers registers all HTTP handlers
 func (s *StreamingAPIService) RegisterHTTPHandlers(mux *http.ServeMux) {
 	// LiveKit Token Generation (NEW)
 	mux.HandleFunc("/api/streaming/token", s.handleGetToken)
+	mux.Handle("/api/streaming/token/exchange", s.authorized(s.handleExchangeToken))
+
+	// Live directory
+	mux.HandleFunc("/api/streaming/list", s.handleListStreams)
+	mux.HandleFunc("/api/streaming/thumbnail", s.handleGetThumbnail)
+
+	// Scheduled streams
+	mux.HandleFunc("/api/streaming/schedule", s.handleSchedule)
+	mux.HandleFunc("/api/streaming/schedule/calendar.ics", s.handleScheduleCalendar)
 
 	// Stream Key Management
-	mux.HandleFunc("/api/streaming/keys/generate", s.handleGenerateStreamKey)
+	mux.Handle("/api/streaming/keys/generate", s.authorized(s.handleGenerateStreamKey))
 	mux.HandleFunc("/api/streaming/keys/validate", s.handleValidateStreamKey)
-	mux.HandleFunc("/api/streaming/keys/revoke", s.handleRevokeStreamKey)
-	mux.HandleFunc("/api/streaming/keys/list", s.handleListStreamKeys)
+	mux.Handle("/api/streaming/keys/revoke", s.authorized(s.handleRevokeStreamKey))
+	mux.Handle("/api/streaming/keys/list", s.authorized(s.handleListStreamKeys))
 
 	// Chat
 	mux.HandleFunc("/api/streaming/chat/create", s.handleCreateChatRoom)
-	mux.HandleFunc("/api/streaming/chat/send", s.handleSendChatMessage)
+	mux.Handle("/api/streaming/chat/send", s.authorized(s.handleSendChatMessage))
 	mux.HandleFunc("/api/streaming/chat/messages", s.handleGetChatMessages)
-	mux.HandleFunc("/api/streaming/chat/mute", s.handleMuteParticipant)
-	mux.HandleFunc("/api/streaming/chat/ban", s.handleBanParticipant)
+	mux.Handle("/api/streaming/chat/mute", s.authorized(s.handleMuteParticipant))
+	mux.Handle("/api/streaming/chat/ban", s.authorized(s.handleBanParticipant))
 	mux.HandleFunc("/api/streaming/chat/ws", s.handleChatWebSocket)
 
+	// Guests on stage
+	s.registerStageHandlers(mux)
+
+	// Raids
+	mux.Handle("/api/streaming/raid", s.authorized(s.handleRaid))
+
 	// Reactions
 	mux.HandleFunc("/api/streaming/reactions/send", s.handleSendReaction)
 	mux.HandleFunc("/api/streaming/reactions/stats", s.handleGetReactionStats)
@@ -97,21 +309,104 @@ func (s *StreamingAPIService) RegisterHTTPHandlers(mux *http.ServeMux) {
 	mux.HandleFunc("/api/streaming/vod/play", s.handlePlayRecording)
 
 	// Notifications
-	mux.HandleFunc("/api/streaming/notifications/subscribe", s.handleSubscribe)
-	mux.HandleFunc("/api/streaming/notifications/unsubscribe", s.handleUnsubscribe)
-	mux.HandleFunc("/api/streaming/notifications/list", s.handleGetNotifications)
-	mux.HandleFunc("/api/streaming/notifications/read", s.handleMarkAsRead)
-	mux.HandleFunc("/api/streaming/notifications/ws", s.handleNotificationsWebSocket)
+	mux.Handle("/api/streaming/notifications/subscribe", s.authorized(s.handleSubscribe))
+	mux.Handle("/api/streaming/notifications/unsubscribe", s.authorized(s.handleUnsubscribe))
+	mux.Handle("/api/streaming/notifications/list", s.authorized(s.handleGetNotifications))
+	mux.Handle("/api/streaming/notifications/read", s.authorized(s.handleMarkAsRead))
+	mux.Handle("/api/streaming/notifications/ws", s.authorized(s.handleNotificationsWebSocket))
+	mux.Handle("/api/streaming/notifications/email", s.authorized(s.handleEmailPreferences))
+	mux.HandleFunc("/api/streaming/notifications/push/key", s.handlePushKey)
+	mux.Handle("/api/streaming/notifications/push/subscribe", s.authorized(s.handlePushSubscribe))
+	mux.Handle("/api/streaming/notifications/push/unsubscribe", s.authorized(s.handlePushUnsubscribe))
 
 	// Analytics
 	mux.HandleFunc("/api/streaming/analytics/stream", s.handleGetStreamAnalytics)
-	mux.HandleFunc("/api/streaming/analytics/dashboard", s.handleGetDashboard)
-	mux.HandleFunc("/api/streaming/analytics/export", s.handleExportAnalytics)
+	mux.Handle("/api/streaming/analytics/dashboard", s.authorized(s.handleGetDashboard))
+	mux.Handle("/api/streaming/analytics/export", s.authorized(s.handleExportAnalytics))
+	mux.Handle("/api/streaming/analytics/live", s.authorized(s.handleLiveAnalytics))
+	mux.HandleFunc("/api/streaming/analytics/qoe", s.handleQoEBeacon)
+
+	// Admin
+	s.registerAdminHandlers(mux)
 
 	s.logger.Infow("registered streaming API handlers")
 }
 
+// authorized wraps handlers that act on behalf of the logged-in user
+func (s *StreamingAPIService) authorized(h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authMiddleware == nil {
+			http.Error(w, "authentication not configured", http.StatusServiceUnavailable)
+			return
+		}
+		s.authMiddleware.Authorize(h).ServeHTTP(w, r)
+	})
+}
+
+// onChatMessage counts messages towards the stream analytics and turns mentions and replies into notifications
+func (s *StreamingAPIService) onChatMessage(message *streaming.ChatMessage) {
+	// fails when the room is not live, which is fine
+	_ = s.analyticsService.RecordChatMessage(context.Background(), message.RoomName, message.SenderID)
+
+	if len(message.MentionedUsers) == 0 && message.ReplyToSenderID == "" {
+		return
+	}
+
+	streamerID, _ := s.lifecycle.streamerOf(message.RoomName)
+	if err := s.notificationService.NotifyChatMessage(context.Background(), message, streamerID); err != nil {
+		s.logger.Warnw("could not notify chat message", err, "room", message.RoomName)
+	}
+}
+
+// onReaction counts reactions towards the stream analytics
+func (s *StreamingAPIService) onReaction(reaction *streaming.Reaction) {
+	_ = s.analyticsService.RecordReaction(context.Background(), reaction.RoomName, reaction.UserID, reaction.Type)
+}
+
+// lookupEmail resolves the email address of an account
+func (s *StreamingAPIService) lookupEmail(ctx context.Context, userID livekit.ParticipantIdentity) (string, error) {
+	if s.users == nil {
+		return "", errors.New("user repository not configured")
+	}
+	user, err := s.users.GetByID(ctx, string(userID))
+	if err != nil {
+		return "", err
+	}
+	return user.Email, nil
+}
+
+// EmailNotifier returns the notifier delivering emails over SMTP, nil when email is not enabled
+func (s *StreamingAPIService) EmailNotifier() *streaming.EmailNotifier {
+	return s.emailNotifier
+}
+
+// DeleteUserData removes what the streaming services hold for a deleted account:
+// stream keys, scheduled streams, recordings, follows in both directions, notifications and notification channels
+func (s *StreamingAPIService) DeleteUserData(ctx context.Context, userID string) {
+	identity := livekit.ParticipantIdentity(userID)
+	s.streamKeyManager.DeleteStreamerKeys(ctx, identity)
+	s.schedules.DeleteStreamer(ctx, identity)
+	s.vodService.DeleteStreamerRecordings(ctx, identity)
+	s.notificationService.RemoveUser(ctx, identity)
+	if s.emailNotifier != nil {
+		s.emailNotifier.RemoveUser(identity)
+	}
+	if s.pushNotifier != nil {
+		s.pushNotifier.RemoveUser(identity)
+	}
+	s.logger.Infow("removed streaming data of deleted account", "userID", userID)
+}
+
+// currentUser returns the identity of the authenticated user
+func currentUser(r *http.Request) (livekit.ParticipantIdentity, bool) {
+	userID, ok := apphandler.UserIDFromContext(r.Context())
+	return livekit.ParticipantIdentity(userID), ok
+}
+
 // LiveKit Token Generation Handler
+
+// handleGetToken issues a token for watching a room anonymously. Viewers get a random guest identity and may
+// neither publish nor send data, chatting needs a token for the logged-in account from handleExchangeToken.
 func (s *StreamingAPIService) handleGetToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost && r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -121,7 +416,6 @@ func (s *StreamingAPIService) handleGetToken(w http.ResponseWriter, r *http.Requ
 	// Parse request
 	var req struct {
 		RoomName    string `json:"room_name"`
-		Identity    string `json:"identity"`
 		IsPublisher bool   `json:"is_publisher"` // true for streamer, false for viewer
 	}
 
@@ -132,39 +426,34 @@ func (s *StreamingAPIService) handleGetToken(w http.ResponseWriter, r *http.Requ
 		}
 	} else {
 		req.RoomName = r.URL.Query().Get("room_name")
-		req.Identity = r.URL.Query().Get("identity")
 		req.IsPublisher = r.URL.Query().Get("is_publisher") == "true"
 	}
 
-	if req.RoomName == "" || req.Identity == "" {
-		http.Error(w, "room_name and identity required", http.StatusBadRequest)
+	if req.RoomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+
+	// publishing needs a logged-in owner of the room, see handleExchangeToken
+	if req.IsPublisher {
+		http.Error(w, "publishing requires a token from /api/streaming/token/exchange", http.StatusForbidden)
 		return
 	}
 
-	// Create video grant
+	// Guest viewer permissions
 	grant := &auth.VideoGrant{
 		RoomJoin: true,
 		Room:     req.RoomName,
 	}
+	grant.SetCanPublish(false)
+	grant.SetCanPublishData(false)
+	grant.SetCanSubscribe(true)
 
-	if req.IsPublisher {
-		// Streamer permissions
-		grant.SetCanPublish(true)
-		grant.SetCanPublishData(true)
-		grant.SetCanSubscribe(true)
-		grant.RoomRecord = true // Allow recording
-	} else {
-		// Viewer permissions
-		grant.SetCanPublish(false)
-		grant.SetCanPublishData(true) // Allow chat/reactions
-		grant.SetCanSubscribe(true)
-	}
-
-	// Create access token
+	identity := utils.NewGuid(guestIdentityPrefix)
 	at := auth.NewAccessToken(s.apiKey, s.apiSecret)
 	at.AddGrant(grant).
-		SetIdentity(req.Identity).
-		SetValidFor(24 * time.Hour) // Valid for 24 hours
+		SetIdentity(identity).
+		SetValidFor(roomTokenTTL)
 
 	token, err := at.ToJWT()
 	if err != nil {
@@ -173,14 +462,13 @@ func (s *StreamingAPIService) handleGetToken(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Return token
-	response := map[string]string{
-		"token": token,
-		"url":   "ws://localhost:7880", // Should be configurable
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(roomTokenResponse{
+		Token:    token,
+		URL:      signalURL(r),
+		Identity: identity,
+		RoomName: req.RoomName,
+	})
 }
 
 // Stream Key Management Handlers
@@ -191,8 +479,9 @@ func (s *StreamingAPIService) handleGenerateStreamKey(w http.ResponseWriter, r *
 		return
 	}
 
+	streamerID, _ := currentUser(r)
+
 	var req struct {
-		StreamerID  string                 `json:"streamer_id"`
 		RoomName    string                 `json:"room_name"`
 		ExpiresIn   *int64                 `json:"expires_in,omitempty"` // seconds
 		Permissions map[string]interface{} `json:"permissions,omitempty"`
@@ -203,6 +492,19 @@ func (s *StreamingAPIService) handleGenerateStreamKey(w http.ResponseWriter, r *
 		return
 	}
 
+	if !s.authorizeAction(w, r, string(streamerID), appauth.ActionGoLive) {
+		return
+	}
+	err := s.checkRoomOwner(r.Context(), livekit.RoomName(req.RoomName), streamerID, false)
+	if errors.Is(err, errRoomOwned) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		s.logger.Errorw("could not look up room owner", err, "room", req.RoomName)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
 	var expiresIn *time.Duration
 	if req.ExpiresIn != nil {
 		d := time.Duration(*req.ExpiresIn) * time.Second
@@ -211,7 +513,7 @@ func (s *StreamingAPIService) handleGenerateStreamKey(w http.ResponseWriter, r *
 
 	streamKey, err := s.streamKeyManager.GenerateStreamKey(
 		r.Context(),
-		livekit.ParticipantIdentity(req.StreamerID),
+		streamerID,
 		livekit.RoomName(req.RoomName),
 		nil, // permissions
 		expiresIn,
@@ -271,6 +573,16 @@ func (s *StreamingAPIService) handleRevokeStreamKey(w http.ResponseWriter, r *ht
 		return
 	}
 
+	owner, ok := s.streamKeyManager.KeyOwner(req.Key)
+	if !ok {
+		http.Error(w, "stream key not found", http.StatusNotFound)
+		return
+	}
+	userID, _ := currentUser(r)
+	if !s.isOwnerOr(w, r, owner, userID, appauth.ActionRevokeKeys) {
+		return
+	}
+
 	err := s.streamKeyManager.RevokeStreamKey(r.Context(), req.Key)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -289,16 +601,17 @@ func (s *StreamingAPIService) handleListStreamKeys(w http.ResponseWriter, r *htt
 		return
 	}
 
-	streamerID := r.URL.Query().Get("streamer_id")
+	// streamers list their own keys unless they may revoke those of others
+	userID, _ := currentUser(r)
+	streamerID := livekit.ParticipantIdentity(r.URL.Query().Get("streamer_id"))
 	if streamerID == "" {
-		http.Error(w, "streamer_id required", http.StatusBadRequest)
+		streamerID = userID
+	}
+	if !s.isOwnerOr(w, r, streamerID, userID, appauth.ActionRevokeKeys) {
 		return
 	}
 
-	keys, err := s.streamKeyManager.GetStreamKeysByStreamer(
-		r.Context(),
-		livekit.ParticipantIdentity(streamerID),
-	)
+	keys, err := s.streamKeyManager.GetStreamKeysByStreamer(r.Context(), streamerID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -364,13 +677,14 @@ func (s *StreamingAPIService) handleSendChatMessage(w http.ResponseWriter, r *ht
 		return
 	}
 
+	senderID, _ := currentUser(r)
+
 	var req struct {
-		RoomName       string   `json:"room_name"`
-		SenderID       string   `json:"sender_id"`
-		SenderName     string   `json:"sender_name"`
-		Content        string   `json:"content"`
-		MessageType    string   `json:"message_type"`
-		MentionedUsers []string `json:"mentioned_users,omitempty"`
+		RoomName    string `json:"room_name"`
+		SenderName  string `json:"sender_name"`
+		Content     string `json:"content"`
+		MessageType string `json:"message_type"`
+		ReplyTo     string `json:"reply_to,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -378,19 +692,22 @@ func (s *StreamingAPIService) handleSendChatMessage(w http.ResponseWriter, r *ht
 		return
 	}
 
-	mentioned := make([]livekit.ParticipantIdentity, len(req.MentionedUsers))
-	for i, u := range req.MentionedUsers {
-		mentioned[i] = livekit.ParticipantIdentity(u)
+	if !s.authorizeAction(w, r, string(senderID), appauth.ActionChat) {
+		return
+	}
+
+	var replyTo *string
+	if req.ReplyTo != "" {
+		replyTo = &req.ReplyTo
 	}
 
 	message, err := s.chatService.SendMessage(
 		r.Context(),
 		livekit.RoomName(req.RoomName),
-		livekit.ParticipantIdentity(req.SenderID),
+		senderID,
 		req.Content,
 		streaming.ChatMessageType(req.MessageType),
-		mentioned,
-		nil,
+		replyTo,
 	)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -437,7 +754,6 @@ func (s *StreamingAPIService) handleMuteParticipant(w http.ResponseWriter, r *ht
 	var req struct {
 		RoomName      string `json:"room_name"`
 		ParticipantID string `json:"participant_id"`
-		ModeratorID   string `json:"moderator_id"`
 		DurationSecs  int64  `json:"duration_secs"`
 	}
 
@@ -446,13 +762,18 @@ func (s *StreamingAPIService) handleMuteParticipant(w http.ResponseWriter, r *ht
 		return
 	}
 
+	moderatorID, _ := currentUser(r)
+	if !s.canModerate(w, r, livekit.RoomName(req.RoomName), moderatorID) {
+		return
+	}
+
 	duration := time.Duration(req.DurationSecs) * time.Second
 
 	err := s.chatService.MuteParticipant(
 		r.Context(),
 		livekit.RoomName(req.RoomName),
 		livekit.ParticipantIdentity(req.ParticipantID),
-		livekit.ParticipantIdentity(req.ModeratorID),
+		moderatorID,
 		duration,
 	)
 	if err != nil {
@@ -473,7 +794,6 @@ func (s *StreamingAPIService) handleBanParticipant(w http.ResponseWriter, r *htt
 	var req struct {
 		RoomName      string `json:"room_name"`
 		ParticipantID string `json:"participant_id"`
-		ModeratorID   string `json:"moderator_id"`
 		DurationSecs  int64  `json:"duration_secs"`
 	}
 
@@ -482,13 +802,18 @@ func (s *StreamingAPIService) handleBanParticipant(w http.ResponseWriter, r *htt
 		return
 	}
 
+	moderatorID, _ := currentUser(r)
+	if !s.canModerate(w, r, livekit.RoomName(req.RoomName), moderatorID) {
+		return
+	}
+
 	duration := time.Duration(req.DurationSecs) * time.Second
 
 	err := s.chatService.BanParticipant(
 		r.Context(),
 		livekit.RoomName(req.RoomName),
 		livekit.ParticipantIdentity(req.ParticipantID),
-		livekit.ParticipantIdentity(req.ModeratorID),
+		moderatorID,
 		duration,
 	)
 	if err != nil {
@@ -502,17 +827,23 @@ func (s *StreamingAPIService) handleBanParticipant(w http.ResponseWriter, r *htt
 
 // WebSocket Handlers
 
+// handleChatWebSocket pushes the chat messages of a room and the changes to its stage, starting with the stage
 func (s *StreamingAPIService) handleChatWebSocket(w http.ResponseWriter, r *http.Request) {
+	roomName := livekit.RoomName(r.URL.Query().Get("room_name"))
+	if roomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.logger.Errorw("failed to upgrade websocket", err)
 		return
 	}
-	defer conn.Close()
 
-	// Handle chat WebSocket connection
-	// Implementation would handle real-time chat messages
-	s.logger.Infow("chat websocket connected")
+	s.logger.Infow("chat websocket connected", "room", roomName)
+	stage := publicStage(s.stages.Get(roomName, time.Now()))
+	s.chatHub.serve(roomName, conn, &chatEvent{Type: "stage", Stage: stage})
 }
 
 func (s *StreamingAPIService) handleReactionsWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -528,15 +859,134 @@ func (s *StreamingAPIService) handleReactionsWebSocket(w http.ResponseWriter, r
 }
 
 func (s *StreamingAPIService) handleNotificationsWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.logger.Errorw("failed to upgrade websocket", err)
 		return
 	}
-	defer conn.Close()
 
-	// Handle notifications WebSocket connection
-	s.logger.Infow("notifications websocket connected")
+	s.logger.Infow("notifications websocket connected", "userID", userID)
+	s.notificationHub.serve(userID, conn)
+}
+
+func (s *StreamingAPIService) handleEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if s.emailNotifier == nil {
+		http.Error(w, "email notifications are not enabled", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var prefs streaming.EmailPreferences
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		s.emailNotifier.SetPreferences(userID, prefs)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.emailNotifier.GetPreferences(userID))
+}
+
+func (s *StreamingAPIService) handlePushKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.pushNotifier == nil {
+		http.Error(w, "push notifications are not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"public_key": s.pushNotifier.PublicKey(),
+	})
+}
+
+func (s *StreamingAPIService) handlePushSubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if s.pushNotifier == nil {
+		http.Error(w, "push notifications are not enabled", http.StatusNotFound)
+		return
+	}
+
+	var sub streaming.PushSubscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.pushNotifier.Subscribe(userID, &sub); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+func (s *StreamingAPIService) handlePushUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if s.pushNotifier == nil {
+		http.Error(w, "push notifications are not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Endpoint string `json:"endpoint"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Endpoint == "" {
+		http.Error(w, "endpoint is required", http.StatusBadRequest)
+		return
+	}
+
+	s.pushNotifier.Unsubscribe(userID, req.Endpoint)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
 }
 
 // Reaction Handlers
@@ -813,25 +1263,200 @@ func (s *StreamingAPIService) handlePlayRecording(w http.ResponseWriter, r *http
 	http.Error(w, "Not implemented", http.StatusNotImplemented)
 }
 
-// Notification Handlers - Simplified implementations
+// Notification Handlers
+
 func (s *StreamingAPIService) handleSubscribe(w http.ResponseWriter, r *http.Request) {
-	// Implementation
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		StreamerID        string `json:"streamer_id"`
+		StreamerName      string `json:"streamer_name"`
+		EnableStreamStart *bool  `json:"enable_stream_start,omitempty"`
+		EnableStreamEnd   *bool  `json:"enable_stream_end,omitempty"`
+		EnableChat        *bool  `json:"enable_chat,omitempty"`
+		EnableMentions    *bool  `json:"enable_mentions,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.StreamerID == "" {
+		http.Error(w, "streamer_id required", http.StatusBadRequest)
+		return
+	}
+	if livekit.ParticipantIdentity(req.StreamerID) == userID {
+		http.Error(w, "cannot subscribe to yourself", http.StatusBadRequest)
+		return
+	}
+
+	// start from the service defaults and apply only what the caller set
+	preferences := &streaming.NotificationSubscription{
+		EnableStreamStart: true,
+		EnableMentions:    true,
+	}
+	if req.EnableStreamStart != nil {
+		preferences.EnableStreamStart = *req.EnableStreamStart
+	}
+	if req.EnableStreamEnd != nil {
+		preferences.EnableStreamEnd = *req.EnableStreamEnd
+	}
+	if req.EnableChat != nil {
+		preferences.EnableChat = *req.EnableChat
+	}
+	if req.EnableMentions != nil {
+		preferences.EnableMentions = *req.EnableMentions
+	}
+
+	err := s.notificationService.Subscribe(
+		r.Context(),
+		userID,
+		livekit.ParticipantIdentity(req.StreamerID),
+		req.StreamerName,
+		preferences,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	followers, _ := s.notificationService.GetFollowerCount(r.Context(), livekit.ParticipantIdentity(req.StreamerID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"streamer_id":    req.StreamerID,
+		"follower_count": followers,
+	})
 }
 
 func (s *StreamingAPIService) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
-	// Implementation
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		StreamerID string `json:"streamer_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.StreamerID == "" {
+		http.Error(w, "streamer_id required", http.StatusBadRequest)
+		return
+	}
+
+	err := s.notificationService.Unsubscribe(r.Context(), userID, livekit.ParticipantIdentity(req.StreamerID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
 }
 
 func (s *StreamingAPIService) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
-	// Implementation
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := defaultNotificationListLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	unreadOnly := boolValue(r.URL.Query().Get("unread_only"))
+
+	notifications, err := s.notificationService.GetNotifications(r.Context(), userID, unreadOnly, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	unread, err := s.notificationService.GetUnreadCount(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"notifications": notifications,
+		"unread_count":  unread,
+	})
 }
 
 func (s *StreamingAPIService) handleMarkAsRead(w http.ResponseWriter, r *http.Request) {
-	// Implementation
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		NotificationID string `json:"notification_id"`
+		All            bool   `json:"all"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case req.All:
+		if err := s.notificationService.MarkAllAsRead(r.Context(), userID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case req.NotificationID != "":
+		if err := s.notificationService.MarkAsRead(r.Context(), userID, req.NotificationID); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "notification_id or all required", http.StatusBadRequest)
+		return
+	}
+
+	s.notificationHub.publishUnreadCount(userID)
+
+	unread, _ := s.notificationService.GetUnreadCount(r.Context(), userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"unread_count": unread,
+	})
 }
 
 // Analytics Handlers
@@ -860,12 +1485,245 @@ func (s *StreamingAPIService) handleGetStreamAnalytics(w http.ResponseWriter, r
 	json.NewEncoder(w).Encode(analytics)
 }
 
+// handleLiveAnalytics streams the analytics of a running stream of the current user over a WebSocket
+func (s *StreamingAPIService) handleLiveAnalytics(w http.ResponseWriter, r *http.Request) {
+	streamerID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	roomName := livekit.RoomName(r.URL.Query().Get("room_name"))
+	if roomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := s.analyticsService.GetStreamAnalytics(r.Context(), roomName)
+	if err != nil || analytics.EndTime != nil {
+		http.Error(w, "stream is not live", http.StatusNotFound)
+		return
+	}
+	if analytics.StreamerID != streamerID {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	updates, unsubscribe, err := s.analyticsService.SubscribeStreamAnalytics(roomName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		unsubscribe()
+		s.logger.Errorw("failed to upgrade websocket", err)
+		return
+	}
+
+	s.logger.Infow("analytics websocket connected", "room", roomName, "streamerID", streamerID)
+	serveAnalyticsSocket(conn, updates, unsubscribe)
+}
+
+// handleQoEBeacon takes playback events reported by a player. Beacons are authenticated with the access token
+// of the viewer, in the body or the access_token query parameter, and any content type is accepted as JSON
+// so that navigator.sendBeacon can post them without a preflight.
+func (s *StreamingAPIService) handleQoEBeacon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var beacon streaming.QoEBeacon
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQoEBeaconSize)).Decode(&beacon); err != nil {
+		prometheus.RecordQoEBeaconRejected("malformed")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(beacon.Events) > streaming.MaxQoEEvents {
+		prometheus.RecordQoEBeaconRejected("malformed")
+		http.Error(w, fmt.Sprintf("at most %d events per beacon", streaming.MaxQoEEvents), http.StatusBadRequest)
+		return
+	}
+	if beacon.Token == "" {
+		beacon.Token = r.URL.Query().Get("access_token")
+	}
+
+	roomName, viewerID, err := s.verifyViewerToken(beacon.Token)
+	if err != nil {
+		prometheus.RecordQoEBeaconRejected("unauthorized")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// implausible events are dropped, the rest of the batch still counts
+	events := make([]streaming.QoEEvent, 0, len(beacon.Events))
+	for _, event := range beacon.Events {
+		if err := event.Validate(); err != nil {
+			s.logger.Debugw("dropping qoe event", "error", err, "room", roomName, "viewerID", viewerID)
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if err := s.analyticsService.RecordQoEEvents(r.Context(), roomName, viewerID, events); err != nil {
+		prometheus.RecordQoEBeaconRejected("not_watching")
+		http.Error(w, "not watching a live stream", http.StatusNotFound)
+		return
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case streaming.QoEEventStall:
+			prometheus.RecordQoEStall(event.Duration())
+		case streaming.QoEEventLatency:
+			prometheus.RecordQoELatency(event.Duration())
+		case streaming.QoEEventDroppedFrames:
+			prometheus.RecordQoEDroppedFrames(event.Frames)
+		case streaming.QoEEventQualityChange:
+			prometheus.RecordQoEQualityChange()
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyViewerToken checks an access token issued by handleGetToken and returns the room and identity it grants
+func (s *StreamingAPIService) verifyViewerToken(token string) (livekit.RoomName, livekit.ParticipantIdentity, error) {
+	v, err := auth.ParseAPIToken(token)
+	if err != nil {
+		return "", "", err
+	}
+	if v.APIKey() != s.apiKey {
+		return "", "", errors.New("unknown api key")
+	}
+	grants, err := v.Verify(s.apiSecret)
+	if err != nil {
+		return "", "", err
+	}
+	if grants.Video == nil || !grants.Video.RoomJoin || grants.Video.Room == "" || grants.Identity == "" {
+		return "", "", errors.New("token does not grant joining a room")
+	}
+	return livekit.RoomName(grants.Video.Room), livekit.ParticipantIdentity(grants.Identity), nil
+}
+
+// handleGetDashboard aggregates the streams of the current user over a date range
 func (s *StreamingAPIService) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
-	// Implementation for dashboard data
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	streamerID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	from, to, err := parseAnalyticsRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records := s.analyticsService.GetStreamerStreams(r.Context(), streamerID, from, to)
+	followTimes := s.notificationService.GetFollowTimes(r.Context(), streamerID)
+	dashboard := streaming.NewStreamerDashboard(streamerID, from, to, records, followTimes)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dashboard)
 }
 
+// handleExportAnalytics exports the viewer sessions or timelines of the current user's streams
 func (s *StreamingAPIService) handleExportAnalytics(w http.ResponseWriter, r *http.Request) {
-	// Implementation for exporting analytics
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	streamerID, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	from, to, err := parseAnalyticsRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	format, err := streaming.ParseExportFormat(query.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data := query.Get("data")
+	if data == "" {
+		data = "sessions"
+	}
+	if data != "sessions" && data != "timelines" {
+		http.Error(w, "data must be sessions or timelines", http.StatusBadRequest)
+		return
+	}
+
+	records := s.analyticsService.GetStreamerStreams(r.Context(), streamerID, from, to)
+	if roomName := query.Get("room_name"); roomName != "" {
+		filtered := records[:0]
+		for _, record := range records {
+			if record.Analytics.RoomName == livekit.RoomName(roomName) {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="analytics-%s.%s"`, data, format))
+	if data == "timelines" {
+		err = streaming.WriteTimelineExport(w, format, streaming.TimelineRows(records))
+	} else {
+		err = streaming.WriteSessionExport(w, format, streaming.SessionRows(records))
+	}
+	if err != nil {
+		s.logger.Warnw("could not write analytics export", err, "streamerID", streamerID)
+	}
+}
+
+// parseAnalyticsRange reads from and to as RFC 3339 times or dates, a date for to includes that whole day.
+// The range defaults to the last 30 days.
+func parseAnalyticsRange(r *http.Request) (time.Time, time.Time, error) {
+	parse := func(name string, endOfDay bool) (time.Time, error) {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			return time.Time{}, nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s, expected RFC 3339 time or YYYY-MM-DD date", name)
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+
+	from, err := parse("from", false)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := parse("to", true)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultAnalyticsRange)
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("from must be before to")
+	}
+	return from, to, nil
 }
@@ -0,0 +1,1318 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/stretchr/testify/require"
+
+	appauth "github.com/livekit/livekit-server/pkg/auth"
+	apphandler "github.com/livekit/livekit-server/pkg/handler"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/storage"
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+type streamingAPITest struct {
+	api    *service.StreamingAPIService
+	server *httptest.Server
+	tokens *appauth.TokenGenerator
+}
+
+func newStreamingAPITest(t *testing.T) *streamingAPITest {
+	key, err := appauth.GenerateSigningKey(appauth.AlgorithmEdDSA)
+	require.NoError(t, err)
+	tokens := appauth.NewTokenGenerator("test", appauth.NewKeySet(key))
+	api := service.NewStreamingAPIService(nil, nil)
+	api.SetAuthMiddleware(apphandler.NewAuthMiddleware(tokens))
+
+	mux := http.NewServeMux()
+	api.RegisterHTTPHandlers(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return &streamingAPITest{api: api, server: server, tokens: tokens}
+}
+
+func (s *streamingAPITest) do(t *testing.T, method, path, userID string, body interface{}) *http.Response {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, s.server.URL+path, &buf)
+	require.NoError(t, err)
+	if userID != "" {
+		token, err := s.tokens.Generate(userID, time.Minute)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = res.Body.Close() })
+	return res
+}
+
+func TestStreamingNotificationsAPI(t *testing.T) {
+	s := newStreamingAPITest(t)
+
+	t.Run("requires authentication", func(t *testing.T) {
+		res := s.do(t, http.MethodGet, "/api/streaming/notifications/list", "", nil)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("subscribe, list and mark read", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/streaming/notifications/subscribe", "viewer", map[string]interface{}{
+			"streamer_id":   "streamer",
+			"streamer_name": "Streamer",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/notifications/subscribe", "viewer", map[string]interface{}{
+			"streamer_id": "streamer",
+		})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+
+		require.NoError(t, s.api.NotificationService().NotifyStreamStarted(context.Background(), "streamer", "Streamer", "room", "hello"))
+
+		res = s.do(t, http.MethodGet, "/api/streaming/notifications/list?unread_only=true", "viewer", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var list struct {
+			Notifications []*streaming.Notification `json:"notifications"`
+			UnreadCount   int                       `json:"unread_count"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&list))
+		require.Len(t, list.Notifications, 1)
+		require.Equal(t, 1, list.UnreadCount)
+		require.Equal(t, streaming.NotificationTypeStreamStarted, list.Notifications[0].Type)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/notifications/read", "viewer", map[string]string{
+			"notification_id": list.Notifications[0].ID,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		unread, err := s.api.NotificationService().GetUnreadCount(context.Background(), "viewer")
+		require.NoError(t, err)
+		require.Zero(t, unread)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/notifications/unsubscribe", "viewer", map[string]string{
+			"streamer_id": "streamer",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		followers, err := s.api.NotificationService().GetFollowerCount(context.Background(), "streamer")
+		require.NoError(t, err)
+		require.Zero(t, followers)
+	})
+
+	t.Run("socket pushes notifications", func(t *testing.T) {
+		token, err := s.tokens.Generate("socket-user", time.Minute)
+		require.NoError(t, err)
+
+		url := "ws" + strings.TrimPrefix(s.server.URL, "http") + "/api/streaming/notifications/ws?access_token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		type event struct {
+			Type         string                  `json:"type"`
+			Notification *streaming.Notification `json:"notification"`
+			UnreadCount  int                     `json:"unread_count"`
+		}
+		var ev event
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, conn.ReadJSON(&ev))
+		require.Equal(t, "unread_count", ev.Type)
+		require.Zero(t, ev.UnreadCount)
+
+		_, err = s.api.NotificationService().SendNotification(
+			context.Background(),
+			"socket-user",
+			streaming.NotificationTypeSystem,
+			"title",
+			"body",
+			streaming.PriorityMedium,
+			"",
+			nil,
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, conn.ReadJSON(&ev))
+		require.Equal(t, "notification", ev.Type)
+		require.Equal(t, "title", ev.Notification.Title)
+		require.Equal(t, 1, ev.UnreadCount)
+
+		require.NoError(t, conn.WriteJSON(map[string]string{"type": "mark_all_read"}))
+		require.NoError(t, conn.ReadJSON(&ev))
+		require.Equal(t, "unread_count", ev.Type)
+		require.Zero(t, ev.UnreadCount)
+	})
+}
+
+func TestStreamingAnalyticsAPI(t *testing.T) {
+	s := newStreamingAPITest(t)
+	ctx := context.Background()
+	analytics := s.api.AnalyticsService()
+	_, err := analytics.StartStreamAnalytics(ctx, "room", "streamer")
+	require.NoError(t, err)
+	require.NoError(t, analytics.RecordViewerJoin(ctx, "room", "alice", "web", "desktop", "", ""))
+	require.NoError(t, analytics.RecordChatMessage(ctx, "room", "alice"))
+
+	t.Run("requires authentication", func(t *testing.T) {
+		res := s.do(t, http.MethodGet, "/api/streaming/analytics/dashboard", "", nil)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("dashboard covers own streams", func(t *testing.T) {
+		res := s.do(t, http.MethodGet, "/api/streaming/analytics/dashboard?from=2020-01-01", "streamer", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var dashboard streaming.StreamerDashboard
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&dashboard))
+		require.Equal(t, 1, dashboard.StreamCount)
+		require.True(t, dashboard.LiveNow)
+		require.Equal(t, "alice", string(dashboard.TopChatters[0].UserID))
+
+		res = s.do(t, http.MethodGet, "/api/streaming/analytics/dashboard", "someone-else", nil)
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&dashboard))
+		require.Zero(t, dashboard.StreamCount)
+	})
+
+	t.Run("rejects invalid ranges", func(t *testing.T) {
+		res := s.do(t, http.MethodGet, "/api/streaming/analytics/dashboard?from=yesterday", "streamer", nil)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res = s.do(t, http.MethodGet, "/api/streaming/analytics/dashboard?from=2025-02-01&to=2025-01-01", "streamer", nil)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("exports sessions", func(t *testing.T) {
+		res := s.do(t, http.MethodGet, "/api/streaming/analytics/export?format=ndjson", "streamer", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+		var row streaming.SessionRow
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&row))
+		require.Equal(t, "alice", row.ViewerID)
+		require.Equal(t, int64(1), row.MessagesSent)
+
+		res = s.do(t, http.MethodGet, "/api/streaming/analytics/export?format=xlsx", "streamer", nil)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("socket streams live analytics of own streams", func(t *testing.T) {
+		res := s.do(t, http.MethodGet, "/api/streaming/analytics/live?room_name=room", "someone-else", nil)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		res = s.do(t, http.MethodGet, "/api/streaming/analytics/live?room_name=missing", "streamer", nil)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		token, err := s.tokens.Generate("streamer", time.Minute)
+		require.NoError(t, err)
+		url := "ws" + strings.TrimPrefix(s.server.URL, "http") + "/api/streaming/analytics/live?room_name=room&access_token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var live streaming.StreamAnalytics
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, conn.ReadJSON(&live))
+		require.Equal(t, 1, live.CurrentViewers)
+		require.Nil(t, live.EndTime)
+
+		// the socket closes once the stream ends
+		require.NoError(t, analytics.StopStreamAnalytics(ctx, "room"))
+		require.NoError(t, conn.ReadJSON(&live))
+		require.NotNil(t, live.EndTime)
+		_, _, err = conn.ReadMessage()
+		require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+	})
+}
+
+func TestStreamingQoEBeacon(t *testing.T) {
+	s := newStreamingAPITest(t)
+	ctx := context.Background()
+	analytics := s.api.AnalyticsService()
+	_, err := analytics.StartStreamAnalytics(ctx, "room", "streamer")
+	require.NoError(t, err)
+
+	viewerToken := func(t *testing.T) (string, livekit.ParticipantIdentity) {
+		res := s.do(t, http.MethodGet, "/api/streaming/token?room_name=room", "", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var body struct {
+			Token    string `json:"token"`
+			Identity string `json:"identity"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		return body.Token, livekit.ParticipantIdentity(body.Identity)
+	}
+	// anonymous viewers get a guest identity
+	token, viewerID := viewerToken(t)
+	require.True(t, strings.HasPrefix(string(viewerID), "guest-"))
+	require.NoError(t, analytics.RecordViewerJoin(ctx, "room", viewerID, "web", "desktop", "", ""))
+
+	// as posted by navigator.sendBeacon with a string body
+	beacon := func(t *testing.T, query string, body interface{}) int {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+		res, err := http.Post(s.server.URL+"/api/streaming/analytics/qoe"+query, "text/plain;charset=UTF-8", bytes.NewReader(b))
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("records events of the viewer", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, beacon(t, "", map[string]interface{}{
+			"token": token,
+			"events": []map[string]interface{}{
+				{"type": "stall", "duration_ms": 1200},
+				{"type": "latency", "latency_ms": 900},
+				{"type": "dropped_frames", "frames": 4},
+				{"type": "rewind"},
+			},
+		}))
+
+		live, err := analytics.GetStreamAnalytics(ctx, "room")
+		require.NoError(t, err)
+		require.Equal(t, 1, live.BufferingEvents)
+		require.Equal(t, 4, live.DroppedFrames)
+		require.Equal(t, 900*time.Millisecond, live.AverageLatency)
+	})
+
+	t.Run("accepts the token in the query", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, beacon(t, "?access_token="+token, map[string]interface{}{
+			"events": []map[string]interface{}{{"type": "stall", "duration_ms": 300}},
+		}))
+	})
+
+	t.Run("rejects invalid beacons", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, beacon(t, "", map[string]interface{}{"token": "garbage"}))
+		// signed for an app user rather than a room
+		appToken, err := s.tokens.Generate("alice", time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, beacon(t, "", map[string]interface{}{"token": appToken}))
+		// a viewer who has not joined
+		other, _ := viewerToken(t)
+		require.Equal(t, http.StatusNotFound, beacon(t, "", map[string]interface{}{"token": other}))
+
+		events := make([]map[string]interface{}, streaming.MaxQoEEvents+1)
+		for i := range events {
+			events[i] = map[string]interface{}{"type": "dropped_frames", "frames": 1}
+		}
+		require.Equal(t, http.StatusBadRequest, beacon(t, "", map[string]interface{}{"token": token, "events": events}))
+	})
+}
+
+func TestStreamingDeleteUserData(t *testing.T) {
+	s := newStreamingAPITest(t)
+	ctx := context.Background()
+
+	res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", "streamer", map[string]string{
+		"room_name": "room",
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, s.api.NotificationService().Subscribe(ctx, "viewer", "streamer", "Streamer", nil))
+	require.NoError(t, s.api.NotificationService().Subscribe(ctx, "streamer", "other", "Other", nil))
+
+	s.api.DeleteUserData(ctx, "streamer")
+
+	res = s.do(t, http.MethodGet, "/api/streaming/keys/list", "streamer", nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var keys []*streaming.StreamKey
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&keys))
+	require.Empty(t, keys)
+
+	// follows are gone in both directions
+	subs, err := s.api.NotificationService().GetSubscriptions(ctx, "viewer")
+	require.NoError(t, err)
+	require.Empty(t, subs)
+	followers, err := s.api.NotificationService().GetFollowerCount(ctx, "other")
+	require.NoError(t, err)
+	require.Zero(t, followers)
+}
+
+type testAccountPolicy map[string]error
+
+func (p testAccountPolicy) Authorize(_ context.Context, userID string, _ appauth.Action) error {
+	return p[userID]
+}
+
+func TestStreamingAccountPolicy(t *testing.T) {
+	s := newStreamingAPITest(t)
+	s.api.SetAccountPolicy(testAccountPolicy{
+		"unverified": appauth.ErrEmailNotVerified,
+		"unknown":    appauth.ErrUnknownAccount,
+	})
+
+	for _, userID := range []string{"unverified", "unknown"} {
+		res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", userID, map[string]string{
+			"room_name": "room",
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/token", "", map[string]any{
+			"room_name":    "room",
+			"identity":     userID,
+			"is_publisher": true,
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/chat/send", userID, map[string]string{
+			"room_name": "room",
+			"content":   "hello",
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+	}
+
+	// the policy applies to the logged-in user, not to whoever the body names
+	res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", "", map[string]string{
+		"streamer_id": "verified",
+		"room_name":   "room",
+	})
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res = s.do(t, http.MethodPost, "/api/streaming/chat/send", "unverified", map[string]string{
+		"room_name": "room",
+		"sender_id": "verified",
+		"content":   "hello",
+	})
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = s.do(t, http.MethodPost, "/api/streaming/keys/generate", "verified", map[string]string{
+		"room_name": "room",
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestStreamingChatModeration(t *testing.T) {
+	s := newStreamingAPITest(t)
+	ctx := context.Background()
+	res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", "streamer", map[string]string{
+		"room_name": "room",
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	chat := s.api.ChatService()
+	_, err := chat.CreateChatRoom(ctx, "room", nil)
+	require.NoError(t, err)
+	require.NoError(t, chat.JoinChatRoom(ctx, "room", "streamer", "Streamer", true))
+	require.NoError(t, chat.JoinChatRoom(ctx, "room", "mod", "Mod", true))
+	require.NoError(t, chat.JoinChatRoom(ctx, "room", "viewer", "Viewer", false))
+
+	ban := func(userID, participantID string) int {
+		res := s.do(t, http.MethodPost, "/api/streaming/chat/ban", userID, map[string]any{
+			"room_name":      "room",
+			"participant_id": participantID,
+			"duration_secs":  3600,
+		})
+		return res.StatusCode
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, ban("", "viewer"))
+		res := s.do(t, http.MethodPost, "/api/streaming/chat/mute", "", map[string]any{
+			"room_name":      "room",
+			"participant_id": "viewer",
+		})
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		_, banned := chat.BannedUntil("room", "viewer")
+		require.False(t, banned)
+	})
+
+	t.Run("not the owner", func(t *testing.T) {
+		// the body cannot name someone else as the moderator
+		res := s.do(t, http.MethodPost, "/api/streaming/chat/ban", "viewer", map[string]any{
+			"room_name":      "room",
+			"participant_id": "streamer",
+			"moderator_id":   "streamer",
+			"duration_secs":  3600,
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.Equal(t, http.StatusForbidden, ban("mod", "viewer"))
+		_, banned := chat.BannedUntil("room", "streamer")
+		require.False(t, banned)
+	})
+
+	t.Run("moderators", func(t *testing.T) {
+		s.api.SetAccountPolicy(testAccountPolicy{"viewer": appauth.ErrPermissionDenied})
+		defer s.api.SetAccountPolicy(nil)
+
+		require.Equal(t, http.StatusForbidden, ban("viewer", "mod"))
+		require.Equal(t, http.StatusOK, ban("mod", "viewer"))
+		_, banned := chat.BannedUntil("room", "viewer")
+		require.True(t, banned)
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, ban("streamer", "mod"))
+		_, banned := chat.BannedUntil("room", "mod")
+		require.True(t, banned)
+	})
+}
+
+func TestStreamingStreamKeyOwnership(t *testing.T) {
+	s := newStreamingAPITest(t)
+	res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", "streamer", map[string]string{
+		"room_name": "room",
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var key streaming.StreamKey
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&key))
+
+	list := func(userID, streamerID string) (int, []*streaming.StreamKey) {
+		res := s.do(t, http.MethodGet, "/api/streaming/keys/list?streamer_id="+streamerID, userID, nil)
+		var keys []*streaming.StreamKey
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&keys))
+		}
+		return res.StatusCode, keys
+	}
+	revoke := func(userID, key string) int {
+		res := s.do(t, http.MethodPost, "/api/streaming/keys/revoke", userID, map[string]string{"key": key})
+		return res.StatusCode
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		code, _ := list("", "streamer")
+		require.Equal(t, http.StatusUnauthorized, code)
+		require.Equal(t, http.StatusUnauthorized, revoke("", key.Key))
+	})
+
+	t.Run("not the owner", func(t *testing.T) {
+		code, _ := list("viewer", "streamer")
+		require.Equal(t, http.StatusForbidden, code)
+		require.Equal(t, http.StatusForbidden, revoke("viewer", key.Key))
+		require.Equal(t, http.StatusNotFound, revoke("viewer", "unknown"))
+
+		code, keys := list("viewer", "")
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, keys)
+	})
+
+	t.Run("permitted", func(t *testing.T) {
+		s.api.SetAccountPolicy(testAccountPolicy{"viewer": appauth.ErrPermissionDenied})
+		defer s.api.SetAccountPolicy(nil)
+
+		code, _ := list("viewer", "streamer")
+		require.Equal(t, http.StatusForbidden, code)
+		code, keys := list("admin", "streamer")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, keys, 1)
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		code, keys := list("streamer", "")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, keys, 1)
+		require.True(t, keys[0].IsActive)
+
+		require.Equal(t, http.StatusOK, revoke("streamer", key.Key))
+		_, keys = list("streamer", "streamer")
+		require.False(t, keys[0].IsActive)
+	})
+}
+
+type testPermissions map[string][]string
+
+func (p testPermissions) HasPermission(_ context.Context, userID, permission string) (bool, error) {
+	return slices.Contains(p[userID], permission), nil
+}
+
+type testRoomService struct {
+	livekit.RoomService
+	// guards rooms and sent, which may change after the response, e.g. at the end of a raid countdown
+	mu    sync.Mutex
+	rooms map[string]bool
+	sent  []*livekit.SendDataRequest
+	// participants of any room by identity
+	participants map[string]*livekit.ParticipantInfo
+}
+
+func (s *testRoomService) hasRoom(room string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rooms[room]
+}
+
+func (s *testRoomService) SendData(ctx context.Context, req *livekit.SendDataRequest) (*livekit.SendDataResponse, error) {
+	if grants := service.GetGrants(ctx); grants == nil || !grants.Video.RoomAdmin || grants.Video.Room != req.Room {
+		return nil, errors.New("permission denied")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, req)
+	return &livekit.SendDataResponse{}, nil
+}
+
+func (s *testRoomService) GetParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	if grants := service.GetGrants(ctx); grants == nil || !grants.Video.RoomAdmin || grants.Video.Room != req.Room {
+		return nil, errors.New("permission denied")
+	}
+	participant, ok := s.participants[req.Identity]
+	if !ok {
+		return nil, service.ErrParticipantNotFound
+	}
+	return participant, nil
+}
+
+func (s *testRoomService) UpdateParticipant(ctx context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+	participant, err := s.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: req.Room, Identity: req.Identity})
+	if err != nil {
+		return nil, err
+	}
+	if req.Permission != nil {
+		participant.Permission = req.Permission
+	}
+	for k, v := range req.Attributes {
+		if v == "" {
+			delete(participant.Attributes, k)
+		} else {
+			participant.Attributes[k] = v
+		}
+	}
+	return participant, nil
+}
+
+func (s *testRoomService) DeleteRoom(ctx context.Context, req *livekit.DeleteRoomRequest) (*livekit.DeleteRoomResponse, error) {
+	if grants := service.GetGrants(ctx); grants == nil || !grants.Video.RoomCreate {
+		return nil, errors.New("permission denied")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.rooms[req.Room] {
+		return nil, service.ErrRoomNotFound
+	}
+	delete(s.rooms, req.Room)
+	return &livekit.DeleteRoomResponse{}, nil
+}
+
+func TestStreamingAdmin(t *testing.T) {
+	s := newStreamingAPITest(t)
+	middleware := apphandler.NewAuthMiddleware(s.tokens)
+	middleware.SetPermissions(testPermissions{
+		"admin":     {storage.PermissionEndStreams, storage.PermissionRevokeKeys},
+		"moderator": {storage.PermissionEndStreams},
+	})
+	s.api.SetAuthMiddleware(middleware)
+	rooms := &testRoomService{rooms: map[string]bool{"live": true}}
+
+	t.Run("end streams", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/admin/streams/end", "admin", map[string]string{"room_name": "live"})
+		require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		s.api.SetRoomService(rooms)
+
+		res = s.do(t, http.MethodPost, "/api/admin/streams/end", "", map[string]string{"room_name": "live"})
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		res = s.do(t, http.MethodPost, "/api/admin/streams/end", "viewer", map[string]string{"room_name": "live"})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res = s.do(t, http.MethodPost, "/api/admin/streams/end", "moderator", map[string]string{"room_name": "live", "reason": "tos"})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Empty(t, rooms.rooms)
+		res = s.do(t, http.MethodPost, "/api/admin/streams/end", "moderator", map[string]string{"room_name": "live"})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("revoke stream keys", func(t *testing.T) {
+		var keys []string
+		for range 2 {
+			res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", "streamer", map[string]string{
+				"room_name": "room",
+			})
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			var key streaming.StreamKey
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&key))
+			keys = append(keys, key.Key)
+		}
+		validate := func(key string) int {
+			return s.do(t, http.MethodPost, "/api/streaming/keys/validate", "", map[string]string{"key": key}).StatusCode
+		}
+
+		res := s.do(t, http.MethodPost, "/api/admin/streams/keys/revoke", "moderator", map[string]string{"key": keys[0]})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		res = s.do(t, http.MethodPost, "/api/admin/streams/keys/revoke", "admin", map[string]string{})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res = s.do(t, http.MethodPost, "/api/admin/streams/keys/revoke", "admin", map[string]string{"key": "unknown"})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res = s.do(t, http.MethodPost, "/api/admin/streams/keys/revoke", "admin", map[string]string{"key": keys[0]})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, http.StatusUnauthorized, validate(keys[0]))
+		require.Equal(t, http.StatusOK, validate(keys[1]))
+
+		res = s.do(t, http.MethodPost, "/api/admin/streams/keys/revoke", "admin", map[string]string{"streamer_id": "streamer"})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var revoked map[string]int
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&revoked))
+		require.Equal(t, 1, revoked["revoked"])
+		require.Equal(t, http.StatusUnauthorized, validate(keys[1]))
+	})
+
+	t.Run("suspended accounts lose their keys", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", "suspended", map[string]string{
+			"room_name": "room",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var key streaming.StreamKey
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&key))
+
+		s.api.EndUserStreams(context.Background(), "suspended")
+		res = s.do(t, http.MethodPost, "/api/streaming/keys/validate", "", map[string]string{"key": key.Key})
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+}
+
+func TestStreamingTokenExchange(t *testing.T) {
+	ctx := context.Background()
+	s := newStreamingAPITest(t)
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	users := storage.NewUserRepository(db)
+
+	res := s.do(t, http.MethodPost, "/api/streaming/token/exchange", "alice", map[string]any{"room_name": "room"})
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	s.api.SetUserRepository(users)
+
+	create := func(email, name, slug string) *storage.User {
+		user := &storage.User{Email: email, PasswordHash: []byte("hash")}
+		require.NoError(t, users.CreateUser(ctx, user))
+		user.DisplayName = sql.NullString{String: name, Valid: name != ""}
+		user.ChannelSlug = sql.NullString{String: slug, Valid: slug != ""}
+		require.NoError(t, users.UpdateProfile(ctx, user))
+		return user
+	}
+	alice := create("alice@example.com", "Alice", "alice")
+	bob := create("bob@example.com", "", "")
+	require.NoError(t, users.MarkEmailVerified(ctx, alice.ID))
+	require.NoError(t, users.GrantRole(ctx, bob.ID, storage.RoleModerator))
+
+	type exchanged struct {
+		grants     *auth.ClaimGrants
+		url        string
+		canPublish bool
+	}
+	exchange := func(t *testing.T, userID string, body map[string]any) (int, *exchanged) {
+		res := s.do(t, http.MethodPost, "/api/streaming/token/exchange", userID, body)
+		if res.StatusCode != http.StatusOK {
+			return res.StatusCode, nil
+		}
+		var v struct {
+			Token      string `json:"token"`
+			URL        string `json:"url"`
+			Identity   string `json:"identity"`
+			CanPublish bool   `json:"can_publish"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&v))
+		verifier, err := auth.ParseAPIToken(v.Token)
+		require.NoError(t, err)
+		grants, err := verifier.Verify("secret")
+		require.NoError(t, err)
+		require.Equal(t, v.Identity, grants.Identity)
+		return res.StatusCode, &exchanged{grants: grants, url: v.URL, canPublish: v.CanPublish}
+	}
+
+	t.Run("requires authentication", func(t *testing.T) {
+		code, _ := exchange(t, "", map[string]any{"room_name": "alice"})
+		require.Equal(t, http.StatusUnauthorized, code)
+		code, _ = exchange(t, "unknown", map[string]any{"room_name": "alice"})
+		require.Equal(t, http.StatusForbidden, code)
+		code, _ = exchange(t, alice.ID, map[string]any{})
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("identity and attributes come from the account", func(t *testing.T) {
+		code, token := exchange(t, alice.ID, map[string]any{"room_name": "alice", "publish": true, "identity": "mallory"})
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, token.canPublish)
+		require.True(t, strings.HasPrefix(token.url, "ws://"))
+		require.Equal(t, alice.ID, token.grants.Identity)
+		require.Equal(t, "Alice", token.grants.Name)
+		require.True(t, token.grants.Video.GetCanPublish())
+		require.False(t, token.grants.Video.GetCanUpdateOwnMetadata())
+		require.Equal(t, map[string]string{"role": "streamer", "channel": "alice", "badges": "verified"}, token.grants.Attributes)
+		var metadata map[string]any
+		require.NoError(t, json.Unmarshal([]byte(token.grants.Metadata), &metadata))
+		require.Equal(t, "Alice", metadata["name"])
+		require.Equal(t, "streamer", metadata["role"])
+
+		code, token = exchange(t, bob.ID, map[string]any{"room_name": "alice"})
+		require.Equal(t, http.StatusOK, code)
+		require.False(t, token.canPublish)
+		require.False(t, token.grants.Video.GetCanPublish())
+		require.True(t, token.grants.Video.GetCanPublishData())
+		require.Equal(t, "bob", token.grants.Name)
+		require.Equal(t, map[string]string{"role": "moderator", "badges": "moderator"}, token.grants.Attributes)
+	})
+
+	t.Run("only owners publish", func(t *testing.T) {
+		code, _ := exchange(t, bob.ID, map[string]any{"room_name": "alice", "publish": true})
+		require.Equal(t, http.StatusForbidden, code)
+
+		// rooms nobody owns go to whoever publishes first
+		code, _ = exchange(t, bob.ID, map[string]any{"room_name": "lobby", "publish": true})
+		require.Equal(t, http.StatusOK, code)
+		code, _ = exchange(t, alice.ID, map[string]any{"room_name": "lobby", "publish": true})
+		require.Equal(t, http.StatusForbidden, code)
+
+		// claiming another room gives up the first
+		code, _ = exchange(t, bob.ID, map[string]any{"room_name": "lounge", "publish": true})
+		require.Equal(t, http.StatusOK, code)
+		code, _ = exchange(t, alice.ID, map[string]any{"room_name": "lobby", "publish": true})
+		require.Equal(t, http.StatusOK, code)
+		code, _ = exchange(t, bob.ID, map[string]any{"room_name": "lobby", "publish": true})
+		require.Equal(t, http.StatusForbidden, code)
+
+		res := s.do(t, http.MethodPost, "/api/streaming/token", "", map[string]any{
+			"room_name":    "lobby",
+			"identity":     bob.ID,
+			"is_publisher": true,
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("stream keys", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", bob.ID, map[string]string{
+			"room_name": "alice",
+		})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/keys/generate", alice.ID, map[string]string{
+			"room_name": "alice",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var key streaming.StreamKey
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&key))
+
+		code, _ := exchange(t, bob.ID, map[string]any{"stream_key": key.Key})
+		require.Equal(t, http.StatusForbidden, code)
+		code, token := exchange(t, alice.ID, map[string]any{"stream_key": key.Key})
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "alice", token.grants.Video.Room)
+		require.True(t, token.grants.Video.GetCanPublish())
+	})
+
+	t.Run("bans", func(t *testing.T) {
+		chat := s.api.ChatService()
+		_, err := chat.CreateChatRoom(ctx, "alice", nil)
+		require.NoError(t, err)
+		require.NoError(t, chat.JoinChatRoom(ctx, "alice", livekit.ParticipantIdentity(alice.ID), "Alice", true))
+		require.NoError(t, chat.BanParticipant(ctx, "alice", livekit.ParticipantIdentity(bob.ID), livekit.ParticipantIdentity(alice.ID), time.Hour))
+
+		code, _ := exchange(t, bob.ID, map[string]any{"room_name": "alice"})
+		require.Equal(t, http.StatusForbidden, code)
+		// anonymous tokens cannot stand in for the account
+		res := s.do(t, http.MethodGet, "/api/streaming/token?room_name=alice&identity="+bob.ID, "", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var guest struct {
+			Token    string `json:"token"`
+			Identity string `json:"identity"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&guest))
+		verifier, err := auth.ParseAPIToken(guest.Token)
+		require.NoError(t, err)
+		grants, err := verifier.Verify("secret")
+		require.NoError(t, err)
+		require.NotEqual(t, bob.ID, grants.Identity)
+		require.False(t, grants.Video.GetCanPublishData())
+
+		// banned only from that room
+		code, _ = exchange(t, bob.ID, map[string]any{"room_name": "lobby"})
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("suspended accounts", func(t *testing.T) {
+		require.NoError(t, users.Suspend(ctx, alice.ID, "spam"))
+		code, _ := exchange(t, alice.ID, map[string]any{"room_name": "alice", "publish": true})
+		require.Equal(t, http.StatusForbidden, code)
+	})
+}
+
+func TestStreamingDirectory(t *testing.T) {
+	ctx := context.Background()
+	s := newStreamingAPITest(t)
+
+	res := s.do(t, http.MethodGet, "/api/streaming/list", "", nil)
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+
+	store := service.NewLocalStore()
+	video := []*livekit.TrackInfo{{Type: livekit.TrackType_VIDEO}}
+	addRoom := func(name, metadata string, participants ...*livekit.ParticipantInfo) {
+		require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: name, Metadata: metadata}, nil))
+		for _, p := range participants {
+			require.NoError(t, store.StoreParticipant(ctx, livekit.RoomName(name), p))
+		}
+	}
+	addRoom("gaming", "",
+		&livekit.ParticipantInfo{Identity: "alice", Name: "Alice", JoinedAtMs: 1000, Tracks: video, Attributes: map[string]string{
+			"title": "Speedrun", "category": "Games", "avatar_url": "/avatars/alice.png",
+		}},
+		&livekit.ParticipantInfo{Identity: "v1", JoinedAtMs: 2000},
+		&livekit.ParticipantInfo{Identity: "v2", JoinedAtMs: 3000},
+		// neither counts as a viewer
+		&livekit.ParticipantInfo{Identity: "recorder", Permission: &livekit.ParticipantPermission{Hidden: true}},
+		&livekit.ParticipantInfo{Identity: "egress", Kind: livekit.ParticipantInfo_EGRESS, Tracks: video},
+	)
+	addRoom("music", `{"title": "Late night jazz", "category": "Music"}`,
+		&livekit.ParticipantInfo{Identity: "bob", JoinedAtMs: 5000, Tracks: video},
+		&livekit.ParticipantInfo{Identity: "v3", JoinedAtMs: 6000},
+	)
+	// nobody publishes video, not live
+	addRoom("lobby", "",
+		&livekit.ParticipantInfo{Identity: "carol", JoinedAtMs: 500},
+	)
+	s.api.SetRoomStore(store)
+
+	list := func(t *testing.T, query string) []service.LiveStream {
+		res := s.do(t, http.MethodGet, "/api/streaming/list"+query, "", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var streams []service.LiveStream
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&streams))
+		return streams
+	}
+	ids := func(streams []service.LiveStream) []string {
+		var ids []string
+		for _, stream := range streams {
+			ids = append(ids, stream.ID)
+		}
+		return ids
+	}
+
+	t.Run("lists rooms with a streamer", func(t *testing.T) {
+		streams := list(t, "")
+		require.Equal(t, []string{"gaming", "music"}, ids(streams))
+		require.Equal(t, service.LiveStream{
+			ID:         "gaming",
+			Title:      "Speedrun",
+			Category:   "Games",
+			StreamerID: "alice",
+			Streamer:   "Alice",
+			Avatar:     "/avatars/alice.png",
+			Viewers:    2,
+			StartTime:  1000,
+		}, streams[0])
+		require.Equal(t, "Late night jazz", streams[1].Title)
+		require.Equal(t, "bob", streams[1].Streamer)
+		require.Equal(t, 1, streams[1].Viewers)
+	})
+
+	t.Run("search, filter and sort", func(t *testing.T) {
+		require.Equal(t, []string{"music"}, ids(list(t, "?q=JAZZ")))
+		require.Equal(t, []string{"gaming"}, ids(list(t, "?q=alice")))
+		require.Equal(t, []string{"music"}, ids(list(t, "?category=music")))
+		require.Empty(t, list(t, "?category=sports"))
+		require.Equal(t, []string{"music", "gaming"}, ids(list(t, "?sort=recent")))
+		require.Equal(t, []string{"music"}, ids(list(t, "?limit=1&offset=1")))
+		require.Empty(t, list(t, "?offset=5"))
+
+		for _, query := range []string{"?sort=name", "?limit=0", "?offset=-1"} {
+			res := s.do(t, http.MethodGet, "/api/streaming/list"+query, "", nil)
+			require.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+		}
+	})
+
+	t.Run("follows the room store", func(t *testing.T) {
+		require.NoError(t, store.DeleteParticipant(ctx, "gaming", "alice"))
+		for _, identity := range []string{"v4", "v5"} {
+			require.NoError(t, store.StoreParticipant(ctx, "music", &livekit.ParticipantInfo{Identity: identity}))
+		}
+		// served from the last listing until it is refreshed
+		require.Len(t, list(t, ""), 2)
+
+		s.api.SetRoomStore(store)
+		streams := list(t, "")
+		require.Equal(t, []string{"music"}, ids(streams))
+		require.Equal(t, 3, streams[0].Viewers)
+	})
+}
+
+func TestStreamingSchedule(t *testing.T) {
+	s := newStreamingAPITest(t)
+	startTime := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	decode := func(t *testing.T, res *http.Response) *streaming.ScheduledStream {
+		var stream streaming.ScheduledStream
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&stream))
+		return &stream
+	}
+
+	res := s.do(t, http.MethodPost, "/api/streaming/schedule", "", map[string]interface{}{"title": "speedrun"})
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = s.do(t, http.MethodPost, "/api/streaming/schedule", "streamer", map[string]interface{}{"title": "no start"})
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = s.do(t, http.MethodPost, "/api/streaming/schedule", "streamer", map[string]interface{}{
+		"title":      "speedrun",
+		"start_time": startTime,
+		"stream_key": "unknown",
+	})
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = s.do(t, http.MethodPost, "/api/streaming/keys/generate", "streamer", map[string]interface{}{
+		"room_name": "speedruns",
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var key streaming.StreamKey
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&key))
+	res = s.do(t, http.MethodPost, "/api/streaming/schedule", "streamer", map[string]interface{}{
+		"title":      "speedrun",
+		"category":   "Games",
+		"start_time": startTime,
+		"stream_key": key.Key,
+	})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	created := decode(t, res)
+	require.Equal(t, streaming.ScheduledStreamUpcoming, created.Status)
+	require.Equal(t, livekit.RoomName("speedruns"), created.RoomName)
+	require.Equal(t, key.Key, created.StreamKey)
+	require.True(t, startTime.Equal(created.StartTime))
+
+	t.Run("shows the schedule without stream keys", func(t *testing.T) {
+		res := s.do(t, http.MethodGet, "/api/streaming/schedule?id="+created.ID, "", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		stream := decode(t, res)
+		require.Equal(t, "speedrun", stream.Title)
+		require.Empty(t, stream.StreamKey)
+
+		res = s.do(t, http.MethodGet, "/api/streaming/schedule?streamer_id=streamer", "", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var streams []*streaming.ScheduledStream
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&streams))
+		require.Len(t, streams, 1)
+		require.Empty(t, streams[0].StreamKey)
+
+		res = s.do(t, http.MethodGet, "/api/streaming/schedule?id=SS_unknown", "", nil)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("only the streamer changes it", func(t *testing.T) {
+		res := s.do(t, http.MethodPut, "/api/streaming/schedule?id="+created.ID, "someone", map[string]interface{}{"title": "mine"})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res = s.do(t, http.MethodPut, "/api/streaming/schedule?id="+created.ID, "streamer", map[string]interface{}{
+			"title":      "100% speedrun",
+			"stream_key": "",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		updated := decode(t, res)
+		require.Equal(t, "100% speedrun", updated.Title)
+		require.Equal(t, "Games", updated.Category)
+		require.Empty(t, updated.RoomName)
+	})
+
+	t.Run("serves a calendar", func(t *testing.T) {
+		res := s.do(t, http.MethodGet, "/api/streaming/schedule/calendar.ics?streamer_id=streamer", "", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, streaming.ICalendarContentType, res.Header.Get("Content-Type"))
+		var body bytes.Buffer
+		_, err := body.ReadFrom(res.Body)
+		require.NoError(t, err)
+		require.Contains(t, body.String(), "SUMMARY:streamer: 100% speedrun\r\n")
+		require.Contains(t, body.String(), "DTSTART:"+startTime.Format("20060102T150405Z")+"\r\n")
+		// long lines are folded
+		unfolded := strings.ReplaceAll(body.String(), "\r\n ", "")
+		require.Contains(t, unfolded, streaming.ScheduledStreamPath(created.ID)+"\r\n")
+
+		res = s.do(t, http.MethodGet, "/api/streaming/schedule/calendar.ics", "", nil)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("cancels it", func(t *testing.T) {
+		res := s.do(t, http.MethodDelete, "/api/streaming/schedule?id="+created.ID, "streamer", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, streaming.ScheduledStreamCancelled, decode(t, res).Status)
+
+		res = s.do(t, http.MethodDelete, "/api/streaming/schedule?id="+created.ID, "streamer", nil)
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+}
+
+func TestStreamingStage(t *testing.T) {
+	s := newStreamingAPITest(t)
+	rooms := &testRoomService{participants: map[string]*livekit.ParticipantInfo{
+		"guest": {
+			Identity:   "guest",
+			Permission: &livekit.ParticipantPermission{CanSubscribe: true, CanPublishData: true},
+			Attributes: map[string]string{"role": "viewer"},
+		},
+	}}
+	s.api.SetRoomService(rooms)
+
+	// the stream key makes host the owner of the room
+	res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", "host", map[string]string{
+		"room_name": "room",
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	getStage := func(t *testing.T, userID string) *streaming.Stage {
+		res := s.do(t, http.MethodGet, "/api/streaming/stage?room_name=room", userID, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var stage streaming.Stage
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&stage))
+		return &stage
+	}
+
+	t.Run("raised hands queue on the chat socket", func(t *testing.T) {
+		url := "ws" + strings.TrimPrefix(s.server.URL, "http") + "/api/streaming/chat/ws?room_name=room"
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		type event struct {
+			Type  string           `json:"type"`
+			Stage *streaming.Stage `json:"stage"`
+		}
+		var ev event
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, conn.ReadJSON(&ev))
+		require.Equal(t, "stage", ev.Type)
+		require.Empty(t, ev.Stage.Queue)
+
+		for range 2 {
+			res := s.do(t, http.MethodPost, "/api/streaming/stage/hand", "guest", map[string]string{"room_name": "room"})
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			var position map[string]int
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&position))
+			require.Equal(t, 1, position["position"])
+		}
+
+		require.NoError(t, conn.ReadJSON(&ev))
+		require.Equal(t, "stage", ev.Type)
+		require.Len(t, ev.Stage.Queue, 1)
+		require.Equal(t, livekit.ParticipantIdentity("guest"), ev.Stage.Queue[0].UserID)
+		require.Empty(t, ev.Stage.Invites)
+	})
+
+	t.Run("only the owner invites", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/streaming/stage/invite", "guest", map[string]string{
+			"room_name": "room",
+			"user_id":   "other",
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		res = s.do(t, http.MethodPost, "/api/streaming/stage/accept", "guest", map[string]string{"room_name": "room"})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("accepting an invite grants publishing", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/streaming/stage/invite", "host", map[string]string{
+			"room_name": "room",
+			"user_id":   "guest",
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		invites, err := s.api.NotificationService().GetNotifications(context.Background(), "guest", true, 10)
+		require.NoError(t, err)
+		require.Len(t, invites, 1)
+		require.Equal(t, streaming.NotificationTypeStageInvite, invites[0].Type)
+		require.Equal(t, "room", invites[0].Data["room_name"])
+
+		require.Len(t, getStage(t, "host").Invites, 1)
+		require.Len(t, getStage(t, "guest").Invites, 1)
+		require.Empty(t, getStage(t, "someone").Invites)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/stage/accept", "guest", map[string]string{"room_name": "room"})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		guest := rooms.participants["guest"]
+		require.True(t, guest.Permission.CanPublish)
+		require.True(t, guest.Permission.CanPublishData)
+		require.Equal(t, "guest", guest.Attributes["stage"])
+
+		stage := getStage(t, "host")
+		require.Len(t, stage.Guests, 1)
+		require.Empty(t, stage.Queue)
+		require.Empty(t, stage.Invites)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/stage/hand", "guest", map[string]string{"room_name": "room"})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+
+	t.Run("invitees must be in the room", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/streaming/stage/invite", "host", map[string]string{
+			"room_name": "room",
+			"user_id":   "absent",
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		res = s.do(t, http.MethodPost, "/api/streaming/stage/accept", "absent", map[string]string{"room_name": "room"})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.Len(t, getStage(t, "host").Guests, 1)
+	})
+
+	t.Run("removing a guest revokes publishing", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/streaming/stage/remove", "someone", map[string]string{
+			"room_name": "room",
+			"user_id":   "guest",
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/stage/remove", "host", map[string]string{
+			"room_name": "room",
+			"user_id":   "guest",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		guest := rooms.participants["guest"]
+		require.False(t, guest.Permission.CanPublish)
+		require.True(t, guest.Permission.CanPublishData)
+		require.NotContains(t, guest.Attributes, "stage")
+		require.Empty(t, getStage(t, "host").Guests)
+
+		res = s.do(t, http.MethodPost, "/api/streaming/stage/remove", "guest", map[string]string{"room_name": "room"})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("lowering a hand leaves the queue", func(t *testing.T) {
+		res := s.do(t, http.MethodPost, "/api/streaming/stage/hand", "guest", map[string]string{"room_name": "room"})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		res = s.do(t, http.MethodDelete, "/api/streaming/stage/hand?room_name=room", "guest", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		res = s.do(t, http.MethodDelete, "/api/streaming/stage/hand?room_name=room", "guest", nil)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.Empty(t, getStage(t, "host").Queue)
+	})
+}
+
+func TestStreamingRaid(t *testing.T) {
+	s := newStreamingAPITest(t)
+	t.Cleanup(s.api.Stop)
+	ctx := context.Background()
+	rooms := &testRoomService{rooms: map[string]bool{"room": true, "final": true}}
+	s.api.SetRoomService(rooms)
+
+	// the stream keys make host the owner of room and final, and friend the owner of other
+	for room, streamer := range map[string]string{"room": "host", "final": "host", "other": "friend"} {
+		res := s.do(t, http.MethodPost, "/api/streaming/keys/generate", streamer, map[string]string{
+			"room_name": room,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	raid := func(t *testing.T, userID string, body map[string]interface{}) *http.Response {
+		return s.do(t, http.MethodPost, "/api/streaming/raid", userID, body)
+	}
+
+	t.Run("only the owner raids a live channel", func(t *testing.T) {
+		res := raid(t, "guest", map[string]interface{}{"room_name": "room", "target_channel": "other"})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		res = raid(t, "host", map[string]interface{}{"room_name": "room", "target_channel": "room"})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res = raid(t, "host", map[string]interface{}{"room_name": "room", "target_channel": "nobody"})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		res = raid(t, "host", map[string]interface{}{"room_name": "room", "target_channel": "other"})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		res = raid(t, "host", map[string]interface{}{"room_name": "room", "target_channel": "other", "countdown": 3600})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.Empty(t, rooms.sent)
+	})
+
+	analytics := s.api.AnalyticsService()
+	_, err := analytics.StartStreamAnalytics(ctx, "room", "host")
+	require.NoError(t, err)
+	_, err = analytics.StartStreamAnalytics(ctx, "other", "friend")
+	require.NoError(t, err)
+	for _, viewerID := range []livekit.ParticipantIdentity{"alice", "bob"} {
+		require.NoError(t, analytics.RecordViewerJoin(ctx, "room", viewerID, "web", "desktop", "", ""))
+	}
+	_, err = s.api.ChatService().CreateChatRoom(ctx, "other", nil)
+	require.NoError(t, err)
+
+	t.Run("viewers are sent over to the target", func(t *testing.T) {
+		url := "ws" + strings.TrimPrefix(s.server.URL, "http") + "/api/streaming/chat/ws?room_name=room"
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		type redirect struct {
+			Type          string `json:"type"`
+			TargetChannel string `json:"target_channel"`
+			Countdown     int    `json:"countdown"`
+			Viewers       int    `json:"viewers"`
+		}
+		type event struct {
+			Type string    `json:"type"`
+			Raid *redirect `json:"raid"`
+		}
+		var ev event
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, conn.ReadJSON(&ev))
+		require.Equal(t, "stage", ev.Type)
+
+		res := raid(t, "host", map[string]interface{}{"room_name": "room", "target_channel": "other", "countdown": 30})
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		res = raid(t, "host", map[string]interface{}{"room_name": "room", "target_channel": "other"})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+
+		require.Len(t, rooms.sent, 1)
+		require.Equal(t, "room", rooms.sent[0].Room)
+		require.Equal(t, livekit.DataPacket_RELIABLE, rooms.sent[0].Kind)
+		require.Equal(t, "raid", rooms.sent[0].GetTopic())
+		var packet redirect
+		require.NoError(t, json.Unmarshal(rooms.sent[0].Data, &packet))
+		require.Equal(t, redirect{Type: "raid", TargetChannel: "other", Countdown: 30, Viewers: 2}, packet)
+
+		require.NoError(t, conn.ReadJSON(&ev))
+		require.Equal(t, "raid", ev.Type)
+		require.Equal(t, packet, *ev.Raid)
+
+		messages, err := s.api.ChatService().GetMessages(ctx, "other", 10, nil)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, streaming.ChatMessageTypeSystemNotice, messages[0].MessageType)
+		require.Equal(t, "raid", messages[0].Metadata["event"])
+
+		notifications, err := s.api.NotificationService().GetNotifications(ctx, "friend", true, 10)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		require.Equal(t, streaming.NotificationTypeRaid, notifications[0].Type)
+		require.Equal(t, "2", notifications[0].Data["viewers"])
+	})
+
+	t.Run("raided viewers are referred by the raid", func(t *testing.T) {
+		require.NoError(t, analytics.RecordViewerJoin(ctx, "other", "alice", "web", "desktop", "", ""))
+		require.NoError(t, analytics.RecordViewerJoin(ctx, "other", "carol", "web", "desktop", "", ""))
+
+		target, err := analytics.GetStreamAnalytics(ctx, "other")
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{streaming.ReferralSourceRaid: 1}, target.ViewersByReferrer)
+		require.Len(t, target.Raids, 1)
+		require.Equal(t, livekit.RoomName("room"), target.Raids[0].FromRoom)
+		require.Equal(t, livekit.ParticipantIdentity("host"), target.Raids[0].FromStreamerID)
+		require.Equal(t, 2, target.Raids[0].Viewers)
+		require.Equal(t, 1, target.Raids[0].Arrived)
+
+		sessions, err := analytics.GetViewerSessions(ctx, "other")
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		require.Equal(t, streaming.ReferralSourceRaid, sessions[0].Referrer)
+		require.Empty(t, sessions[1].Referrer)
+	})
+
+	t.Run("raiding may end the stream", func(t *testing.T) {
+		res := raid(t, "host", map[string]interface{}{
+			"room_name":      "final",
+			"target_channel": "other",
+			"countdown":      0,
+			"end_stream":     true,
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		require.Eventually(t, func() bool { return !rooms.hasRoom("final") }, 5*time.Second, 10*time.Millisecond)
+		require.True(t, rooms.hasRoom("room"))
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+const (
+	chatSocketBufferSize = 256
+	chatSocketWriteWait  = 10 * time.Second
+	chatSocketPongWait   = 60 * time.Second
+	chatSocketPingPeriod = chatSocketPongWait * 9 / 10
+)
+
+// chatEvent is a message pushed to the chat sockets of a room
+type chatEvent struct {
+	Type    string                 `json:"type"`
+	Message *streaming.ChatMessage `json:"message,omitempty"`
+	Stage   *streaming.Stage       `json:"stage,omitempty"`
+	Raid    *raidRedirect          `json:"raid,omitempty"`
+}
+
+type chatConn struct {
+	conn *websocket.Conn
+	send chan *chatEvent
+	done chan struct{}
+	once sync.Once
+}
+
+func (c *chatConn) close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// chatHub fans out chat messages, stage changes and raids to the WebSocket connections watching each room.
+// Clients send messages over HTTP, the sockets only push.
+type chatHub struct {
+	mu    sync.RWMutex
+	conns map[livekit.RoomName]map[*chatConn]struct{}
+
+	logger logger.Logger
+}
+
+func newChatHub() *chatHub {
+	return &chatHub{
+		conns:  make(map[livekit.RoomName]map[*chatConn]struct{}),
+		logger: logger.GetLogger(),
+	}
+}
+
+func (h *chatHub) onMessage(message *streaming.ChatMessage) {
+	h.broadcast(message.RoomName, &chatEvent{Type: "message", Message: message})
+}
+
+func (h *chatHub) broadcast(roomName livekit.RoomName, event *chatEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.conns[roomName] {
+		select {
+		case c.send <- event:
+		default:
+			// slow consumer, drop the connection rather than block delivery to others
+			h.logger.Infow("dropping slow chat socket", "room", roomName)
+			c.close()
+		}
+	}
+}
+
+func (h *chatHub) add(roomName livekit.RoomName, c *chatConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conns, ok := h.conns[roomName]
+	if !ok {
+		conns = make(map[*chatConn]struct{})
+		h.conns[roomName] = conns
+	}
+	conns[c] = struct{}{}
+}
+
+func (h *chatHub) remove(roomName livekit.RoomName, c *chatConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conns := h.conns[roomName]
+	delete(conns, c)
+	if len(conns) == 0 {
+		delete(h.conns, roomName)
+	}
+}
+
+// serve runs a chat socket until the client disconnects, pushing first ahead of any other event when it is set
+func (h *chatHub) serve(roomName livekit.RoomName, conn *websocket.Conn, first *chatEvent) {
+	c := &chatConn{
+		conn: conn,
+		send: make(chan *chatEvent, chatSocketBufferSize),
+		done: make(chan struct{}),
+	}
+	if first != nil {
+		c.send <- first
+	}
+	h.add(roomName, c)
+	defer h.remove(roomName, c)
+
+	go h.writeLoop(c)
+	defer c.close()
+
+	// clients only send control messages, reading notices them going away
+	conn.SetReadLimit(512)
+	_ = conn.SetReadDeadline(time.Now().Add(chatSocketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(chatSocketPongWait))
+	})
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.logger.Debugw("chat socket closed", "room", roomName, "error", err)
+			}
+			return
+		}
+	}
+}
+
+func (h *chatHub) writeLoop(c *chatConn) {
+	ticker := time.NewTicker(chatSocketPingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			_ = c.conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(chatSocketWriteWait),
+			)
+			return
+		case event := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(chatSocketWriteWait))
+			if err := c.conn.WriteJSON(event); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(chatSocketWriteWait)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// how long a listing of the live streams is served before the rooms are read again
+	directoryRefreshInterval = 5 * time.Second
+	defaultDirectoryLimit    = 50
+	maxDirectoryLimit        = 100
+)
+
+// directory sort orders
+const (
+	directorySortViewers = "viewers"
+	directorySortRecent  = "recent"
+)
+
+var errRoomStoreNotConfigured = errors.New("room store not configured")
+
+// LiveStream is a stream listed in the live directory
+type LiveStream struct {
+	// the room of the stream
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Category   string `json:"category,omitempty"`
+	Thumbnail  string `json:"thumbnail,omitempty"`
+	StreamerID string `json:"streamerId"`
+	Streamer   string `json:"streamer"`
+	Avatar     string `json:"avatar,omitempty"`
+	Viewers    int    `json:"viewers"`
+	// unix milliseconds
+	StartTime int64 `json:"startTime"`
+}
+
+// liveDirectoryQuery filters and orders the live directory
+type liveDirectoryQuery struct {
+	// matches title, streamer name, room and category
+	Search   string
+	Category string
+	Sort     string
+	Limit    int
+	Offset   int
+}
+
+// liveDirectory lists the rooms that have a streamer publishing video, read from the room store. With Redis the
+// store holds the rooms and participants of every node, so the directory shows streams across the cluster.
+// Room events on this node make the next listing read the store again instead of waiting for the refresh interval.
+type liveDirectory struct {
+	streamerOf   func(livekit.RoomName) (livekit.ParticipantIdentity, bool)
+	thumbnailURL func(context.Context, livekit.RoomName) string
+	logger       logger.Logger
+
+	mu          sync.Mutex
+	store       ServiceStore
+	streams     []*LiveStream
+	refreshedAt time.Time
+}
+
+func newLiveDirectory(
+	streamerOf func(livekit.RoomName) (livekit.ParticipantIdentity, bool),
+	thumbnailURL func(context.Context, livekit.RoomName) string,
+) *liveDirectory {
+	return &liveDirectory{
+		streamerOf:   streamerOf,
+		thumbnailURL: thumbnailURL,
+		logger:       logger.GetLogger(),
+	}
+}
+
+func (d *liveDirectory) setStore(store ServiceStore) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.store = store
+	d.refreshedAt = time.Time{}
+}
+
+func (d *liveDirectory) invalidate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.refreshedAt = time.Time{}
+}
+
+func (d *liveDirectory) OnRoomStarted(_ *livekit.Room) {}
+
+func (d *liveDirectory) OnRoomClosed(_ *livekit.Room) {
+	d.invalidate()
+}
+
+func (d *liveDirectory) OnParticipantJoined(_ *livekit.Room, _ types.LocalParticipant) {
+	d.invalidate()
+}
+
+func (d *liveDirectory) OnParticipantLeft(_ *livekit.Room, _ types.LocalParticipant) {
+	d.invalidate()
+}
+
+func (d *liveDirectory) OnTrackPublished(_ *livekit.Room, _ types.LocalParticipant, _ types.MediaTrack) {
+	d.invalidate()
+}
+
+// list returns the live streams matching a query
+func (d *liveDirectory) list(ctx context.Context, query liveDirectoryQuery) ([]*LiveStream, error) {
+	streams, err := d.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	search := strings.ToLower(query.Search)
+	result := make([]*LiveStream, 0, len(streams))
+	for _, s := range streams {
+		if query.Category != "" && !strings.EqualFold(s.Category, query.Category) {
+			continue
+		}
+		if search != "" && !slices.ContainsFunc([]string{s.Title, s.Streamer, s.ID, s.Category}, func(field string) bool {
+			return strings.Contains(strings.ToLower(field), search)
+		}) {
+			continue
+		}
+		result = append(result, s)
+	}
+
+	switch query.Sort {
+	case directorySortRecent:
+		slices.SortStableFunc(result, func(a, b *LiveStream) int {
+			return compareInt64(b.StartTime, a.StartTime)
+		})
+	default:
+		slices.SortStableFunc(result, func(a, b *LiveStream) int {
+			if a.Viewers != b.Viewers {
+				return b.Viewers - a.Viewers
+			}
+			return compareInt64(a.StartTime, b.StartTime)
+		})
+	}
+
+	if query.Offset >= len(result) {
+		return []*LiveStream{}, nil
+	}
+	result = result[query.Offset:]
+	if query.Limit > 0 && query.Limit < len(result) {
+		result = result[:query.Limit]
+	}
+	return result, nil
+}
+
+// snapshot returns every live stream, reading the rooms again when the last read is too old
+func (d *liveDirectory) snapshot(ctx context.Context) ([]*LiveStream, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.store == nil {
+		return nil, errRoomStoreNotConfigured
+	}
+	if time.Since(d.refreshedAt) < directoryRefreshInterval {
+		return d.streams, nil
+	}
+
+	rooms, err := d.store.ListRooms(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	streams := make([]*LiveStream, 0, len(rooms))
+	for _, room := range rooms {
+		participants, err := d.store.ListParticipants(ctx, livekit.RoomName(room.Name))
+		if err != nil {
+			d.logger.Warnw("could not list participants", err, "room", room.Name)
+			continue
+		}
+		if stream := d.liveStream(room, participants); stream != nil {
+			// a thumbnail set by the streamer wins over captured ones
+			if stream.Thumbnail == "" {
+				stream.Thumbnail = d.thumbnailURL(ctx, livekit.RoomName(room.Name))
+			}
+			streams = append(streams, stream)
+		}
+	}
+	d.streams = streams
+	d.refreshedAt = time.Now()
+	return streams, nil
+}
+
+// liveStream describes a room as a live stream, nil when nobody is streaming in it. The streamer is who the stream
+// lifecycle saw going live on this node, or for rooms on other nodes the first participant publishing video.
+func (d *liveDirectory) liveStream(room *livekit.Room, participants []*livekit.ParticipantInfo) *LiveStream {
+	streamerID, known := d.streamerOf(livekit.RoomName(room.Name))
+
+	var streamer *livekit.ParticipantInfo
+	viewers := 0
+	for _, p := range participants {
+		if !isEndUserInfo(p) || p.State == livekit.ParticipantInfo_DISCONNECTED {
+			continue
+		}
+		viewers++
+		switch {
+		case known:
+			if livekit.ParticipantIdentity(p.Identity) == streamerID {
+				streamer = p
+			}
+		case publishesVideo(p) && (streamer == nil || p.JoinedAtMs < streamer.JoinedAtMs):
+			streamer = p
+		}
+	}
+	if streamer == nil {
+		return nil
+	}
+
+	stream := &LiveStream{
+		ID:         room.Name,
+		Title:      streamTitle(room, streamer),
+		Category:   streamDetail(room, streamer, attributeCategory),
+		Thumbnail:  streamDetail(room, streamer, attributeThumbnail),
+		StreamerID: streamer.Identity,
+		Streamer:   streamer.Name,
+		Avatar:     streamDetail(room, streamer, attributeAvatarURL),
+		Viewers:    viewers - 1,
+		StartTime:  streamer.JoinedAtMs,
+	}
+	if stream.Streamer == "" {
+		stream.Streamer = streamer.Identity
+	}
+	if stream.StartTime == 0 {
+		stream.StartTime = streamer.JoinedAt * 1000
+	}
+	return stream
+}
+
+// isEndUserInfo is isEndUser for participants read from the room store
+func isEndUserInfo(p *livekit.ParticipantInfo) bool {
+	if p.GetPermission().GetHidden() || p.GetPermission().GetRecorder() {
+		return false
+	}
+	return p.Kind != livekit.ParticipantInfo_EGRESS && p.Kind != livekit.ParticipantInfo_AGENT
+}
+
+func publishesVideo(p *livekit.ParticipantInfo) bool {
+	return slices.ContainsFunc(p.Tracks, func(t *livekit.TrackInfo) bool {
+		return t.Type == livekit.TrackType_VIDEO
+	})
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// SetRoomStore sets the store the live directory reads rooms and participants from
+func (s *StreamingAPIService) SetRoomStore(store ServiceStore) {
+	s.directory.setStore(store)
+}
+
+// handleListStreams lists the live streams, ?q= searches them, ?category= filters them and ?sort= orders them by
+// viewers (default) or recent. ?limit= and ?offset= page through the results.
+func (s *StreamingAPIService) handleListStreams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := liveDirectoryQuery{
+		Search:   params.Get("q"),
+		Category: params.Get("category"),
+		Sort:     params.Get("sort"),
+		Limit:    defaultDirectoryLimit,
+	}
+	if query.Sort != "" && query.Sort != directorySortViewers && query.Sort != directorySortRecent {
+		http.Error(w, "sort must be viewers or recent", http.StatusBadRequest)
+		return
+	}
+	var err error
+	if v := params.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		query.Limit = min(query.Limit, maxDirectoryLimit)
+	}
+	if v := params.Get("offset"); v != "" {
+		if query.Offset, err = strconv.Atoi(v); err != nil || query.Offset < 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	streams, err := s.directory.list(r.Context(), query)
+	if errors.Is(err, errRoomStoreNotConfigured) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		s.logger.Errorw("could not list live streams", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(streams)
+}
@@ -0,0 +1,266 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+type streamLifecycleParams struct {
+	// how long a streamer may be gone before the stream is considered ended
+	ReconnectGrace time.Duration
+	// minimum time between two go-live notifications for the same streamer
+	GoLiveCooldown time.Duration
+}
+
+var defaultStreamLifecycleParams = streamLifecycleParams{
+	ReconnectGrace: 30 * time.Second,
+	GoLiveCooldown: 10 * time.Minute,
+}
+
+type liveStream struct {
+	roomName     livekit.RoomName
+	streamerID   livekit.ParticipantIdentity
+	streamerName string
+	title        string
+	startedAt    time.Time
+
+	// set while the streamer is gone and the stream may still resume
+	endedAt time.Time
+	ending  bool
+	endGen  int
+
+	stopAnalytics context.CancelFunc
+}
+
+// streamLifecycle turns room events into go-live and stream-end notifications.
+// A room goes live when its first participant publishes video; that participant is the streamer.
+type streamLifecycle struct {
+	params        streamLifecycleParams
+	notifications *streaming.NotificationService
+	analytics     *streaming.AnalyticsService
+	logger        logger.Logger
+
+	// called when a stream goes live and when it ends, set before any room events
+	onLive  func(roomName livekit.RoomName, streamerID livekit.ParticipantIdentity)
+	onEnded func(roomName livekit.RoomName, streamerID livekit.ParticipantIdentity)
+
+	mu         sync.Mutex
+	streams    map[livekit.RoomName]*liveStream
+	lastGoLive map[livekit.ParticipantIdentity]time.Time
+}
+
+func newStreamLifecycle(
+	params streamLifecycleParams,
+	notifications *streaming.NotificationService,
+	analytics *streaming.AnalyticsService,
+) *streamLifecycle {
+	return &streamLifecycle{
+		params:        params,
+		notifications: notifications,
+		analytics:     analytics,
+		logger:        logger.GetLogger(),
+		streams:       make(map[livekit.RoomName]*liveStream),
+		lastGoLive:    make(map[livekit.ParticipantIdentity]time.Time),
+	}
+}
+
+func (l *streamLifecycle) OnRoomStarted(_ *livekit.Room) {}
+
+func (l *streamLifecycle) OnParticipantJoined(_ *livekit.Room, _ types.LocalParticipant) {}
+
+func (l *streamLifecycle) OnTrackPublished(room *livekit.Room, participant types.LocalParticipant, track types.MediaTrack) {
+	if track.Kind() != livekit.TrackType_VIDEO || !isEndUser(participant) {
+		return
+	}
+
+	roomName := livekit.RoomName(room.Name)
+	l.mu.Lock()
+	if s, ok := l.streams[roomName]; ok {
+		if s.streamerID == participant.Identity() && s.ending {
+			// streamer came back within the grace period, carry on with the same stream
+			s.ending = false
+			s.endedAt = time.Time{}
+			l.logger.Infow("streamer resumed", "room", roomName, "streamerID", s.streamerID)
+		}
+		l.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	info := participant.ToProto()
+	s := &liveStream{
+		roomName:     roomName,
+		streamerID:   participant.Identity(),
+		streamerName: info.Name,
+		title:        streamTitle(room, info),
+		startedAt:    now,
+	}
+	if s.streamerName == "" {
+		s.streamerName = string(s.streamerID)
+	}
+	l.streams[roomName] = s
+
+	notify := true
+	if last, ok := l.lastGoLive[s.streamerID]; ok && now.Sub(last) < l.params.GoLiveCooldown {
+		notify = false
+	} else {
+		l.lastGoLive[s.streamerID] = now
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopAnalytics = cancel
+	l.mu.Unlock()
+
+	if _, err := l.analytics.StartStreamAnalytics(ctx, roomName, s.streamerID); err != nil {
+		l.logger.Warnw("could not start stream analytics", err, "room", roomName)
+	}
+
+	l.logger.Infow("stream went live", "room", roomName, "streamerID", s.streamerID, "notify", notify)
+	if l.onLive != nil {
+		l.onLive(roomName, s.streamerID)
+	}
+	if notify {
+		go func() {
+			if err := l.notifications.NotifyStreamStarted(context.Background(), s.streamerID, s.streamerName, roomName, s.title); err != nil {
+				l.logger.Errorw("could not notify stream started", err, "room", roomName)
+			}
+		}()
+	}
+}
+
+func (l *streamLifecycle) OnParticipantLeft(room *livekit.Room, participant types.LocalParticipant) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.streams[livekit.RoomName(room.Name)]; ok && s.streamerID == participant.Identity() {
+		l.beginEndLocked(s)
+	}
+}
+
+func (l *streamLifecycle) OnRoomClosed(room *livekit.Room) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.streams[livekit.RoomName(room.Name)]; ok {
+		l.beginEndLocked(s)
+	}
+}
+
+// streamerOf returns the streamer of a live room
+func (l *streamLifecycle) streamerOf(roomName livekit.RoomName) (livekit.ParticipantIdentity, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.streams[roomName]; ok {
+		return s.streamerID, true
+	}
+	return "", false
+}
+
+// roomsOf returns the rooms a streamer is live in
+func (l *streamLifecycle) roomsOf(streamerID livekit.ParticipantIdentity) []livekit.RoomName {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var rooms []livekit.RoomName
+	for _, s := range l.streams {
+		if s.streamerID == streamerID {
+			rooms = append(rooms, s.roomName)
+		}
+	}
+	return rooms
+}
+
+func (l *streamLifecycle) beginEndLocked(s *liveStream) {
+	if s.ending {
+		return
+	}
+	s.ending = true
+	s.endedAt = time.Now()
+	s.endGen++
+	gen := s.endGen
+	time.AfterFunc(l.params.ReconnectGrace, func() {
+		l.endStream(s, gen)
+	})
+}
+
+func (l *streamLifecycle) endStream(s *liveStream, gen int) {
+	l.mu.Lock()
+	if l.streams[s.roomName] != s || !s.ending || s.endGen != gen {
+		l.mu.Unlock()
+		return
+	}
+	delete(l.streams, s.roomName)
+	duration := s.endedAt.Sub(s.startedAt)
+	l.mu.Unlock()
+
+	ctx := context.Background()
+	viewers := 0
+	if err := l.analytics.StopStreamAnalytics(ctx, s.roomName); err == nil {
+		if analytics, err := l.analytics.GetStreamAnalytics(ctx, s.roomName); err == nil {
+			viewers = analytics.UniqueViewers
+		}
+	}
+	s.stopAnalytics()
+
+	l.logger.Infow("stream ended", "room", s.roomName, "streamerID", s.streamerID, "duration", duration, "viewers", viewers)
+	if l.onEnded != nil {
+		l.onEnded(s.roomName, s.streamerID)
+	}
+	if err := l.notifications.NotifyStreamEnded(ctx, s.streamerID, s.streamerName, duration.Round(time.Second), viewers); err != nil {
+		l.logger.Errorw("could not notify stream ended", err, "room", s.roomName)
+	}
+}
+
+// isEndUser filters out participants that join on behalf of the server, such as recorders and agents
+func isEndUser(participant types.LocalParticipant) bool {
+	if participant.Hidden() || participant.IsRecorder() || participant.IsAgent() {
+		return false
+	}
+	return participant.Kind() != livekit.ParticipantInfo_EGRESS
+}
+
+// streamTitle looks for a title in the streamer's attributes, then in participant and room metadata
+func streamTitle(room *livekit.Room, info *livekit.ParticipantInfo) string {
+	return streamDetail(room, info, attributeTitle)
+}
+
+// streamDetail looks for a detail of a stream such as its title or category in the streamer's attributes,
+// then in participant and room metadata
+func streamDetail(room *livekit.Room, info *livekit.ParticipantInfo, key string) string {
+	if value := info.Attributes[key]; value != "" {
+		return value
+	}
+	for _, metadata := range []string{info.Metadata, room.Metadata} {
+		var m map[string]any
+		if metadata == "" || json.Unmarshal([]byte(metadata), &m) != nil {
+			continue
+		}
+		if value, ok := m[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func newFakeStreamer(identity string) *typesfakes.FakeLocalParticipant {
+	p := &typesfakes.FakeLocalParticipant{}
+	p.IdentityReturns(livekit.ParticipantIdentity(identity))
+	p.KindReturns(livekit.ParticipantInfo_STANDARD)
+	p.ToProtoReturns(&livekit.ParticipantInfo{
+		Identity: identity,
+		Name:     "Streamer",
+		Metadata: `{"title":"my stream"}`,
+	})
+	return p
+}
+
+func newFakeTrack(kind livekit.TrackType) *typesfakes.FakeMediaTrack {
+	t := &typesfakes.FakeMediaTrack{}
+	t.KindReturns(kind)
+	return t
+}
+
+func TestStreamLifecycle(t *testing.T) {
+	params := streamLifecycleParams{
+		ReconnectGrace: 50 * time.Millisecond,
+		GoLiveCooldown: time.Hour,
+	}
+	room := &livekit.Room{Name: "room"}
+
+	setup := func(t *testing.T) (*streamLifecycle, *streaming.NotificationService) {
+		notifications := streaming.NewNotificationService(nil)
+		prefs := &streaming.NotificationSubscription{EnableStreamStart: true, EnableStreamEnd: true}
+		require.NoError(t, notifications.Subscribe(context.Background(), "follower", "streamer", "Streamer", prefs))
+		return newStreamLifecycle(params, notifications, streaming.NewAnalyticsService(nil)), notifications
+	}
+	notificationsOfType := func(ns *streaming.NotificationService, typ streaming.NotificationType) []*streaming.Notification {
+		all, _ := ns.GetNotifications(context.Background(), "follower", false, 100)
+		var out []*streaming.Notification
+		for _, n := range all {
+			if n.Type == typ {
+				out = append(out, n)
+			}
+		}
+		return out
+	}
+
+	t.Run("go live on first video track and end after grace", func(t *testing.T) {
+		l, ns := setup(t)
+		streamer := newFakeStreamer("streamer")
+
+		l.OnTrackPublished(room, streamer, newFakeTrack(livekit.TrackType_AUDIO))
+		l.OnTrackPublished(room, streamer, newFakeTrack(livekit.TrackType_VIDEO))
+		l.OnTrackPublished(room, streamer, newFakeTrack(livekit.TrackType_VIDEO))
+
+		require.Eventually(t, func() bool {
+			return len(notificationsOfType(ns, streaming.NotificationTypeStreamStarted)) == 1
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, "my stream", notificationsOfType(ns, streaming.NotificationTypeStreamStarted)[0].Body)
+
+		l.OnParticipantLeft(room, streamer)
+		l.OnRoomClosed(room)
+		require.Eventually(t, func() bool {
+			return len(notificationsOfType(ns, streaming.NotificationTypeStreamEnded)) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("brief reconnect does not notify again", func(t *testing.T) {
+		l, ns := setup(t)
+		streamer := newFakeStreamer("streamer")
+
+		l.OnTrackPublished(room, streamer, newFakeTrack(livekit.TrackType_VIDEO))
+		l.OnParticipantLeft(room, streamer)
+		l.OnTrackPublished(room, streamer, newFakeTrack(livekit.TrackType_VIDEO))
+
+		time.Sleep(3 * params.ReconnectGrace)
+		require.Len(t, notificationsOfType(ns, streaming.NotificationTypeStreamStarted), 1)
+		require.Empty(t, notificationsOfType(ns, streaming.NotificationTypeStreamEnded))
+	})
+
+	t.Run("restart within cooldown does not notify again", func(t *testing.T) {
+		l, ns := setup(t)
+		streamer := newFakeStreamer("streamer")
+
+		l.OnTrackPublished(room, streamer, newFakeTrack(livekit.TrackType_VIDEO))
+		l.OnRoomClosed(room)
+		require.Eventually(t, func() bool {
+			return len(notificationsOfType(ns, streaming.NotificationTypeStreamEnded)) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		l.OnTrackPublished(room, streamer, newFakeTrack(livekit.TrackType_VIDEO))
+		time.Sleep(20 * time.Millisecond)
+		require.Len(t, notificationsOfType(ns, streaming.NotificationTypeStreamStarted), 1)
+	})
+
+	t.Run("egress is not a streamer", func(t *testing.T) {
+		l, ns := setup(t)
+		egress := newFakeStreamer("streamer")
+		egress.KindReturns(livekit.ParticipantInfo_EGRESS)
+
+		l.OnTrackPublished(room, egress, newFakeTrack(livekit.TrackType_VIDEO))
+		time.Sleep(20 * time.Millisecond)
+		require.Empty(t, notificationsOfType(ns, streaming.NotificationTypeStreamStarted))
+	})
+}
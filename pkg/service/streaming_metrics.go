@@ -0,0 +1,38 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collectors returns the prometheus collectors of the streaming services
+func (s *StreamingAPIService) Collectors() []prometheus.Collector {
+	collectors := []prometheus.Collector{
+		s.streamKeyManager.Collector(),
+		s.chatService.Collector(),
+		s.reactionService.Collector(),
+		s.vodService.Collector(),
+		s.notificationService.Collector(),
+		s.analyticsService.Collector(),
+	}
+	if s.emailNotifier != nil {
+		collectors = append(collectors, s.emailNotifier.Collector())
+	}
+	if s.pushNotifier != nil {
+		collectors = append(collectors, s.pushNotifier.Collector())
+	}
+	return collectors
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+const (
+	notificationSocketBufferSize = 64
+	notificationSocketWriteWait  = 10 * time.Second
+	notificationSocketPongWait   = 60 * time.Second
+	notificationSocketPingPeriod = notificationSocketPongWait * 9 / 10
+)
+
+// notificationEvent is a message pushed to notification sockets
+type notificationEvent struct {
+	Type         string                  `json:"type"`
+	Notification *streaming.Notification `json:"notification,omitempty"`
+	Stage        *streaming.Stage        `json:"stage,omitempty"`
+	UnreadCount  int                     `json:"unread_count"`
+}
+
+// notificationClientMessage is a message received from a notification socket
+type notificationClientMessage struct {
+	Type           string `json:"type"`
+	NotificationID string `json:"notification_id,omitempty"`
+}
+
+type notificationConn struct {
+	conn *websocket.Conn
+	send chan *notificationEvent
+	done chan struct{}
+	once sync.Once
+}
+
+func (c *notificationConn) close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// notificationHub fans out notifications to the WebSocket connections of each user
+type notificationHub struct {
+	mu    sync.RWMutex
+	conns map[livekit.ParticipantIdentity]map[*notificationConn]struct{}
+
+	notifications *streaming.NotificationService
+	logger        logger.Logger
+}
+
+func newNotificationHub(notifications *streaming.NotificationService) *notificationHub {
+	h := &notificationHub{
+		conns:         make(map[livekit.ParticipantIdentity]map[*notificationConn]struct{}),
+		notifications: notifications,
+		logger:        logger.GetLogger(),
+	}
+	notifications.RegisterNotificationHandler(streaming.ChannelWebSocket, h.onNotification)
+	return h
+}
+
+func (h *notificationHub) onNotification(notification *streaming.Notification) {
+	unread, _ := h.notifications.GetUnreadCount(context.Background(), notification.UserID)
+	h.broadcast(notification.UserID, &notificationEvent{
+		Type:         "notification",
+		Notification: notification,
+		UnreadCount:  unread,
+	})
+}
+
+// publishUnreadCount pushes the current unread count to all sockets of a user
+func (h *notificationHub) publishUnreadCount(userID livekit.ParticipantIdentity) {
+	unread, _ := h.notifications.GetUnreadCount(context.Background(), userID)
+	h.broadcast(userID, &notificationEvent{
+		Type:        "unread_count",
+		UnreadCount: unread,
+	})
+}
+
+// publishStage pushes the stage of a room to all sockets of its owner
+func (h *notificationHub) publishStage(userID livekit.ParticipantIdentity, stage *streaming.Stage) {
+	unread, _ := h.notifications.GetUnreadCount(context.Background(), userID)
+	h.broadcast(userID, &notificationEvent{
+		Type:        "stage",
+		Stage:       stage,
+		UnreadCount: unread,
+	})
+}
+
+func (h *notificationHub) broadcast(userID livekit.ParticipantIdentity, event *notificationEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.conns[userID] {
+		select {
+		case c.send <- event:
+		default:
+			// slow consumer, drop the connection rather than block delivery to others
+			h.logger.Infow("dropping slow notification socket", "userID", userID)
+			c.close()
+		}
+	}
+}
+
+func (h *notificationHub) add(userID livekit.ParticipantIdentity, c *notificationConn) {
+	h.mu.Lock()
+	conns, ok := h.conns[userID]
+	if !ok {
+		conns = make(map[*notificationConn]struct{})
+		h.conns[userID] = conns
+	}
+	conns[c] = struct{}{}
+	first := len(conns) == 1
+	h.mu.Unlock()
+
+	if first {
+		h.notifications.SetUserOnlineStatus(context.Background(), userID, true)
+	}
+}
+
+func (h *notificationHub) remove(userID livekit.ParticipantIdentity, c *notificationConn) {
+	h.mu.Lock()
+	conns := h.conns[userID]
+	delete(conns, c)
+	last := len(conns) == 0
+	if last {
+		delete(h.conns, userID)
+	}
+	h.mu.Unlock()
+
+	if last {
+		h.notifications.SetUserOnlineStatus(context.Background(), userID, false)
+	}
+}
+
+// serve runs a notification socket until the client disconnects
+func (h *notificationHub) serve(userID livekit.ParticipantIdentity, conn *websocket.Conn) {
+	c := &notificationConn{
+		conn: conn,
+		send: make(chan *notificationEvent, notificationSocketBufferSize),
+		done: make(chan struct{}),
+	}
+	h.add(userID, c)
+	defer h.remove(userID, c)
+
+	go h.writeLoop(c)
+	defer c.close()
+
+	h.publishUnreadCount(userID)
+
+	conn.SetReadLimit(4096)
+	_ = conn.SetReadDeadline(time.Now().Add(notificationSocketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(notificationSocketPongWait))
+	})
+
+	for {
+		var msg notificationClientMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.logger.Debugw("notification socket closed", "userID", userID, "error", err)
+			}
+			return
+		}
+
+		ctx := context.Background()
+		switch msg.Type {
+		case "mark_read":
+			if err := h.notifications.MarkAsRead(ctx, userID, msg.NotificationID); err != nil {
+				h.logger.Debugw("could not mark notification as read", "userID", userID, "error", err)
+				continue
+			}
+		case "mark_all_read":
+			_ = h.notifications.MarkAllAsRead(ctx, userID)
+		default:
+			continue
+		}
+		h.publishUnreadCount(userID)
+	}
+}
+
+func (h *notificationHub) writeLoop(c *notificationConn) {
+	ticker := time.NewTicker(notificationSocketPingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			_ = c.conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(notificationSocketWriteWait),
+			)
+			return
+		case event := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(notificationSocketWriteWait))
+			if err := c.conn.WriteJSON(event); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(notificationSocketWriteWait)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+const (
+	defaultRaidCountdown = 10 * time.Second
+	maxRaidCountdown     = time.Minute
+	// raided viewers joining the target this long after the countdown still count as referred by the raid
+	raidReferralWindow = 5 * time.Minute
+	// topic of the data packets carrying the redirect, and the event of the chat notice announcing the raid
+	raidTopic = "raid"
+)
+
+// raidRedirect tells the viewers of a stream to move over to another channel once the countdown is over.
+// Viewers get it as a data packet in the room and as an event on the chat socket.
+type raidRedirect struct {
+	Type          string           `json:"type"`
+	FromRoom      livekit.RoomName `json:"from_room"`
+	FromName      string           `json:"from_name"`
+	TargetChannel string           `json:"target_channel"`
+	TargetName    string           `json:"target_name"`
+	Countdown     int              `json:"countdown"` // seconds
+	RedirectAt    time.Time        `json:"redirect_at"`
+	Viewers       int              `json:"viewers"`
+}
+
+// raidTracker keeps the raids under way, a room raids one channel at a time until its countdown is over
+type raidTracker struct {
+	mu     sync.Mutex
+	timers map[livekit.RoomName]*time.Timer
+}
+
+func newRaidTracker() *raidTracker {
+	return &raidTracker{timers: make(map[livekit.RoomName]*time.Timer)}
+}
+
+// start reports false when the room is raiding already, otherwise onDone runs when the countdown is over
+func (t *raidTracker) start(roomName livekit.RoomName, countdown time.Duration, onDone func()) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.timers[roomName]; ok {
+		return false
+	}
+	t.timers[roomName] = time.AfterFunc(countdown, func() {
+		t.mu.Lock()
+		delete(t.timers, roomName)
+		t.mu.Unlock()
+		if onDone != nil {
+			onDone()
+		}
+	})
+	return true
+}
+
+// cancel drops the raid of a room without running its onDone
+func (t *raidTracker) cancel(roomName livekit.RoomName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if timer, ok := t.timers[roomName]; ok {
+		timer.Stop()
+		delete(t.timers, roomName)
+	}
+}
+
+func (t *raidTracker) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for roomName, timer := range t.timers {
+		timer.Stop()
+		delete(t.timers, roomName)
+	}
+}
+
+// sendRaidRedirect sends the redirect to everyone in the room as a reliable data packet
+func (s *StreamingAPIService) sendRaidRedirect(ctx context.Context, redirect *raidRedirect) error {
+	if s.roomService == nil {
+		return errRoomServiceNotConfigured
+	}
+	data, err := json.Marshal(redirect)
+	if err != nil {
+		return err
+	}
+	// the room service checks the grants of API keys, raids act on behalf of the server
+	ctx = WithGrants(ctx, &auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true, Room: string(redirect.FromRoom)}}, "")
+	topic := raidTopic
+	_, err = s.roomService.SendData(ctx, &livekit.SendDataRequest{
+		Room:  string(redirect.FromRoom),
+		Data:  data,
+		Kind:  livekit.DataPacket_RELIABLE,
+		Topic: &topic,
+	})
+	return err
+}
+
+// handleRaid sends the viewers of a stream over to another live channel. They are told to move with a countdown,
+// the target channel is announced the raid in its chat and its streamer is notified. With end_stream, the raiding
+// stream ends once the countdown is over.
+func (s *StreamingAPIService) handleRaid(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := currentUser(r)
+
+	var req struct {
+		RoomName      string `json:"room_name"`
+		TargetChannel string `json:"target_channel"`
+		Countdown     *int   `json:"countdown,omitempty"` // seconds
+		EndStream     bool   `json:"end_stream,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomName == "" || req.TargetChannel == "" {
+		http.Error(w, "room_name and target_channel required", http.StatusBadRequest)
+		return
+	}
+	roomName := livekit.RoomName(req.RoomName)
+	targetRoom := livekit.RoomName(req.TargetChannel)
+	if targetRoom == roomName {
+		http.Error(w, "a stream cannot raid itself", http.StatusBadRequest)
+		return
+	}
+	countdown := defaultRaidCountdown
+	if req.Countdown != nil {
+		countdown = time.Duration(*req.Countdown) * time.Second
+		if countdown < 0 || countdown > maxRaidCountdown {
+			http.Error(w, fmt.Sprintf("countdown must be between 0 and %d seconds", int(maxRaidCountdown.Seconds())), http.StatusBadRequest)
+			return
+		}
+	}
+	if !s.isRoomOwner(w, r, roomName, userID) {
+		return
+	}
+
+	targetOwner, ok, err := s.roomOwner(r.Context(), targetRoom)
+	if err != nil {
+		s.logger.Errorw("could not look up room owner", err, "room", targetRoom)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "channel not found", http.StatusNotFound)
+		return
+	}
+	if target, err := s.analyticsService.GetStreamAnalytics(r.Context(), targetRoom); err != nil || target.EndTime != nil {
+		http.Error(w, "the channel is not live", http.StatusConflict)
+		return
+	}
+	viewers := 0
+	if source, err := s.analyticsService.GetStreamAnalytics(r.Context(), roomName); err == nil && source.EndTime == nil {
+		viewers = source.CurrentViewers
+	}
+
+	var onDone func()
+	if req.EndStream {
+		onDone = func() {
+			if err := s.endStream(context.Background(), roomName); err != nil && !errors.Is(err, ErrRoomNotFound) {
+				s.logger.Warnw("could not end stream after raid", err, "room", roomName)
+			}
+		}
+	}
+	if !s.raids.start(roomName, countdown, onDone) {
+		http.Error(w, "a raid is already under way", http.StatusConflict)
+		return
+	}
+
+	fromName := s.displayName(r.Context(), userID)
+	redirect := &raidRedirect{
+		Type:          "raid",
+		FromRoom:      roomName,
+		FromName:      fromName,
+		TargetChannel: string(targetRoom),
+		TargetName:    s.displayName(r.Context(), targetOwner),
+		Countdown:     int(countdown.Seconds()),
+		RedirectAt:    time.Now().Add(countdown),
+		Viewers:       viewers,
+	}
+	if err = s.sendRaidRedirect(r.Context(), redirect); err != nil {
+		s.raids.cancel(roomName)
+		if errors.Is(err, errRoomServiceNotConfigured) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		} else {
+			s.logger.Errorw("could not send raid redirect", err, "room", roomName)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	s.chatHub.broadcast(roomName, &chatEvent{Type: "raid", Raid: redirect})
+
+	raid, err := s.analyticsService.RecordRaid(r.Context(), roomName, targetRoom, countdown+raidReferralWindow)
+	if err != nil {
+		// the target ended meanwhile, the viewers are on their way regardless
+		s.logger.Warnw("could not record raid", err, "room", roomName, "target", targetRoom)
+		raid = &streaming.RaidReferral{FromRoom: roomName, FromStreamerID: userID, At: time.Now(), Viewers: viewers}
+	}
+	if raid.FromStreamerID == "" {
+		raid.FromStreamerID = userID
+	}
+
+	_, err = s.chatService.SendSystemNotice(r.Context(), targetRoom,
+		fmt.Sprintf("%s is raiding with %d viewers!", fromName, raid.Viewers),
+		map[string]string{
+			"event":     raidTopic,
+			"user_id":   string(userID),
+			"from_room": string(roomName),
+			"viewers":   strconv.Itoa(raid.Viewers),
+		},
+	)
+	if err != nil {
+		s.logger.Debugw("could not post raid notice", "room", targetRoom, "error", err)
+	}
+	if err = s.notificationService.NotifyRaid(r.Context(), targetOwner, targetRoom, raid, fromName); err != nil {
+		s.logger.Warnw("could not notify raid", err, "room", targetRoom, "userID", targetOwner)
+	}
+
+	s.logger.Infow("stream raiding",
+		"room", roomName,
+		"target", targetRoom,
+		"viewers", raid.Viewers,
+		"countdown", countdown,
+		"endStream", req.EndStream,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(redirect)
+}
@@ -0,0 +1,390 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	appauth "github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+// how often due reminders are sent and missed streams are swept
+const scheduleCheckInterval = 30 * time.Second
+
+// streamScheduler reminds followers of scheduled streams about to start, and moves scheduled streams
+// to live and ended as the stream lifecycle sees their streamers go live and end
+type streamScheduler struct {
+	schedules     *streaming.ScheduleManager
+	notifications *streaming.NotificationService
+	logger        logger.Logger
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newStreamScheduler(schedules *streaming.ScheduleManager, notifications *streaming.NotificationService) *streamScheduler {
+	s := &streamScheduler{
+		schedules:     schedules,
+		notifications: notifications,
+		logger:        logger.GetLogger(),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *streamScheduler) close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+}
+
+func (s *streamScheduler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.check(now)
+		}
+	}
+}
+
+// check sends the reminders that are due and sweeps missed and finished streams
+func (s *streamScheduler) check(now time.Time) {
+	ctx := context.Background()
+	for _, stream := range s.schedules.DueReminders(now) {
+		if err := s.notifications.NotifyStreamReminder(ctx, stream); err != nil {
+			s.logger.Warnw("could not notify scheduled stream", err, "id", stream.ID)
+		}
+	}
+	s.schedules.Sweep(ctx, now)
+}
+
+// onLive starts the scheduled stream a streamer going live is for
+func (s *streamScheduler) onLive(roomName livekit.RoomName, streamerID livekit.ParticipantIdentity) {
+	s.schedules.StartLive(streamerID, roomName, time.Now())
+}
+
+// onEnded ends the scheduled stream of a stream that ended
+func (s *streamScheduler) onEnded(roomName livekit.RoomName, streamerID livekit.ParticipantIdentity) {
+	s.schedules.EndLive(streamerID, roomName, time.Now())
+}
+
+// scheduledStreamRequest holds the fields of a scheduled stream to create or change
+type scheduledStreamRequest struct {
+	Title       *string    `json:"title"`
+	Description *string    `json:"description"`
+	Category    *string    `json:"category"`
+	StartTime   *time.Time `json:"start_time"`
+	Thumbnail   *string    `json:"thumbnail"`
+	// links a stream key of the streamer, going live with it starts the stream. Empty to unlink.
+	StreamKey *string `json:"stream_key"`
+}
+
+// handleSchedule lists and shows scheduled streams to anyone, and lets streamers create (POST),
+// change (PUT ?id=) and cancel (DELETE ?id=) theirs
+func (s *StreamingAPIService) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleGetSchedule(w, r)
+	case http.MethodPost:
+		s.authorized(s.handleCreateScheduledStream).ServeHTTP(w, r)
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		s.authorized(s.handleEditScheduledStream).ServeHTTP(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleGetSchedule returns the scheduled stream of ?id=, or the upcoming and live scheduled streams of
+// ?channel= or ?streamer_id=, with past ones when ?include_past=true
+func (s *StreamingAPIService) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if id := params.Get("id"); id != "" {
+		stream, err := s.schedules.Get(r.Context(), id)
+		if errors.Is(err, streaming.ErrScheduledStreamNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(publicScheduledStream(stream))
+		return
+	}
+
+	streamerID, ok := s.scheduleOwner(w, r)
+	if !ok {
+		return
+	}
+	streams := s.schedules.ListByStreamer(r.Context(), streamerID, params.Get("include_past") == "true")
+	for i, stream := range streams {
+		streams[i] = publicScheduledStream(stream)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(streams)
+}
+
+// handleScheduleCalendar serves the scheduled streams of ?channel= or ?streamer_id= as an iCalendar feed
+func (s *StreamingAPIService) handleScheduleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	streamerID, ok := s.scheduleOwner(w, r)
+	if !ok {
+		return
+	}
+
+	streams := s.schedules.ListByStreamer(r.Context(), streamerID, true)
+	name := s.displayName(r.Context(), streamerID)
+	base := httpURL(r)
+	feed := &streaming.ICalendarFeed{
+		Name:    name + "'s streams",
+		Domain:  r.Host,
+		Streams: streams,
+		URL: func(stream *streaming.ScheduledStream) string {
+			return base + streaming.ScheduledStreamPath(stream.ID)
+		},
+	}
+	for _, stream := range streams {
+		if stream.StreamerName == "" {
+			stream.StreamerName = name
+		}
+	}
+
+	w.Header().Set("Content-Type", streaming.ICalendarContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="schedule.ics"`)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(feed.Encode())
+}
+
+func (s *StreamingAPIService) handleCreateScheduledStream(w http.ResponseWriter, r *http.Request) {
+	userID, _ := currentUser(r)
+
+	var req scheduledStreamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !s.authorizeAction(w, r, string(userID), appauth.ActionGoLive) {
+		return
+	}
+
+	stream := &streaming.ScheduledStream{
+		StreamerID:   userID,
+		StreamerName: s.displayName(r.Context(), userID),
+	}
+	update, ok := s.scheduledStreamUpdate(w, r, userID, &req)
+	if !ok {
+		return
+	}
+	applyScheduledStreamUpdate(stream, update)
+
+	created, err := s.schedules.Create(r.Context(), stream)
+	if errors.Is(err, streaming.ErrInvalidScheduledStream) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (s *StreamingAPIService) handleEditScheduledStream(w http.ResponseWriter, r *http.Request) {
+	userID, _ := currentUser(r)
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	stream, err := s.schedules.Get(r.Context(), id)
+	if errors.Is(err, streaming.ErrScheduledStreamNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if stream.StreamerID != userID {
+		http.Error(w, "not your scheduled stream", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		stream, err = s.schedules.Cancel(r.Context(), id)
+	} else {
+		var req scheduledStreamRequest
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		update, ok := s.scheduledStreamUpdate(w, r, userID, &req)
+		if !ok {
+			return
+		}
+		stream, err = s.schedules.Update(r.Context(), id, update)
+	}
+	switch {
+	case errors.Is(err, streaming.ErrInvalidScheduledStream):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, streaming.ErrScheduledStreamNotEditable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, streaming.ErrScheduledStreamNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stream)
+}
+
+// scheduledStreamUpdate turns a request into an update, checking that a linked stream key belongs to the user
+func (s *StreamingAPIService) scheduledStreamUpdate(
+	w http.ResponseWriter,
+	r *http.Request,
+	userID livekit.ParticipantIdentity,
+	req *scheduledStreamRequest,
+) (streaming.ScheduledStreamUpdate, bool) {
+	update := streaming.ScheduledStreamUpdate{
+		Title:       req.Title,
+		Description: req.Description,
+		Category:    req.Category,
+		StartTime:   req.StartTime,
+		Thumbnail:   req.Thumbnail,
+		StreamKey:   req.StreamKey,
+	}
+	if req.StreamKey != nil {
+		var roomName livekit.RoomName
+		if *req.StreamKey != "" {
+			streamKey, err := s.streamKeyManager.ValidateStreamKey(r.Context(), *req.StreamKey)
+			if err != nil || streamKey.StreamerID != userID {
+				http.Error(w, "invalid stream key", http.StatusBadRequest)
+				return update, false
+			}
+			roomName = streamKey.RoomName
+		}
+		update.RoomName = &roomName
+	}
+	return update, true
+}
+
+func applyScheduledStreamUpdate(stream *streaming.ScheduledStream, update streaming.ScheduledStreamUpdate) {
+	if update.Title != nil {
+		stream.Title = *update.Title
+	}
+	if update.Description != nil {
+		stream.Description = *update.Description
+	}
+	if update.Category != nil {
+		stream.Category = *update.Category
+	}
+	if update.StartTime != nil {
+		stream.StartTime = *update.StartTime
+	}
+	if update.Thumbnail != nil {
+		stream.Thumbnail = *update.Thumbnail
+	}
+	if update.StreamKey != nil {
+		stream.StreamKey = *update.StreamKey
+	}
+	if update.RoomName != nil {
+		stream.RoomName = *update.RoomName
+	}
+}
+
+// scheduleOwner resolves the streamer of ?channel= or ?streamer_id=, writing the response when it fails
+func (s *StreamingAPIService) scheduleOwner(w http.ResponseWriter, r *http.Request) (livekit.ParticipantIdentity, bool) {
+	params := r.URL.Query()
+	if streamerID := params.Get("streamer_id"); streamerID != "" {
+		return livekit.ParticipantIdentity(streamerID), true
+	}
+	channel := params.Get("channel")
+	if channel == "" {
+		http.Error(w, "channel or streamer_id required", http.StatusBadRequest)
+		return "", false
+	}
+	if s.users == nil {
+		http.Error(w, "user repository not configured", http.StatusServiceUnavailable)
+		return "", false
+	}
+	user, err := s.users.GetByChannelSlug(r.Context(), channel)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "channel not found", http.StatusNotFound)
+		return "", false
+	} else if err != nil {
+		s.logger.Errorw("could not look up channel", err, "channel", channel)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return "", false
+	}
+	return livekit.ParticipantIdentity(user.ID), true
+}
+
+// displayName is the name of a user as shown to others, their identity when the account is unknown
+func (s *StreamingAPIService) displayName(ctx context.Context, userID livekit.ParticipantIdentity) string {
+	if s.users != nil {
+		if user, err := s.users.GetByID(ctx, string(userID)); err == nil {
+			return newParticipantMetadata(user, nil, false).Name
+		}
+	}
+	return string(userID)
+}
+
+// publicScheduledStream hides the stream key of a scheduled stream shown to others than its streamer
+func publicScheduledStream(stream *streaming.ScheduledStream) *streaming.ScheduledStream {
+	public := *stream
+	public.StreamKey = ""
+	return &public
+}
+
+// httpURL is the address of this server as the client reached it
+func httpURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
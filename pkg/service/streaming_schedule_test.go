@@ -0,0 +1,90 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func TestStreamScheduler(t *testing.T) {
+	ctx := context.Background()
+	room := &livekit.Room{Name: "room"}
+
+	setup := func(t *testing.T) (*streamScheduler, *streamLifecycle, *streaming.NotificationService) {
+		notifications := streaming.NewNotificationService(nil)
+		require.NoError(t, notifications.Subscribe(ctx, "follower", "streamer", "Streamer", nil))
+		scheduler := newStreamScheduler(streaming.NewScheduleManager(streaming.ScheduleSettings{}), notifications)
+		t.Cleanup(scheduler.close)
+
+		lifecycle := newStreamLifecycle(streamLifecycleParams{
+			ReconnectGrace: 10 * time.Millisecond,
+			GoLiveCooldown: time.Hour,
+		}, notifications, streaming.NewAnalyticsService(nil))
+		lifecycle.onLive = scheduler.onLive
+		lifecycle.onEnded = scheduler.onEnded
+		return scheduler, lifecycle, notifications
+	}
+
+	t.Run("reminds followers ahead of the start", func(t *testing.T) {
+		scheduler, _, notifications := setup(t)
+		stream, err := scheduler.schedules.Create(ctx, &streaming.ScheduledStream{
+			StreamerID: "streamer",
+			Title:      "speedrun",
+			StartTime:  time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		scheduler.check(time.Now())
+		unread, _ := notifications.GetUnreadCount(ctx, "follower")
+		require.Zero(t, unread)
+
+		scheduler.check(stream.StartTime.Add(-5 * time.Minute))
+		scheduler.check(stream.StartTime.Add(-time.Minute))
+		reminders, _ := notifications.GetNotifications(ctx, "follower", false, 10)
+		require.Len(t, reminders, 1)
+		require.Equal(t, streaming.NotificationTypeStreamReminder, reminders[0].Type)
+	})
+
+	t.Run("goes live with the streamer", func(t *testing.T) {
+		scheduler, lifecycle, _ := setup(t)
+		stream, err := scheduler.schedules.Create(ctx, &streaming.ScheduledStream{
+			StreamerID: "streamer",
+			Title:      "speedrun",
+			StartTime:  time.Now().Add(10 * time.Minute),
+		})
+		require.NoError(t, err)
+
+		streamer := newFakeStreamer("streamer")
+		lifecycle.OnTrackPublished(room, streamer, newFakeTrack(livekit.TrackType_VIDEO))
+		live, err := scheduler.schedules.Get(ctx, stream.ID)
+		require.NoError(t, err)
+		require.Equal(t, streaming.ScheduledStreamLive, live.Status)
+		require.Equal(t, livekit.RoomName("room"), live.RoomName)
+
+		lifecycle.OnRoomClosed(room)
+		require.Eventually(t, func() bool {
+			ended, _ := scheduler.schedules.Get(ctx, stream.ID)
+			return ended.Status == streaming.ScheduledStreamEnded
+		}, time.Second, 10*time.Millisecond)
+	})
+}
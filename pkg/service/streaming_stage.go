@@ -0,0 +1,435 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	appauth "github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+// attributeStage marks the guests a streamer brought on stage, it is removed when they leave the stage
+const (
+	attributeStage = "stage"
+	stageRoleGuest = "guest"
+)
+
+// events of the chat notices about the stage, in the metadata of the notices
+const (
+	stageEventJoin  = "stage_joined"
+	stageEventLeave = "stage_left"
+	stageEventHand  = "hand_raised"
+)
+
+// stageTracker forgets participants leaving a room from its stage, and the stage of closed rooms
+type stageTracker struct {
+	stages *streaming.StageManager
+	// called when a participant leaving changed the stage of a room
+	onChange func(roomName livekit.RoomName)
+}
+
+func (t *stageTracker) OnRoomStarted(_ *livekit.Room) {}
+
+func (t *stageTracker) OnParticipantJoined(_ *livekit.Room, _ types.LocalParticipant) {}
+
+func (t *stageTracker) OnTrackPublished(_ *livekit.Room, _ types.LocalParticipant, _ types.MediaTrack) {
+}
+
+func (t *stageTracker) OnParticipantLeft(room *livekit.Room, participant types.LocalParticipant) {
+	roomName := livekit.RoomName(room.Name)
+	if t.stages.Leave(roomName, participant.Identity()) && t.onChange != nil {
+		// looking up the owner may hit the database, keep it off the room
+		go t.onChange(roomName)
+	}
+}
+
+func (t *stageTracker) OnRoomClosed(room *livekit.Room) {
+	t.stages.Close(livekit.RoomName(room.Name))
+}
+
+// registerStageHandlers registers the endpoints bringing viewers on stage: the owner of a room invites them,
+// possibly from the queue of raised hands, and they go on stage when they accept
+func (s *StreamingAPIService) registerStageHandlers(mux *http.ServeMux) {
+	mux.Handle("/api/streaming/stage", s.authorized(s.handleGetStage))
+	mux.Handle("/api/streaming/stage/invite", s.authorized(s.handleStageInvite))
+	mux.Handle("/api/streaming/stage/accept", s.authorized(s.handleStageAccept))
+	mux.Handle("/api/streaming/stage/decline", s.authorized(s.handleStageDecline))
+	mux.Handle("/api/streaming/stage/remove", s.authorized(s.handleStageRemove))
+	mux.Handle("/api/streaming/stage/hand", s.authorized(s.handleStageHand))
+}
+
+// setOnStage grants a participant the permission to publish and marks them as a guest, or revokes both, which
+// unpublishes their tracks. Their other permissions, like sending chat data, are kept.
+func (s *StreamingAPIService) setOnStage(ctx context.Context, roomName livekit.RoomName, userID livekit.ParticipantIdentity, onStage bool) error {
+	if s.roomService == nil {
+		return errRoomServiceNotConfigured
+	}
+	// the room service checks the grants of API keys, the stage acts on behalf of the server
+	ctx = WithGrants(ctx, &auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true, Room: string(roomName)}}, "")
+	participant, err := s.roomService.GetParticipant(ctx, &livekit.RoomParticipantIdentity{
+		Room:     string(roomName),
+		Identity: string(userID),
+	})
+	if err != nil {
+		return err
+	}
+
+	permission := &livekit.ParticipantPermission{CanSubscribe: true}
+	if participant.Permission != nil {
+		permission = utils.CloneProto(participant.Permission)
+	}
+	permission.CanPublish = onStage
+	stage := ""
+	if onStage {
+		stage = stageRoleGuest
+	}
+	_, err = s.roomService.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room:       string(roomName),
+		Identity:   string(userID),
+		Permission: permission,
+		// an empty value removes the attribute
+		Attributes: map[string]string{attributeStage: stage},
+	})
+	return err
+}
+
+// publishStage pushes the stage of a room to its chat sockets, and with the pending invites to the
+// notification sockets of its owner
+func (s *StreamingAPIService) publishStage(roomName livekit.RoomName) {
+	stage := s.stages.Get(roomName, time.Now())
+	if owner, ok, err := s.roomOwner(context.Background(), roomName); err != nil {
+		s.logger.Warnw("could not look up room owner", err, "room", roomName)
+	} else if ok {
+		s.notificationHub.publishStage(owner, stage)
+	}
+	s.chatHub.broadcast(roomName, &chatEvent{Type: "stage", Stage: publicStage(stage)})
+}
+
+// stageNotice tells the chat of a room about a change to its stage, rooms without a chat are skipped
+func (s *StreamingAPIService) stageNotice(roomName livekit.RoomName, userID livekit.ParticipantIdentity, event, content string) {
+	_, err := s.chatService.SendSystemNotice(context.Background(), roomName, content, map[string]string{
+		"event":   event,
+		"user_id": string(userID),
+	})
+	if err != nil {
+		s.logger.Debugw("could not post stage notice", "room", roomName, "error", err)
+	}
+}
+
+// isRoomOwner checks that a user owns a room, writing the response when they do not
+func (s *StreamingAPIService) isRoomOwner(w http.ResponseWriter, r *http.Request, roomName livekit.RoomName, userID livekit.ParticipantIdentity) bool {
+	owner, ok, err := s.roomOwner(r.Context(), roomName)
+	if err != nil {
+		s.logger.Errorw("could not look up room owner", err, "room", roomName)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return false
+	}
+	if !ok || owner != userID {
+		http.Error(w, "only the owner of the room can do this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// publicStage hides the pending invites of a stage shown to others than the owner of the room
+func publicStage(stage *streaming.Stage) *streaming.Stage {
+	public := *stage
+	public.Invites = nil
+	return &public
+}
+
+// handleGetStage returns the stage of a room. Its owner sees every pending invite, others only their own.
+func (s *StreamingAPIService) handleGetStage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := currentUser(r)
+	roomName := livekit.RoomName(r.URL.Query().Get("room_name"))
+	if roomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+
+	stage := s.stages.Get(roomName, time.Now())
+	owner, ok, err := s.roomOwner(r.Context(), roomName)
+	if err != nil {
+		s.logger.Errorw("could not look up room owner", err, "room", roomName)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if !ok || owner != userID {
+		invites := stage.Invites
+		stage = publicStage(stage)
+		for _, invite := range invites {
+			if invite.UserID == userID {
+				stage.Invites = append(stage.Invites, invite)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stage)
+}
+
+// handleStageInvite invites a viewer on stage, they are notified and have until the invite expires to accept
+func (s *StreamingAPIService) handleStageInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := currentUser(r)
+
+	var req struct {
+		RoomName string `json:"room_name"`
+		UserID   string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomName == "" || req.UserID == "" {
+		http.Error(w, "room_name and user_id required", http.StatusBadRequest)
+		return
+	}
+	roomName := livekit.RoomName(req.RoomName)
+	guestID := livekit.ParticipantIdentity(req.UserID)
+	if guestID == userID {
+		http.Error(w, "the owner of the room is on stage already", http.StatusBadRequest)
+		return
+	}
+	if !s.isRoomOwner(w, r, roomName, userID) {
+		return
+	}
+
+	invite, err := s.stages.Invite(roomName, guestID, s.displayName(r.Context(), guestID), userID, time.Now())
+	if errors.Is(err, streaming.ErrAlreadyOnStage) || errors.Is(err, streaming.ErrStageFull) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = s.notificationService.NotifyStageInvite(r.Context(), invite, s.displayName(r.Context(), userID)); err != nil {
+		s.logger.Warnw("could not notify stage invite", err, "room", roomName, "userID", guestID)
+	}
+	s.publishStage(roomName)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+// handleStageAccept puts the current user on stage, granting them the permission to publish in the room they are in
+func (s *StreamingAPIService) handleStageAccept(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := currentUser(r)
+
+	var req struct {
+		RoomName string `json:"room_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+	roomName := livekit.RoomName(req.RoomName)
+	if !s.authorizeAction(w, r, string(userID), appauth.ActionGoLive) {
+		return
+	}
+
+	guest, err := s.stages.Accept(roomName, userID, time.Now())
+	switch {
+	case err == nil:
+	case errors.Is(err, streaming.ErrStageInviteNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if err = s.setOnStage(r.Context(), roomName, userID, true); err != nil {
+		_ = s.stages.Remove(roomName, userID)
+		s.publishStage(roomName)
+		switch {
+		case errors.Is(err, ErrParticipantNotFound):
+			http.Error(w, "join the room before going on stage", http.StatusConflict)
+		case errors.Is(err, errRoomServiceNotConfigured):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		default:
+			s.logger.Errorw("could not bring guest on stage", err, "room", roomName, "userID", userID)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.logger.Infow("guest joined the stage", "room", roomName, "userID", userID)
+	s.stageNotice(roomName, userID, stageEventJoin, fmt.Sprintf("%s joined the stage", guest.UserName))
+	s.publishStage(roomName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guest)
+}
+
+// handleStageDecline turns down the pending invite of the current user
+func (s *StreamingAPIService) handleStageDecline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := currentUser(r)
+
+	var req struct {
+		RoomName string `json:"room_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+	roomName := livekit.RoomName(req.RoomName)
+
+	if err := s.stages.Decline(roomName, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.publishStage(roomName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// handleStageRemove takes a guest off stage, revoking their permission to publish. The owner of the room may
+// remove anyone, guests only themselves, which is the default when no user_id is given.
+func (s *StreamingAPIService) handleStageRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := currentUser(r)
+
+	var req struct {
+		RoomName string `json:"room_name"`
+		UserID   string `json:"user_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+	roomName := livekit.RoomName(req.RoomName)
+	guestID := userID
+	if req.UserID != "" {
+		guestID = livekit.ParticipantIdentity(req.UserID)
+	}
+	if guestID != userID && !s.isRoomOwner(w, r, roomName, userID) {
+		return
+	}
+	if !s.stages.IsGuest(roomName, guestID) {
+		http.Error(w, streaming.ErrNotOnStage.Error(), http.StatusNotFound)
+		return
+	}
+
+	// guests stay on stage until their permissions are revoked, so that removing them can be retried
+	err := s.setOnStage(r.Context(), roomName, guestID, false)
+	switch {
+	case err == nil, errors.Is(err, ErrParticipantNotFound):
+	case errors.Is(err, errRoomServiceNotConfigured):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+		s.logger.Errorw("could not take guest off stage", err, "room", roomName, "userID", guestID)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if err = s.stages.Remove(roomName, guestID); err != nil {
+		// left the room meanwhile
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.logger.Infow("guest left the stage", "room", roomName, "userID", guestID, "removedBy", userID)
+	s.stageNotice(roomName, guestID, stageEventLeave, fmt.Sprintf("%s left the stage", s.displayName(r.Context(), guestID)))
+	s.publishStage(roomName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// handleStageHand raises the hand of the current user with POST, queueing them for an invite, and lowers it with
+// DELETE ?room_name=
+func (s *StreamingAPIService) handleStageHand(w http.ResponseWriter, r *http.Request) {
+	userID, _ := currentUser(r)
+
+	switch r.Method {
+	case http.MethodPost:
+		var req struct {
+			RoomName string `json:"room_name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomName == "" {
+			http.Error(w, "room_name required", http.StatusBadRequest)
+			return
+		}
+		roomName := livekit.RoomName(req.RoomName)
+		if until, banned := s.chatService.BannedUntil(roomName, userID); banned {
+			http.Error(w, fmt.Sprintf("banned from this room until %s", until.UTC().Format(time.RFC3339)), http.StatusForbidden)
+			return
+		}
+		if !s.authorizeAction(w, r, string(userID), appauth.ActionGoLive) {
+			return
+		}
+
+		name := s.displayName(r.Context(), userID)
+		position, raised, err := s.stages.RaiseHand(roomName, userID, name, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if raised {
+			s.stageNotice(roomName, userID, stageEventHand, fmt.Sprintf("%s raised their hand", name))
+			s.publishStage(roomName)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"position": position})
+
+	case http.MethodDelete:
+		roomName := livekit.RoomName(r.URL.Query().Get("room_name"))
+		if roomName == "" {
+			http.Error(w, "room_name required", http.StatusBadRequest)
+			return
+		}
+		if !s.stages.LowerHand(roomName, userID) {
+			http.Error(w, "hand not raised", http.StatusNotFound)
+			return
+		}
+		s.publishStage(roomName)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v4"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/mime"
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+const (
+	// thumbnailSubscriberID identifies thumbnail sinks among the down tracks of a receiver
+	thumbnailSubscriberID = livekit.ParticipantID("PA_thumbnails")
+	// key frames collected for thumbnails are dropped past this size, they may be anything the publisher sends
+	maxThumbnailFrameSize = 2 << 20
+)
+
+var errThumbnailsDisabled = errors.New("thumbnails not enabled")
+
+// thumbnailCapturer periodically grabs a key frame of the video of each live stream on this node and stores it
+// as a JPEG. Only VP8 video is captured, streams in other codecs have no thumbnail.
+type thumbnailCapturer struct {
+	settings   streaming.ThumbnailSettings
+	streamerOf func(livekit.RoomName) (livekit.ParticipantIdentity, bool)
+	logger     logger.Logger
+
+	mu    sync.Mutex
+	store streaming.ThumbnailStore
+	sinks map[livekit.RoomName]*thumbnailSink
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newThumbnailCapturer(
+	settings streaming.ThumbnailSettings,
+	streamerOf func(livekit.RoomName) (livekit.ParticipantIdentity, bool),
+) *thumbnailCapturer {
+	if settings.Interval <= 0 {
+		settings.Interval = streaming.DefaultThumbnailSettings.Interval
+	}
+	if settings.TTL <= 0 {
+		settings.TTL = streaming.DefaultThumbnailSettings.TTL
+	}
+	if settings.Width <= 0 {
+		settings.Width = streaming.DefaultThumbnailSettings.Width
+	}
+	if settings.Quality <= 0 {
+		settings.Quality = streaming.DefaultThumbnailSettings.Quality
+	}
+	c := &thumbnailCapturer{
+		settings:   settings,
+		streamerOf: streamerOf,
+		logger:     logger.GetLogger(),
+		sinks:      make(map[livekit.RoomName]*thumbnailSink),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if settings.Enabled {
+		c.store = streaming.NewMemoryThumbnailStore()
+		go c.run()
+	} else {
+		close(c.done)
+	}
+	return c
+}
+
+func (c *thumbnailCapturer) setStore(store streaming.ThumbnailStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.settings.Enabled {
+		c.store = store
+	}
+}
+
+func (c *thumbnailCapturer) getStore() streaming.ThumbnailStore {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store
+}
+
+func (c *thumbnailCapturer) close() {
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+	<-c.done
+}
+
+func (c *thumbnailCapturer) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.settings.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.captureAll()
+		}
+	}
+}
+
+// captureAll asks the video of every stream for a key frame, which the sinks pick up as it arrives
+func (c *thumbnailCapturer) captureAll() {
+	c.mu.Lock()
+	sinks := make([]*thumbnailSink, 0, len(c.sinks))
+	for _, sink := range c.sinks {
+		sinks = append(sinks, sink)
+	}
+	c.mu.Unlock()
+
+	for _, sink := range sinks {
+		sink.capture()
+	}
+}
+
+func (c *thumbnailCapturer) OnRoomStarted(_ *livekit.Room) {}
+
+func (c *thumbnailCapturer) OnParticipantJoined(_ *livekit.Room, _ types.LocalParticipant) {}
+
+func (c *thumbnailCapturer) OnTrackPublished(room *livekit.Room, participant types.LocalParticipant, track types.MediaTrack) {
+	if !c.settings.Enabled || track.Kind() != livekit.TrackType_VIDEO {
+		return
+	}
+	roomName := livekit.RoomName(room.Name)
+	if streamerID, ok := c.streamerOf(roomName); !ok || streamerID != participant.Identity() {
+		return
+	}
+
+	for _, receiver := range track.Receivers() {
+		if receiver.Mime() != mime.MimeTypeVP8 {
+			continue
+		}
+		sink := newThumbnailSink(roomName, participant.Identity(), receiver, c.onFrame)
+		if err := receiver.AddDownTrack(sink); err != nil {
+			c.logger.Warnw("could not capture thumbnails", err, "room", roomName)
+			return
+		}
+
+		c.mu.Lock()
+		previous := c.sinks[roomName]
+		c.sinks[roomName] = sink
+		c.mu.Unlock()
+		if previous != nil {
+			if previous.receiver != receiver {
+				previous.detach()
+			} else {
+				// replaced among the down tracks of the receiver already
+				previous.Close()
+			}
+		}
+		// the first thumbnail does not wait for the interval
+		sink.capture()
+		return
+	}
+}
+
+func (c *thumbnailCapturer) OnParticipantLeft(room *livekit.Room, participant types.LocalParticipant) {
+	c.remove(livekit.RoomName(room.Name), participant.Identity())
+}
+
+func (c *thumbnailCapturer) OnRoomClosed(room *livekit.Room) {
+	roomName := livekit.RoomName(room.Name)
+	c.remove(roomName, "")
+	if store := c.getStore(); store != nil {
+		if err := store.Delete(context.Background(), roomName); err != nil {
+			c.logger.Warnw("could not delete thumbnail", err, "room", roomName)
+		}
+	}
+}
+
+// remove stops capturing a room, when streamerID is set only if that streamer is the one captured
+func (c *thumbnailCapturer) remove(roomName livekit.RoomName, streamerID livekit.ParticipantIdentity) {
+	c.mu.Lock()
+	sink, ok := c.sinks[roomName]
+	if ok && (streamerID == "" || sink.streamerID == streamerID) {
+		delete(c.sinks, roomName)
+	} else {
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		sink.detach()
+	}
+}
+
+func (c *thumbnailCapturer) onFrame(roomName livekit.RoomName, frame []byte) {
+	// the frame comes straight from the publisher, a decoder bug must not take the server down
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Errorw("panic encoding thumbnail", nil, "room", roomName, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	store := c.getStore()
+	if store == nil {
+		return
+	}
+	encoded, err := streaming.EncodeVP8Thumbnail(frame, c.settings.Width, c.settings.Quality)
+	if err != nil {
+		c.logger.Debugw("could not encode thumbnail", "error", err, "room", roomName)
+		return
+	}
+	thumbnail := &streaming.Thumbnail{Image: encoded, CapturedAt: time.Now()}
+	if err = store.Put(context.Background(), roomName, thumbnail, c.settings.TTL); err != nil {
+		c.logger.Warnw("could not store thumbnail", err, "room", roomName)
+	}
+}
+
+// thumbnailURL returns where the thumbnail of a stream is served, versioned by its capture time
+func (c *thumbnailCapturer) thumbnailURL(ctx context.Context, roomName livekit.RoomName) string {
+	store := c.getStore()
+	if store == nil {
+		return ""
+	}
+	capturedAt, err := store.CapturedAt(ctx, roomName)
+	if err != nil {
+		return ""
+	}
+	query := url.Values{"room_name": {string(roomName)}, "t": {strconv.FormatInt(capturedAt.UnixMilli(), 10)}}
+	return "/api/streaming/thumbnail?" + query.Encode()
+}
+
+// thumbnailSink sits among the down tracks of a receiver and, when asked to capture, collects the packets of the
+// next VP8 key frame on the lowest spatial layer being forwarded
+type thumbnailSink struct {
+	roomName   livekit.RoomName
+	streamerID livekit.ParticipantIdentity
+	receiver   sfu.TrackReceiver
+	onFrame    func(livekit.RoomName, []byte)
+
+	// layers forwarded since the last capture, a bit per spatial layer
+	layers    atomic.Uint32
+	capturing atomic.Bool
+	closed    atomic.Bool
+
+	mu        sync.Mutex
+	layer     int32
+	frame     []byte
+	timestamp uint32
+	sequence  uint16
+}
+
+func newThumbnailSink(
+	roomName livekit.RoomName,
+	streamerID livekit.ParticipantIdentity,
+	receiver sfu.TrackReceiver,
+	onFrame func(livekit.RoomName, []byte),
+) *thumbnailSink {
+	return &thumbnailSink{
+		roomName:   roomName,
+		streamerID: streamerID,
+		receiver:   receiver,
+		onFrame:    onFrame,
+		layer:      -1,
+	}
+}
+
+// capture grabs the next key frame, requesting one from the publisher
+func (s *thumbnailSink) capture() {
+	if s.closed.Load() {
+		return
+	}
+	layers := s.layers.Swap(0)
+	layer := int32(0)
+	if layers != 0 {
+		layer = int32(bits.TrailingZeros32(layers))
+	}
+
+	s.mu.Lock()
+	s.layer = layer
+	s.frame = nil
+	s.mu.Unlock()
+
+	s.capturing.Store(true)
+	s.receiver.SendPLI(layer, false)
+}
+
+func (s *thumbnailSink) detach() {
+	s.Close()
+	s.receiver.DeleteDownTrack(thumbnailSubscriberID)
+}
+
+func (s *thumbnailSink) WriteRTP(p *buffer.ExtPacket, layer int32) error {
+	if layer >= 0 && layer < 32 {
+		s.layers.Or(1 << layer)
+	}
+	if !s.capturing.Load() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if layer != s.layer || p.Packet == nil {
+		return nil
+	}
+	var vp8 codecs.VP8Packet
+	payload, err := vp8.Unmarshal(p.Packet.Payload)
+	if err != nil || len(payload) == 0 {
+		return nil
+	}
+
+	if s.frame == nil {
+		// waiting for the first packet of a key frame
+		if !p.KeyFrame || vp8.S != 1 || vp8.PID != 0 {
+			return nil
+		}
+		s.timestamp = p.Packet.Timestamp
+	} else if p.IsOutOfOrder || p.Packet.Timestamp != s.timestamp || p.Packet.SequenceNumber != s.sequence+1 {
+		// lost part of the frame, wait for the next key frame
+		s.frame = nil
+		return nil
+	}
+	if len(s.frame)+len(payload) > maxThumbnailFrameSize {
+		// wait for a smaller key frame
+		s.frame = nil
+		return nil
+	}
+	s.sequence = p.Packet.SequenceNumber
+	s.frame = append(s.frame, payload...)
+
+	if p.Packet.Marker {
+		frame := s.frame
+		s.frame = nil
+		s.capturing.Store(false)
+		// decoding is too slow for the forwarding path
+		go s.onFrame(s.roomName, frame)
+	}
+	return nil
+}
+
+func (s *thumbnailSink) UpTrackLayersChange() {}
+
+func (s *thumbnailSink) UpTrackBitrateAvailabilityChange() {}
+
+func (s *thumbnailSink) UpTrackMaxPublishedLayerChange(_ int32) {}
+
+func (s *thumbnailSink) UpTrackMaxTemporalLayerSeenChange(_ int32) {}
+
+func (s *thumbnailSink) UpTrackBitrateReport(_ []int32, _ sfu.Bitrates) {}
+
+func (s *thumbnailSink) Close() {
+	s.closed.Store(true)
+	s.capturing.Store(false)
+}
+
+func (s *thumbnailSink) IsClosed() bool {
+	return s.closed.Load()
+}
+
+func (s *thumbnailSink) ID() string {
+	return "thumbnails_" + string(s.receiver.TrackID())
+}
+
+func (s *thumbnailSink) SubscriberID() livekit.ParticipantID {
+	return thumbnailSubscriberID
+}
+
+func (s *thumbnailSink) HandleRTCPSenderReportData(_ webrtc.PayloadType, _ int32, _ *livekit.RTCPSenderReportState) error {
+	return nil
+}
+
+func (s *thumbnailSink) Resync() {}
+
+func (s *thumbnailSink) SetReceiver(_ sfu.TrackReceiver) {}
+
+// SetThumbnailStore sets where thumbnails are kept, e.g. Redis so every node serves the thumbnails of the cluster.
+// Thumbnails are kept in memory until it is set.
+func (s *StreamingAPIService) SetThumbnailStore(store streaming.ThumbnailStore) {
+	s.thumbnails.setStore(store)
+}
+
+// handleGetThumbnail serves the latest thumbnail of a live stream as a JPEG
+func (s *StreamingAPIService) handleGetThumbnail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	roomName := r.URL.Query().Get("room_name")
+	if roomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+	store := s.thumbnails.getStore()
+	if store == nil {
+		http.Error(w, errThumbnailsDisabled.Error(), http.StatusNotFound)
+		return
+	}
+
+	thumbnail, err := store.Get(r.Context(), livekit.RoomName(roomName))
+	if errors.Is(err, streaming.ErrThumbnailNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		s.logger.Errorw("could not load thumbnail", err, "room", roomName)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	// links from the directory carry the capture time, so a new capture is a new URL
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.thumbnails.settings.Interval.Seconds())))
+	w.Header().Set("Last-Modified", thumbnail.CapturedAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(thumbnail.Image)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(thumbnail.Image)
+}
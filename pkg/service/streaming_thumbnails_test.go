@@ -0,0 +1,222 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/mime"
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+type fakeVideoReceiver struct {
+	sfu.TrackReceiver
+
+	mu      sync.Mutex
+	sink    sfu.TrackSender
+	plis    []int32
+	deleted bool
+}
+
+func (r *fakeVideoReceiver) Mime() mime.MimeType {
+	return mime.MimeTypeVP8
+}
+
+func (r *fakeVideoReceiver) TrackID() livekit.TrackID {
+	return "TR_video"
+}
+
+func (r *fakeVideoReceiver) AddDownTrack(track sfu.TrackSender) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sink = track
+	return nil
+}
+
+func (r *fakeVideoReceiver) DeleteDownTrack(_ livekit.ParticipantID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = true
+}
+
+func (r *fakeVideoReceiver) SendPLI(layer int32, _ bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.plis = append(r.plis, layer)
+}
+
+// keyFramePackets packetizes the VP8 key frame from the streaming testdata
+func keyFramePackets(t *testing.T, firstSequence uint16) []*buffer.ExtPacket {
+	frame, err := os.ReadFile("../streaming/testdata/keyframe.vp8")
+	require.NoError(t, err)
+
+	payloads := (&codecs.VP8Payloader{}).Payload(1200, frame)
+	require.Greater(t, len(payloads), 1)
+	packets := make([]*buffer.ExtPacket, 0, len(payloads))
+	for i, payload := range payloads {
+		packets = append(packets, &buffer.ExtPacket{
+			Packet: &rtp.Packet{
+				Header: rtp.Header{
+					SequenceNumber: firstSequence + uint16(i),
+					Timestamp:      uint32(firstSequence) * 3000,
+					Marker:         i == len(payloads)-1,
+				},
+				Payload: payload,
+			},
+			KeyFrame: i == 0,
+		})
+	}
+	return packets
+}
+
+func TestThumbnailCapture(t *testing.T) {
+	room := &livekit.Room{Name: "room"}
+	settings := streaming.ThumbnailSettings{Enabled: true, Interval: time.Hour, Width: 64}
+
+	setup := func(t *testing.T) (*thumbnailCapturer, *fakeVideoReceiver) {
+		c := newThumbnailCapturer(settings, func(livekit.RoomName) (livekit.ParticipantIdentity, bool) {
+			return "streamer", true
+		})
+		t.Cleanup(c.close)
+
+		receiver := &fakeVideoReceiver{}
+		track := newFakeTrack(livekit.TrackType_VIDEO)
+		track.ReceiversReturns([]sfu.TrackReceiver{receiver})
+		c.OnTrackPublished(room, newFakeStreamer("streamer"), track)
+		require.NotNil(t, receiver.sink)
+		return c, receiver
+	}
+
+	t.Run("captures the next key frame", func(t *testing.T) {
+		c, receiver := setup(t)
+		require.Equal(t, []int32{0}, receiver.plis)
+
+		for _, p := range keyFramePackets(t, 100) {
+			require.NoError(t, receiver.sink.WriteRTP(p, 0))
+		}
+
+		var thumbnail *streaming.Thumbnail
+		require.Eventually(t, func() bool {
+			thumbnail, _ = c.getStore().Get(context.Background(), "room")
+			return thumbnail != nil
+		}, time.Second, 10*time.Millisecond)
+		img, err := jpeg.Decode(bytes.NewReader(thumbnail.Image))
+		require.NoError(t, err)
+		require.Equal(t, 64, img.Bounds().Dx())
+		require.Contains(t, c.thumbnailURL(context.Background(), "room"), "/api/streaming/thumbnail?room_name=room")
+	})
+
+	t.Run("drops incomplete frames", func(t *testing.T) {
+		c, receiver := setup(t)
+
+		packets := keyFramePackets(t, 100)
+		for _, p := range append(packets[:1:1], packets[2:]...) {
+			require.NoError(t, receiver.sink.WriteRTP(p, 0))
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		_, err := c.getStore().Get(context.Background(), "room")
+		require.ErrorIs(t, err, streaming.ErrThumbnailNotFound)
+	})
+
+	t.Run("drops oversized frames", func(t *testing.T) {
+		c, receiver := setup(t)
+
+		first := keyFramePackets(t, 100)[0]
+		require.NoError(t, receiver.sink.WriteRTP(first, 0))
+		// continuation packets, the VP8 descriptor without the start of partition bit
+		payload := append([]byte{0x00}, bytes.Repeat([]byte{0xaa}, 1200)...)
+		for i := 1; i <= maxThumbnailFrameSize/1200+1; i++ {
+			require.NoError(t, receiver.sink.WriteRTP(&buffer.ExtPacket{Packet: &rtp.Packet{
+				Header:  rtp.Header{SequenceNumber: 100 + uint16(i), Timestamp: first.Packet.Timestamp},
+				Payload: payload,
+			}}, 0))
+		}
+		sink := receiver.sink.(*thumbnailSink)
+		sink.mu.Lock()
+		require.Nil(t, sink.frame)
+		sink.mu.Unlock()
+
+		// still waiting for a key frame that fits
+		for _, p := range keyFramePackets(t, 5000) {
+			require.NoError(t, receiver.sink.WriteRTP(p, 0))
+		}
+		require.Eventually(t, func() bool {
+			_, err := c.getStore().Get(context.Background(), "room")
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("captures the lowest layer forwarded", func(t *testing.T) {
+		c, receiver := setup(t)
+
+		// layer 0 paused by dynacast
+		for _, p := range keyFramePackets(t, 100) {
+			require.NoError(t, receiver.sink.WriteRTP(p, 1))
+		}
+		_, err := c.getStore().Get(context.Background(), "room")
+		require.ErrorIs(t, err, streaming.ErrThumbnailNotFound)
+
+		c.captureAll()
+		require.Equal(t, []int32{0, 1}, receiver.plis)
+	})
+
+	t.Run("stops when the streamer leaves", func(t *testing.T) {
+		c, receiver := setup(t)
+
+		c.OnParticipantLeft(room, newFakeStreamer("viewer"))
+		require.False(t, receiver.deleted)
+
+		c.OnParticipantLeft(room, newFakeStreamer("streamer"))
+		require.True(t, receiver.deleted)
+		require.True(t, receiver.sink.IsClosed())
+	})
+
+	t.Run("serves thumbnails", func(t *testing.T) {
+		s := NewStreamingAPIService(&streaming.Config{Thumbnails: settings}, nil)
+		defer s.Stop()
+
+		get := func(roomName string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			s.handleGetThumbnail(w, httptest.NewRequest(http.MethodGet, "/api/streaming/thumbnail?room_name="+roomName, nil))
+			return w
+		}
+		require.Equal(t, http.StatusNotFound, get("room").Code)
+
+		thumbnail := &streaming.Thumbnail{Image: []byte("jpeg"), CapturedAt: time.Now()}
+		require.NoError(t, s.thumbnails.getStore().Put(context.Background(), "room", thumbnail, time.Minute))
+		w := get("room")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+		require.Equal(t, "jpeg", w.Body.String())
+		require.Equal(t, http.StatusBadRequest, get("").Code)
+	})
+}
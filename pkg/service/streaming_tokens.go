@@ -0,0 +1,371 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	appauth "github.com/livekit/livekit-server/pkg/auth"
+	"github.com/livekit/livekit-server/pkg/storage"
+)
+
+const (
+	// room tokens are valid this long
+	roomTokenTTL = 6 * time.Hour
+	// claims of rooms nobody owned last this long, long enough to start publishing
+	roomClaimTTL = 10 * time.Minute
+	// anonymous viewers get an identity with this prefix
+	guestIdentityPrefix = "guest-"
+)
+
+// participant attributes set from the account, participants cannot change them
+const (
+	attributeRole      = "role"
+	attributeAvatarURL = "avatar_url"
+	attributeChannel   = "channel"
+	attributeBadges    = "badges"
+
+	// set by streamers when asking to publish, listed in the live directory
+	attributeTitle     = "title"
+	attributeCategory  = "category"
+	attributeThumbnail = "thumbnail"
+)
+
+// participant roles in a room, the streamer is the owner of the room
+const (
+	participantRoleStreamer  = "streamer"
+	participantRoleAdmin     = "admin"
+	participantRoleModerator = "moderator"
+	participantRoleViewer    = "viewer"
+)
+
+var errRoomOwned = errors.New("room belongs to another streamer")
+
+// participantMetadata is the metadata of participants joining with an exchanged token
+type participantMetadata struct {
+	Name        string   `json:"name"`
+	AvatarURL   string   `json:"avatar_url,omitempty"`
+	ChannelSlug string   `json:"channel_slug,omitempty"`
+	Role        string   `json:"role"`
+	Badges      []string `json:"badges,omitempty"`
+}
+
+type roomTokenResponse struct {
+	Token      string `json:"token"`
+	URL        string `json:"url"`
+	Identity   string `json:"identity"`
+	RoomName   string `json:"room_name"`
+	CanPublish bool   `json:"can_publish"`
+}
+
+type roomClaim struct {
+	owner     livekit.ParticipantIdentity
+	expiresAt time.Time
+}
+
+// roomClaims remembers who asked to publish into rooms nobody owned, until they go live in them.
+// A user holds one claim at a time, claiming another room gives up the previous one.
+type roomClaims struct {
+	mu     sync.Mutex
+	claims map[livekit.RoomName]roomClaim
+}
+
+func newRoomClaims() *roomClaims {
+	return &roomClaims{claims: make(map[livekit.RoomName]roomClaim)}
+}
+
+// owner returns the claimant of a room, dropping expired claims
+func (c *roomClaims) owner(roomName livekit.RoomName) (livekit.ParticipantIdentity, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	claim, ok := c.claims[roomName]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(claim.expiresAt) {
+		delete(c.claims, roomName)
+		return "", false
+	}
+	return claim.owner, true
+}
+
+// claim gives a room to a user unless someone else claimed it first, returning the owner
+func (c *roomClaims) claim(roomName livekit.RoomName, userID livekit.ParticipantIdentity) livekit.ParticipantIdentity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if claim, ok := c.claims[roomName]; ok && now.Before(claim.expiresAt) && claim.owner != userID {
+		return claim.owner
+	}
+	for claimed, claim := range c.claims {
+		if claim.owner == userID || !now.Before(claim.expiresAt) {
+			delete(c.claims, claimed)
+		}
+	}
+	c.claims[roomName] = roomClaim{owner: userID, expiresAt: now.Add(roomClaimTTL)}
+	return userID
+}
+
+// roomOwner returns who may publish into a room: the user whose channel slug it is, the holder of an active
+// stream key for it, the streamer live in it, or whoever claimed it first
+func (s *StreamingAPIService) roomOwner(ctx context.Context, roomName livekit.RoomName) (livekit.ParticipantIdentity, bool, error) {
+	if s.users != nil {
+		user, err := s.users.GetByChannelSlug(ctx, string(roomName))
+		if err == nil {
+			return livekit.ParticipantIdentity(user.ID), true, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return "", false, err
+		}
+	}
+	if owner, ok := s.streamKeyManager.RoomOwner(roomName); ok {
+		return owner, true, nil
+	}
+	if owner, ok := s.lifecycle.streamerOf(roomName); ok {
+		return owner, true, nil
+	}
+	owner, ok := s.roomClaims.owner(roomName)
+	return owner, ok, nil
+}
+
+// checkRoomOwner returns errRoomOwned unless the user owns the room or nobody does, in which case
+// the user claims it when claim is set
+func (s *StreamingAPIService) checkRoomOwner(ctx context.Context, roomName livekit.RoomName, userID livekit.ParticipantIdentity, claim bool) error {
+	owner, ok, err := s.roomOwner(ctx, roomName)
+	if err != nil {
+		return err
+	}
+	if !ok && claim {
+		owner = s.roomClaims.claim(roomName, userID)
+	}
+	if ok || claim {
+		if owner != userID {
+			return errRoomOwned
+		}
+	}
+	return nil
+}
+
+// handleExchangeToken issues a LiveKit room token for the logged-in user. Identity, name, metadata and
+// attributes come from their account, and only the owner of a room may publish into it.
+// A stream key of the user stands in for the room it was created for. Publishers may give the title
+// and category of their stream, otherwise those of the stream they scheduled for around now are used.
+func (s *StreamingAPIService) handleExchangeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := currentUser(r)
+	if s.users == nil {
+		http.Error(w, "user repository not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		RoomName  string `json:"room_name"`
+		Publish   bool   `json:"publish"`
+		StreamKey string `json:"stream_key,omitempty"`
+		// shown in the live directory, for publishers only
+		Title    string `json:"title,omitempty"`
+		Category string `json:"category,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.StreamKey != "" {
+		streamKey, err := s.streamKeyManager.ValidateStreamKey(r.Context(), req.StreamKey)
+		if err != nil || streamKey.StreamerID != userID {
+			http.Error(w, "invalid stream key", http.StatusForbidden)
+			return
+		}
+		req.RoomName = string(streamKey.RoomName)
+		req.Publish = true
+	}
+	if req.RoomName == "" {
+		http.Error(w, "room_name required", http.StatusBadRequest)
+		return
+	}
+	roomName := livekit.RoomName(req.RoomName)
+
+	user, err := s.users.GetByID(r.Context(), string(userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, appauth.ErrUnknownAccount.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		s.logger.Errorw("could not load user", err, "userID", userID)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		http.Error(w, appauth.ErrAccountSuspended.Error(), http.StatusForbidden)
+		return
+	}
+	if until, banned := s.chatService.BannedUntil(roomName, userID); banned {
+		http.Error(w, fmt.Sprintf("banned from this room until %s", until.UTC().Format(time.RFC3339)), http.StatusForbidden)
+		return
+	}
+
+	canChat := true
+	if req.Publish {
+		if !s.authorizeAction(w, r, string(userID), appauth.ActionGoLive) {
+			return
+		}
+		if err = s.checkRoomOwner(r.Context(), roomName, userID, true); errors.Is(err, errRoomOwned) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		} else if err != nil {
+			s.logger.Errorw("could not look up room owner", err, "room", roomName)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if req.StreamKey != "" {
+			s.streamKeyManager.MarkKeyAsUsed(r.Context(), req.StreamKey)
+		}
+	} else if s.policy != nil {
+		canChat = s.policy.Authorize(r.Context(), string(userID), appauth.ActionChat) == nil
+	}
+
+	roles, err := s.users.Roles(r.Context(), user.ID)
+	if err != nil {
+		s.logger.Errorw("could not load roles", err, "userID", userID)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	metadata := newParticipantMetadata(user, roles, req.Publish)
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	attributes := metadata.attributes()
+	if req.Publish {
+		// a stream scheduled around now fills in what the streamer left out
+		if scheduled, ok := s.schedules.Upcoming(userID, roomName, time.Now()); ok {
+			if req.Title == "" {
+				req.Title = scheduled.Title
+			}
+			if req.Category == "" {
+				req.Category = scheduled.Category
+			}
+			if scheduled.Thumbnail != "" {
+				attributes[attributeThumbnail] = scheduled.Thumbnail
+			}
+		}
+		if req.Title != "" {
+			attributes[attributeTitle] = req.Title
+		}
+		if req.Category != "" {
+			attributes[attributeCategory] = req.Category
+		}
+	}
+
+	grant := &auth.VideoGrant{RoomJoin: true, Room: req.RoomName}
+	grant.SetCanSubscribe(true)
+	grant.SetCanPublish(req.Publish)
+	grant.SetCanPublishData(req.Publish || canChat)
+	// name, metadata and attributes come from the account
+	grant.SetCanUpdateOwnMetadata(false)
+	grant.RoomRecord = req.Publish
+
+	at := auth.NewAccessToken(s.apiKey, s.apiSecret)
+	at.AddGrant(grant).
+		SetIdentity(user.ID).
+		SetName(metadata.Name).
+		SetMetadata(string(encoded)).
+		SetAttributes(attributes).
+		SetValidFor(roomTokenTTL)
+	token, err := at.ToJWT()
+	if err != nil {
+		s.logger.Errorw("failed to generate token", err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roomTokenResponse{
+		Token:      token,
+		URL:        signalURL(r),
+		Identity:   user.ID,
+		RoomName:   req.RoomName,
+		CanPublish: req.Publish,
+	})
+}
+
+func newParticipantMetadata(user *storage.User, roles []string, streamer bool) *participantMetadata {
+	metadata := &participantMetadata{
+		Name:        user.DisplayName.String,
+		AvatarURL:   user.AvatarURL.String,
+		ChannelSlug: user.ChannelSlug.String,
+		Role:        participantRoleViewer,
+	}
+	if metadata.Name == "" {
+		metadata.Name, _, _ = strings.Cut(user.Email, "@")
+	}
+	switch {
+	case streamer:
+		metadata.Role = participantRoleStreamer
+	case slices.Contains(roles, storage.RoleAdmin):
+		metadata.Role = participantRoleAdmin
+	case slices.Contains(roles, storage.RoleModerator):
+		metadata.Role = participantRoleModerator
+	}
+	if user.EmailVerifiedAt.Valid {
+		metadata.Badges = append(metadata.Badges, "verified")
+	}
+	for _, role := range roles {
+		if role != storage.RoleViewer {
+			metadata.Badges = append(metadata.Badges, role)
+		}
+	}
+	return metadata
+}
+
+func (m *participantMetadata) attributes() map[string]string {
+	attributes := map[string]string{attributeRole: m.Role}
+	if m.AvatarURL != "" {
+		attributes[attributeAvatarURL] = m.AvatarURL
+	}
+	if m.ChannelSlug != "" {
+		attributes[attributeChannel] = m.ChannelSlug
+	}
+	if len(m.Badges) > 0 {
+		attributes[attributeBadges] = strings.Join(m.Badges, ",")
+	}
+	return attributes
+}
+
+// signalURL is the WebSocket URL of this server as the client reached it
+func signalURL(r *http.Request) string {
+	scheme := "ws"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "wss"
+	}
+	return scheme + "://" + r.Host
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"regexp"
 	"strconv"
 	"strings"
@@ -96,6 +97,43 @@ func GetClientIP(r *http.Request) string {
 	return ip
 }
 
+// GetTrustedClientIP only honours X-Forwarded-For when the request comes through one of the trusted proxies.
+// It walks the forwarded chain back from the closest hop and returns the first address that is not a trusted proxy.
+func GetTrustedClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	isTrusted := func(address string) bool {
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	client := remote
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && isTrusted(client); i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		client = hop
+	}
+	return client
+}
+
 func SetRoomConfiguration(createRequest *livekit.CreateRoomRequest, conf *livekit.RoomConfiguration) {
 	if conf == nil {
 		return
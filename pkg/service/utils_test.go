@@ -16,6 +16,8 @@ package service_test
 
 import (
 	"context"
+	"net/http/httptest"
+	"net/netip"
 	"testing"
 	"time"
 
@@ -75,3 +77,36 @@ func TestIsValidDomain(t *testing.T) {
 		require.Equal(t, service.IsValidDomain(key), result)
 	}
 }
+
+func TestGetTrustedClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.1/32")}
+	cases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expectedIP   string
+	}{
+		{"direct", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted proxy", "198.51.100.1:1234", []string{"203.0.113.7"}, "198.51.100.1"},
+		{"trusted proxy", "10.1.2.3:1234", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"chain of trusted proxies", "10.1.2.3:1234", []string{"203.0.113.7, 192.0.2.1", "10.9.9.9"}, "203.0.113.7"},
+		{"spoofed chain", "10.1.2.3:1234", []string{"1.1.1.1, 203.0.113.7"}, "203.0.113.7"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/rtc", nil)
+			r.RemoteAddr = c.remoteAddr
+			for _, header := range c.forwardedFor {
+				r.Header.Add("X-Forwarded-For", header)
+			}
+			require.Equal(t, c.expectedIP, service.GetTrustedClientIP(r, trusted))
+		})
+	}
+
+	t.Run("no trusted proxies", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/rtc", nil)
+		r.RemoteAddr = "10.1.2.3:1234"
+		r.Header.Add("X-Forwarded-For", "203.0.113.7")
+		require.Equal(t, "10.1.2.3", service.GetTrustedClientIP(r, nil))
+	})
+}
@@ -57,7 +57,8 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		config.DefaultAPIConfig,
 		wire.Bind(new(routing.MessageRouter), new(routing.Router)),
 		wire.Bind(new(livekit.RoomService), new(*RoomService)),
-		telemetry.NewAnalyticsService,
+		NewStreamingStatsTap,
+		wire.Bind(new(telemetry.AnalyticsService), new(*StreamingStatsTap)),
 		telemetry.NewTelemetryService,
 		getMessageBus,
 		NewIOInfoService,
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// purposes of account tokens
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposePasswordReset = "password_reset"
+)
+
+// CreateAccountToken stores the hash of a single use token sent to a user
+func (r *UserRepository) CreateAccountToken(ctx context.Context, userID, purpose string, tokenHash []byte, expiresAt time.Time) error {
+	const query = `
+	INSERT INTO account_tokens (token_hash, user_id, purpose, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.ExecContext(ctx, query, tokenHash, userID, purpose, time.Now().UTC(), expiresAt.UTC())
+	return err
+}
+
+// ConsumeAccountToken removes the token with the given hash and purpose, returning the user it was issued to.
+// sql.ErrNoRows is returned for unknown or already used tokens and ErrTokenExpired for expired ones.
+func (r *UserRepository) ConsumeAccountToken(ctx context.Context, purpose string, tokenHash []byte) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `SELECT user_id, expires_at FROM account_tokens WHERE token_hash = $1 AND purpose = $2`,
+		tokenHash, purpose).Scan(&userID, &expiresAt)
+	if err != nil {
+		return "", err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM account_tokens WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return "", err
+	}
+	// a concurrent request used it first
+	if err = expectOneRow(res); err != nil {
+		return "", err
+	}
+	if err = tx.Commit(); err != nil {
+		return "", err
+	}
+	if time.Now().After(expiresAt) {
+		return "", ErrTokenExpired
+	}
+	return userID, nil
+}
+
+// DeleteAccountTokens removes the tokens of a user for a purpose, e.g. when a new one replaces them
+func (r *UserRepository) DeleteAccountTokens(ctx context.Context, userID, purpose string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM account_tokens WHERE user_id = $1 AND purpose = $2`, userID, purpose)
+	return err
+}
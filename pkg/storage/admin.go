@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxUserListLimit = 100
+
+// UserFilter selects the users listed by ListUsers
+type UserFilter struct {
+	// matched against email, display name and channel slug, ignoring case
+	Query         string
+	Role          string
+	SuspendedOnly bool
+	Limit         int
+	Offset        int
+}
+
+// ListUsers returns the users matching a filter, newest first
+func (r *UserRepository) ListUsers(ctx context.Context, filter UserFilter) ([]*User, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := arg("%" + strings.ToLower(q) + "%")
+		where = append(where, fmt.Sprintf(
+			"(LOWER(email) LIKE %[1]s OR LOWER(display_name) LIKE %[1]s OR LOWER(channel_slug) LIKE %[1]s)", pattern))
+	}
+	if filter.Role != "" && filter.Role != RoleViewer {
+		where = append(where, "id IN (SELECT user_id FROM user_roles WHERE role = "+arg(filter.Role)+")")
+	}
+	if filter.SuspendedOnly {
+		where = append(where, "suspended_at IS NOT NULL")
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > maxUserListLimit {
+		limit = maxUserListLimit
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, id LIMIT ` + arg(limit) + ` OFFSET ` + arg(max(filter.Offset, 0))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		u := &User{}
+		var id sql.NullString
+		if err = scanUser(rows, &id, u); err != nil {
+			return nil, err
+		}
+		u.ID = id.String
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Suspend marks a user suspended, sql.ErrNoRows is returned when there is none
+func (r *UserRepository) Suspend(ctx context.Context, id, reason string) error {
+	const query = `UPDATE users SET suspended_at = $1, suspension_reason = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), sql.NullString{String: reason, Valid: reason != ""}, id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// Reinstate lifts the suspension of a user, sql.ErrNoRows is returned when there is none
+func (r *UserRepository) Reinstate(ctx context.Context, id string) error {
+	const query = `UPDATE users SET suspended_at = NULL, suspension_reason = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
@@ -91,6 +91,26 @@ func openOrInitSQLite(conn string) (*sql.DB, error) {
 		db.Close()
 		return nil, err
 	}
+	if err = ensureSessionSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = ensureAccountTokenSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = ensureIdentitySchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = ensureMFASchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = ensureRBACSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
@@ -108,5 +128,161 @@ func ensureUserSchema(db *sql.DB) error {
 	}
 	// Ensure id is generated if not provided (for sqlite we can use randomblob)
 	// Inserts from repo pass id via RETURNING in Postgres; for sqlite we let app layer treat id as TEXT and set via db
+	return migrateUserProfile(db)
+}
+
+// profileColumns are the user columns added after the initial schema, see sql/schema/0002_user_profile.up.sql
+// sql/schema/0004_email_verification.up.sql and sql/schema/0007_rbac.up.sql
+var profileColumns = []struct{ name, definition string }{
+	{"avatar_url", "TEXT"},
+	{"bio", "TEXT"},
+	{"channel_slug", "TEXT"},
+	{"pending_email", "TEXT"},
+	{"email_change_token_hash", "BLOB"},
+	{"email_change_expires_at", "TIMESTAMP"},
+	{"email_verified_at", "TIMESTAMP"},
+	{"suspended_at", "TIMESTAMP"},
+	{"suspension_reason", "TEXT"},
+}
+
+// migrateUserProfile adds the profile columns to sqlite databases created before they existed
+func migrateUserProfile(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('users')`)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range profileColumns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err = db.Exec(fmt.Sprintf(`ALTER TABLE users ADD COLUMN %s %s`, column.name, column.definition)); err != nil {
+			return err
+		}
+		if column.name == "email_verified_at" {
+			// accounts from before verification existed are not asked to verify
+			if _, err = db.Exec(`UPDATE users SET email_verified_at = created_at`); err != nil {
+				return err
+			}
+		}
+	}
+	// sqlite cannot add unique columns, the index enforces it instead
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS users_channel_slug_key ON users (channel_slug)`)
+	return err
+}
+
+func ensureSessionSchema(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  refresh_token_hash BLOB NOT NULL,
+  previous_token_hash BLOB,
+  user_agent TEXT,
+  ip_address TEXT,
+  created_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP
+)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id)`)
+	return err
+}
+
+func ensureAccountTokenSchema(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS account_tokens (
+  token_hash BLOB PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS account_tokens_user_id_idx ON account_tokens (user_id, purpose)`)
+	return err
+}
+
+func ensureIdentitySchema(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS user_identities (
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email TEXT,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (provider, subject)
+)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id)`)
+	return err
+}
+
+func ensureMFASchema(db *sql.DB) error {
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS user_roles (
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (user_id, role)
+)`,
+		`CREATE TABLE IF NOT EXISTS user_mfa (
+  user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  totp_secret TEXT NOT NULL,
+  enabled_at TIMESTAMP,
+  last_counter INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL
+)`,
+		`CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  code_hash BLOB PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE
+)`,
+		`CREATE INDEX IF NOT EXISTS mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureRBACSchema creates the permissions of the roles, seeding the defaults of roles that have none
+func ensureRBACSchema(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS role_permissions (
+  role TEXT NOT NULL,
+  permission TEXT NOT NULL,
+  PRIMARY KEY (role, permission)
+)`)
+	if err != nil {
+		return err
+	}
+	var seeded bool
+	if err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM role_permissions)`).Scan(&seeded); err != nil || seeded {
+		return err
+	}
+	for _, role := range AllRoles {
+		for _, permission := range DefaultRolePermissions[role] {
+			if _, err = db.Exec(`INSERT INTO role_permissions (role, permission) VALUES ($1, $2)`, role, permission); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
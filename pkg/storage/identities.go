@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Identity links an account of an external identity provider to a user
+type Identity struct {
+	Provider string
+	// the subject claim of the provider, stable across email changes
+	Subject   string
+	UserID    string
+	Email     string
+	CreatedAt time.Time
+}
+
+// GetByIdentity returns the user a provider account is linked to
+func (r *UserRepository) GetByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	return r.getUser(ctx, `SELECT `+userColumns+` FROM users
+	WHERE id = (SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2)`, provider, subject)
+}
+
+// LinkIdentity links a provider account to a user, ErrIdentityLinked is returned when it already is
+func (r *UserRepository) LinkIdentity(ctx context.Context, identity *Identity) error {
+	identity.CreatedAt = time.Now().UTC()
+	const query = `
+	INSERT INTO user_identities (provider, subject, user_id, email, created_at)
+	VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.ExecContext(ctx, query, identity.Provider, identity.Subject, identity.UserID,
+		sql.NullString{String: identity.Email, Valid: identity.Email != ""}, identity.CreatedAt)
+	if err != nil && isUniqueViolation(err) {
+		return ErrIdentityLinked
+	}
+	return err
+}
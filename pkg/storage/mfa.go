@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// MFA is the second factor of a user
+type MFA struct {
+	UserID     string
+	TOTPSecret string
+	// unset while the enrollment waits for a first code
+	EnabledAt sql.NullTime
+	// the time step of the last accepted code, codes of it and earlier steps are not accepted again
+	LastCounter int64
+}
+
+// GetMFA returns the second factor of a user, sql.ErrNoRows if they never enrolled
+func (r *UserRepository) GetMFA(ctx context.Context, userID string) (*MFA, error) {
+	m := &MFA{UserID: userID}
+	err := r.db.QueryRowContext(ctx, `SELECT totp_secret, enabled_at, last_counter FROM user_mfa WHERE user_id = $1`, userID).
+		Scan(&m.TOTPSecret, &m.EnabledAt, &m.LastCounter)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetPendingTOTP stores the secret of a new enrollment, replacing an unconfirmed one.
+// sql.ErrNoRows is returned when the user already has an enabled second factor.
+func (r *UserRepository) SetPendingTOTP(ctx context.Context, userID, secret string) error {
+	const query = `
+	INSERT INTO user_mfa (user_id, totp_secret, last_counter, created_at) VALUES ($1, $2, 0, $3)
+	ON CONFLICT (user_id) DO UPDATE SET totp_secret = excluded.totp_secret, last_counter = 0, created_at = excluded.created_at
+	WHERE user_mfa.enabled_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, userID, secret, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// EnableMFA confirms a pending enrollment with the counter of its first code and stores its recovery codes
+func (r *UserRepository) EnableMFA(ctx context.Context, userID string, counter int64, recoveryCodeHashes [][]byte) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE user_mfa SET enabled_at = $1, last_counter = $2
+	WHERE user_id = $3 AND enabled_at IS NULL`, time.Now().UTC(), counter, userID)
+	if err != nil {
+		return err
+	}
+	if err = expectOneRow(res); err != nil {
+		return err
+	}
+	if err = replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPCounter records the time step of an accepted code. sql.ErrNoRows is returned
+// when a code of the same or a later step was accepted before, so each code works once.
+func (r *UserRepository) UseTOTPCounter(ctx context.Context, userID string, counter int64) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE user_mfa SET last_counter = $1
+	WHERE user_id = $2 AND enabled_at IS NOT NULL AND last_counter < $1`, counter, userID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// ReplaceRecoveryCodes invalidates the recovery codes of a user in favour of new ones
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes [][]byte) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, codeHashes [][]byte) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes (code_hash, user_id) VALUES ($1, $2)`, hash, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode removes a recovery code of a user, sql.ErrNoRows is returned for unknown or used codes
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash []byte) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1 AND code_hash = $2`, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (r *UserRepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// DeleteMFA removes the second factor and recovery codes of a user
+func (r *UserRepository) DeleteMFA(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"mfa_recovery_codes", "user_mfa"} {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
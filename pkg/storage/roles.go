@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// roles of users, every user is a viewer whether or not the role is granted
+const (
+	RoleViewer    = "viewer"
+	RoleStreamer  = "streamer"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// AllRoles lists the roles from least to most privileged
+var AllRoles = []string{RoleViewer, RoleStreamer, RoleModerator, RoleAdmin}
+
+// permissions granted to roles in role_permissions
+const (
+	PermissionChat       = "chat:send"
+	PermissionGoLive     = "stream:publish"
+	PermissionModerate   = "chat:moderate"
+	PermissionListUsers  = "users:read"
+	PermissionSuspend    = "users:suspend"
+	PermissionAssignRole = "roles:assign"
+	PermissionEndStreams = "streams:end"
+	PermissionRevokeKeys = "stream_keys:revoke"
+)
+
+// DefaultRolePermissions seeds role_permissions, operators may change the table afterwards,
+// e.g. removing stream:publish from viewers so only streamers go live
+var DefaultRolePermissions = map[string][]string{
+	RoleViewer:    {PermissionChat, PermissionGoLive},
+	RoleStreamer:  {PermissionChat, PermissionGoLive},
+	RoleModerator: {PermissionChat, PermissionModerate, PermissionListUsers, PermissionEndStreams},
+	RoleAdmin: {
+		PermissionChat, PermissionGoLive, PermissionModerate, PermissionListUsers, PermissionSuspend,
+		PermissionAssignRole, PermissionEndStreams, PermissionRevokeKeys,
+	},
+}
+
+// Roles returns the roles granted to a user
+func (r *UserRepository) Roles(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err = rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// GrantRole gives a user a role, granting a role the user has is a no-op
+func (r *UserRepository) GrantRole(ctx context.Context, userID, role string) error {
+	const query = `
+	INSERT INTO user_roles (user_id, role, created_at) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, role) DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, userID, role, time.Now().UTC())
+	return err
+}
+
+// RevokeRole takes a role from a user
+func (r *UserRepository) RevokeRole(ctx context.Context, userID, role string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`, userID, role)
+	return err
+}
+
+// HasPermission reports whether any role of a user, including the viewer role everyone has, grants a permission
+func (r *UserRepository) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	const query = `
+	SELECT EXISTS (
+		SELECT 1 FROM role_permissions
+		WHERE permission = $1 AND (role = $2 OR role IN (SELECT role FROM user_roles WHERE user_id = $3))
+	)`
+	var ok bool
+	err := r.db.QueryRowContext(ctx, query, permission, RoleViewer, userID).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login of a user on one device, kept alive by refreshing its token
+type Session struct {
+	ID     string
+	UserID string
+	// hash of the current refresh token, and of the one it replaced to detect reuse of rotated tokens
+	RefreshTokenHash  []byte
+	PreviousTokenHash []byte
+	UserAgent         string
+	IPAddress         string
+	CreatedAt         time.Time
+	LastUsedAt        time.Time
+	ExpiresAt         time.Time
+	RevokedAt         sql.NullTime
+}
+
+// Active reports whether the session is neither revoked nor expired at the given time
+func (s *Session) Active(now time.Time) bool {
+	return !s.RevokedAt.Valid && now.Before(s.ExpiresAt)
+}
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+const sessionColumns = `id, user_id, refresh_token_hash, previous_token_hash, user_agent, ip_address,
+	created_at, last_used_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner) (*Session, error) {
+	s := &Session{}
+	var userAgent, ipAddress sql.NullString
+	err := row.Scan(&s.ID, &s.UserID, &s.RefreshTokenHash, &s.PreviousTokenHash, &userAgent, &ipAddress,
+		&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.UserAgent = userAgent.String
+	s.IPAddress = ipAddress.String
+	return s, nil
+}
+
+// CreateSession stores a new session, generating its ID when empty
+func (r *SessionRepository) CreateSession(ctx context.Context, s *Session) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	s.CreatedAt = now
+	s.LastUsedAt = now
+
+	const query = `
+	INSERT INTO sessions (id, user_id, refresh_token_hash, user_agent, ip_address, created_at, last_used_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.ExecContext(ctx, query, s.ID, s.UserID, s.RefreshTokenHash, s.UserAgent, s.IPAddress,
+		s.CreatedAt, s.LastUsedAt, s.ExpiresAt.UTC())
+	return err
+}
+
+func (r *SessionRepository) GetSession(ctx context.Context, id string) (*Session, error) {
+	return scanSession(r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, id))
+}
+
+// ListSessions returns the sessions of a user that are still active, most recently used first
+func (r *SessionRepository) ListSessions(ctx context.Context, userID string) ([]*Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT `+sessionColumns+`
+	FROM sessions WHERE user_id = $1 AND revoked_at IS NULL
+	ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	sessions := make([]*Session, 0)
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		if s.Active(now) {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, rows.Err()
+}
+
+// RotateSession replaces the refresh token of a session if its current one has the given hash.
+// sql.ErrNoRows is returned when the session was revoked or rotated in the meantime.
+func (r *SessionRepository) RotateSession(ctx context.Context, id string, oldHash, newHash []byte, expiresAt time.Time) error {
+	const query = `
+	UPDATE sessions
+	SET previous_token_hash = refresh_token_hash, refresh_token_hash = $1, last_used_at = $2, expires_at = $3
+	WHERE id = $4 AND refresh_token_hash = $5 AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, newHash, time.Now().UTC(), expiresAt.UTC(), id, oldHash)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// RevokeSession ends a session of a user, sql.ErrNoRows is returned when there is no such active session
+func (r *SessionRepository) RevokeSession(ctx context.Context, userID, id string) error {
+	const query = `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id, userID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// RevokeUserSessions ends all sessions of a user but the one with ID except, returning how many were ended
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userID, except string) (int64, error) {
+	const query = `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND id <> $3 AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), userID, except)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteUserSessions removes all sessions of a user
+func (r *SessionRepository) DeleteUserSessions(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID)
+	return err
+}
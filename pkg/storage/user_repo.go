@@ -3,18 +3,49 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrEmailTaken       = errors.New("email already registered")
+	ErrChannelSlugTaken = errors.New("channel slug already taken")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrIdentityLinked   = errors.New("identity already linked to an account")
+)
+
 type User struct {
-	ID           string
-	Email        string
-	PasswordHash []byte
-	DisplayName  sql.NullString
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID              string
+	Email           string
+	PasswordHash    []byte
+	DisplayName     sql.NullString
+	AvatarURL       sql.NullString
+	Bio             sql.NullString
+	ChannelSlug     sql.NullString
+	// address waiting for confirmation of an email change
+	PendingEmail    sql.NullString
+	// unset until the user follows the verification link sent to their email
+	EmailVerifiedAt sql.NullTime
+	// set while an admin has suspended the account
+	SuspendedAt      sql.NullTime
+	SuspensionReason sql.NullString
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+const userColumns = `id, email, password_hash, display_name, avatar_url, bio, channel_slug, pending_email,
+	email_verified_at, suspended_at, suspension_reason, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner, id *sql.NullString, u *User) error {
+	return row.Scan(id, &u.Email, &u.PasswordHash, &u.DisplayName, &u.AvatarURL, &u.Bio, &u.ChannelSlug,
+		&u.PendingEmail, &u.EmailVerifiedAt, &u.SuspendedAt, &u.SuspensionReason, &u.CreatedAt, &u.UpdatedAt)
 }
 
 type UserRepository struct {
@@ -72,13 +103,10 @@ func (r *UserRepository) CreateUser(ctx context.Context, u *User) error {
 }
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
-	const query = `
-	SELECT id, email, password_hash, display_name, created_at, updated_at
-	FROM users WHERE email = $1`
+	const query = `SELECT ` + userColumns + ` FROM users WHERE email = $1`
 	u := User{}
 	var id sql.NullString
-	err := r.db.QueryRowContext(ctx, query, email).
-		Scan(&id, &u.Email, &u.PasswordHash, &u.DisplayName, &u.CreatedAt, &u.UpdatedAt)
+	err := scanUser(r.db.QueryRowContext(ctx, query, email), &id, &u)
 	if err != nil {
 		return nil, err
 	}
@@ -99,3 +127,164 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, e
 	
 	return &u, nil
 }
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error) {
+	return r.getUser(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+}
+
+// GetByChannelSlug returns the user owning a channel
+func (r *UserRepository) GetByChannelSlug(ctx context.Context, slug string) (*User, error) {
+	return r.getUser(ctx, `SELECT `+userColumns+` FROM users WHERE channel_slug = $1`, slug)
+}
+
+func (r *UserRepository) getUser(ctx context.Context, query string, args ...any) (*User, error) {
+	u := User{}
+	var id sql.NullString
+	if err := scanUser(r.db.QueryRowContext(ctx, query, args...), &id, &u); err != nil {
+		return nil, err
+	}
+	u.ID = id.String
+	return &u, nil
+}
+
+// UpdateProfile saves the public profile fields of a user: display name, avatar, bio and channel slug
+func (r *UserRepository) UpdateProfile(ctx context.Context, u *User) error {
+	const query = `
+	UPDATE users
+	SET display_name = $1, avatar_url = $2, bio = $3, channel_slug = $4, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $5`
+	res, err := r.db.ExecContext(ctx, query, u.DisplayName, u.AvatarURL, u.Bio, u.ChannelSlug, u.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrChannelSlugTaken
+		}
+		return err
+	}
+	if err = expectOneRow(res); err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx, `SELECT updated_at FROM users WHERE id = $1`, u.ID).Scan(&u.UpdatedAt)
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *UserRepository) UpdatePassword(ctx context.Context, id string, passwordHash []byte) error {
+	const query = `UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	res, err := r.db.ExecContext(ctx, query, passwordHash, id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// SetPendingEmail records an email change waiting for confirmation with the token of the given hash,
+// replacing any earlier pending change
+func (r *UserRepository) SetPendingEmail(ctx context.Context, id, email string, tokenHash []byte, expiresAt time.Time) error {
+	const query = `
+	UPDATE users
+	SET pending_email = $1, email_change_token_hash = $2, email_change_expires_at = $3, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $4`
+	res, err := r.db.ExecContext(ctx, query, email, tokenHash, expiresAt.UTC(), id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// ConfirmEmailChange makes the pending email of the user holding the token of the given hash their email.
+// Tokens are single use, sql.ErrNoRows is returned for unknown or used tokens.
+func (r *UserRepository) ConfirmEmailChange(ctx context.Context, tokenHash []byte) (*User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id, email string
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `
+	SELECT id, pending_email, email_change_expires_at
+	FROM users WHERE email_change_token_hash = $1 AND pending_email IS NOT NULL`, tokenHash).
+		Scan(&id, &email, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	const clear = `pending_email = NULL, email_change_token_hash = NULL, email_change_expires_at = NULL`
+	if time.Now().After(expiresAt) {
+		if _, err = tx.ExecContext(ctx, `UPDATE users SET `+clear+` WHERE id = $1`, id); err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenExpired
+	}
+
+	// following the link proves the new address belongs to the user
+	_, err = tx.ExecContext(ctx, `UPDATE users SET email = $1, `+clear+`, email_verified_at = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $3`, email, time.Now().UTC(), id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// DeleteUser removes a user, sql.ErrNoRows is returned when there is none
+func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// sqlite does not enforce the cascade unless foreign keys are enabled
+	if _, err = tx.ExecContext(ctx, `DELETE FROM account_tokens WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+	for _, table := range []string{"user_identities", "user_roles", "user_mfa", "mfa_recovery_codes"} {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, id); err != nil {
+			return err
+		}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if err = expectOneRow(res); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkEmailVerified records that the user confirmed owning their email
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id string) error {
+	const query = `UPDATE users SET email_verified_at = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation of either database
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique") || strings.Contains(msg, "duplicate") || strings.Contains(msg, "23505")
+}
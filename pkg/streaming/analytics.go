@@ -17,6 +17,10 @@ package streaming
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -72,13 +76,23 @@ type StreamAnalytics struct {
 	ViewersByPlatform map[string]int `json:"viewers_by_platform"`
 	ViewersByDevice   map[string]int `json:"viewers_by_device"`
 
-	// Time-series data points
-	ViewerTimeline   []TimeSeriesDataPoint `json:"viewer_timeline"`
-	ChatTimeline     []TimeSeriesDataPoint `json:"chat_timeline"`
-	ReactionTimeline []TimeSeriesDataPoint `json:"reaction_timeline"`
-	BitrateTimeline  []TimeSeriesDataPoint `json:"bitrate_timeline"`
+	// Referral metrics, viewers by where they came from and the raids the stream received
+	ViewersByReferrer map[string]int  `json:"viewers_by_referrer"`
+	Raids             []*RaidReferral `json:"raids,omitempty"`
+
+	// Time-series data points, one per timeline resolution, older points cover longer spans
+	ViewerTimeline   []TimeSeriesDataPoint `json:"viewer_timeline"`   // average concurrent viewers
+	ChatTimeline     []TimeSeriesDataPoint `json:"chat_timeline"`     // messages per minute
+	ReactionTimeline []TimeSeriesDataPoint `json:"reaction_timeline"` // reactions per minute
+	BitrateTimeline  []TimeSeriesDataPoint `json:"bitrate_timeline"`  // average kbps
 
 	LastUpdated time.Time `json:"last_updated"`
+
+	bitrateSum     int
+	bitrateSamples int
+	latencySum     time.Duration
+	latencySamples int
+	timeline       *streamTimeline
 }
 
 // TimeSeriesDataPoint represents a metric at a point in time
@@ -87,6 +101,19 @@ type TimeSeriesDataPoint struct {
 	Value     float64   `json:"value"`
 }
 
+// ReferralSourceRaid tags viewers sent over by another stream raiding this one
+const ReferralSourceRaid = "raid"
+
+// RaidReferral is a raid received by a stream: viewers of another stream were sent over to it
+type RaidReferral struct {
+	FromRoom       livekit.RoomName            `json:"from_room"`
+	FromStreamerID livekit.ParticipantIdentity `json:"from_streamer_id"`
+	At             time.Time                   `json:"at"`
+	// viewers watching the raiding stream when it raided, and how many of them joined
+	Viewers int `json:"viewers"`
+	Arrived int `json:"arrived"`
+}
+
 // ViewerSession represents a single viewer's session
 type ViewerSession struct {
 	ViewerID          livekit.ParticipantIdentity `json:"viewer_id"`
@@ -104,6 +131,16 @@ type ViewerSession struct {
 	BufferingCount    int                         `json:"buffering_count"`
 	QualityLevel      string                      `json:"quality_level"`
 	ConnectionQuality string                      `json:"connection_quality"` // excellent, good, fair, poor
+	Referrer          string                      `json:"referrer,omitempty"`
+
+	bitrateSum     int
+	bitrateSamples int
+}
+
+// StreamRecord is a snapshot of a stream and its viewer sessions
+type StreamRecord struct {
+	Analytics *StreamAnalytics
+	Sessions  []*ViewerSession
 }
 
 // AnalyticsService manages stream analytics
@@ -111,10 +148,36 @@ type AnalyticsService struct {
 	mu              sync.RWMutex
 	streamAnalytics map[livekit.RoomName]*StreamAnalytics
 	viewerSessions  map[livekit.RoomName]map[livekit.ParticipantIdentity]*ViewerSession
-	logger          logger.Logger
-	config          *AnalyticsConfig
+	// viewers expected to join a stream, by the stream they are expected in
+	referrals map[livekit.RoomName]map[livekit.ParticipantIdentity]*pendingReferral
+	// ended streams that have since been replaced by a new stream in the same room
+	pastStreams []*pastStream
+	logger      logger.Logger
+	config      *AnalyticsConfig
+	geoLookups  chan geoLookup
+	subscribers map[livekit.RoomName]map[chan *StreamAnalytics]struct{}
+	metrics     *analyticsMetrics
+}
+
+type pastStream struct {
+	analytics *StreamAnalytics
+	sessions  map[livekit.ParticipantIdentity]*ViewerSession
 }
 
+type pendingReferral struct {
+	source    string
+	raid      *RaidReferral
+	expiresAt time.Time
+}
+
+type geoLookup struct {
+	session *ViewerSession
+	ip      netip.Addr
+}
+
+// pending location lookups beyond this are dropped rather than waited on
+const geoLookupQueueSize = 1024
+
 // AnalyticsConfig defines analytics service configuration
 type AnalyticsConfig struct {
 	EnableRealTime        bool          `json:"enable_real_time"`
@@ -126,25 +189,42 @@ type AnalyticsConfig struct {
 	EnableDeviceDetection bool          `json:"enable_device_detection"`
 }
 
-// NewAnalyticsService creates a new analytics service
+// DefaultAnalyticsConfig is the configuration used when none is given
+var DefaultAnalyticsConfig = AnalyticsConfig{
+	EnableRealTime:        true,
+	UpdateInterval:        10 * time.Second,
+	TimelineResolution:    1 * time.Minute,
+	MaxTimelinePoints:     1000,
+	RetentionDays:         90,
+	EnableGeoIP:           true,
+	EnableDeviceDetection: true,
+}
+
+// NewAnalyticsService creates a new analytics service.
+// Unset intervals and limits of the config take their default values.
 func NewAnalyticsService(config *AnalyticsConfig) *AnalyticsService {
 	if config == nil {
-		config = &AnalyticsConfig{
-			EnableRealTime:        true,
-			UpdateInterval:        10 * time.Second,
-			TimelineResolution:    1 * time.Minute,
-			MaxTimelinePoints:     1000,
-			RetentionDays:         90,
-			EnableGeoIP:           true,
-			EnableDeviceDetection: true,
-		}
+		config = &DefaultAnalyticsConfig
+	}
+	conf := *config
+	if conf.UpdateInterval <= 0 {
+		conf.UpdateInterval = DefaultAnalyticsConfig.UpdateInterval
+	}
+	if conf.TimelineResolution <= 0 {
+		conf.TimelineResolution = DefaultAnalyticsConfig.TimelineResolution
+	}
+	if conf.MaxTimelinePoints < 2 {
+		conf.MaxTimelinePoints = DefaultAnalyticsConfig.MaxTimelinePoints
 	}
 
 	return &AnalyticsService{
 		streamAnalytics: make(map[livekit.RoomName]*StreamAnalytics),
 		viewerSessions:  make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*ViewerSession),
+		referrals:       make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*pendingReferral),
 		logger:          logger.GetLogger(),
-		config:          config,
+		config:          &conf,
+		subscribers:     make(map[livekit.RoomName]map[chan *StreamAnalytics]struct{}),
+		metrics:         newAnalyticsMetrics(),
 	}
 }
 
@@ -157,8 +237,15 @@ func (as *AnalyticsService) StartStreamAnalytics(
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
-	if _, exists := as.streamAnalytics[roomName]; exists {
-		return nil, fmt.Errorf("analytics already started for this stream")
+	// a room can host several streams over time, only one may be running
+	if existing, exists := as.streamAnalytics[roomName]; exists {
+		if existing.EndTime == nil {
+			return nil, fmt.Errorf("analytics already started for this stream")
+		}
+		as.pastStreams = append(as.pastStreams, &pastStream{
+			analytics: existing,
+			sessions:  as.viewerSessions[roomName],
+		})
 	}
 
 	analytics := &StreamAnalytics{
@@ -170,12 +257,14 @@ func (as *AnalyticsService) StartStreamAnalytics(
 		ViewersByRegion:   make(map[string]int),
 		ViewersByPlatform: make(map[string]int),
 		ViewersByDevice:   make(map[string]int),
+		ViewersByReferrer: make(map[string]int),
 		ViewerTimeline:    make([]TimeSeriesDataPoint, 0),
 		ChatTimeline:      make([]TimeSeriesDataPoint, 0),
 		ReactionTimeline:  make([]TimeSeriesDataPoint, 0),
 		BitrateTimeline:   make([]TimeSeriesDataPoint, 0),
 		LastUpdated:       time.Now(),
 	}
+	analytics.timeline = newStreamTimeline(analytics, as.config.TimelineResolution, as.config.MaxTimelinePoints)
 
 	as.streamAnalytics[roomName] = analytics
 	as.viewerSessions[roomName] = make(map[livekit.ParticipantIdentity]*ViewerSession)
@@ -187,7 +276,7 @@ func (as *AnalyticsService) StartStreamAnalytics(
 
 	// Start real-time updates if enabled
 	if as.config.EnableRealTime {
-		go as.updateAnalyticsLoop(ctx, roomName)
+		go as.updateAnalyticsLoop(ctx, roomName, analytics)
 	}
 
 	return analytics, nil
@@ -221,7 +310,14 @@ func (as *AnalyticsService) StopStreamAnalytics(
 	}
 
 	// Final update
+	analytics.timeline.close(now)
 	as.calculateMetrics(analytics, roomName)
+	as.publishLocked(roomName, analytics)
+	for sub := range as.subscribers[roomName] {
+		close(sub)
+	}
+	delete(as.subscribers, roomName)
+	delete(as.referrals, roomName)
 
 	as.logger.Infow("stopped stream analytics",
 		"roomName", roomName,
@@ -288,6 +384,7 @@ func (as *AnalyticsService) RecordViewerJoin(
 	if analytics.CurrentViewers > analytics.PeakViewers {
 		analytics.PeakViewers = analytics.CurrentViewers
 	}
+	analytics.timeline.setViewers(session.JoinedAt, analytics.CurrentViewers)
 
 	// Update geographic distribution
 	if country != "" {
@@ -305,6 +402,18 @@ func (as *AnalyticsService) RecordViewerJoin(
 		analytics.ViewersByDevice[device]++
 	}
 
+	// Attribute viewers sent over by a referral
+	if referral, ok := as.referrals[roomName][viewerID]; ok {
+		delete(as.referrals[roomName], viewerID)
+		if session.JoinedAt.Before(referral.expiresAt) {
+			session.Referrer = referral.source
+			analytics.ViewersByReferrer[referral.source]++
+			if referral.raid != nil {
+				referral.raid.Arrived++
+			}
+		}
+	}
+
 	as.logger.Debugw("viewer joined",
 		"roomName", roomName,
 		"viewerID", viewerID,
@@ -314,6 +423,130 @@ func (as *AnalyticsService) RecordViewerJoin(
 	return nil
 }
 
+// RecordRaid records the stream in fromRoom raiding the live stream in toRoom. The viewers watching fromRoom
+// who join toRoom within window are attributed to the raid.
+func (as *AnalyticsService) RecordRaid(
+	ctx context.Context,
+	fromRoom livekit.RoomName,
+	toRoom livekit.RoomName,
+	window time.Duration,
+) (*RaidReferral, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	target, exists := as.streamAnalytics[toRoom]
+	if !exists || target.EndTime != nil {
+		return nil, fmt.Errorf("stream is not live")
+	}
+
+	now := time.Now()
+	raid := &RaidReferral{
+		FromRoom: fromRoom,
+		At:       now,
+	}
+	if source, ok := as.streamAnalytics[fromRoom]; ok {
+		raid.FromStreamerID = source.StreamerID
+	}
+
+	pending, ok := as.referrals[toRoom]
+	if !ok {
+		pending = make(map[livekit.ParticipantIdentity]*pendingReferral)
+		as.referrals[toRoom] = pending
+	}
+	for viewerID, referral := range pending {
+		if !now.Before(referral.expiresAt) {
+			delete(pending, viewerID)
+		}
+	}
+	for viewerID, session := range as.viewerSessions[fromRoom] {
+		if session.LeftAt != nil {
+			continue
+		}
+		raid.Viewers++
+		pending[viewerID] = &pendingReferral{
+			source:    ReferralSourceRaid,
+			raid:      raid,
+			expiresAt: now.Add(window),
+		}
+	}
+	target.Raids = append(target.Raids, raid)
+
+	as.logger.Infow("stream raided",
+		"roomName", toRoom,
+		"fromRoom", fromRoom,
+		"viewers", raid.Viewers,
+	)
+
+	copied := *raid
+	return &copied, nil
+}
+
+// SetGeoResolver enables location lookups of viewers, unless GeoIP is disabled in the config
+func (as *AnalyticsService) SetGeoResolver(resolver GeoResolver) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if !as.config.EnableGeoIP || as.geoLookups != nil {
+		return
+	}
+	as.geoLookups = make(chan geoLookup, geoLookupQueueSize)
+	go as.geoLookupWorker(resolver, as.geoLookups)
+}
+
+// LocateViewer resolves the location of a viewer in the background and fills in their session.
+// The address is the one reported in ClientInfo, private and unparseable addresses are ignored.
+func (as *AnalyticsService) LocateViewer(
+	roomName livekit.RoomName,
+	viewerID livekit.ParticipantIdentity,
+	address string,
+) {
+	ip, ok := parseClientAddress(address)
+	if !ok {
+		return
+	}
+
+	as.mu.RLock()
+	session := as.viewerSessions[roomName][viewerID]
+	queue := as.geoLookups
+	as.mu.RUnlock()
+
+	if queue == nil || session == nil {
+		return
+	}
+	select {
+	case queue <- geoLookup{session: session, ip: ip}:
+	default:
+		as.logger.Debugw("geoip lookups backed up, skipping viewer", "roomName", roomName, "viewerID", viewerID)
+	}
+}
+
+func (as *AnalyticsService) geoLookupWorker(resolver GeoResolver, lookups <-chan geoLookup) {
+	for lookup := range lookups {
+		location, err := resolver.Resolve(lookup.ip)
+		if err != nil {
+			as.logger.Debugw("could not resolve viewer location", "error", err)
+			continue
+		}
+		if location.Country == "" {
+			continue
+		}
+
+		as.mu.Lock()
+		session := lookup.session
+		analytics, exists := as.streamAnalytics[session.RoomName]
+		// the stream may have been restarted in the meantime
+		if exists && as.viewerSessions[session.RoomName][session.ViewerID] == session && session.Country == "" {
+			session.Country = location.Country
+			session.Region = location.Region
+			analytics.ViewersByCountry[location.Country]++
+			if location.Region != "" {
+				analytics.ViewersByRegion[location.Region]++
+			}
+		}
+		as.mu.Unlock()
+	}
+}
+
 // RecordViewerLeave records a viewer leaving the stream
 func (as *AnalyticsService) RecordViewerLeave(
 	ctx context.Context,
@@ -346,6 +579,7 @@ func (as *AnalyticsService) RecordViewerLeave(
 	if analytics.CurrentViewers < 0 {
 		analytics.CurrentViewers = 0
 	}
+	analytics.timeline.setViewers(now, analytics.CurrentViewers)
 
 	as.logger.Debugw("viewer left",
 		"roomName", roomName,
@@ -367,11 +601,12 @@ func (as *AnalyticsService) RecordChatMessage(
 	defer as.mu.Unlock()
 
 	analytics, exists := as.streamAnalytics[roomName]
-	if !exists {
+	if !exists || analytics.EndTime != nil {
 		return fmt.Errorf("analytics not found")
 	}
 
 	analytics.TotalMessages++
+	analytics.timeline.addMessage(time.Now())
 
 	// Update session
 	if sessions, ok := as.viewerSessions[roomName]; ok {
@@ -394,12 +629,13 @@ func (as *AnalyticsService) RecordReaction(
 	defer as.mu.Unlock()
 
 	analytics, exists := as.streamAnalytics[roomName]
-	if !exists {
+	if !exists || analytics.EndTime != nil {
 		return fmt.Errorf("analytics not found")
 	}
 
 	analytics.TotalReactions++
 	analytics.ReactionBreakdown[reactionType]++
+	analytics.timeline.addReaction(time.Now())
 
 	// Update session
 	if sessions, ok := as.viewerSessions[roomName]; ok {
@@ -428,18 +664,101 @@ func (as *AnalyticsService) RecordBitrateUpdate(
 	if bitrate > analytics.PeakBitrate {
 		analytics.PeakBitrate = bitrate
 	}
+	analytics.bitrateSum += bitrate
+	analytics.bitrateSamples++
+	analytics.AverageBitrate = analytics.bitrateSum / analytics.bitrateSamples
+	analytics.timeline.addBitrate(time.Now(), bitrate)
 
 	return nil
 }
 
-// GetStreamAnalytics retrieves analytics for a stream
-func (as *AnalyticsService) GetStreamAnalytics(
+// RecordViewerStats records delivery stats of a viewer: the bitrate they receive in kbps,
+// their connection quality and the video quality level they are served.
+// Zero and empty values are left unchanged, a new quality level counts as a quality change of the stream.
+func (as *AnalyticsService) RecordViewerStats(
 	ctx context.Context,
 	roomName livekit.RoomName,
-) (*StreamAnalytics, error) {
+	viewerID livekit.ParticipantIdentity,
+	bitrate int,
+	connectionQuality string,
+	qualityLevel string,
+) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	analytics, exists := as.streamAnalytics[roomName]
+	if !exists {
+		return fmt.Errorf("analytics not found")
+	}
+
+	session, exists := as.viewerSessions[roomName][viewerID]
+	if !exists || session.LeftAt != nil {
+		return fmt.Errorf("session not found")
+	}
+
+	if bitrate > 0 {
+		session.bitrateSum += bitrate
+		session.bitrateSamples++
+		session.AvgBitrate = session.bitrateSum / session.bitrateSamples
+	}
+
+	if connectionQuality != "" {
+		session.ConnectionQuality = connectionQuality
+	}
+
+	setQualityLevel(analytics, session, qualityLevel)
+
+	return nil
+}
+
+// setQualityLevel records the quality level a viewer is served, a new level counts as a quality change of the stream
+func setQualityLevel(analytics *StreamAnalytics, session *ViewerSession, qualityLevel string) {
+	if qualityLevel == "" || qualityLevel == session.QualityLevel {
+		return
+	}
+	// the first known level replaces the initial "auto" and is not a switch
+	if session.QualityLevel != "auto" {
+		analytics.QualityChanges++
+	}
+	session.QualityLevel = qualityLevel
+}
+
+// ActiveStreamStart returns when the running stream of a room started
+func (as *AnalyticsService) ActiveStreamStart(roomName livekit.RoomName) (time.Time, bool) {
 	as.mu.RLock()
 	defer as.mu.RUnlock()
 
+	analytics, exists := as.streamAnalytics[roomName]
+	if !exists || analytics.EndTime != nil {
+		return time.Time{}, false
+	}
+	return analytics.StartTime, true
+}
+
+// ConnectionQualityFromScore maps a connection score on the MOS scale (1 to 4.5) to a connection quality label
+func ConnectionQualityFromScore(score float32) string {
+	switch {
+	case score <= 0:
+		return ""
+	case score > 4:
+		return "excellent"
+	case score > 3:
+		return "good"
+	case score > 2:
+		return "fair"
+	default:
+		return "poor"
+	}
+}
+
+// GetStreamAnalytics retrieves a snapshot of the analytics for a stream
+func (as *AnalyticsService) GetStreamAnalytics(
+	ctx context.Context,
+	roomName livekit.RoomName,
+) (*StreamAnalytics, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
 	analytics, exists := as.streamAnalytics[roomName]
 	if !exists {
 		return nil, fmt.Errorf("analytics not found")
@@ -448,10 +767,10 @@ func (as *AnalyticsService) GetStreamAnalytics(
 	// Calculate latest metrics
 	as.calculateMetrics(analytics, roomName)
 
-	return analytics, nil
+	return snapshotStream(analytics, nil).Analytics, nil
 }
 
-// GetViewerSessions retrieves all viewer sessions for a stream
+// GetViewerSessions retrieves snapshots of all viewer sessions for a stream, in order of joining
 func (as *AnalyticsService) GetViewerSessions(
 	ctx context.Context,
 	roomName livekit.RoomName,
@@ -459,21 +778,88 @@ func (as *AnalyticsService) GetViewerSessions(
 	as.mu.RLock()
 	defer as.mu.RUnlock()
 
-	sessions, exists := as.viewerSessions[roomName]
+	analytics, exists := as.streamAnalytics[roomName]
 	if !exists {
 		return []*ViewerSession{}, nil
 	}
+	return snapshotStream(analytics, as.viewerSessions[roomName]).Sessions, nil
+}
 
-	result := make([]*ViewerSession, 0, len(sessions))
-	for _, session := range sessions {
-		result = append(result, session)
+// GetStreamerStreams returns snapshots of the streams of a streamer that overlap with [from, to),
+// oldest first. A zero time leaves that side of the range open.
+func (as *AnalyticsService) GetStreamerStreams(
+	ctx context.Context,
+	streamerID livekit.ParticipantIdentity,
+	from time.Time,
+	to time.Time,
+) []*StreamRecord {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	inRange := func(analytics *StreamAnalytics) bool {
+		if analytics.StreamerID != streamerID {
+			return false
+		}
+		if !to.IsZero() && !analytics.StartTime.Before(to) {
+			return false
+		}
+		return from.IsZero() || analytics.EndTime == nil || analytics.EndTime.After(from)
 	}
 
-	return result, nil
+	var records []*StreamRecord
+	for _, past := range as.pastStreams {
+		if inRange(past.analytics) {
+			records = append(records, snapshotStream(past.analytics, past.sessions))
+		}
+	}
+	for roomName, analytics := range as.streamAnalytics {
+		if inRange(analytics) {
+			as.calculateMetrics(analytics, roomName)
+			records = append(records, snapshotStream(analytics, as.viewerSessions[roomName]))
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Analytics.StartTime.Before(records[j].Analytics.StartTime)
+	})
+	return records
 }
 
 // Helper functions
 
+func snapshotStream(analytics *StreamAnalytics, sessions map[livekit.ParticipantIdentity]*ViewerSession) *StreamRecord {
+	a := *analytics
+	a.timeline = nil
+	a.ReactionBreakdown = maps.Clone(analytics.ReactionBreakdown)
+	a.ViewersByCountry = maps.Clone(analytics.ViewersByCountry)
+	a.ViewersByRegion = maps.Clone(analytics.ViewersByRegion)
+	a.ViewersByPlatform = maps.Clone(analytics.ViewersByPlatform)
+	a.ViewersByDevice = maps.Clone(analytics.ViewersByDevice)
+	a.ViewersByReferrer = maps.Clone(analytics.ViewersByReferrer)
+	a.Raids = nil
+	for _, raid := range analytics.Raids {
+		r := *raid
+		a.Raids = append(a.Raids, &r)
+	}
+	a.ViewerTimeline = slices.Clone(analytics.ViewerTimeline)
+	a.ChatTimeline = slices.Clone(analytics.ChatTimeline)
+	a.ReactionTimeline = slices.Clone(analytics.ReactionTimeline)
+	a.BitrateTimeline = slices.Clone(analytics.BitrateTimeline)
+
+	record := &StreamRecord{
+		Analytics: &a,
+		Sessions:  make([]*ViewerSession, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		s := *session
+		record.Sessions = append(record.Sessions, &s)
+	}
+	sort.Slice(record.Sessions, func(i, j int) bool {
+		return record.Sessions[i].JoinedAt.Before(record.Sessions[j].JoinedAt)
+	})
+	return record
+}
+
 func (as *AnalyticsService) calculateMetrics(analytics *StreamAnalytics, roomName livekit.RoomName) {
 	sessions, ok := as.viewerSessions[roomName]
 	if !ok {
@@ -501,25 +887,30 @@ func (as *AnalyticsService) calculateMetrics(analytics *StreamAnalytics, roomNam
 
 	analytics.UniqueMessagers = len(uniqueMessagers)
 
-	// Calculate rates
+	// Calculate rates, up to now while the stream is live
+	now := time.Now()
+	end := now
 	if analytics.EndTime != nil {
-		duration := analytics.EndTime.Sub(analytics.StartTime)
-		minutes := duration.Minutes()
-		if minutes > 0 {
-			analytics.MessagesPerMinute = float64(analytics.TotalMessages) / minutes
-			analytics.ReactionsPerMinute = float64(analytics.TotalReactions) / minutes
-		}
+		end = *analytics.EndTime
+	} else {
+		analytics.timeline.advance(now)
 	}
+	if minutes := end.Sub(analytics.StartTime).Minutes(); minutes > 0 {
+		analytics.MessagesPerMinute = float64(analytics.TotalMessages) / minutes
+		analytics.ReactionsPerMinute = float64(analytics.TotalReactions) / minutes
+	}
+	analytics.AverageViewers = analytics.timeline.averageViewers(now)
 
 	// Calculate viewer retention
 	if analytics.TotalViewers > 0 {
 		analytics.ViewerRetention = float64(completedSessions) / float64(analytics.TotalViewers) * 100
 	}
 
-	analytics.LastUpdated = time.Now()
+	analytics.LastUpdated = now
 }
 
-func (as *AnalyticsService) updateAnalyticsLoop(ctx context.Context, roomName livekit.RoomName) {
+// updateAnalyticsLoop closes timeline buckets and publishes the analytics of a stream until it ends
+func (as *AnalyticsService) updateAnalyticsLoop(ctx context.Context, roomName livekit.RoomName, analytics *StreamAnalytics) {
 	ticker := time.NewTicker(as.config.UpdateInterval)
 	defer ticker.Stop()
 
@@ -529,27 +920,70 @@ func (as *AnalyticsService) updateAnalyticsLoop(ctx context.Context, roomName li
 			return
 		case <-ticker.C:
 			as.mu.Lock()
-			analytics, exists := as.streamAnalytics[roomName]
-			if !exists {
+			if as.streamAnalytics[roomName] != analytics || analytics.EndTime != nil {
 				as.mu.Unlock()
 				return
 			}
 
-			// Add timeline data points
-			now := time.Now()
-			analytics.ViewerTimeline = append(analytics.ViewerTimeline, TimeSeriesDataPoint{
-				Timestamp: now,
-				Value:     float64(analytics.CurrentViewers),
-			})
+			as.calculateMetrics(analytics, roomName)
+			as.publishLocked(roomName, analytics)
+			as.mu.Unlock()
+		}
+	}
+}
+
+// SubscribeStreamAnalytics returns a channel receiving snapshots of the analytics of the running stream of a room,
+// the current one first and then one on every update. A subscriber that falls behind only receives the latest snapshot.
+// The channel is closed after the final snapshot once the stream ends, or when the returned function is called.
+func (as *AnalyticsService) SubscribeStreamAnalytics(roomName livekit.RoomName) (<-chan *StreamAnalytics, func(), error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	analytics, exists := as.streamAnalytics[roomName]
+	if !exists || analytics.EndTime != nil {
+		return nil, nil, fmt.Errorf("stream is not live")
+	}
 
-			// Limit timeline points
-			if len(analytics.ViewerTimeline) > as.config.MaxTimelinePoints {
-				analytics.ViewerTimeline = analytics.ViewerTimeline[1:]
+	sub := make(chan *StreamAnalytics, 1)
+	subs, ok := as.subscribers[roomName]
+	if !ok {
+		subs = make(map[chan *StreamAnalytics]struct{})
+		as.subscribers[roomName] = subs
+	}
+	subs[sub] = struct{}{}
+
+	as.calculateMetrics(analytics, roomName)
+	sub <- snapshotStream(analytics, nil).Analytics
+
+	unsubscribe := func() {
+		as.mu.Lock()
+		defer as.mu.Unlock()
+
+		if _, ok := as.subscribers[roomName][sub]; ok {
+			delete(as.subscribers[roomName], sub)
+			if len(as.subscribers[roomName]) == 0 {
+				delete(as.subscribers, roomName)
 			}
+			close(sub)
+		}
+	}
+	return sub, unsubscribe, nil
+}
 
-			as.calculateMetrics(analytics, roomName)
-			as.mu.Unlock()
+func (as *AnalyticsService) publishLocked(roomName livekit.RoomName, analytics *StreamAnalytics) {
+	subs := as.subscribers[roomName]
+	if len(subs) == 0 {
+		return
+	}
+
+	snapshot := snapshotStream(analytics, nil).Analytics
+	for sub := range subs {
+		// replace a snapshot the subscriber has not picked up yet
+		select {
+		case <-sub:
+		default:
 		}
+		sub <- snapshot
 	}
 }
 
@@ -568,6 +1002,16 @@ func (as *AnalyticsService) CleanupOldAnalytics(ctx context.Context) int {
 			count++
 		}
 	}
+	pastStreams := as.pastStreams[:0]
+	for _, past := range as.pastStreams {
+		if past.analytics.EndTime.Before(cutoff) {
+			count++
+			continue
+		}
+		pastStreams = append(pastStreams, past)
+	}
+	clear(as.pastStreams[len(pastStreams):])
+	as.pastStreams = pastStreams
 
 	if count > 0 {
 		as.logger.Infow("cleaned up old analytics", "count", count)
@@ -0,0 +1,142 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func TestLiveStreamAnalytics(t *testing.T) {
+	ctx := context.Background()
+	as := streaming.NewAnalyticsService(&streaming.AnalyticsConfig{
+		EnableRealTime:     true,
+		UpdateInterval:     20 * time.Millisecond,
+		TimelineResolution: 50 * time.Millisecond,
+		RetentionDays:      1,
+	})
+
+	_, _, err := as.SubscribeStreamAnalytics("room")
+	require.Error(t, err)
+
+	_, err = as.StartStreamAnalytics(ctx, "room", "streamer")
+	require.NoError(t, err)
+	updates, unsubscribe, err := as.SubscribeStreamAnalytics("room")
+	require.NoError(t, err)
+	defer unsubscribe()
+	other, unsubscribeOther, err := as.SubscribeStreamAnalytics("room")
+	require.NoError(t, err)
+
+	first := <-updates
+	require.Zero(t, first.CurrentViewers)
+
+	require.NoError(t, as.RecordViewerJoin(ctx, "room", "alice", "web", "desktop", "", ""))
+	require.NoError(t, as.RecordChatMessage(ctx, "room", "alice"))
+	require.NoError(t, as.RecordReaction(ctx, "room", "alice", streaming.ReactionTypeHeart))
+	require.NoError(t, as.RecordBitrateUpdate(ctx, "room", 2500))
+
+	// all timelines fill and rates are available while live
+	var live *streaming.StreamAnalytics
+	require.Eventually(t, func() bool {
+		live = <-updates
+		return len(live.ViewerTimeline) > 0 && live.CurrentViewers == 1
+	}, 2*time.Second, time.Millisecond)
+	require.NotEmpty(t, live.ChatTimeline)
+	require.NotEmpty(t, live.ReactionTimeline)
+	require.Equal(t, 2500.0, live.BitrateTimeline[0].Value)
+	require.Positive(t, live.MessagesPerMinute)
+	require.Positive(t, live.ReactionsPerMinute)
+	require.Positive(t, live.AverageViewers)
+
+	// a subscriber that stopped listening holds at most the latest snapshot
+	unsubscribeOther()
+	require.LessOrEqual(t, len(other), 1)
+	for range other {
+	}
+
+	// the final snapshot is delivered before the channel closes
+	require.NoError(t, as.StopStreamAnalytics(ctx, "room"))
+	var last *streaming.StreamAnalytics
+	for analytics := range updates {
+		last = analytics
+	}
+	require.NotNil(t, last.EndTime)
+
+	_, _, err = as.SubscribeStreamAnalytics("room")
+	require.Error(t, err)
+
+	// the timelines of an ended stream no longer move
+	analytics, err := as.GetStreamAnalytics(ctx, "room")
+	require.NoError(t, err)
+	points := len(analytics.ViewerTimeline)
+	time.Sleep(120 * time.Millisecond)
+	analytics, err = as.GetStreamAnalytics(ctx, "room")
+	require.NoError(t, err)
+	require.Len(t, analytics.ViewerTimeline, points)
+}
+
+func TestRaidReferrals(t *testing.T) {
+	ctx := context.Background()
+	as := streaming.NewAnalyticsService(&streaming.AnalyticsConfig{})
+
+	_, err := as.StartStreamAnalytics(ctx, "raider", "host")
+	require.NoError(t, err)
+	_, err = as.RecordRaid(ctx, "raider", "target", time.Minute)
+	require.Error(t, err)
+
+	_, err = as.StartStreamAnalytics(ctx, "target", "friend")
+	require.NoError(t, err)
+	for _, viewerID := range []livekit.ParticipantIdentity{"alice", "bob", "carol"} {
+		require.NoError(t, as.RecordViewerJoin(ctx, "raider", viewerID, "web", "desktop", "", ""))
+	}
+	require.NoError(t, as.RecordViewerLeave(ctx, "raider", "carol"))
+
+	raid, err := as.RecordRaid(ctx, "raider", "target", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, livekit.ParticipantIdentity("host"), raid.FromStreamerID)
+	require.Equal(t, 2, raid.Viewers)
+
+	// viewers who had left before the raid are not referred, and a referral only counts once
+	for _, viewerID := range []livekit.ParticipantIdentity{"alice", "carol"} {
+		require.NoError(t, as.RecordViewerJoin(ctx, "target", viewerID, "web", "desktop", "", ""))
+	}
+	require.NoError(t, as.RecordViewerLeave(ctx, "target", "alice"))
+	require.NoError(t, as.RecordViewerJoin(ctx, "target", "alice", "web", "desktop", "", ""))
+
+	// referrals expire with the window
+	_, err = as.RecordRaid(ctx, "raider", "target", 0)
+	require.NoError(t, err)
+	require.NoError(t, as.RecordViewerJoin(ctx, "target", "bob", "web", "desktop", "", ""))
+
+	target, err := as.GetStreamAnalytics(ctx, "target")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{streaming.ReferralSourceRaid: 1}, target.ViewersByReferrer)
+	require.Len(t, target.Raids, 2)
+	require.Equal(t, 1, target.Raids[0].Arrived)
+	require.Zero(t, target.Raids[1].Arrived)
+
+	// the snapshot is a copy
+	target.Raids[0].Arrived = 10
+	target, err = as.GetStreamAnalytics(ctx, "target")
+	require.NoError(t, err)
+	require.Equal(t, 1, target.Raids[0].Arrived)
+}
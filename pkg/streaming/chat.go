@@ -17,6 +17,8 @@ package streaming
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,8 +41,15 @@ type ChatMessage struct {
 	IsDeleted      bool                          `json:"is_deleted"`
 	IsModerated    bool                          `json:"is_moderated"`
 	ReplyTo        *string                       `json:"reply_to,omitempty"`
+	// author of the message replied to, resolved by the server
+	ReplyToSenderID livekit.ParticipantIdentity `json:"reply_to_sender_id,omitempty"`
 }
 
+// maxMentionsPerMessage bounds how many users a single message can notify
+const maxMentionsPerMessage = 5
+
+var mentionPattern = regexp.MustCompile(`@([\p{L}\p{N}_.\-]+)`)
+
 // ChatMessageType defines the type of chat message
 type ChatMessageType string
 
@@ -93,6 +102,7 @@ type ChatService struct {
 	logger          logger.Logger
 	messageHandlers []ChatMessageHandler
 	badWords        []string
+	metrics         *chatMetrics
 }
 
 // ChatMessageHandler is a callback for new messages
@@ -105,6 +115,7 @@ func NewChatService() *ChatService {
 		logger:          logger.GetLogger(),
 		messageHandlers: make([]ChatMessageHandler, 0),
 		badWords:        []string{"spam", "badword1", "badword2"}, // Add more as needed
+		metrics:         newChatMetrics(),
 	}
 }
 
@@ -266,7 +277,6 @@ func (cs *ChatService) SendMessage(
 	senderID livekit.ParticipantIdentity,
 	content string,
 	messageType ChatMessageType,
-	mentionedUsers []livekit.ParticipantIdentity,
 	replyTo *string,
 ) (*ChatMessage, error) {
 	cs.mu.RLock()
@@ -274,6 +284,7 @@ func (cs *ChatService) SendMessage(
 	cs.mu.RUnlock()
 
 	if !exists {
+		cs.metrics.rejected.WithLabelValues(chatRejectNoRoom).Inc()
 		return nil, fmt.Errorf("chat room not found")
 	}
 
@@ -297,17 +308,20 @@ func (cs *ChatService) SendMessage(
 
 	// Check if participant is muted
 	if participant.IsMuted {
+		cs.metrics.rejected.WithLabelValues(chatRejectMuted).Inc()
 		return nil, fmt.Errorf("participant is muted")
 	}
 
 	// Check message length
 	if len(content) > room.Settings.MaxMessageLength {
+		cs.metrics.rejected.WithLabelValues(chatRejectTooLong).Inc()
 		return nil, fmt.Errorf("message too long")
 	}
 
 	// Check rate limiting
 	recentMessages := cs.countRecentMessages(room, senderID, time.Minute)
 	if recentMessages >= room.Settings.MaxMessagesPerMin {
+		cs.metrics.rejected.WithLabelValues(chatRejectRateLimited).Inc()
 		return nil, fmt.Errorf("rate limit exceeded")
 	}
 
@@ -315,6 +329,7 @@ func (cs *ChatService) SendMessage(
 	if room.Settings.SlowModeDelay > 0 {
 		lastMsg := cs.getLastMessage(room, senderID)
 		if lastMsg != nil && time.Since(lastMsg.Timestamp) < room.Settings.SlowModeDelay {
+			cs.metrics.rejected.WithLabelValues(chatRejectSlowMode).Inc()
 			return nil, fmt.Errorf("slow mode active, please wait")
 		}
 	}
@@ -324,22 +339,37 @@ func (cs *ChatService) SendMessage(
 		content = cs.filterBadWords(content)
 	}
 
+	// Mentions are resolved from the content rather than trusted from the client
+	var mentionedUsers []livekit.ParticipantIdentity
+	if room.Settings.EnableMentions {
+		mentionedUsers = resolveMentions(room, senderID, content)
+	}
+
+	var replyToSenderID livekit.ParticipantIdentity
+	if replyTo != nil {
+		if parent := findMessage(room, *replyTo); parent != nil && parent.MessageType != ChatMessageTypeJoinLeave {
+			replyToSenderID = parent.SenderID
+		}
+	}
+
 	message := &ChatMessage{
-		ID:             fmt.Sprintf("msg-%d-%s", time.Now().UnixNano(), senderID),
-		RoomName:       roomName,
-		SenderID:       senderID,
-		SenderName:     participant.Name,
-		Content:        content,
-		Timestamp:      time.Now(),
-		MessageType:    messageType,
-		MentionedUsers: mentionedUsers,
-		ReplyTo:        replyTo,
-		IsDeleted:      false,
-		IsModerated:    false,
+		ID:              fmt.Sprintf("msg-%d-%s", time.Now().UnixNano(), senderID),
+		RoomName:        roomName,
+		SenderID:        senderID,
+		SenderName:      participant.Name,
+		Content:         content,
+		Timestamp:       time.Now(),
+		MessageType:     messageType,
+		MentionedUsers:  mentionedUsers,
+		ReplyTo:         replyTo,
+		IsDeleted:       false,
+		IsModerated:     false,
+		ReplyToSenderID: replyToSenderID,
 	}
 
 	room.Messages = append(room.Messages, message)
 	participant.MessageCount++
+	cs.metrics.messageSent(messageType)
 
 	cs.logger.Debugw("chat message sent",
 		"roomName", roomName,
@@ -353,6 +383,42 @@ func (cs *ChatService) SendMessage(
 	return message, nil
 }
 
+// SendSystemNotice posts a notice of the server to a room, bypassing the limits of participants
+func (cs *ChatService) SendSystemNotice(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	content string,
+	metadata map[string]string,
+) (*ChatMessage, error) {
+	cs.mu.RLock()
+	room, exists := cs.rooms[roomName]
+	cs.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("chat room not found")
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	message := &ChatMessage{
+		ID:          fmt.Sprintf("sys-%d", time.Now().UnixNano()),
+		RoomName:    roomName,
+		SenderID:    "system",
+		SenderName:  "System",
+		Content:     content,
+		Timestamp:   time.Now(),
+		MessageType: ChatMessageTypeSystemNotice,
+		Metadata:    metadata,
+	}
+	room.Messages = append(room.Messages, message)
+	cs.metrics.messageSent(ChatMessageTypeSystemNotice)
+
+	cs.notifyHandlers(message)
+
+	return message, nil
+}
+
 // DeleteMessage deletes a chat message (moderator action)
 func (cs *ChatService) DeleteMessage(
 	ctx context.Context,
@@ -483,6 +549,26 @@ func (cs *ChatService) BanParticipant(
 	return nil
 }
 
+// BannedUntil returns when the ban of a participant from a chat room ends, false when they are not banned
+func (cs *ChatService) BannedUntil(roomName livekit.RoomName, participantID livekit.ParticipantIdentity) (time.Time, bool) {
+	cs.mu.RLock()
+	room, exists := cs.rooms[roomName]
+	cs.mu.RUnlock()
+
+	if !exists {
+		return time.Time{}, false
+	}
+
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+
+	banExpiry, banned := room.BannedUsers[participantID]
+	if !banned || !time.Now().Before(banExpiry) {
+		return time.Time{}, false
+	}
+	return banExpiry, true
+}
+
 // GetMessages returns recent messages from a chat room
 func (cs *ChatService) GetMessages(
 	ctx context.Context,
@@ -541,6 +627,49 @@ func (cs *ChatService) getLastMessage(room *ChatRoom, senderID livekit.Participa
 	return nil
 }
 
+// resolveMentions maps @name tokens to participants of the room by identity or display name
+func resolveMentions(room *ChatRoom, senderID livekit.ParticipantIdentity, content string) []livekit.ParticipantIdentity {
+	matches := mentionPattern.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	byName := make(map[string]livekit.ParticipantIdentity, len(room.Participants)*2)
+	for identity, p := range room.Participants {
+		if name := strings.ToLower(strings.ReplaceAll(p.Name, " ", "")); name != "" {
+			byName[name] = identity
+		}
+	}
+	// identities take precedence over display names
+	for identity := range room.Participants {
+		byName[strings.ToLower(string(identity))] = identity
+	}
+
+	var mentioned []livekit.ParticipantIdentity
+	seen := make(map[livekit.ParticipantIdentity]bool)
+	for _, m := range matches {
+		identity, ok := byName[strings.ToLower(strings.TrimRight(m[1], ".-"))]
+		if !ok || identity == senderID || seen[identity] {
+			continue
+		}
+		seen[identity] = true
+		mentioned = append(mentioned, identity)
+		if len(mentioned) == maxMentionsPerMessage {
+			break
+		}
+	}
+	return mentioned
+}
+
+func findMessage(room *ChatRoom, messageID string) *ChatMessage {
+	for i := len(room.Messages) - 1; i >= 0; i-- {
+		if room.Messages[i].ID == messageID {
+			return room.Messages[i]
+		}
+	}
+	return nil
+}
+
 func (cs *ChatService) filterBadWords(content string) string {
 	// Simple bad word filter - in production, use more sophisticated filtering
 	for _, badWord := range cs.badWords {
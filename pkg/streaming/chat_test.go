@@ -0,0 +1,150 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func newMentionsChat(t *testing.T, settings *streaming.ChatRoomSettings) *streaming.ChatService {
+	ctx := context.Background()
+	cs := streaming.NewChatService()
+	_, err := cs.CreateChatRoom(ctx, "room", settings)
+	require.NoError(t, err)
+	require.NoError(t, cs.JoinChatRoom(ctx, "room", "u-alice", "Alice Smith", false))
+	require.NoError(t, cs.JoinChatRoom(ctx, "room", "u-bob", "Bob", false))
+	require.NoError(t, cs.JoinChatRoom(ctx, "room", "u-carol", "carol", false))
+	return cs
+}
+
+func TestChatMentions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("resolves names and identities", func(t *testing.T) {
+		cs := newMentionsChat(t, nil)
+		msg, err := cs.SendMessage(ctx, "room", "u-bob", "hey @AliceSmith, @u-carol and @bob and @nobody.", streaming.ChatMessageTypeText, nil)
+		require.NoError(t, err)
+		require.Equal(t, []livekit.ParticipantIdentity{"u-alice", "u-carol"}, msg.MentionedUsers)
+	})
+
+	t.Run("ignored when mentions are disabled", func(t *testing.T) {
+		cs := newMentionsChat(t, &streaming.ChatRoomSettings{MaxMessageLength: 500, MaxMessagesPerMin: 20})
+		msg, err := cs.SendMessage(ctx, "room", "u-bob", "hey @carol", streaming.ChatMessageTypeText, nil)
+		require.NoError(t, err)
+		require.Empty(t, msg.MentionedUsers)
+	})
+
+	t.Run("resolves reply author", func(t *testing.T) {
+		cs := newMentionsChat(t, nil)
+		parent, err := cs.SendMessage(ctx, "room", "u-alice", "first", streaming.ChatMessageTypeText, nil)
+		require.NoError(t, err)
+		reply, err := cs.SendMessage(ctx, "room", "u-bob", "second", streaming.ChatMessageTypeText, &parent.ID)
+		require.NoError(t, err)
+		require.Equal(t, livekit.ParticipantIdentity("u-alice"), reply.ReplyToSenderID)
+	})
+}
+
+func TestNotifyChatMessage(t *testing.T) {
+	ctx := context.Background()
+	countOfType := func(ns *streaming.NotificationService, userID livekit.ParticipantIdentity, typ streaming.NotificationType) int {
+		notifications, _ := ns.GetNotifications(ctx, userID, false, 100)
+		count := 0
+		for _, n := range notifications {
+			if n.Type == typ {
+				count++
+			}
+		}
+		return count
+	}
+	message := func(senderID livekit.ParticipantIdentity, mentioned ...livekit.ParticipantIdentity) *streaming.ChatMessage {
+		return &streaming.ChatMessage{
+			ID:             "msg",
+			RoomName:       "room",
+			SenderID:       senderID,
+			SenderName:     string(senderID),
+			Content:        "hello",
+			MentionedUsers: mentioned,
+		}
+	}
+
+	t.Run("mentions and replies", func(t *testing.T) {
+		ns := streaming.NewNotificationService(nil)
+		msg := message("bob", "alice", "carol", "bob")
+		msg.ReplyToSenderID = "alice"
+		require.NoError(t, ns.NotifyChatMessage(ctx, msg, "streamer"))
+
+		require.Equal(t, 1, countOfType(ns, "alice", streaming.NotificationTypeReply))
+		require.Zero(t, countOfType(ns, "alice", streaming.NotificationTypeMention))
+		require.Equal(t, 1, countOfType(ns, "carol", streaming.NotificationTypeMention))
+		require.Zero(t, countOfType(ns, "bob", streaming.NotificationTypeMention))
+
+		notifications, _ := ns.GetNotifications(ctx, "carol", false, 1)
+		require.Equal(t, "bob mentioned you", notifications[0].Title)
+		require.Equal(t, "/watch/room", notifications[0].ActionURL)
+	})
+
+	t.Run("honors subscription preferences", func(t *testing.T) {
+		ns := streaming.NewNotificationService(nil)
+		require.NoError(t, ns.Subscribe(ctx, "alice", "streamer", "Streamer", &streaming.NotificationSubscription{EnableMentions: false}))
+
+		require.NoError(t, ns.NotifyChatMessage(ctx, message("bob", "alice"), "streamer"))
+		require.Zero(t, countOfType(ns, "alice", streaming.NotificationTypeMention))
+
+		require.NoError(t, ns.NotifyChatMessage(ctx, message("bob", "alice"), "other-streamer"))
+		require.Equal(t, 1, countOfType(ns, "alice", streaming.NotificationTypeMention))
+	})
+
+	t.Run("rate limits mention spam", func(t *testing.T) {
+		config := streaming.DefaultNotificationConfig
+		config.MentionCooldown = time.Hour
+		config.MaxMentionsPerMinute = 3
+		ns := streaming.NewNotificationService(&config)
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, ns.NotifyChatMessage(ctx, message("bob", "alice"), ""))
+		}
+		require.Equal(t, 1, countOfType(ns, "alice", streaming.NotificationTypeMention))
+
+		for _, sender := range []livekit.ParticipantIdentity{"carol", "dave", "erin", "frank"} {
+			require.NoError(t, ns.NotifyChatMessage(ctx, message(sender, "alice"), ""))
+		}
+		require.Equal(t, 3, countOfType(ns, "alice", streaming.NotificationTypeMention))
+	})
+
+	t.Run("rate limits mentions sent by one user", func(t *testing.T) {
+		config := streaming.DefaultNotificationConfig
+		config.MaxMentionsSentPerMinute = 2
+		ns := streaming.NewNotificationService(&config)
+
+		recipients := []livekit.ParticipantIdentity{"alice", "carol", "dave"}
+		for _, userID := range recipients {
+			require.NoError(t, ns.NotifyChatMessage(ctx, message("bob", userID), ""))
+		}
+		require.NoError(t, ns.NotifyChatMessage(ctx, message("erin", "dave"), ""))
+
+		require.Equal(t, 1, countOfType(ns, "alice", streaming.NotificationTypeMention))
+		require.Equal(t, 1, countOfType(ns, "carol", streaming.NotificationTypeMention))
+		// bob was over the limit, erin was not
+		require.Equal(t, 1, countOfType(ns, "dave", streaming.NotificationTypeMention))
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// Viewer platforms
+const (
+	PlatformWeb     = "web"
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformDesktop = "desktop"
+	PlatformOther   = "other"
+)
+
+// Viewer devices
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+)
+
+// DescribeClient derives the platform and device of a viewer from the client info sent by the SDK.
+// Values that cannot be determined are returned empty.
+func DescribeClient(info *livekit.ClientInfo) (platform string, device string) {
+	if info == nil {
+		return "", ""
+	}
+
+	osName := strings.ToLower(info.Os)
+	model := strings.ToLower(info.DeviceModel)
+	isIOS := strings.Contains(osName, "ios") || strings.Contains(osName, "ipados") || strings.HasPrefix(model, "iphone") || strings.HasPrefix(model, "ipad")
+	isAndroid := strings.Contains(osName, "android")
+	isDesktop := strings.Contains(osName, "mac") || strings.Contains(osName, "windows") || strings.Contains(osName, "linux") || strings.Contains(osName, "chrome os")
+
+	switch {
+	case info.Sdk == livekit.ClientInfo_JS || info.Sdk == livekit.ClientInfo_UNITY_WEB || info.Browser != "":
+		platform = PlatformWeb
+	case isIOS || info.Sdk == livekit.ClientInfo_SWIFT && !isDesktop:
+		platform = PlatformIOS
+	case isAndroid || info.Sdk == livekit.ClientInfo_ANDROID:
+		platform = PlatformAndroid
+	case isDesktop:
+		platform = PlatformDesktop
+	case info.Sdk != livekit.ClientInfo_UNKNOWN:
+		platform = PlatformOther
+	}
+
+	switch {
+	case strings.HasPrefix(model, "ipad") || strings.Contains(model, "tablet") || strings.Contains(model, "tab "):
+		device = DeviceTablet
+	case isIOS || isAndroid || platform == PlatformIOS || platform == PlatformAndroid:
+		device = DeviceMobile
+	case isDesktop:
+		device = DeviceDesktop
+	}
+
+	return platform, device
+}
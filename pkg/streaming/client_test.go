@@ -0,0 +1,52 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func TestDescribeClient(t *testing.T) {
+	cases := []struct {
+		name     string
+		info     *livekit.ClientInfo
+		platform string
+		device   string
+	}{
+		{"unknown", nil, "", ""},
+		{"desktop browser", &livekit.ClientInfo{Sdk: livekit.ClientInfo_JS, Os: "Windows", Browser: "Firefox"}, streaming.PlatformWeb, streaming.DeviceDesktop},
+		{"mobile browser", &livekit.ClientInfo{Sdk: livekit.ClientInfo_JS, Os: "Android", Browser: "Chrome"}, streaming.PlatformWeb, streaming.DeviceMobile},
+		{"iphone app", &livekit.ClientInfo{Sdk: livekit.ClientInfo_SWIFT, Os: "iOS", DeviceModel: "iPhone15,2"}, streaming.PlatformIOS, streaming.DeviceMobile},
+		{"ipad app", &livekit.ClientInfo{Sdk: livekit.ClientInfo_SWIFT, Os: "iPadOS", DeviceModel: "iPad13,4"}, streaming.PlatformIOS, streaming.DeviceTablet},
+		{"mac app", &livekit.ClientInfo{Sdk: livekit.ClientInfo_SWIFT, Os: "macOS"}, streaming.PlatformDesktop, streaming.DeviceDesktop},
+		{"android app", &livekit.ClientInfo{Sdk: livekit.ClientInfo_ANDROID, Os: "android", DeviceModel: "Pixel 8"}, streaming.PlatformAndroid, streaming.DeviceMobile},
+		{"flutter desktop", &livekit.ClientInfo{Sdk: livekit.ClientInfo_FLUTTER, Os: "linux"}, streaming.PlatformDesktop, streaming.DeviceDesktop},
+		{"server sdk", &livekit.ClientInfo{Sdk: livekit.ClientInfo_GO}, streaming.PlatformOther, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			platform, device := streaming.DescribeClient(c.info)
+			require.Equal(t, c.platform, platform)
+			require.Equal(t, c.device, device)
+		})
+	}
+}
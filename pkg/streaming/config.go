@@ -0,0 +1,72 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import "time"
+
+// Config holds the server configuration of the streaming features
+type Config struct {
+	Email EmailConfig `yaml:"email,omitempty"`
+	Push  PushConfig  `yaml:"push,omitempty"`
+	GeoIP GeoIPConfig `yaml:"geoip,omitempty"`
+
+	Analytics  AnalyticsSettings `yaml:"analytics,omitempty"`
+	Thumbnails ThumbnailSettings `yaml:"thumbnails,omitempty"`
+	Schedule   ScheduleSettings  `yaml:"schedule,omitempty"`
+	Stage      StageSettings     `yaml:"stage,omitempty"`
+}
+
+// AnalyticsSettings configures how stream analytics are aggregated, unset values take their defaults
+type AnalyticsSettings struct {
+	// how often live analytics are recalculated and pushed to subscribers
+	UpdateInterval time.Duration `yaml:"update_interval,omitempty"`
+	// bucket size of the stream timelines
+	TimelineResolution time.Duration `yaml:"timeline_resolution,omitempty"`
+	// beyond this, older timeline points are merged to a lower resolution
+	MaxTimelinePoints int `yaml:"max_timeline_points,omitempty"`
+	RetentionDays     int `yaml:"retention_days,omitempty"`
+}
+
+// ThumbnailSettings configures the still frames of live streams shown in the live directory,
+// unset values take their defaults
+type ThumbnailSettings struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// how often a frame of each live stream is captured
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// how long a thumbnail is served after its capture
+	TTL time.Duration `yaml:"ttl,omitempty"`
+	// larger frames are scaled down to this width
+	Width   int `yaml:"width,omitempty"`
+	Quality int `yaml:"quality,omitempty"`
+}
+
+// ScheduleSettings configures scheduled streams, unset values take their defaults
+type ScheduleSettings struct {
+	// how long before the start of a scheduled stream followers are reminded of it
+	ReminderLead time.Duration `yaml:"reminder_lead,omitempty"`
+	// how far from its start time, before or after, going live starts a scheduled stream. Streams that
+	// are not live by the end of the window are missed.
+	LiveWindow time.Duration `yaml:"live_window,omitempty"`
+	// how long finished, cancelled and missed streams stay on the schedule
+	Retention time.Duration `yaml:"retention,omitempty"`
+}
+
+// StageSettings configures guests brought on stage by streamers, unset values take their defaults
+type StageSettings struct {
+	// how many guests may be on stage at once, besides the streamer
+	MaxGuests int `yaml:"max_guests,omitempty"`
+	// how long an invite on stage may be accepted
+	InviteTTL time.Duration `yaml:"invite_ttl,omitempty"`
+}
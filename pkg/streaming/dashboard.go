@@ -0,0 +1,244 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"sort"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const dashboardTopChatters = 10
+
+// StreamerDashboard aggregates the streams of a streamer over a date range
+type StreamerDashboard struct {
+	StreamerID livekit.ParticipantIdentity `json:"streamer_id"`
+	From       time.Time                   `json:"from"`
+	To         time.Time                   `json:"to"`
+	LiveNow    bool                        `json:"live_now"`
+
+	StreamCount     int     `json:"stream_count"`
+	StreamHours     float64 `json:"stream_hours"`
+	TotalWatchHours float64 `json:"total_watch_hours"`
+
+	// Viewer metrics
+	TotalViewers     int           `json:"total_viewers"`
+	UniqueViewers    int           `json:"unique_viewers"`
+	AverageViewers   float64       `json:"average_viewers"`
+	PeakViewers      int           `json:"peak_viewers"`
+	AverageWatchTime time.Duration `json:"average_watch_time"`
+
+	// Follower metrics
+	FollowerCount  int                   `json:"follower_count"`
+	NewFollowers   int                   `json:"new_followers"`
+	FollowerGrowth []TimeSeriesDataPoint `json:"follower_growth"` // new followers per day
+
+	TopChatters []*TopChatter         `json:"top_chatters"`
+	Engagement  EngagementMetrics     `json:"engagement"`
+	Streams     []*StreamSummary      `json:"streams"`
+	ByPlatform  map[string]int        `json:"viewers_by_platform"`
+	ByCountry   map[string]int        `json:"viewers_by_country"`
+	ByReferrer  map[string]int        `json:"viewers_by_referrer"`
+	Reactions   map[ReactionType]int  `json:"reaction_breakdown"`
+	Timeline    []TimeSeriesDataPoint `json:"viewer_timeline"`
+}
+
+// TopChatter represents a viewer who sent the most chat messages
+type TopChatter struct {
+	UserID       livekit.ParticipantIdentity `json:"user_id"`
+	MessageCount int                         `json:"message_count"`
+}
+
+// EngagementMetrics are normalized by audience size so streams of different reach compare
+type EngagementMetrics struct {
+	TotalMessages          int     `json:"total_messages"`
+	TotalReactions         int     `json:"total_reactions"`
+	ChatParticipationRate  float64 `json:"chat_participation_rate"` // percentage of unique viewers who chatted
+	MessagesPerViewerHour  float64 `json:"messages_per_viewer_hour"`
+	ReactionsPerViewerHour float64 `json:"reactions_per_viewer_hour"`
+	ShareCount             int     `json:"share_count"`
+	LikeCount              int     `json:"like_count"`
+	FollowCount            int     `json:"follow_count"`
+}
+
+// StreamSummary is a single stream within the dashboard
+type StreamSummary struct {
+	RoomName       livekit.RoomName `json:"room_name"`
+	StartTime      time.Time        `json:"start_time"`
+	EndTime        *time.Time       `json:"end_time,omitempty"`
+	Duration       time.Duration    `json:"duration"`
+	UniqueViewers  int              `json:"unique_viewers"`
+	AverageViewers float64          `json:"average_viewers"`
+	PeakViewers    int              `json:"peak_viewers"`
+	WatchHours     float64          `json:"watch_hours"`
+	TotalMessages  int              `json:"total_messages"`
+}
+
+// NewStreamerDashboard aggregates stream records over [from, to).
+// Streams count in full when they overlap the range. followTimes are when the current followers followed,
+// so follower growth is net of those who have since unfollowed.
+func NewStreamerDashboard(
+	streamerID livekit.ParticipantIdentity,
+	from time.Time,
+	to time.Time,
+	records []*StreamRecord,
+	followTimes []time.Time,
+) *StreamerDashboard {
+	now := time.Now()
+	d := &StreamerDashboard{
+		StreamerID:     streamerID,
+		From:           from,
+		To:             to,
+		FollowerCount:  len(followTimes),
+		FollowerGrowth: make([]TimeSeriesDataPoint, 0),
+		TopChatters:    make([]*TopChatter, 0),
+		Streams:        make([]*StreamSummary, 0, len(records)),
+		ByPlatform:     make(map[string]int),
+		ByCountry:      make(map[string]int),
+		ByReferrer:     make(map[string]int),
+		Reactions:      make(map[ReactionType]int),
+		Timeline:       make([]TimeSeriesDataPoint, 0),
+	}
+
+	var streamTime, watchTime, closedWatchTime time.Duration
+	closedSessions := 0
+	uniqueViewers := make(map[livekit.ParticipantIdentity]bool)
+	chatters := make(map[livekit.ParticipantIdentity]int)
+	for _, record := range records {
+		a := record.Analytics
+		end := now
+		if a.EndTime != nil {
+			end = *a.EndTime
+		} else {
+			d.LiveNow = true
+		}
+		duration := end.Sub(a.StartTime)
+
+		summary := &StreamSummary{
+			RoomName:      a.RoomName,
+			StartTime:     a.StartTime,
+			EndTime:       a.EndTime,
+			Duration:      duration,
+			UniqueViewers: a.UniqueViewers,
+			PeakViewers:   a.PeakViewers,
+			TotalMessages: a.TotalMessages,
+		}
+
+		var streamWatchTime time.Duration
+		for _, session := range record.Sessions {
+			watched := session.WatchDuration
+			if session.LeftAt == nil {
+				watched = end.Sub(session.JoinedAt)
+			} else {
+				closedWatchTime += watched
+				closedSessions++
+			}
+			streamWatchTime += watched
+			uniqueViewers[session.ViewerID] = true
+			if session.MessagesSent > 0 {
+				chatters[session.ViewerID] += session.MessagesSent
+			}
+		}
+		summary.WatchHours = streamWatchTime.Hours()
+		if duration > 0 {
+			summary.AverageViewers = float64(streamWatchTime) / float64(duration)
+		}
+		d.Streams = append(d.Streams, summary)
+
+		streamTime += duration
+		watchTime += streamWatchTime
+		d.TotalViewers += a.TotalViewers
+		d.PeakViewers = max(d.PeakViewers, a.PeakViewers)
+		d.Engagement.TotalMessages += a.TotalMessages
+		d.Engagement.TotalReactions += a.TotalReactions
+		d.Engagement.ShareCount += a.ShareCount
+		d.Engagement.LikeCount += a.LikeCount
+		d.Engagement.FollowCount += a.FollowCount
+		for platform, count := range a.ViewersByPlatform {
+			d.ByPlatform[platform] += count
+		}
+		for country, count := range a.ViewersByCountry {
+			d.ByCountry[country] += count
+		}
+		for referrer, count := range a.ViewersByReferrer {
+			d.ByReferrer[referrer] += count
+		}
+		for reaction, count := range a.ReactionBreakdown {
+			d.Reactions[reaction] += count
+		}
+		d.Timeline = append(d.Timeline, a.ViewerTimeline...)
+	}
+
+	d.StreamCount = len(records)
+	d.StreamHours = streamTime.Hours()
+	d.TotalWatchHours = watchTime.Hours()
+	d.UniqueViewers = len(uniqueViewers)
+	if streamTime > 0 {
+		// concurrent viewers averaged over the time spent live
+		d.AverageViewers = float64(watchTime) / float64(streamTime)
+	}
+	if closedSessions > 0 {
+		d.AverageWatchTime = closedWatchTime / time.Duration(closedSessions)
+	}
+
+	if d.UniqueViewers > 0 {
+		d.Engagement.ChatParticipationRate = float64(len(chatters)) / float64(d.UniqueViewers) * 100
+	}
+	if d.TotalWatchHours > 0 {
+		d.Engagement.MessagesPerViewerHour = float64(d.Engagement.TotalMessages) / d.TotalWatchHours
+		d.Engagement.ReactionsPerViewerHour = float64(d.Engagement.TotalReactions) / d.TotalWatchHours
+	}
+
+	for userID, count := range chatters {
+		d.TopChatters = append(d.TopChatters, &TopChatter{UserID: userID, MessageCount: count})
+	}
+	sort.Slice(d.TopChatters, func(i, j int) bool {
+		if d.TopChatters[i].MessageCount != d.TopChatters[j].MessageCount {
+			return d.TopChatters[i].MessageCount > d.TopChatters[j].MessageCount
+		}
+		return d.TopChatters[i].UserID < d.TopChatters[j].UserID
+	})
+	if len(d.TopChatters) > dashboardTopChatters {
+		d.TopChatters = d.TopChatters[:dashboardTopChatters]
+	}
+
+	d.FollowerGrowth = followerGrowth(followTimes, from, to)
+	for _, point := range d.FollowerGrowth {
+		d.NewFollowers += int(point.Value)
+	}
+
+	return d
+}
+
+// followerGrowth counts follows per UTC day within [from, to)
+func followerGrowth(followTimes []time.Time, from time.Time, to time.Time) []TimeSeriesDataPoint {
+	perDay := make(map[time.Time]int)
+	for _, t := range followTimes {
+		if t.Before(from) || (!to.IsZero() && !t.Before(to)) {
+			continue
+		}
+		perDay[t.UTC().Truncate(24*time.Hour)]++
+	}
+
+	growth := make([]TimeSeriesDataPoint, 0, len(perDay))
+	for day, count := range perDay {
+		growth = append(growth, TimeSeriesDataPoint{Timestamp: day, Value: float64(count)})
+	}
+	sort.Slice(growth, func(i, j int) bool {
+		return growth[i].Timestamp.Before(growth[j].Timestamp)
+	})
+	return growth
+}
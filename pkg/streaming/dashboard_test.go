@@ -0,0 +1,90 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+// runStream records a stream in which each viewer sends the given number of chat messages
+func runStream(t *testing.T, as *streaming.AnalyticsService, roomName livekit.RoomName, stop bool, viewers map[livekit.ParticipantIdentity]int) {
+	ctx := context.Background()
+	_, err := as.StartStreamAnalytics(ctx, roomName, "streamer")
+	require.NoError(t, err)
+	for viewerID, messages := range viewers {
+		require.NoError(t, as.RecordViewerJoin(ctx, roomName, viewerID, "web", "desktop", "VN", "VN-SG"))
+		for i := 0; i < messages; i++ {
+			require.NoError(t, as.RecordChatMessage(ctx, roomName, viewerID))
+		}
+	}
+	require.NoError(t, as.RecordReaction(ctx, roomName, "alice", streaming.ReactionTypeHeart))
+	if stop {
+		for viewerID := range viewers {
+			require.NoError(t, as.RecordViewerLeave(ctx, roomName, viewerID))
+		}
+		require.NoError(t, as.StopStreamAnalytics(ctx, roomName))
+	}
+}
+
+func TestStreamerDashboard(t *testing.T) {
+	ctx := context.Background()
+	as := streaming.NewAnalyticsService(&streaming.AnalyticsConfig{RetentionDays: 1})
+
+	runStream(t, as, "room", true, map[livekit.ParticipantIdentity]int{"alice": 3, "bob": 1})
+	// a new stream in the same room keeps the previous one
+	runStream(t, as, "room", false, map[livekit.ParticipantIdentity]int{"alice": 2, "carol": 0})
+	_, err := as.StartStreamAnalytics(ctx, "other-room", "other-streamer")
+	require.NoError(t, err)
+
+	records := as.GetStreamerStreams(ctx, "streamer", time.Time{}, time.Time{})
+	require.Len(t, records, 2)
+	require.NotNil(t, records[0].Analytics.EndTime)
+	require.Nil(t, records[1].Analytics.EndTime)
+	require.Len(t, records[0].Sessions, 2)
+
+	require.Empty(t, as.GetStreamerStreams(ctx, "streamer", time.Time{}, time.Now().Add(-time.Hour)))
+
+	now := time.Now()
+	followTimes := []time.Time{now.Add(-40 * 24 * time.Hour), now.Add(-2 * time.Hour), now.Add(-time.Hour)}
+	d := streaming.NewStreamerDashboard("streamer", now.Add(-30*24*time.Hour), now.Add(time.Minute), records, followTimes)
+
+	require.True(t, d.LiveNow)
+	require.Equal(t, 2, d.StreamCount)
+	require.Equal(t, 4, d.TotalViewers)
+	require.Equal(t, 3, d.UniqueViewers)
+	require.Equal(t, 2, d.PeakViewers)
+	require.Equal(t, 3, d.FollowerCount)
+	require.Equal(t, 2, d.NewFollowers)
+	require.Equal(t, 6, d.Engagement.TotalMessages)
+	require.Equal(t, 2, d.Engagement.TotalReactions)
+	require.InDelta(t, 200.0/3, d.Engagement.ChatParticipationRate, 0.01)
+	require.Equal(t, map[string]int{"VN": 4}, d.ByCountry)
+
+	require.Len(t, d.TopChatters, 2)
+	require.Equal(t, streaming.TopChatter{UserID: "alice", MessageCount: 5}, *d.TopChatters[0])
+	require.Equal(t, streaming.TopChatter{UserID: "bob", MessageCount: 1}, *d.TopChatters[1])
+
+	require.Len(t, d.Streams, 2)
+	require.Greater(t, d.TotalWatchHours, 0.0)
+	require.Greater(t, d.AverageViewers, 0.0)
+}
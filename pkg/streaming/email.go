@@ -0,0 +1,369 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultSMTPPort           = 587
+	defaultEmailDigestPeriod  = time.Hour
+	defaultEmailMaxRetries    = 3
+	defaultEmailRetryBackoff  = 2 * time.Second
+	maxEmailDigestEntries     = 100
+	emailPermanentFailureCode = 500
+)
+
+// EmailConfig configures delivery of notifications by email
+type EmailConfig struct {
+	Enabled     bool   `yaml:"enabled,omitempty"`
+	FromAddress string `yaml:"from_address,omitempty"`
+	SMTPServer  string `yaml:"smtp_server,omitempty"`
+	SMTPPort    int    `yaml:"smtp_port,omitempty"`
+	Username    string `yaml:"username,omitempty"`
+	Password    string `yaml:"password,omitempty"`
+	// used to turn relative action URLs into absolute links
+	BaseURL string `yaml:"base_url,omitempty"`
+	// low priority notifications are batched into one digest per user at this interval
+	DigestInterval time.Duration `yaml:"digest_interval,omitempty"`
+	// number of retries after a failed delivery, backoff doubles after each attempt
+	MaxRetries   int           `yaml:"max_retries,omitempty"`
+	RetryBackoff time.Duration `yaml:"retry_backoff,omitempty"`
+}
+
+// EmailPreferences are a user's choices for email notifications
+type EmailPreferences struct {
+	OptOut bool `json:"opt_out"`
+	// send everything below urgent priority as part of the digest
+	DigestOnly bool `json:"digest_only"`
+}
+
+// EmailAddressLookup resolves the email address of a user
+type EmailAddressLookup func(ctx context.Context, userID livekit.ParticipantIdentity) (string, error)
+
+// EmailNotifier delivers notifications over SMTP
+type EmailNotifier struct {
+	config EmailConfig
+	lookup EmailAddressLookup
+	logger logger.Logger
+
+	mu          sync.Mutex
+	preferences map[livekit.ParticipantIdentity]*EmailPreferences
+	digests     map[livekit.ParticipantIdentity][]*Notification
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	deliveries *prometheus.CounterVec
+}
+
+// NewEmailNotifier creates an email notifier and starts its digest loop
+func NewEmailNotifier(config EmailConfig, lookup EmailAddressLookup) *EmailNotifier {
+	if config.SMTPPort == 0 {
+		config.SMTPPort = defaultSMTPPort
+	}
+	if config.DigestInterval == 0 {
+		config.DigestInterval = defaultEmailDigestPeriod
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultEmailMaxRetries
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = defaultEmailRetryBackoff
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	e := &EmailNotifier{
+		config:      config,
+		lookup:      lookup,
+		logger:      logger.GetLogger(),
+		preferences: make(map[livekit.ParticipantIdentity]*EmailPreferences),
+		digests:     make(map[livekit.ParticipantIdentity][]*Notification),
+		stop:        make(chan struct{}),
+		deliveries:  newEmailDeliveries(),
+	}
+
+	go e.digestWorker()
+
+	return e
+}
+
+// Stop ends the digest loop and aborts pending retries
+func (e *EmailNotifier) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+	})
+}
+
+// SetPreferences updates the email preferences of a user
+func (e *EmailNotifier) SetPreferences(userID livekit.ParticipantIdentity, prefs EmailPreferences) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.preferences[userID] = &prefs
+	if prefs.OptOut {
+		delete(e.digests, userID)
+	}
+}
+
+// GetPreferences returns the email preferences of a user
+func (e *EmailNotifier) GetPreferences(userID livekit.ParticipantIdentity) EmailPreferences {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if prefs, ok := e.preferences[userID]; ok {
+		return *prefs
+	}
+	return EmailPreferences{}
+}
+
+// RemoveUser drops the preferences and pending digest of a user
+func (e *EmailNotifier) RemoveUser(userID livekit.ParticipantIdentity) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.preferences, userID)
+	delete(e.digests, userID)
+}
+
+// SendMail sends a plain text message, for emails outside of notifications such as account confirmations
+func (e *EmailNotifier) SendMail(ctx context.Context, to, subject, body string) error {
+	text := html.EscapeString(body)
+	return e.send(to, subject, body, "<p>"+strings.ReplaceAll(text, "\n", "<br>")+"</p>")
+}
+
+// Handle is the NotificationHandler for ChannelEmail
+func (e *EmailNotifier) Handle(notification *Notification) {
+	prefs := e.GetPreferences(notification.UserID)
+	if prefs.OptOut {
+		return
+	}
+
+	if notification.Priority == PriorityLow || (prefs.DigestOnly && notification.Priority != PriorityUrgent) {
+		e.queueDigest(notification)
+		return
+	}
+
+	err := e.sendNotification(context.Background(), notification)
+	e.deliveries.WithLabelValues("notification", deliveryResult(err)).Inc()
+	if err != nil {
+		e.logger.Warnw("could not send notification email", err,
+			"userID", notification.UserID,
+			"notificationID", notification.ID,
+		)
+	}
+}
+
+// FlushDigests sends the pending digest of every user
+func (e *EmailNotifier) FlushDigests(ctx context.Context) {
+	e.mu.Lock()
+	digests := e.digests
+	e.digests = make(map[livekit.ParticipantIdentity][]*Notification)
+	e.mu.Unlock()
+
+	for userID, notifications := range digests {
+		err := e.sendDigest(ctx, userID, notifications)
+		e.deliveries.WithLabelValues("digest", deliveryResult(err)).Inc()
+		if err != nil {
+			e.logger.Warnw("could not send notification digest", err,
+				"userID", userID,
+				"count", len(notifications),
+			)
+		}
+	}
+}
+
+func (e *EmailNotifier) queueDigest(notification *Notification) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pending := append(e.digests[notification.UserID], notification)
+	if len(pending) > maxEmailDigestEntries {
+		pending = pending[len(pending)-maxEmailDigestEntries:]
+	}
+	e.digests[notification.UserID] = pending
+}
+
+func (e *EmailNotifier) digestWorker() {
+	ticker := time.NewTicker(e.config.DigestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			e.FlushDigests(context.Background())
+		}
+	}
+}
+
+func (e *EmailNotifier) sendNotification(ctx context.Context, notification *Notification) error {
+	to, err := e.lookup(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	subject, text, html, err := renderNotificationEmail(notification, e.link(notification.ActionURL))
+	if err != nil {
+		return err
+	}
+	return e.send(to, subject, text, html)
+}
+
+func (e *EmailNotifier) sendDigest(ctx context.Context, userID livekit.ParticipantIdentity, notifications []*Notification) error {
+	if e.GetPreferences(userID).OptOut {
+		return nil
+	}
+
+	to, err := e.lookup(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	items := make([]emailDigestItem, 0, len(notifications))
+	for _, n := range notifications {
+		items = append(items, emailDigestItem{Notification: n, ActionURL: e.link(n.ActionURL)})
+	}
+	subject, text, html, err := renderDigestEmail(items)
+	if err != nil {
+		return err
+	}
+	return e.send(to, subject, text, html)
+}
+
+// send delivers a message, retrying transient failures with exponential backoff
+func (e *EmailNotifier) send(to, subject, text, html string) error {
+	msg, err := buildEmailMessage(e.config.FromAddress, to, subject, text, html, time.Now())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(e.config.SMTPServer, strconv.Itoa(e.config.SMTPPort))
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.SMTPServer)
+	}
+
+	envelopeFrom := e.config.FromAddress
+	if parsed, err := mail.ParseAddress(envelopeFrom); err == nil {
+		envelopeFrom = parsed.Address
+	}
+
+	backoff := e.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = smtp.SendMail(addr, auth, envelopeFrom, []string{to}, msg)
+		if err == nil {
+			return nil
+		}
+
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) && protoErr.Code >= emailPermanentFailureCode {
+			return err
+		}
+		if attempt >= e.config.MaxRetries {
+			return err
+		}
+
+		e.logger.Debugw("email delivery failed, retrying", "error", err, "attempt", attempt+1, "backoff", backoff)
+		select {
+		case <-e.stop:
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (e *EmailNotifier) link(actionURL string) string {
+	if actionURL == "" || !strings.HasPrefix(actionURL, "/") {
+		return actionURL
+	}
+	return e.config.BaseURL + actionURL
+}
+
+// buildEmailMessage formats a multipart/alternative message with text and HTML bodies
+func buildEmailMessage(from, to, subject, text, html string, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(w)
+		if _, err = qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.TrimSuffix(from[at+1:], ">")
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("UTF-8", subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%d@%s>", now.UnixNano(), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// emailTemplateSource holds the templates of one kind of email.
+// The HTML template fills the "content" block of the shared layout.
+type emailTemplateSource struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+type emailTemplateData struct {
+	Notification *Notification
+	ActionURL    string
+}
+
+type emailDigestItem struct {
+	Notification *Notification
+	ActionURL    string
+}
+
+type emailDigestData struct {
+	Items []emailDigestItem
+}
+
+const emailHTMLLayout = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #222;">
+{{block "content" .}}{{end}}
+<p style="color: #888; font-size: 12px;">You can change your email preferences in your notification settings.</p>
+</body>
+</html>`
+
+var defaultEmailTemplateSource = emailTemplateSource{
+	Subject: `{{.Notification.Title}}`,
+	Text: `{{.Notification.Title}}
+{{with .Notification.Body}}
+{{.}}
+{{end}}{{with .ActionURL}}
+{{.}}
+{{end}}`,
+	HTML: `{{define "content"}}<h2>{{.Notification.Title}}</h2>
+{{with .Notification.Body}}<p>{{.}}</p>{{end}}
+{{with .ActionURL}}<p><a href="{{.}}">Open</a></p>{{end}}{{end}}`,
+}
+
+var emailTemplateSources = map[NotificationType]emailTemplateSource{
+	NotificationTypeStreamStarted: {
+		Subject: `{{.Notification.Title}}`,
+		Text: `{{index .Notification.Data "streamer_name"}} just went live{{with .Notification.Body}}: {{.}}{{end}}
+{{with .ActionURL}}
+Watch now: {{.}}
+{{end}}`,
+		HTML: `{{define "content"}}<h2>{{index .Notification.Data "streamer_name"}} is live!</h2>
+{{with .Notification.Body}}<p>{{.}}</p>{{end}}
+{{with .ActionURL}}<p><a href="{{.}}">Watch now</a></p>{{end}}{{end}}`,
+	},
+	NotificationTypeStreamEnded: {
+		Subject: `{{.Notification.Title}}`,
+		Text: `{{.Notification.Title}}
+{{.Notification.Body}}
+`,
+		HTML: `{{define "content"}}<h2>{{.Notification.Title}}</h2>
+<p>{{.Notification.Body}}</p>{{end}}`,
+	},
+	NotificationTypeStreamReminder: {
+		Subject: `{{.Notification.Title}}`,
+		Text: `{{index .Notification.Data "streamer_name"}} goes live at {{index .Notification.Data "start_time"}}{{with .Notification.Body}}: {{.}}{{end}}
+{{with .ActionURL}}
+Don't miss it: {{.}}
+{{end}}`,
+		HTML: `{{define "content"}}<h2>{{.Notification.Title}}</h2>
+{{with .Notification.Body}}<p>{{.}}</p>{{end}}
+<p>Starts at {{index .Notification.Data "start_time"}}</p>
+{{with .ActionURL}}<p><a href="{{.}}">See the countdown</a></p>{{end}}{{end}}`,
+	},
+	NotificationTypeNewFollower: {
+		Subject: `You have a new follower`,
+		Text: `{{.Notification.Title}}
+{{with .Notification.Body}}{{.}}
+{{end}}`,
+		HTML: `{{define "content"}}<h2>{{.Notification.Title}}</h2>
+{{with .Notification.Body}}<p>{{.}}</p>{{end}}{{end}}`,
+	},
+	NotificationTypeMention: {
+		Subject: `{{.Notification.Title}}`,
+		Text: `{{.Notification.Title}}
+
+> {{.Notification.Body}}
+{{with .ActionURL}}
+Reply: {{.}}
+{{end}}`,
+		HTML: `{{define "content"}}<h2>{{.Notification.Title}}</h2>
+<blockquote>{{.Notification.Body}}</blockquote>
+{{with .ActionURL}}<p><a href="{{.}}">Reply</a></p>{{end}}{{end}}`,
+	},
+	NotificationTypeReply: {
+		Subject: `{{.Notification.Title}}`,
+		Text: `{{.Notification.Title}}
+
+> {{.Notification.Body}}
+{{with .ActionURL}}
+View conversation: {{.}}
+{{end}}`,
+		HTML: `{{define "content"}}<h2>{{.Notification.Title}}</h2>
+<blockquote>{{.Notification.Body}}</blockquote>
+{{with .ActionURL}}<p><a href="{{.}}">View conversation</a></p>{{end}}{{end}}`,
+	},
+	NotificationTypeStageInvite: {
+		Subject: `{{.Notification.Title}}`,
+		Text: `{{.Notification.Title}}, the invite is open until {{index .Notification.Data "expires_at"}}.
+{{with .ActionURL}}
+Join the stream: {{.}}
+{{end}}`,
+		HTML: `{{define "content"}}<h2>{{.Notification.Title}}</h2>
+<p>The invite is open until {{index .Notification.Data "expires_at"}}.</p>
+{{with .ActionURL}}<p><a href="{{.}}">Join the stream</a></p>{{end}}{{end}}`,
+	},
+	NotificationTypeRaid: {
+		Subject: `{{.Notification.Title}}`,
+		Text: `{{.Notification.Title}}. {{.Notification.Body}}
+{{with .ActionURL}}
+Welcome them: {{.}}
+{{end}}`,
+		HTML: `{{define "content"}}<h2>{{.Notification.Title}}</h2>
+<p>{{.Notification.Body}}</p>
+{{with .ActionURL}}<p><a href="{{.}}">Welcome them</a></p>{{end}}{{end}}`,
+	},
+	NotificationTypeGift: {
+		Subject: `{{.Notification.Title}}`,
+		Text: `{{.Notification.Title}}
+{{with .Notification.Body}}{{.}}
+{{end}}`,
+		HTML: `{{define "content"}}<h2>&#127873; {{.Notification.Title}}</h2>
+{{with .Notification.Body}}<p>{{.}}</p>{{end}}{{end}}`,
+	},
+}
+
+const (
+	emailDigestSubject = `{{len .Items}} new notification{{if gt (len .Items) 1}}s{{end}}`
+	emailDigestText    = `Here is what you missed:
+{{range .Items}}
+- {{.Notification.Title}}{{with .Notification.Body}}: {{.}}{{end}}{{with .ActionURL}}
+  {{.}}{{end}}{{end}}
+`
+	emailDigestHTML = `{{define "content"}}<h2>Here is what you missed</h2>
+<ul>
+{{range .Items}}<li>{{if .ActionURL}}<a href="{{.ActionURL}}">{{.Notification.Title}}</a>{{else}}{{.Notification.Title}}{{end}}{{with .Notification.Body}}<br>{{.}}{{end}}</li>
+{{end}}</ul>{{end}}`
+)
+
+var (
+	emailTemplates       = map[NotificationType]*emailTemplate{}
+	defaultEmailTemplate = mustParseEmailTemplate("default", defaultEmailTemplateSource)
+	digestEmailTemplate  = mustParseEmailTemplate("digest", emailTemplateSource{
+		Subject: emailDigestSubject,
+		Text:    emailDigestText,
+		HTML:    emailDigestHTML,
+	})
+)
+
+func init() {
+	for typ, src := range emailTemplateSources {
+		emailTemplates[typ] = mustParseEmailTemplate(string(typ), src)
+	}
+}
+
+func mustParseEmailTemplate(name string, src emailTemplateSource) *emailTemplate {
+	layout := htmltemplate.Must(htmltemplate.New(name).Parse(emailHTMLLayout))
+	return &emailTemplate{
+		subject: texttemplate.Must(texttemplate.New(name).Parse(src.Subject)),
+		text:    texttemplate.Must(texttemplate.New(name).Parse(src.Text)),
+		html:    htmltemplate.Must(layout.Parse(src.HTML)),
+	}
+}
+
+func (t *emailTemplate) render(data interface{}) (subject, text, html string, err error) {
+	var buf bytes.Buffer
+	if err = t.subject.Execute(&buf, data); err != nil {
+		return
+	}
+	// header values must stay on one line
+	subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err = t.text.Execute(&buf, data); err != nil {
+		return
+	}
+	text = buf.String()
+
+	buf.Reset()
+	if err = t.html.Execute(&buf, data); err != nil {
+		return
+	}
+	html = buf.String()
+	return
+}
+
+func renderNotificationEmail(notification *Notification, actionURL string) (subject, text, html string, err error) {
+	t, ok := emailTemplates[notification.Type]
+	if !ok {
+		t = defaultEmailTemplate
+	}
+	return t.render(&emailTemplateData{Notification: notification, ActionURL: actionURL})
+}
+
+func renderDigestEmail(items []emailDigestItem) (subject, text, html string, err error) {
+	return digestEmailTemplate.render(&emailDigestData{Items: items})
+}
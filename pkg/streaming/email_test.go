@@ -0,0 +1,165 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+	"github.com/livekit/livekit-server/pkg/testutils"
+)
+
+func smtpConfig(smtp *testutils.SMTPStandIn) streaming.EmailConfig {
+	host, port := smtp.Addr()
+	return streaming.EmailConfig{
+		Enabled:        true,
+		FromAddress:    "Live <noreply@example.com>",
+		SMTPServer:     host,
+		SMTPPort:       port,
+		BaseURL:        "https://live.example.com/",
+		DigestInterval: time.Hour,
+		MaxRetries:     2,
+		RetryBackoff:   10 * time.Millisecond,
+	}
+}
+
+func emailLookup(ctx context.Context, userID livekit.ParticipantIdentity) (string, error) {
+	return string(userID) + "@example.com", nil
+}
+
+func TestEmailNotifier(t *testing.T) {
+	t.Run("sends templated email", func(t *testing.T) {
+		smtp := testutils.NewSMTPStandIn(t)
+		e := streaming.NewEmailNotifier(smtpConfig(smtp), emailLookup)
+		defer e.Stop()
+
+		e.Handle(&streaming.Notification{
+			ID:        "n1",
+			UserID:    "alice",
+			Type:      streaming.NotificationTypeStreamStarted,
+			Title:     "Bob is live!",
+			Body:      "Speedrun <any%>",
+			ActionURL: "/watch/room",
+			Priority:  streaming.PriorityHigh,
+			Data:      map[string]string{"streamer_name": "Bob"},
+		})
+
+		msg := smtp.Receive(t)
+		require.Equal(t, "noreply@example.com", msg.From)
+		require.Equal(t, []string{"alice@example.com"}, msg.To)
+
+		subject, text, html := testutils.ParseEmail(t, msg.Data)
+		require.Equal(t, "Bob is live!", subject)
+		require.Contains(t, text, "Bob just went live: Speedrun <any%>")
+		require.Contains(t, text, "https://live.example.com/watch/room")
+		require.Contains(t, html, "Speedrun &lt;any%&gt;")
+		require.Contains(t, html, `href="https://live.example.com/watch/room"`)
+	})
+
+	t.Run("uses default template for other types", func(t *testing.T) {
+		smtp := testutils.NewSMTPStandIn(t)
+		e := streaming.NewEmailNotifier(smtpConfig(smtp), emailLookup)
+		defer e.Stop()
+
+		e.Handle(&streaming.Notification{
+			UserID:   "alice",
+			Type:     streaming.NotificationTypeSystem,
+			Title:    "Maintenance tonight",
+			Body:     "Back soon",
+			Priority: streaming.PriorityMedium,
+		})
+
+		subject, text, _ := testutils.ParseEmail(t, smtp.Receive(t).Data)
+		require.Equal(t, "Maintenance tonight", subject)
+		require.Contains(t, text, "Back soon")
+	})
+
+	t.Run("respects opt out", func(t *testing.T) {
+		smtp := testutils.NewSMTPStandIn(t)
+		e := streaming.NewEmailNotifier(smtpConfig(smtp), emailLookup)
+		defer e.Stop()
+
+		e.SetPreferences("alice", streaming.EmailPreferences{OptOut: true})
+		e.Handle(&streaming.Notification{UserID: "alice", Title: "hi", Priority: streaming.PriorityUrgent})
+		e.Handle(&streaming.Notification{UserID: "alice", Title: "hi", Priority: streaming.PriorityLow})
+		e.FlushDigests(context.Background())
+		smtp.ExpectNone(t)
+	})
+
+	t.Run("batches low priority into digest", func(t *testing.T) {
+		smtp := testutils.NewSMTPStandIn(t)
+		e := streaming.NewEmailNotifier(smtpConfig(smtp), emailLookup)
+		defer e.Stop()
+
+		e.Handle(&streaming.Notification{UserID: "alice", Type: streaming.NotificationTypeStreamEnded, Title: "Bob's stream ended", Priority: streaming.PriorityLow})
+		e.Handle(&streaming.Notification{UserID: "alice", Type: streaming.NotificationTypeStreamEnded, Title: "Carol's stream ended", Priority: streaming.PriorityLow})
+		smtp.ExpectNone(t)
+
+		e.FlushDigests(context.Background())
+		msg := smtp.Receive(t)
+		require.Equal(t, []string{"alice@example.com"}, msg.To)
+		subject, text, html := testutils.ParseEmail(t, msg.Data)
+		require.Equal(t, "2 new notifications", subject)
+		require.Contains(t, text, "Bob's stream ended")
+		require.Contains(t, text, "Carol's stream ended")
+		require.Contains(t, html, "Carol&#39;s stream ended")
+
+		e.FlushDigests(context.Background())
+		smtp.ExpectNone(t)
+	})
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		smtp := testutils.NewSMTPStandIn(t)
+		smtp.Failures.Store(2)
+		e := streaming.NewEmailNotifier(smtpConfig(smtp), emailLookup)
+		defer e.Stop()
+
+		e.Handle(&streaming.Notification{UserID: "alice", Title: "hi", Priority: streaming.PriorityHigh})
+		require.Equal(t, []string{"alice@example.com"}, smtp.Receive(t).To)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		smtp := testutils.NewSMTPStandIn(t)
+		smtp.Failures.Store(3)
+		e := streaming.NewEmailNotifier(smtpConfig(smtp), emailLookup)
+		defer e.Stop()
+
+		e.Handle(&streaming.Notification{UserID: "alice", Title: "hi", Priority: streaming.PriorityHigh})
+		smtp.ExpectNone(t)
+	})
+}
+
+func TestNotificationServiceEmailChannel(t *testing.T) {
+	smtp := testutils.NewSMTPStandIn(t)
+	e := streaming.NewEmailNotifier(smtpConfig(smtp), emailLookup)
+	defer e.Stop()
+
+	config := streaming.DefaultNotificationConfig
+	config.EnableEmail = true
+	ns := streaming.NewNotificationService(&config)
+	ns.RegisterNotificationHandler(streaming.ChannelEmail, e.Handle)
+
+	_, err := ns.SendNotification(context.Background(), "alice", streaming.NotificationTypeSystem, "hello", "", streaming.PriorityHigh, "", nil)
+	require.NoError(t, err)
+
+	subject, _, _ := testutils.ParseEmail(t, smtp.Receive(t).Data)
+	require.Equal(t, "hello", subject)
+}
@@ -0,0 +1,257 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ExportFormat is the file format of an analytics export
+type ExportFormat string
+
+const (
+	ExportFormatCSV     ExportFormat = "csv"
+	ExportFormatNDJSON  ExportFormat = "ndjson"
+	ExportFormatParquet ExportFormat = "parquet"
+)
+
+// ParseExportFormat validates a format name, defaulting to CSV
+func ParseExportFormat(format string) (ExportFormat, error) {
+	switch f := ExportFormat(format); f {
+	case "":
+		return ExportFormatCSV, nil
+	case ExportFormatCSV, ExportFormatNDJSON, ExportFormatParquet:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// ContentType returns the media type of the format
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatNDJSON:
+		return "application/x-ndjson"
+	case ExportFormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "text/csv"
+	}
+}
+
+// TimelineRow is a point of a stream timeline
+type TimelineRow struct {
+	RoomName        string    `json:"room_name" parquet:"room_name"`
+	StreamStartedAt time.Time `json:"stream_started_at" parquet:"stream_started_at"`
+	Metric          string    `json:"metric" parquet:"metric"`
+	Timestamp       time.Time `json:"timestamp" parquet:"timestamp"`
+	Value           float64   `json:"value" parquet:"value"`
+}
+
+// SessionRow is a viewer session of a stream
+type SessionRow struct {
+	RoomName          string     `json:"room_name" parquet:"room_name"`
+	StreamStartedAt   time.Time  `json:"stream_started_at" parquet:"stream_started_at"`
+	ViewerID          string     `json:"viewer_id" parquet:"viewer_id"`
+	JoinedAt          time.Time  `json:"joined_at" parquet:"joined_at"`
+	LeftAt            *time.Time `json:"left_at,omitempty" parquet:"left_at,optional"`
+	WatchSeconds      float64    `json:"watch_seconds" parquet:"watch_seconds"`
+	MessagesSent      int64      `json:"messages_sent" parquet:"messages_sent"`
+	ReactionsSent     int64      `json:"reactions_sent" parquet:"reactions_sent"`
+	Platform          string     `json:"platform" parquet:"platform"`
+	Device            string     `json:"device" parquet:"device"`
+	Country           string     `json:"country" parquet:"country"`
+	Region            string     `json:"region" parquet:"region"`
+	AvgBitrate        int64      `json:"avg_bitrate" parquet:"avg_bitrate"`
+	BufferingCount    int64      `json:"buffering_count" parquet:"buffering_count"`
+	QualityLevel      string     `json:"quality_level" parquet:"quality_level"`
+	ConnectionQuality string     `json:"connection_quality" parquet:"connection_quality"`
+	Referrer          string     `json:"referrer" parquet:"referrer"`
+}
+
+var timelineColumns = []string{"room_name", "stream_started_at", "metric", "timestamp", "value"}
+
+func (r TimelineRow) csvRecord() []string {
+	return []string{
+		csvText(r.RoomName),
+		r.StreamStartedAt.UTC().Format(time.RFC3339),
+		csvText(r.Metric),
+		r.Timestamp.UTC().Format(time.RFC3339),
+		strconv.FormatFloat(r.Value, 'f', -1, 64),
+	}
+}
+
+var sessionColumns = []string{
+	"room_name", "stream_started_at", "viewer_id", "joined_at", "left_at", "watch_seconds",
+	"messages_sent", "reactions_sent", "platform", "device", "country", "region",
+	"avg_bitrate", "buffering_count", "quality_level", "connection_quality",
+	"referrer",
+}
+
+func (r SessionRow) csvRecord() []string {
+	leftAt := ""
+	if r.LeftAt != nil {
+		leftAt = r.LeftAt.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		csvText(r.RoomName),
+		r.StreamStartedAt.UTC().Format(time.RFC3339),
+		csvText(r.ViewerID),
+		r.JoinedAt.UTC().Format(time.RFC3339),
+		leftAt,
+		strconv.FormatFloat(r.WatchSeconds, 'f', 3, 64),
+		strconv.FormatInt(r.MessagesSent, 10),
+		strconv.FormatInt(r.ReactionsSent, 10),
+		csvText(r.Platform),
+		csvText(r.Device),
+		csvText(r.Country),
+		csvText(r.Region),
+		strconv.FormatInt(r.AvgBitrate, 10),
+		strconv.FormatInt(r.BufferingCount, 10),
+		csvText(r.QualityLevel),
+		csvText(r.ConnectionQuality),
+		csvText(r.Referrer),
+	}
+}
+
+// csvText keeps spreadsheets from evaluating text cells as formulas. Room names, identities and referrers come
+// from users, a leading quote makes spreadsheet apps show them as text.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// TimelineRows flattens the timelines of streams
+func TimelineRows(records []*StreamRecord) []TimelineRow {
+	var rows []TimelineRow
+	for _, record := range records {
+		a := record.Analytics
+		for _, timeline := range []struct {
+			metric string
+			points []TimeSeriesDataPoint
+		}{
+			{"viewers", a.ViewerTimeline},
+			{"chat", a.ChatTimeline},
+			{"reactions", a.ReactionTimeline},
+			{"bitrate", a.BitrateTimeline},
+		} {
+			for _, point := range timeline.points {
+				rows = append(rows, TimelineRow{
+					RoomName:        string(a.RoomName),
+					StreamStartedAt: a.StartTime,
+					Metric:          timeline.metric,
+					Timestamp:       point.Timestamp,
+					Value:           point.Value,
+				})
+			}
+		}
+	}
+	return rows
+}
+
+// SessionRows flattens the viewer sessions of streams
+func SessionRows(records []*StreamRecord) []SessionRow {
+	var rows []SessionRow
+	now := time.Now()
+	for _, record := range records {
+		for _, session := range record.Sessions {
+			watched := session.WatchDuration
+			if session.LeftAt == nil {
+				watched = now.Sub(session.JoinedAt)
+			}
+			rows = append(rows, SessionRow{
+				RoomName:          string(record.Analytics.RoomName),
+				StreamStartedAt:   record.Analytics.StartTime,
+				ViewerID:          string(session.ViewerID),
+				JoinedAt:          session.JoinedAt,
+				LeftAt:            session.LeftAt,
+				WatchSeconds:      watched.Seconds(),
+				MessagesSent:      int64(session.MessagesSent),
+				ReactionsSent:     int64(session.ReactionsSent),
+				Platform:          session.Platform,
+				Device:            session.Device,
+				Country:           session.Country,
+				Region:            session.Region,
+				AvgBitrate:        int64(session.AvgBitrate),
+				BufferingCount:    int64(session.BufferingCount),
+				QualityLevel:      session.QualityLevel,
+				ConnectionQuality: session.ConnectionQuality,
+				Referrer:          session.Referrer,
+			})
+		}
+	}
+	return rows
+}
+
+type exportRow interface {
+	TimelineRow | SessionRow
+	csvRecord() []string
+}
+
+// WriteTimelineExport writes timeline rows in the given format
+func WriteTimelineExport(w io.Writer, format ExportFormat, rows []TimelineRow) error {
+	return writeExport(w, format, timelineColumns, rows)
+}
+
+// WriteSessionExport writes session rows in the given format
+func WriteSessionExport(w io.Writer, format ExportFormat, rows []SessionRow) error {
+	return writeExport(w, format, sessionColumns, rows)
+}
+
+func writeExport[T exportRow](w io.Writer, format ExportFormat, columns []string, rows []T) error {
+	switch format {
+	case ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := cw.Write(row.csvRecord()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case ExportFormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case ExportFormatParquet:
+		pw := parquet.NewGenericWriter[T](w)
+		if _, err := pw.Write(rows); err != nil {
+			return err
+		}
+		return pw.Close()
+
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func exportRecords(t *testing.T) []*streaming.StreamRecord {
+	as := streaming.NewAnalyticsService(&streaming.AnalyticsConfig{RetentionDays: 1})
+	runStream(t, as, "room", true, map[livekit.ParticipantIdentity]int{"alice": 2})
+	runStream(t, as, "room", false, map[livekit.ParticipantIdentity]int{"bob": 1})
+	return as.GetStreamerStreams(context.Background(), "streamer", time.Time{}, time.Time{})
+}
+
+func TestExportFormats(t *testing.T) {
+	records := exportRecords(t)
+	rows := streaming.SessionRows(records)
+	require.Len(t, rows, 2)
+	require.Equal(t, "alice", rows[0].ViewerID)
+	require.NotNil(t, rows[0].LeftAt)
+	require.Nil(t, rows[1].LeftAt)
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, streaming.WriteSessionExport(&buf, streaming.ExportFormatCSV, rows))
+		lines, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, lines, 3)
+		require.Equal(t, "viewer_id", lines[0][2])
+		require.Equal(t, "alice", lines[1][2])
+		require.Equal(t, "2", lines[1][6])
+		require.Empty(t, lines[2][4])
+	})
+
+	t.Run("csv formulas", func(t *testing.T) {
+		injected := []streaming.SessionRow{{
+			RoomName: "room",
+			ViewerID: "=HYPERLINK(\"https://evil.example.com\")",
+			Device:   "+1",
+			Platform: "-2",
+			Region:   "@SUM(A1)",
+			Country:  "\tUS",
+			Referrer: "https://example.com/=x",
+		}}
+		var buf bytes.Buffer
+		require.NoError(t, streaming.WriteSessionExport(&buf, streaming.ExportFormatCSV, injected))
+		lines, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, "'=HYPERLINK(\"https://evil.example.com\")", lines[1][2])
+		require.Equal(t, "'-2", lines[1][8])
+		require.Equal(t, "'+1", lines[1][9])
+		require.Equal(t, "'\tUS", lines[1][10])
+		require.Equal(t, "'@SUM(A1)", lines[1][11])
+		require.Equal(t, "https://example.com/=x", lines[1][16])
+		require.Equal(t, "room", lines[1][0])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, streaming.WriteSessionExport(&buf, streaming.ExportFormatNDJSON, rows))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		var row map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+		require.Equal(t, "bob", row["viewer_id"])
+		require.NotContains(t, row, "left_at")
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, streaming.WriteSessionExport(&buf, streaming.ExportFormatParquet, rows))
+		read, err := parquet.Read[streaming.SessionRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Len(t, read, 2)
+		require.Equal(t, "alice", read[0].ViewerID)
+		require.Equal(t, int64(2), read[0].MessagesSent)
+		require.Equal(t, rows[0].JoinedAt.UnixMilli(), read[0].JoinedAt.UnixMilli())
+		require.NotNil(t, read[0].LeftAt)
+		require.Nil(t, read[1].LeftAt)
+	})
+
+	t.Run("timelines", func(t *testing.T) {
+		timeline := streaming.TimelineRows([]*streaming.StreamRecord{{
+			Analytics: &streaming.StreamAnalytics{
+				RoomName:        "room",
+				ViewerTimeline:  []streaming.TimeSeriesDataPoint{{Timestamp: time.Now(), Value: 3}},
+				BitrateTimeline: []streaming.TimeSeriesDataPoint{{Timestamp: time.Now(), Value: 2500}},
+			},
+		}})
+		require.Len(t, timeline, 2)
+		require.Equal(t, "bitrate", timeline[1].Metric)
+
+		var buf bytes.Buffer
+		require.NoError(t, streaming.WriteTimelineExport(&buf, streaming.ExportFormatParquet, timeline))
+		read, err := parquet.Read[streaming.TimelineRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Equal(t, 2500.0, read[1].Value)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := streaming.ParseExportFormat("xlsx")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoIPConfig configures viewer location lookups
+type GeoIPConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// path of a MaxMind format database, e.g. GeoLite2-City.mmdb or GeoLite2-Country.mmdb
+	DatabasePath string `yaml:"database_path,omitempty"`
+}
+
+// GeoLocation is where an address is located.
+// Region is an ISO 3166-2 subdivision code such as "US-CA", it is empty when unknown.
+type GeoLocation struct {
+	Country string
+	Region  string
+}
+
+// GeoResolver resolves the location of an IP address
+type GeoResolver interface {
+	Resolve(ip netip.Addr) (GeoLocation, error)
+}
+
+// MMDBResolver resolves locations from a local MaxMind format database
+type MMDBResolver struct {
+	reader *maxminddb.Reader
+}
+
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+}
+
+// OpenMMDBResolver opens the database at path
+func OpenMMDBResolver(path string) (*MMDBResolver, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open geoip database: %w", err)
+	}
+	return &MMDBResolver{reader: reader}, nil
+}
+
+func (r *MMDBResolver) Resolve(ip netip.Addr) (GeoLocation, error) {
+	var record mmdbRecord
+	if err := r.reader.Lookup(net.IP(ip.AsSlice()), &record); err != nil {
+		return GeoLocation{}, err
+	}
+
+	location := GeoLocation{Country: record.Country.ISOCode}
+	if location.Country != "" && len(record.Subdivisions) > 0 && record.Subdivisions[0].ISOCode != "" {
+		location.Region = location.Country + "-" + record.Subdivisions[0].ISOCode
+	}
+	return location, nil
+}
+
+// Close releases the database
+func (r *MMDBResolver) Close() error {
+	return r.reader.Close()
+}
+
+// ParseTrustedProxies parses a list of addresses and CIDR ranges
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// parseClientAddress parses the address of a client info, which may hold a forwarded chain
+func parseClientAddress(address string) (netip.Addr, bool) {
+	address, _, _ = strings.Cut(address, ",")
+	addr, err := netip.ParseAddr(strings.TrimSpace(address))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
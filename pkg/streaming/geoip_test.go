@@ -0,0 +1,199 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+// mmdbValue encodes a value in the MaxMind DB data format, supporting the types a city database uses
+func mmdbValue(v interface{}) []byte {
+	control := func(typ byte, size int) []byte {
+		if typ > 7 {
+			return []byte{byte(size), typ - 7}
+		}
+		return []byte{typ<<5 | byte(size)}
+	}
+	unsigned := func(typ byte, n uint64) []byte {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], n)
+		trimmed := bytes.TrimLeft(b[:], "\x00")
+		return append(control(typ, len(trimmed)), trimmed...)
+	}
+
+	switch v := v.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case uint16:
+		return unsigned(5, uint64(v))
+	case uint32:
+		return unsigned(6, uint64(v))
+	case uint64:
+		return unsigned(9, v)
+	case []interface{}:
+		out := control(11, len(v))
+		for _, item := range v {
+			out = append(out, mmdbValue(item)...)
+		}
+		return out
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := control(7, len(v))
+		for _, key := range keys {
+			out = append(out, mmdbValue(key)...)
+			out = append(out, mmdbValue(v[key])...)
+		}
+		return out
+	}
+	panic("unsupported type")
+}
+
+// writeMMDB writes an IPv4 database holding a single record for a /8 network
+func writeMMDB(t *testing.T, network netip.Prefix, record map[string]interface{}) string {
+	const nodeCount = 8
+	var tree []byte
+	record24 := func(n uint32) []byte { return []byte{byte(n >> 16), byte(n >> 8), byte(n)} }
+	first := network.Addr().As4()[0]
+	for i := 0; i < nodeCount; i++ {
+		next := uint32(i + 1)
+		if i == nodeCount-1 {
+			// data section offset 0
+			next = nodeCount + 16
+		}
+		left, right := next, uint32(nodeCount)
+		if first&(0x80>>i) != 0 {
+			left, right = right, left
+		}
+		tree = append(tree, record24(left)...)
+		tree = append(tree, record24(right)...)
+	}
+
+	var db []byte
+	db = append(db, tree...)
+	db = append(db, make([]byte, 16)...)
+	db = append(db, mmdbValue(record)...)
+	db = append(db, "\xab\xcd\xefMaxMind.com"...)
+	db = append(db, mmdbValue(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               "GeoLite2-City",
+		"description":                 map[string]interface{}{"en": "test"},
+		"ip_version":                  uint16(4),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})...)
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	require.NoError(t, os.WriteFile(path, db, 0o600))
+	return path
+}
+
+func TestMMDBResolver(t *testing.T) {
+	path := writeMMDB(t, netip.MustParsePrefix("81.0.0.0/8"), map[string]interface{}{
+		"country":      map[string]interface{}{"iso_code": "DE"},
+		"subdivisions": []interface{}{map[string]interface{}{"iso_code": "BE"}},
+	})
+	resolver, err := streaming.OpenMMDBResolver(path)
+	require.NoError(t, err)
+	defer resolver.Close()
+
+	location, err := resolver.Resolve(netip.MustParseAddr("81.2.69.160"))
+	require.NoError(t, err)
+	require.Equal(t, streaming.GeoLocation{Country: "DE", Region: "DE-BE"}, location)
+
+	location, err = resolver.Resolve(netip.MustParseAddr("82.2.69.160"))
+	require.NoError(t, err)
+	require.Empty(t, location)
+
+	_, err = streaming.OpenMMDBResolver(filepath.Join(t.TempDir(), "missing.mmdb"))
+	require.Error(t, err)
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := streaming.ParseTrustedProxies([]string{"10.1.0.0/16", "192.0.2.1", "2001:db8::/32"})
+	require.NoError(t, err)
+	require.True(t, prefixes[0].Contains(netip.MustParseAddr("10.1.200.3")))
+	require.True(t, prefixes[1].Contains(netip.MustParseAddr("192.0.2.1")))
+	require.False(t, prefixes[1].Contains(netip.MustParseAddr("192.0.2.2")))
+	require.True(t, prefixes[2].Contains(netip.MustParseAddr("2001:db8::1")))
+
+	_, err = streaming.ParseTrustedProxies([]string{"proxy.local"})
+	require.Error(t, err)
+}
+
+type geoResolverFunc func(ip netip.Addr) (streaming.GeoLocation, error)
+
+func (f geoResolverFunc) Resolve(ip netip.Addr) (streaming.GeoLocation, error) {
+	return f(ip)
+}
+
+func TestLocateViewer(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	resolver := geoResolverFunc(func(ip netip.Addr) (streaming.GeoLocation, error) {
+		<-release
+		if ip == netip.MustParseAddr("203.0.113.7") {
+			return streaming.GeoLocation{Country: "VN", Region: "VN-SG"}, nil
+		}
+		return streaming.GeoLocation{}, nil
+	})
+
+	as := streaming.NewAnalyticsService(&streaming.AnalyticsConfig{EnableGeoIP: true})
+	as.SetGeoResolver(resolver)
+	_, err := as.StartStreamAnalytics(ctx, "room", "streamer")
+	require.NoError(t, err)
+
+	// lookups happen in the background, a slow resolver does not hold up joins
+	require.NoError(t, as.RecordViewerJoin(ctx, "room", "alice", "web", "desktop", "", ""))
+	as.LocateViewer("room", "alice", "203.0.113.7, 10.0.0.1")
+	require.NoError(t, as.RecordViewerJoin(ctx, "room", "bob", "web", "desktop", "", ""))
+	as.LocateViewer("room", "bob", "192.168.1.20")
+	as.LocateViewer("room", "nobody", "203.0.113.7")
+	close(release)
+
+	require.Eventually(t, func() bool {
+		analytics, _ := as.GetStreamAnalytics(ctx, "room")
+		return analytics.ViewersByCountry["VN"] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	analytics, _ := as.GetStreamAnalytics(ctx, "room")
+	require.Equal(t, map[string]int{"VN": 1}, analytics.ViewersByCountry)
+	require.Equal(t, map[string]int{"VN-SG": 1}, analytics.ViewersByRegion)
+	sessions, _ := as.GetViewerSessions(ctx, "room")
+	for _, session := range sessions {
+		if session.ViewerID == "alice" {
+			require.Equal(t, "VN", session.Country)
+			require.Equal(t, "VN-SG", session.Region)
+		}
+	}
+}
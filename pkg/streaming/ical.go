@@ -0,0 +1,128 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ICalendarContentType is the media type of iCalendar feeds
+const ICalendarContentType = "text/calendar; charset=utf-8"
+
+const (
+	icalTimeFormat = "20060102T150405Z"
+	// lines longer than this many octets are folded, RFC 5545 section 3.1
+	icalLineLength = 75
+	// how often calendar apps should fetch the feed again
+	icalRefreshInterval = "PT1H"
+)
+
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// ICalendarFeed is the calendar of the scheduled streams of a channel
+type ICalendarFeed struct {
+	Name string
+	// qualifies the UIDs of events, e.g. the host serving the feed
+	Domain  string
+	Streams []*ScheduledStream
+	// link of the event of a stream, optional
+	URL func(*ScheduledStream) string
+}
+
+// Encode renders the feed as an iCalendar (RFC 5545) document, one event per stream
+func (f *ICalendarFeed) Encode() []byte {
+	var buf bytes.Buffer
+	writeICalLine(&buf, "BEGIN", "VCALENDAR")
+	writeICalLine(&buf, "VERSION", "2.0")
+	writeICalLine(&buf, "PRODID", "-//LiveKit//Scheduled Streams//EN")
+	writeICalLine(&buf, "CALSCALE", "GREGORIAN")
+	writeICalLine(&buf, "METHOD", "PUBLISH")
+	if f.Name != "" {
+		writeICalLine(&buf, "X-WR-CALNAME", escapeICalText(f.Name))
+	}
+	writeICalLine(&buf, "REFRESH-INTERVAL;VALUE=DURATION", icalRefreshInterval)
+	writeICalLine(&buf, "X-PUBLISHED-TTL", icalRefreshInterval)
+
+	for _, stream := range f.Streams {
+		writeICalLine(&buf, "BEGIN", "VEVENT")
+		writeICalLine(&buf, "UID", stream.ID+"@"+f.Domain)
+		writeICalLine(&buf, "DTSTAMP", formatICalTime(stream.UpdatedAt))
+		writeICalLine(&buf, "LAST-MODIFIED", formatICalTime(stream.UpdatedAt))
+		start := stream.StartTime
+		if stream.LiveAt != nil {
+			start = *stream.LiveAt
+		}
+		writeICalLine(&buf, "DTSTART", formatICalTime(start))
+		if stream.EndedAt != nil {
+			writeICalLine(&buf, "DTEND", formatICalTime(*stream.EndedAt))
+		}
+		summary := stream.Title
+		if stream.StreamerName != "" {
+			summary = stream.StreamerName + ": " + stream.Title
+		}
+		writeICalLine(&buf, "SUMMARY", escapeICalText(summary))
+		if stream.Description != "" {
+			writeICalLine(&buf, "DESCRIPTION", escapeICalText(stream.Description))
+		}
+		if stream.Category != "" {
+			writeICalLine(&buf, "CATEGORIES", escapeICalText(stream.Category))
+		}
+		if f.URL != nil {
+			if url := f.URL(stream); url != "" {
+				writeICalLine(&buf, "URL", url)
+			}
+		}
+		switch stream.Status {
+		case ScheduledStreamCancelled, ScheduledStreamMissed:
+			writeICalLine(&buf, "STATUS", "CANCELLED")
+		default:
+			writeICalLine(&buf, "STATUS", "CONFIRMED")
+		}
+		writeICalLine(&buf, "END", "VEVENT")
+	}
+
+	writeICalLine(&buf, "END", "VCALENDAR")
+	return buf.Bytes()
+}
+
+func escapeICalText(s string) string {
+	return icalTextEscaper.Replace(s)
+}
+
+func formatICalTime(t time.Time) string {
+	return t.UTC().Format(icalTimeFormat)
+}
+
+// writeICalLine writes a content line, folding it without splitting characters
+func writeICalLine(buf *bytes.Buffer, name, value string) {
+	line := name + ":" + value
+	limit := icalLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of continuation lines counts towards their length
+		limit = icalLineLength - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
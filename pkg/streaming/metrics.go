@@ -0,0 +1,374 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "livekit"
+	metricsSubsystem = "streaming"
+
+	// label value of anything outside the known set, so clients cannot grow label cardinality
+	otherLabelValue = "other"
+)
+
+// streamViewerBuckets are the buckets of the distribution of viewers over live streams
+var streamViewerBuckets = []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000}
+
+func newCounter(name, help string) prometheus.Counter {
+	return prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      name,
+		Help:      help,
+	})
+}
+
+func newCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      name,
+		Help:      help,
+	}, labels)
+}
+
+func newDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, metricsSubsystem, name), help, labels, nil)
+}
+
+// boundedLabel returns value if it is one of known, otherwise "other"
+func boundedLabel[T ~string](value T, known ...T) string {
+	if slices.Contains(known, value) {
+		return string(value)
+	}
+	return otherLabelValue
+}
+
+// serviceCollector is the prometheus collector of a streaming service: counters the service updates as events happen,
+// and metrics read from the service state on every scrape
+type serviceCollector struct {
+	counters []prometheus.Collector
+	descs    []*prometheus.Desc
+	scrape   func(ch chan<- prometheus.Metric)
+}
+
+func (c *serviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, counter := range c.counters {
+		counter.Describe(ch)
+	}
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+func (c *serviceCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, counter := range c.counters {
+		counter.Collect(ch)
+	}
+	if c.scrape != nil {
+		c.scrape(ch)
+	}
+}
+
+// ---------------------------------------------
+
+type analyticsMetrics struct {
+	liveStreams   *prometheus.Desc
+	viewers       *prometheus.Desc
+	streamViewers *prometheus.Desc
+}
+
+func newAnalyticsMetrics() *analyticsMetrics {
+	return &analyticsMetrics{
+		liveStreams:   newDesc("live_streams", "Streams currently live."),
+		viewers:       newDesc("viewers", "Viewers currently watching a live stream."),
+		streamViewers: newDesc("stream_viewers", "Distribution of current viewers over live streams."),
+	}
+}
+
+// Collector returns the prometheus collector of live streams and their viewers
+func (as *AnalyticsService) Collector() prometheus.Collector {
+	m := as.metrics
+	return &serviceCollector{
+		descs: []*prometheus.Desc{m.liveStreams, m.viewers, m.streamViewers},
+		scrape: func(ch chan<- prometheus.Metric) {
+			as.mu.RLock()
+			live, viewers := 0, 0
+			buckets := make(map[float64]uint64, len(streamViewerBuckets))
+			for _, analytics := range as.streamAnalytics {
+				if analytics.EndTime != nil {
+					continue
+				}
+				live++
+				viewers += analytics.CurrentViewers
+				for _, bound := range streamViewerBuckets {
+					if float64(analytics.CurrentViewers) <= bound {
+						buckets[bound]++
+					}
+				}
+			}
+			as.mu.RUnlock()
+
+			ch <- prometheus.MustNewConstMetric(m.liveStreams, prometheus.GaugeValue, float64(live))
+			ch <- prometheus.MustNewConstMetric(m.viewers, prometheus.GaugeValue, float64(viewers))
+			ch <- prometheus.MustNewConstHistogram(m.streamViewers, uint64(live), float64(viewers), buckets)
+		},
+	}
+}
+
+// ---------------------------------------------
+
+const (
+	chatRejectNoRoom      = "no_room"
+	chatRejectMuted       = "muted"
+	chatRejectTooLong     = "too_long"
+	chatRejectRateLimited = "rate_limited"
+	chatRejectSlowMode    = "slow_mode"
+)
+
+type chatMetrics struct {
+	messages  *prometheus.CounterVec
+	rejected  *prometheus.CounterVec
+	chatRooms *prometheus.Desc
+}
+
+func newChatMetrics() *chatMetrics {
+	return &chatMetrics{
+		messages:  newCounterVec("chat_messages_total", "Chat messages sent.", "type"),
+		rejected:  newCounterVec("chat_messages_rejected_total", "Chat messages rejected, by reason.", "reason"),
+		chatRooms: newDesc("chat_rooms", "Open chat rooms."),
+	}
+}
+
+func (m *chatMetrics) messageSent(messageType ChatMessageType) {
+	m.messages.WithLabelValues(boundedLabel(messageType,
+		ChatMessageTypeText,
+		ChatMessageTypeEmoji,
+		ChatMessageTypeSystemNotice,
+		ChatMessageTypeGift,
+		ChatMessageTypeJoinLeave,
+	)).Inc()
+}
+
+// Collector returns the prometheus collector of chat activity
+func (cs *ChatService) Collector() prometheus.Collector {
+	m := cs.metrics
+	return &serviceCollector{
+		counters: []prometheus.Collector{m.messages, m.rejected},
+		descs:    []*prometheus.Desc{m.chatRooms},
+		scrape: func(ch chan<- prometheus.Metric) {
+			cs.mu.RLock()
+			rooms := len(cs.rooms)
+			cs.mu.RUnlock()
+			ch <- prometheus.MustNewConstMetric(m.chatRooms, prometheus.GaugeValue, float64(rooms))
+		},
+	}
+}
+
+// ---------------------------------------------
+
+type reactionMetrics struct {
+	reactions   *prometheus.CounterVec
+	rateLimited prometheus.Counter
+}
+
+func newReactionMetrics() *reactionMetrics {
+	return &reactionMetrics{
+		reactions:   newCounterVec("reactions_total", "Reactions sent, by type.", "type"),
+		rateLimited: newCounter("reactions_rate_limited_total", "Reactions rejected by rate limits."),
+	}
+}
+
+func (m *reactionMetrics) reactionSent(reactionType ReactionType) {
+	m.reactions.WithLabelValues(boundedLabel(reactionType,
+		ReactionTypeLike,
+		ReactionTypeHeart,
+		ReactionTypeWow,
+		ReactionTypeLaugh,
+		ReactionTypeSad,
+		ReactionTypeFire,
+		ReactionTypeClap,
+		ReactionTypeParty,
+	)).Inc()
+}
+
+// Collector returns the prometheus collector of reactions
+func (rs *ReactionService) Collector() prometheus.Collector {
+	return &serviceCollector{
+		counters: []prometheus.Collector{rs.metrics.reactions, rs.metrics.rateLimited},
+	}
+}
+
+// ---------------------------------------------
+
+type vodMetrics struct {
+	playbacks       prometheus.Counter
+	activePlaybacks *prometheus.Desc
+	recordings      *prometheus.Desc
+}
+
+func newVODMetrics() *vodMetrics {
+	return &vodMetrics{
+		playbacks:       newCounter("vod_playbacks_total", "Recording playbacks started."),
+		activePlaybacks: newDesc("vod_active_playbacks", "Recording playbacks in progress."),
+		recordings:      newDesc("vod_recordings", "Recordings, by status.", "status"),
+	}
+}
+
+// Collector returns the prometheus collector of recordings and their playbacks
+func (vs *VODService) Collector() prometheus.Collector {
+	m := vs.metrics
+	return &serviceCollector{
+		counters: []prometheus.Collector{m.playbacks},
+		descs:    []*prometheus.Desc{m.activePlaybacks, m.recordings},
+		scrape: func(ch chan<- prometheus.Metric) {
+			vs.mu.RLock()
+			active := len(vs.playbackSessions)
+			statuses := make(map[string]int)
+			for _, recording := range vs.recordings {
+				statuses[boundedLabel(recording.Status,
+					VODStatusRecording,
+					VODStatusProcessing,
+					VODStatusReady,
+					VODStatusFailed,
+					VODStatusArchived,
+					VODStatusDeleted,
+				)]++
+			}
+			vs.mu.RUnlock()
+
+			ch <- prometheus.MustNewConstMetric(m.activePlaybacks, prometheus.GaugeValue, float64(active))
+			for status, count := range statuses {
+				ch <- prometheus.MustNewConstMetric(m.recordings, prometheus.GaugeValue, float64(count), status)
+			}
+		},
+	}
+}
+
+// ---------------------------------------------
+
+type notificationMetrics struct {
+	notifications *prometheus.CounterVec
+}
+
+func newNotificationMetrics() *notificationMetrics {
+	return &notificationMetrics{
+		notifications: newCounterVec("notifications_total", "Notifications created, by type.", "type"),
+	}
+}
+
+func (m *notificationMetrics) notificationCreated(notificationType NotificationType) {
+	m.notifications.WithLabelValues(boundedLabel(notificationType,
+		NotificationTypeStreamStarted,
+		NotificationTypeStreamEnded,
+		NotificationTypeStreamReminder,
+		NotificationTypeNewFollower,
+		NotificationTypeMention,
+		NotificationTypeReply,
+		NotificationTypeModerator,
+		NotificationTypeGift,
+		NotificationTypeStageInvite,
+		NotificationTypeRaid,
+		NotificationTypeSystem,
+	)).Inc()
+}
+
+// Collector returns the prometheus collector of notifications
+func (ns *NotificationService) Collector() prometheus.Collector {
+	return &serviceCollector{
+		counters: []prometheus.Collector{ns.metrics.notifications},
+	}
+}
+
+const (
+	deliveryResultSent    = "sent"
+	deliveryResultFailed  = "failed"
+	deliveryResultExpired = "expired"
+)
+
+// Collector returns the prometheus collector of notification and digest emails
+func (e *EmailNotifier) Collector() prometheus.Collector {
+	return &serviceCollector{
+		counters: []prometheus.Collector{e.deliveries},
+	}
+}
+
+func newEmailDeliveries() *prometheus.CounterVec {
+	return newCounterVec("email_deliveries_total", "Notification emails, by kind (notification or digest) and result.", "kind", "result")
+}
+
+// Collector returns the prometheus collector of web push deliveries
+func (p *PushNotifier) Collector() prometheus.Collector {
+	return &serviceCollector{
+		counters: []prometheus.Collector{p.deliveries},
+	}
+}
+
+func newPushDeliveries() *prometheus.CounterVec {
+	return newCounterVec("push_deliveries_total", "Web push messages, by result. Expired subscriptions are pruned.", "result")
+}
+
+func deliveryResult(err error) string {
+	if err != nil {
+		return deliveryResultFailed
+	}
+	return deliveryResultSent
+}
+
+// ---------------------------------------------
+
+const (
+	streamKeyValid    = "valid"
+	streamKeyNotFound = "not_found"
+	streamKeyInactive = "inactive"
+	streamKeyExpired  = "expired"
+)
+
+type streamKeyMetrics struct {
+	validations *prometheus.CounterVec
+	activeKeys  *prometheus.Desc
+}
+
+func newStreamKeyMetrics() *streamKeyMetrics {
+	return &streamKeyMetrics{
+		validations: newCounterVec("stream_key_validations_total", "Stream key validations, by result.", "result"),
+		activeKeys:  newDesc("stream_keys_active", "Stream keys that are active."),
+	}
+}
+
+// Collector returns the prometheus collector of stream keys
+func (m *StreamKeyManager) Collector() prometheus.Collector {
+	metrics := m.metrics
+	return &serviceCollector{
+		counters: []prometheus.Collector{metrics.validations},
+		descs:    []*prometheus.Desc{metrics.activeKeys},
+		scrape: func(ch chan<- prometheus.Metric) {
+			m.mu.RLock()
+			active := 0
+			for _, key := range m.keys {
+				if key.IsActive {
+					active++
+				}
+			}
+			m.mu.RUnlock()
+			ch <- prometheus.MustNewConstMetric(metrics.activeKeys, prometheus.GaugeValue, float64(active))
+		},
+	}
+}
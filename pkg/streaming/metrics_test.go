@@ -0,0 +1,122 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func TestStreamingMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("chat", func(t *testing.T) {
+		cs := streaming.NewChatService()
+		_, err := cs.CreateChatRoom(ctx, "room", nil)
+		require.NoError(t, err)
+
+		_, err = cs.SendMessage(ctx, "room", "alice", "hi", streaming.ChatMessageTypeText, nil)
+		require.NoError(t, err)
+		// unknown types do not grow the label set
+		_, err = cs.SendMessage(ctx, "room", "alice", "hi", "made-up", nil)
+		require.NoError(t, err)
+		_, err = cs.SendMessage(ctx, "missing", "alice", "hi", streaming.ChatMessageTypeText, nil)
+		require.Error(t, err)
+
+		require.NoError(t, testutil.CollectAndCompare(cs.Collector(), strings.NewReader(`
+# HELP livekit_streaming_chat_messages_total Chat messages sent.
+# TYPE livekit_streaming_chat_messages_total counter
+livekit_streaming_chat_messages_total{type="other"} 1
+livekit_streaming_chat_messages_total{type="text"} 1
+# HELP livekit_streaming_chat_messages_rejected_total Chat messages rejected, by reason.
+# TYPE livekit_streaming_chat_messages_rejected_total counter
+livekit_streaming_chat_messages_rejected_total{reason="no_room"} 1
+# HELP livekit_streaming_chat_rooms Open chat rooms.
+# TYPE livekit_streaming_chat_rooms gauge
+livekit_streaming_chat_rooms 1
+`)))
+	})
+
+	t.Run("stream keys", func(t *testing.T) {
+		m := streaming.NewStreamKeyManager()
+		key, err := m.GenerateStreamKey(ctx, "streamer", "room", nil, nil)
+		require.NoError(t, err)
+
+		_, err = m.ValidateStreamKey(ctx, key.Key)
+		require.NoError(t, err)
+		_, err = m.ValidateStreamKey(ctx, "bogus")
+		require.Error(t, err)
+		require.NoError(t, m.RevokeStreamKey(ctx, key.Key))
+		_, err = m.ValidateStreamKey(ctx, key.Key)
+		require.Error(t, err)
+
+		require.NoError(t, testutil.CollectAndCompare(m.Collector(), strings.NewReader(`
+# HELP livekit_streaming_stream_key_validations_total Stream key validations, by result.
+# TYPE livekit_streaming_stream_key_validations_total counter
+livekit_streaming_stream_key_validations_total{result="inactive"} 1
+livekit_streaming_stream_key_validations_total{result="not_found"} 1
+livekit_streaming_stream_key_validations_total{result="valid"} 1
+# HELP livekit_streaming_stream_keys_active Stream keys that are active.
+# TYPE livekit_streaming_stream_keys_active gauge
+livekit_streaming_stream_keys_active 0
+`)))
+	})
+
+	t.Run("live streams", func(t *testing.T) {
+		as := streaming.NewAnalyticsService(nil)
+		_, err := as.StartStreamAnalytics(ctx, "a", "streamer-a")
+		require.NoError(t, err)
+		_, err = as.StartStreamAnalytics(ctx, "b", "streamer-b")
+		require.NoError(t, err)
+		for _, viewer := range []livekit.ParticipantIdentity{"v1", "v2", "v3"} {
+			require.NoError(t, as.RecordViewerJoin(ctx, "a", viewer, "web", "desktop", "", ""))
+		}
+		_, err = as.StartStreamAnalytics(ctx, "ended", "streamer-c")
+		require.NoError(t, err)
+		require.NoError(t, as.StopStreamAnalytics(ctx, "ended"))
+
+		collector := as.Collector()
+		require.Equal(t, 3, testutil.CollectAndCount(collector))
+		require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP livekit_streaming_live_streams Streams currently live.
+# TYPE livekit_streaming_live_streams gauge
+livekit_streaming_live_streams 2
+# HELP livekit_streaming_viewers Viewers currently watching a live stream.
+# TYPE livekit_streaming_viewers gauge
+livekit_streaming_viewers 3
+`), "livekit_streaming_live_streams", "livekit_streaming_viewers"))
+	})
+
+	t.Run("all services register together", func(t *testing.T) {
+		registry := prometheus.NewPedanticRegistry()
+		require.NoError(t, registry.Register(streaming.NewStreamKeyManager().Collector()))
+		require.NoError(t, registry.Register(streaming.NewChatService().Collector()))
+		require.NoError(t, registry.Register(streaming.NewReactionService(nil).Collector()))
+		require.NoError(t, registry.Register(streaming.NewVODService(nil).Collector()))
+		require.NoError(t, registry.Register(streaming.NewNotificationService(nil).Collector()))
+		require.NoError(t, registry.Register(streaming.NewAnalyticsService(nil).Collector()))
+		_, err := registry.Gather()
+		require.NoError(t, err)
+	})
+}
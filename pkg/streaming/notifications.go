@@ -17,6 +17,8 @@ package streaming
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
@@ -28,14 +30,17 @@ import (
 type NotificationType string
 
 const (
-	NotificationTypeStreamStarted NotificationType = "stream_started"
-	NotificationTypeStreamEnded   NotificationType = "stream_ended"
-	NotificationTypeNewFollower   NotificationType = "new_follower"
-	NotificationTypeMention       NotificationType = "mention"
-	NotificationTypeReply         NotificationType = "reply"
-	NotificationTypeModerator     NotificationType = "moderator"
-	NotificationTypeGift          NotificationType = "gift"
-	NotificationTypeSystem        NotificationType = "system"
+	NotificationTypeStreamStarted  NotificationType = "stream_started"
+	NotificationTypeStreamEnded    NotificationType = "stream_ended"
+	NotificationTypeStreamReminder NotificationType = "stream_reminder" // a scheduled stream is about to start
+	NotificationTypeNewFollower    NotificationType = "new_follower"
+	NotificationTypeMention        NotificationType = "mention"
+	NotificationTypeReply          NotificationType = "reply"
+	NotificationTypeModerator      NotificationType = "moderator"
+	NotificationTypeGift           NotificationType = "gift"
+	NotificationTypeStageInvite    NotificationType = "stage_invite" // a streamer invites a viewer on stage
+	NotificationTypeRaid           NotificationType = "raid"         // another streamer sends their viewers over
+	NotificationTypeSystem         NotificationType = "system"
 )
 
 // Notification represents a single notification
@@ -94,9 +99,13 @@ type NotificationService struct {
 	subscriptions        map[livekit.ParticipantIdentity][]*NotificationSubscription   // userID -> subscriptions
 	streamerFollowers    map[livekit.ParticipantIdentity][]livekit.ParticipantIdentity // streamerID -> followerIDs
 	onlineUsers          map[livekit.ParticipantIdentity]bool
+	mentionLog           map[livekit.ParticipantIdentity][]time.Time                               // recipient -> recent mention notifications
+	lastMention          map[livekit.ParticipantIdentity]map[livekit.ParticipantIdentity]time.Time // recipient -> sender -> last mention notification
+	sentMentionLog       map[livekit.ParticipantIdentity][]time.Time                               // sender -> recent mention notifications
 	notificationHandlers map[NotificationChannel][]NotificationHandler
 	logger               logger.Logger
 	config               *NotificationConfig
+	metrics              *notificationMetrics
 }
 
 // NotificationConfig defines notification service configuration
@@ -109,21 +118,37 @@ type NotificationConfig struct {
 	EmailFromAddress        string        `json:"email_from_address"`
 	SMTPServer              string        `json:"smtp_server"`
 	SMTPPort                int           `json:"smtp_port"`
+	// minimum time between mention or reply notifications from one sender to one user, 0 for none
+	MentionCooldown time.Duration `json:"mention_cooldown"`
+	// maximum mention or reply notifications a user receives per minute, 0 for no limit
+	MaxMentionsPerMinute int `json:"max_mentions_per_minute"`
+	// maximum mention or reply notifications a user sends per minute across all recipients, 0 for no limit
+	MaxMentionsSentPerMinute int `json:"max_mentions_sent_per_minute"`
 }
 
+// DefaultNotificationConfig is used when no configuration is given
+var DefaultNotificationConfig = NotificationConfig{
+	MaxNotificationsPerUser:  1000,
+	NotificationTTL:          30 * 24 * time.Hour, // 30 days
+	EnableWebSocket:          true,
+	EnableEmail:              false,
+	EnablePush:               false,
+	MentionCooldown:          30 * time.Second,
+	MaxMentionsPerMinute:     10,
+	MaxMentionsSentPerMinute: 20,
+}
+
+// maxMentionBodyLength bounds how much of a chat message is copied into a notification
+const maxMentionBodyLength = 140
+
 // NotificationHandler is a callback for sending notifications
 type NotificationHandler func(notification *Notification)
 
 // NewNotificationService creates a new notification service
 func NewNotificationService(config *NotificationConfig) *NotificationService {
 	if config == nil {
-		config = &NotificationConfig{
-			MaxNotificationsPerUser: 1000,
-			NotificationTTL:         30 * 24 * time.Hour, // 30 days
-			EnableWebSocket:         true,
-			EnableEmail:             false,
-			EnablePush:              false,
-		}
+		defaults := DefaultNotificationConfig
+		config = &defaults
 	}
 
 	return &NotificationService{
@@ -131,9 +156,13 @@ func NewNotificationService(config *NotificationConfig) *NotificationService {
 		subscriptions:        make(map[livekit.ParticipantIdentity][]*NotificationSubscription),
 		streamerFollowers:    make(map[livekit.ParticipantIdentity][]livekit.ParticipantIdentity),
 		onlineUsers:          make(map[livekit.ParticipantIdentity]bool),
+		mentionLog:           make(map[livekit.ParticipantIdentity][]time.Time),
+		lastMention:          make(map[livekit.ParticipantIdentity]map[livekit.ParticipantIdentity]time.Time),
+		sentMentionLog:       make(map[livekit.ParticipantIdentity][]time.Time),
 		notificationHandlers: make(map[NotificationChannel][]NotificationHandler),
 		logger:               logger.GetLogger(),
 		config:               config,
+		metrics:              newNotificationMetrics(),
 	}
 }
 
@@ -282,7 +311,7 @@ func (ns *NotificationService) NotifyStreamStarted(
 		}
 
 		ns.addNotification(followerID, notification)
-		ns.sendNotification(notification, ChannelWebSocket)
+		ns.deliver(notification)
 	}
 
 	return nil
@@ -329,6 +358,210 @@ func (ns *NotificationService) NotifyStreamEnded(
 		}
 
 		ns.addNotification(followerID, notification)
+		ns.deliver(notification)
+	}
+
+	return nil
+}
+
+// NotifyStreamReminder reminds followers who get go-live notifications of a scheduled stream about to start
+func (ns *NotificationService) NotifyStreamReminder(
+	ctx context.Context,
+	stream *ScheduledStream,
+) error {
+	ns.mu.RLock()
+	followers := slices.Clone(ns.streamerFollowers[stream.StreamerID])
+	ns.mu.RUnlock()
+
+	streamerName := stream.StreamerName
+	if streamerName == "" {
+		streamerName = string(stream.StreamerID)
+	}
+
+	for _, followerID := range followers {
+		ns.mu.RLock()
+		shouldNotify := false
+		for _, sub := range ns.subscriptions[followerID] {
+			if sub.StreamerID == stream.StreamerID && sub.EnableStreamStart {
+				shouldNotify = true
+				break
+			}
+		}
+		ns.mu.RUnlock()
+
+		if !shouldNotify {
+			continue
+		}
+
+		notification := &Notification{
+			ID:        fmt.Sprintf("notif-%d-%s", time.Now().UnixNano(), followerID),
+			UserID:    followerID,
+			Type:      NotificationTypeStreamReminder,
+			Title:     fmt.Sprintf("%s goes live soon", streamerName),
+			Body:      stream.Title,
+			ImageURL:  stream.Thumbnail,
+			ActionURL: ScheduledStreamPath(stream.ID),
+			Priority:  PriorityMedium,
+			CreatedAt: time.Now(),
+			IsRead:    false,
+			ExpiresAt: &stream.StartTime,
+			Data: map[string]string{
+				"streamer_id":   string(stream.StreamerID),
+				"streamer_name": streamerName,
+				"schedule_id":   stream.ID,
+				"start_time":    stream.StartTime.UTC().Format(time.RFC3339),
+			},
+		}
+		if stream.RoomName != "" {
+			notification.Data["room_name"] = string(stream.RoomName)
+		}
+
+		ns.addNotification(followerID, notification)
+		ns.deliver(notification)
+	}
+
+	return nil
+}
+
+// NotifyStageInvite tells a viewer a streamer invites them on stage, the notification expires with the invite
+func (ns *NotificationService) NotifyStageInvite(
+	ctx context.Context,
+	invite *StageInvite,
+	hostName string,
+) error {
+	if hostName == "" {
+		hostName = string(invite.InvitedBy)
+	}
+
+	notification := &Notification{
+		ID:        fmt.Sprintf("notif-%d-%s", time.Now().UnixNano(), invite.UserID),
+		UserID:    invite.UserID,
+		Type:      NotificationTypeStageInvite,
+		Title:     fmt.Sprintf("%s invited you on stage", hostName),
+		Body:      "Accept to join the stream as a guest.",
+		ActionURL: fmt.Sprintf("/watch/%s", invite.RoomName),
+		Priority:  PriorityHigh,
+		CreatedAt: time.Now(),
+		IsRead:    false,
+		ExpiresAt: &invite.ExpiresAt,
+		Data: map[string]string{
+			"room_name":  string(invite.RoomName),
+			"host_id":    string(invite.InvitedBy),
+			"host_name":  hostName,
+			"expires_at": invite.ExpiresAt.UTC().Format(time.RFC3339),
+		},
+	}
+
+	ns.addNotification(invite.UserID, notification)
+	ns.deliver(notification)
+
+	return nil
+}
+
+// NotifyRaid tells a streamer another stream is raiding theirs
+func (ns *NotificationService) NotifyRaid(
+	ctx context.Context,
+	streamerID livekit.ParticipantIdentity,
+	toRoom livekit.RoomName,
+	raid *RaidReferral,
+	raiderName string,
+) error {
+	if raiderName == "" {
+		raiderName = string(raid.FromStreamerID)
+	}
+
+	notification := &Notification{
+		ID:        fmt.Sprintf("notif-%d-%s", time.Now().UnixNano(), streamerID),
+		UserID:    streamerID,
+		Type:      NotificationTypeRaid,
+		Title:     fmt.Sprintf("%s is raiding your stream", raiderName),
+		Body:      fmt.Sprintf("%d viewers are on their way.", raid.Viewers),
+		ActionURL: fmt.Sprintf("/watch/%s", toRoom),
+		Priority:  PriorityHigh,
+		CreatedAt: time.Now(),
+		IsRead:    false,
+		Data: map[string]string{
+			"room_name":   string(toRoom),
+			"from_room":   string(raid.FromRoom),
+			"raider_id":   string(raid.FromStreamerID),
+			"raider_name": raiderName,
+			"viewers":     strconv.Itoa(raid.Viewers),
+		},
+	}
+
+	ns.addNotification(streamerID, notification)
+	ns.deliver(notification)
+
+	return nil
+}
+
+// NotifyChatMessage notifies the users mentioned in, or replied to by, a chat message.
+// streamerID owns the room and selects which follower preferences apply, it may be empty.
+func (ns *NotificationService) NotifyChatMessage(
+	ctx context.Context,
+	message *ChatMessage,
+	streamerID livekit.ParticipantIdentity,
+) error {
+	if message.IsDeleted {
+		return nil
+	}
+
+	recipients := make(map[livekit.ParticipantIdentity]NotificationType)
+	order := make([]livekit.ParticipantIdentity, 0, len(message.MentionedUsers)+1)
+	if message.ReplyToSenderID != "" {
+		recipients[message.ReplyToSenderID] = NotificationTypeReply
+		order = append(order, message.ReplyToSenderID)
+	}
+	for _, userID := range message.MentionedUsers {
+		if _, ok := recipients[userID]; !ok {
+			recipients[userID] = NotificationTypeMention
+			order = append(order, userID)
+		}
+	}
+
+	body := []rune(message.Content)
+	if len(body) > maxMentionBodyLength {
+		body = append(body[:maxMentionBodyLength-1], '…')
+	}
+
+	for _, userID := range order {
+		if userID == message.SenderID || !ns.mentionsEnabled(userID, streamerID) {
+			continue
+		}
+		if !ns.allowMention(message.SenderID, userID) {
+			ns.logger.Debugw("mention notification rate limited",
+				"senderID", message.SenderID,
+				"userID", userID,
+			)
+			continue
+		}
+
+		notificationType := recipients[userID]
+		title := fmt.Sprintf("%s mentioned you", message.SenderName)
+		if notificationType == NotificationTypeReply {
+			title = fmt.Sprintf("%s replied to you", message.SenderName)
+		}
+
+		notification := &Notification{
+			ID:        fmt.Sprintf("notif-%d-%s", time.Now().UnixNano(), userID),
+			UserID:    userID,
+			Type:      notificationType,
+			Title:     title,
+			Body:      string(body),
+			ActionURL: fmt.Sprintf("/watch/%s", message.RoomName),
+			Priority:  PriorityMedium,
+			CreatedAt: time.Now(),
+			IsRead:    false,
+			Data: map[string]string{
+				"room_name":   string(message.RoomName),
+				"message_id":  message.ID,
+				"sender_id":   string(message.SenderID),
+				"sender_name": message.SenderName,
+			},
+		}
+
+		ns.addNotification(userID, notification)
+		ns.deliver(notification)
 	}
 
 	return nil
@@ -359,7 +592,7 @@ func (ns *NotificationService) SendNotification(
 	}
 
 	ns.addNotification(userID, notification)
-	ns.sendNotification(notification, ChannelWebSocket)
+	ns.deliver(notification)
 
 	return notification, nil
 }
@@ -483,8 +716,81 @@ func (ns *NotificationService) SetUserOnlineStatus(
 	}
 }
 
+// IsUserOnline returns whether a user has a live notification connection
+func (ns *NotificationService) IsUserOnline(userID livekit.ParticipantIdentity) bool {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	return ns.onlineUsers[userID]
+}
+
 // Helper functions
 
+// mentionsEnabled applies the user's preference for the streamer's room, users not following allow mentions
+func (ns *NotificationService) mentionsEnabled(userID, streamerID livekit.ParticipantIdentity) bool {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	for _, sub := range ns.subscriptions[userID] {
+		if sub.StreamerID == streamerID {
+			return sub.EnableMentions
+		}
+	}
+	return true
+}
+
+// allowMention records a mention notification unless it exceeds the spam limits
+func (ns *NotificationService) allowMention(senderID, userID livekit.ParticipantIdentity) bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	now := time.Now()
+
+	senders := ns.lastMention[userID]
+	for id, at := range senders {
+		if now.Sub(at) >= ns.config.MentionCooldown {
+			delete(senders, id)
+		}
+	}
+	if _, ok := senders[senderID]; ok {
+		return false
+	}
+
+	sent := lastMinute(ns.sentMentionLog[senderID], now)
+	if ns.config.MaxMentionsSentPerMinute > 0 && len(sent) >= ns.config.MaxMentionsSentPerMinute {
+		ns.sentMentionLog[senderID] = sent
+		return false
+	}
+	recent := lastMinute(ns.mentionLog[userID], now)
+	if ns.config.MaxMentionsPerMinute > 0 && len(recent) >= ns.config.MaxMentionsPerMinute {
+		ns.sentMentionLog[senderID] = sent
+		ns.mentionLog[userID] = recent
+		return false
+	}
+	ns.sentMentionLog[senderID] = append(sent, now)
+	ns.mentionLog[userID] = append(recent, now)
+
+	if ns.config.MentionCooldown > 0 {
+		if senders == nil {
+			senders = make(map[livekit.ParticipantIdentity]time.Time)
+			ns.lastMention[userID] = senders
+		}
+		senders[senderID] = now
+	}
+	return true
+}
+
+// lastMinute drops the times older than a minute, reusing the slice
+func lastMinute(times []time.Time, now time.Time) []time.Time {
+	recent := times[:0]
+	for _, at := range times {
+		if now.Sub(at) < time.Minute {
+			recent = append(recent, at)
+		}
+	}
+	return recent
+}
+
 func (ns *NotificationService) addNotification(
 	userID livekit.ParticipantIdentity,
 	notification *Notification,
@@ -503,11 +809,32 @@ func (ns *NotificationService) addNotification(
 	ns.notifications[userID] = userNotifications
 }
 
+// deliver hands a notification to the handlers of every enabled channel
+func (ns *NotificationService) deliver(notification *Notification) {
+	ns.metrics.notificationCreated(notification.Type)
+	if ns.config.EnableWebSocket {
+		ns.sendNotification(notification, ChannelWebSocket)
+	}
+	if ns.config.EnableEmail {
+		ns.sendNotification(notification, ChannelEmail)
+	}
+	// push reaches users who are not connected, only for notifications worth interrupting them
+	if ns.config.EnablePush && isPushPriority(notification.Priority) && !ns.IsUserOnline(notification.UserID) {
+		ns.sendNotification(notification, ChannelPush)
+	}
+}
+
+func isPushPriority(priority NotificationPriority) bool {
+	return priority == PriorityHigh || priority == PriorityUrgent
+}
+
 func (ns *NotificationService) sendNotification(
 	notification *Notification,
 	channel NotificationChannel,
 ) {
+	ns.mu.RLock()
 	handlers, exists := ns.notificationHandlers[channel]
+	ns.mu.RUnlock()
 	if !exists {
 		return
 	}
@@ -555,6 +882,31 @@ func (ns *NotificationService) CleanupExpiredNotifications(ctx context.Context)
 	return count
 }
 
+// RemoveUser drops the notifications of a user, the streamers they follow and their followers
+func (ns *NotificationService) RemoveUser(ctx context.Context, userID livekit.ParticipantIdentity) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	for _, sub := range ns.subscriptions[userID] {
+		ns.streamerFollowers[sub.StreamerID] = slices.DeleteFunc(ns.streamerFollowers[sub.StreamerID],
+			func(followerID livekit.ParticipantIdentity) bool { return followerID == userID })
+	}
+	for _, followerID := range ns.streamerFollowers[userID] {
+		ns.subscriptions[followerID] = slices.DeleteFunc(ns.subscriptions[followerID],
+			func(sub *NotificationSubscription) bool { return sub.StreamerID == userID })
+	}
+
+	delete(ns.subscriptions, userID)
+	delete(ns.streamerFollowers, userID)
+	delete(ns.notifications, userID)
+	delete(ns.onlineUsers, userID)
+	delete(ns.mentionLog, userID)
+	delete(ns.lastMention, userID)
+	delete(ns.sentMentionLog, userID)
+
+	ns.logger.Infow("removed user from notifications", "userID", userID)
+}
+
 // GetSubscriptions returns all subscriptions for a user
 func (ns *NotificationService) GetSubscriptions(
 	ctx context.Context,
@@ -586,3 +938,24 @@ func (ns *NotificationService) GetFollowerCount(
 
 	return len(followers), nil
 }
+
+// GetFollowTimes returns when each current follower of a streamer started following
+func (ns *NotificationService) GetFollowTimes(
+	ctx context.Context,
+	streamerID livekit.ParticipantIdentity,
+) []time.Time {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	followers := ns.streamerFollowers[streamerID]
+	times := make([]time.Time, 0, len(followers))
+	for _, followerID := range followers {
+		for _, sub := range ns.subscriptions[followerID] {
+			if sub.StreamerID == streamerID {
+				times = append(times, sub.CreatedAt)
+				break
+			}
+		}
+	}
+	return times
+}
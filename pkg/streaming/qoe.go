@@ -0,0 +1,140 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// QoEEventType is a playback event reported by players
+type QoEEventType string
+
+const (
+	// QoEEventStall is a playback stall, with its duration
+	QoEEventStall QoEEventType = "stall"
+	// QoEEventQualityChange is a switch to another quality level: low, medium or high
+	QoEEventQualityChange QoEEventType = "quality_change"
+	// QoEEventDroppedFrames is a number of frames dropped since the previous report
+	QoEEventDroppedFrames QoEEventType = "dropped_frames"
+	// QoEEventLatency is a glass-to-glass latency measurement
+	QoEEventLatency QoEEventType = "latency"
+)
+
+const (
+	// MaxQoEEvents is the most events a single beacon may carry
+	MaxQoEEvents = 100
+
+	maxQoEStall         = 10 * time.Minute
+	maxQoELatency       = time.Minute
+	maxQoEDroppedFrames = 100000
+)
+
+// QoEEvent is a single playback event of a QoE beacon
+type QoEEvent struct {
+	Type       QoEEventType `json:"type"`
+	DurationMs float64      `json:"duration_ms,omitempty"` // stall
+	Quality    string       `json:"quality,omitempty"`     // quality_change
+	Frames     int          `json:"frames,omitempty"`      // dropped_frames
+	LatencyMs  float64      `json:"latency_ms,omitempty"`  // latency
+}
+
+// QoEBeacon is a batch of playback events posted by a player, typically with navigator.sendBeacon.
+// The token is the access token of the viewer, as beacons cannot carry headers.
+type QoEBeacon struct {
+	Token  string     `json:"token"`
+	Events []QoEEvent `json:"events"`
+}
+
+// Duration returns the duration of a stall or the latency of a latency measurement
+func (e *QoEEvent) Duration() time.Duration {
+	switch e.Type {
+	case QoEEventStall:
+		return time.Duration(e.DurationMs * float64(time.Millisecond))
+	case QoEEventLatency:
+		return time.Duration(e.LatencyMs * float64(time.Millisecond))
+	default:
+		return 0
+	}
+}
+
+// Validate checks that an event is known and its values are plausible
+func (e *QoEEvent) Validate() error {
+	switch e.Type {
+	case QoEEventStall:
+		if d := e.Duration(); d < 0 || d > maxQoEStall {
+			return fmt.Errorf("invalid stall duration %vms", e.DurationMs)
+		}
+	case QoEEventQualityChange:
+		switch e.Quality {
+		case "low", "medium", "high":
+		default:
+			return fmt.Errorf("invalid quality %q", e.Quality)
+		}
+	case QoEEventDroppedFrames:
+		if e.Frames < 0 || e.Frames > maxQoEDroppedFrames {
+			return fmt.Errorf("invalid dropped frames %d", e.Frames)
+		}
+	case QoEEventLatency:
+		if d := e.Duration(); d <= 0 || d > maxQoELatency {
+			return fmt.Errorf("invalid latency %vms", e.LatencyMs)
+		}
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+	return nil
+}
+
+// RecordQoEEvents records validated playback events of a viewer of a live stream
+func (as *AnalyticsService) RecordQoEEvents(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	viewerID livekit.ParticipantIdentity,
+	events []QoEEvent,
+) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	analytics, exists := as.streamAnalytics[roomName]
+	if !exists || analytics.EndTime != nil {
+		return fmt.Errorf("analytics not found")
+	}
+
+	session, exists := as.viewerSessions[roomName][viewerID]
+	if !exists || session.LeftAt != nil {
+		return fmt.Errorf("session not found")
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case QoEEventStall:
+			analytics.BufferingEvents++
+			session.BufferingCount++
+		case QoEEventQualityChange:
+			setQualityLevel(analytics, session, event.Quality)
+		case QoEEventDroppedFrames:
+			analytics.DroppedFrames += event.Frames
+		case QoEEventLatency:
+			analytics.latencySum += event.Duration()
+			analytics.latencySamples++
+			analytics.AverageLatency = analytics.latencySum / time.Duration(analytics.latencySamples)
+		}
+	}
+
+	return nil
+}
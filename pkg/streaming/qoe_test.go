@@ -0,0 +1,93 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func TestQoEEventValidate(t *testing.T) {
+	valid := []streaming.QoEEvent{
+		{Type: streaming.QoEEventStall, DurationMs: 1500},
+		{Type: streaming.QoEEventQualityChange, Quality: "medium"},
+		{Type: streaming.QoEEventDroppedFrames, Frames: 12},
+		{Type: streaming.QoEEventLatency, LatencyMs: 800},
+	}
+	for _, event := range valid {
+		require.NoError(t, event.Validate(), event.Type)
+	}
+
+	invalid := []streaming.QoEEvent{
+		{Type: "seek"},
+		{Type: streaming.QoEEventStall, DurationMs: -1},
+		{Type: streaming.QoEEventStall, DurationMs: float64(time.Hour.Milliseconds())},
+		{Type: streaming.QoEEventQualityChange, Quality: "1080p"},
+		{Type: streaming.QoEEventDroppedFrames, Frames: -3},
+		{Type: streaming.QoEEventLatency},
+	}
+	for _, event := range invalid {
+		require.Error(t, event.Validate(), event)
+	}
+
+	require.Equal(t, 1500*time.Millisecond, valid[0].Duration())
+	require.Equal(t, 800*time.Millisecond, valid[3].Duration())
+}
+
+func TestRecordQoEEvents(t *testing.T) {
+	ctx := context.Background()
+	as := streaming.NewAnalyticsService(&streaming.AnalyticsConfig{RetentionDays: 1})
+	_, err := as.StartStreamAnalytics(ctx, "room", "streamer")
+	require.NoError(t, err)
+
+	require.Error(t, as.RecordQoEEvents(ctx, "room", "alice", nil))
+	require.NoError(t, as.RecordViewerJoin(ctx, "room", "alice", "web", "desktop", "", ""))
+
+	// the server already saw the viewer switch to high, the player reporting it again is no new change
+	require.NoError(t, as.RecordViewerStats(ctx, "room", "alice", 0, "", "high"))
+	require.NoError(t, as.RecordQoEEvents(ctx, "room", "alice", []streaming.QoEEvent{
+		{Type: streaming.QoEEventStall, DurationMs: 500},
+		{Type: streaming.QoEEventStall, DurationMs: 2000},
+		{Type: streaming.QoEEventQualityChange, Quality: "high"},
+		{Type: streaming.QoEEventQualityChange, Quality: "low"},
+		{Type: streaming.QoEEventDroppedFrames, Frames: 7},
+		{Type: streaming.QoEEventDroppedFrames, Frames: 3},
+		{Type: streaming.QoEEventLatency, LatencyMs: 400},
+		{Type: streaming.QoEEventLatency, LatencyMs: 800},
+	}))
+
+	analytics, err := as.GetStreamAnalytics(ctx, "room")
+	require.NoError(t, err)
+	require.Equal(t, 2, analytics.BufferingEvents)
+	require.Equal(t, 1, analytics.QualityChanges)
+	require.Equal(t, 10, analytics.DroppedFrames)
+	require.Equal(t, 600*time.Millisecond, analytics.AverageLatency)
+
+	sessions, err := as.GetViewerSessions(ctx, "room")
+	require.NoError(t, err)
+	require.Equal(t, 2, sessions[0].BufferingCount)
+	require.Equal(t, "low", sessions[0].QualityLevel)
+
+	// viewers who left or streams that ended take no more events
+	require.NoError(t, as.RecordViewerLeave(ctx, "room", "alice"))
+	require.Error(t, as.RecordQoEEvents(ctx, "room", "alice", nil))
+	require.NoError(t, as.StopStreamAnalytics(ctx, "room"))
+	require.Error(t, as.RecordQoEEvents(ctx, "room", "alice", nil))
+}
@@ -98,6 +98,7 @@ type ReactionService struct {
 	logger           logger.Logger
 	reactionHandlers []ReactionHandler
 	config           *ReactionConfig
+	metrics          *reactionMetrics
 }
 
 // ReactionConfig defines reaction service configuration
@@ -133,6 +134,7 @@ func NewReactionService(config *ReactionConfig) *ReactionService {
 		logger:           logger.GetLogger(),
 		reactionHandlers: make([]ReactionHandler, 0),
 		config:           config,
+		metrics:          newReactionMetrics(),
 	}
 }
 
@@ -213,6 +215,7 @@ func (rs *ReactionService) SendReaction(
 	// Check rate limit
 	if rs.config.EnableRateLimit {
 		if err := rs.checkRateLimit(room, userID); err != nil {
+			rs.metrics.rateLimited.Inc()
 			return nil, err
 		}
 	}
@@ -239,6 +242,7 @@ func (rs *ReactionService) SendReaction(
 	// Update stats
 	room.Stats.TotalReactions++
 	room.Stats.ReactionCounts[reactionType]++
+	rs.metrics.reactionSent(reactionType)
 	room.Stats.RecentReactions = append([]*Reaction{reaction}, room.Stats.RecentReactions...)
 	if len(room.Stats.RecentReactions) > rs.config.MaxRecentReactions {
 		room.Stats.RecentReactions = room.Stats.RecentReactions[:rs.config.MaxRecentReactions]
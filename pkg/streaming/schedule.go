@@ -0,0 +1,465 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+var (
+	ErrScheduledStreamNotFound = errors.New("scheduled stream not found")
+	// scheduled streams can only be changed before they go live
+	ErrScheduledStreamNotEditable = errors.New("scheduled stream already started or cancelled")
+	ErrInvalidScheduledStream     = errors.New("invalid scheduled stream")
+)
+
+// ScheduledStreamPath is the page of a scheduled stream, which reminders and calendar feeds link to
+func ScheduledStreamPath(id string) string {
+	return "/examples/scheduled-stream.html?id=" + url.QueryEscape(id)
+}
+
+// ScheduledStreamStatus is where a scheduled stream is in its life
+type ScheduledStreamStatus string
+
+const (
+	ScheduledStreamUpcoming  ScheduledStreamStatus = "scheduled"
+	ScheduledStreamLive      ScheduledStreamStatus = "live"
+	ScheduledStreamEnded     ScheduledStreamStatus = "ended"
+	ScheduledStreamCancelled ScheduledStreamStatus = "cancelled"
+	// the streamer did not go live within the live window of the start time
+	ScheduledStreamMissed ScheduledStreamStatus = "missed"
+)
+
+const maxScheduledStreamTitleLength = 140
+
+// DefaultScheduleSettings are used for unset schedule settings
+var DefaultScheduleSettings = ScheduleSettings{
+	ReminderLead: 15 * time.Minute,
+	LiveWindow:   time.Hour,
+	Retention:    30 * 24 * time.Hour,
+}
+
+// ScheduledStream is a broadcast announced ahead of time
+type ScheduledStream struct {
+	ID           string                      `json:"id"`
+	StreamerID   livekit.ParticipantIdentity `json:"streamer_id"`
+	StreamerName string                      `json:"streamer_name"`
+	Title        string                      `json:"title"`
+	Description  string                      `json:"description,omitempty"`
+	Category     string                      `json:"category,omitempty"`
+	StartTime    time.Time                   `json:"start_time"`
+	Thumbnail    string                      `json:"thumbnail,omitempty"`
+	// the stream key the streamer will go live with, only shown to them
+	StreamKey string `json:"stream_key,omitempty"`
+	// the room of the stream key, or the room the stream went live in
+	RoomName   livekit.RoomName      `json:"room_name,omitempty"`
+	Status     ScheduledStreamStatus `json:"status"`
+	RemindedAt *time.Time            `json:"reminded_at,omitempty"`
+	LiveAt     *time.Time            `json:"live_at,omitempty"`
+	EndedAt    *time.Time            `json:"ended_at,omitempty"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// ScheduledStreamUpdate changes the fields of a scheduled stream that are set
+type ScheduledStreamUpdate struct {
+	Title       *string
+	Description *string
+	Category    *string
+	StartTime   *time.Time
+	Thumbnail   *string
+	// an empty key unlinks the stream key
+	StreamKey *string
+	RoomName  *livekit.RoomName
+}
+
+// ScheduleManager keeps the scheduled streams of all streamers, reminds followers of them and
+// follows them through going live and ending
+type ScheduleManager struct {
+	settings ScheduleSettings
+	logger   logger.Logger
+
+	mu      sync.RWMutex
+	streams map[string]*ScheduledStream
+	// streamerID -> scheduled stream IDs
+	streamerStreams map[livekit.ParticipantIdentity][]string
+}
+
+// NewScheduleManager creates a schedule manager, unset settings take their defaults
+func NewScheduleManager(settings ScheduleSettings) *ScheduleManager {
+	if settings.ReminderLead <= 0 {
+		settings.ReminderLead = DefaultScheduleSettings.ReminderLead
+	}
+	if settings.LiveWindow <= 0 {
+		settings.LiveWindow = DefaultScheduleSettings.LiveWindow
+	}
+	if settings.Retention <= 0 {
+		settings.Retention = DefaultScheduleSettings.Retention
+	}
+	return &ScheduleManager{
+		settings:        settings,
+		logger:          logger.GetLogger(),
+		streams:         make(map[string]*ScheduledStream),
+		streamerStreams: make(map[livekit.ParticipantIdentity][]string),
+	}
+}
+
+// Create schedules a stream, its ID, status and timestamps are set here
+func (m *ScheduleManager) Create(ctx context.Context, stream *ScheduledStream) (*ScheduledStream, error) {
+	now := time.Now()
+	if err := validateScheduledStream(stream, now); err != nil {
+		return nil, err
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate id: %w", err)
+	}
+
+	created := *stream
+	created.ID = "SS_" + hex.EncodeToString(idBytes)
+	created.Status = ScheduledStreamUpcoming
+	created.RemindedAt = nil
+	created.LiveAt = nil
+	created.EndedAt = nil
+	created.CreatedAt = now
+	created.UpdatedAt = now
+
+	m.mu.Lock()
+	m.streams[created.ID] = &created
+	m.streamerStreams[created.StreamerID] = append(m.streamerStreams[created.StreamerID], created.ID)
+	m.mu.Unlock()
+
+	m.logger.Infow("stream scheduled",
+		"id", created.ID,
+		"streamerID", created.StreamerID,
+		"startTime", created.StartTime,
+	)
+
+	result := created
+	return &result, nil
+}
+
+// Get returns a scheduled stream
+func (m *ScheduleManager) Get(ctx context.Context, id string) (*ScheduledStream, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stream, ok := m.streams[id]
+	if !ok {
+		return nil, ErrScheduledStreamNotFound
+	}
+	result := *stream
+	return &result, nil
+}
+
+// Update changes a scheduled stream that has not started yet. Moving the start time sends the reminder again.
+func (m *ScheduleManager) Update(ctx context.Context, id string, update ScheduledStreamUpdate) (*ScheduledStream, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stream, ok := m.streams[id]
+	if !ok {
+		return nil, ErrScheduledStreamNotFound
+	}
+	if stream.Status != ScheduledStreamUpcoming {
+		return nil, ErrScheduledStreamNotEditable
+	}
+
+	updated := *stream
+	if update.Title != nil {
+		updated.Title = *update.Title
+	}
+	if update.Description != nil {
+		updated.Description = *update.Description
+	}
+	if update.Category != nil {
+		updated.Category = *update.Category
+	}
+	if update.Thumbnail != nil {
+		updated.Thumbnail = *update.Thumbnail
+	}
+	if update.StreamKey != nil {
+		updated.StreamKey = *update.StreamKey
+	}
+	if update.RoomName != nil {
+		updated.RoomName = *update.RoomName
+	}
+	now := time.Now()
+	if update.StartTime != nil && !update.StartTime.Equal(stream.StartTime) {
+		updated.StartTime = *update.StartTime
+		updated.RemindedAt = nil
+		if err := validateScheduledStream(&updated, now); err != nil {
+			return nil, err
+		}
+	} else if err := validateScheduledStreamFields(&updated); err != nil {
+		return nil, err
+	}
+	updated.UpdatedAt = now
+	*stream = updated
+
+	result := updated
+	return &result, nil
+}
+
+// Cancel calls off a scheduled stream that has not started yet
+func (m *ScheduleManager) Cancel(ctx context.Context, id string) (*ScheduledStream, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stream, ok := m.streams[id]
+	if !ok {
+		return nil, ErrScheduledStreamNotFound
+	}
+	if stream.Status != ScheduledStreamUpcoming {
+		return nil, ErrScheduledStreamNotEditable
+	}
+	stream.Status = ScheduledStreamCancelled
+	stream.UpdatedAt = time.Now()
+
+	m.logger.Infow("scheduled stream cancelled", "id", id, "streamerID", stream.StreamerID)
+
+	result := *stream
+	return &result, nil
+}
+
+// ListByStreamer returns the scheduled streams of a streamer by start time, with the past ones
+// that are still retained when includePast is set
+func (m *ScheduleManager) ListByStreamer(
+	ctx context.Context,
+	streamerID livekit.ParticipantIdentity,
+	includePast bool,
+) []*ScheduledStream {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	streams := make([]*ScheduledStream, 0, len(m.streamerStreams[streamerID]))
+	for _, id := range m.streamerStreams[streamerID] {
+		stream, ok := m.streams[id]
+		if !ok {
+			continue
+		}
+		if !includePast && stream.Status != ScheduledStreamUpcoming && stream.Status != ScheduledStreamLive {
+			continue
+		}
+		result := *stream
+		streams = append(streams, &result)
+	}
+	slices.SortFunc(streams, func(a, b *ScheduledStream) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return streams
+}
+
+// Upcoming returns the scheduled stream a streamer going live now in a room would start, if any.
+// Streams linked to a room only match that room, the one starting closest to now wins.
+func (m *ScheduleManager) Upcoming(
+	streamerID livekit.ParticipantIdentity,
+	roomName livekit.RoomName,
+	now time.Time,
+) (*ScheduledStream, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stream := m.upcomingLocked(streamerID, roomName, now)
+	if stream == nil {
+		return nil, false
+	}
+	result := *stream
+	return &result, true
+}
+
+func (m *ScheduleManager) upcomingLocked(
+	streamerID livekit.ParticipantIdentity,
+	roomName livekit.RoomName,
+	now time.Time,
+) *ScheduledStream {
+	var match *ScheduledStream
+	var matchOffset time.Duration
+	for _, id := range m.streamerStreams[streamerID] {
+		stream, ok := m.streams[id]
+		if !ok || stream.Status != ScheduledStreamUpcoming {
+			continue
+		}
+		if stream.RoomName != "" && stream.RoomName != roomName {
+			continue
+		}
+		offset := now.Sub(stream.StartTime).Abs()
+		if offset > m.settings.LiveWindow {
+			continue
+		}
+		if match == nil || offset < matchOffset {
+			match, matchOffset = stream, offset
+		}
+	}
+	return match
+}
+
+// StartLive marks the scheduled stream matching a stream that went live as live, see Upcoming
+func (m *ScheduleManager) StartLive(
+	streamerID livekit.ParticipantIdentity,
+	roomName livekit.RoomName,
+	now time.Time,
+) (*ScheduledStream, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stream := m.upcomingLocked(streamerID, roomName, now)
+	if stream == nil {
+		return nil, false
+	}
+	stream.Status = ScheduledStreamLive
+	stream.RoomName = roomName
+	stream.LiveAt = &now
+	stream.UpdatedAt = now
+
+	m.logger.Infow("scheduled stream went live", "id", stream.ID, "streamerID", streamerID, "room", roomName)
+
+	result := *stream
+	return &result, true
+}
+
+// EndLive marks the live scheduled stream of a room as ended
+func (m *ScheduleManager) EndLive(
+	streamerID livekit.ParticipantIdentity,
+	roomName livekit.RoomName,
+	now time.Time,
+) (*ScheduledStream, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range m.streamerStreams[streamerID] {
+		stream, ok := m.streams[id]
+		if !ok || stream.Status != ScheduledStreamLive || stream.RoomName != roomName {
+			continue
+		}
+		stream.Status = ScheduledStreamEnded
+		stream.EndedAt = &now
+		stream.UpdatedAt = now
+
+		result := *stream
+		return &result, true
+	}
+	return nil, false
+}
+
+// DueReminders returns the upcoming streams starting within the reminder lead of now that were not
+// reminded of yet, and marks them as reminded
+func (m *ScheduleManager) DueReminders(now time.Time) []*ScheduledStream {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*ScheduledStream
+	for _, stream := range m.streams {
+		if stream.Status != ScheduledStreamUpcoming || stream.RemindedAt != nil {
+			continue
+		}
+		if stream.StartTime.Sub(now) > m.settings.ReminderLead || !now.Before(stream.StartTime) {
+			continue
+		}
+		stream.RemindedAt = &now
+		result := *stream
+		due = append(due, &result)
+	}
+	return due
+}
+
+// Sweep marks streams that did not go live within the live window as missed, and forgets
+// finished streams past the retention. It returns how many streams were missed.
+func (m *ScheduleManager) Sweep(ctx context.Context, now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	missed := 0
+	for id, stream := range m.streams {
+		switch stream.Status {
+		case ScheduledStreamUpcoming:
+			if now.Sub(stream.StartTime) > m.settings.LiveWindow {
+				stream.Status = ScheduledStreamMissed
+				stream.UpdatedAt = now
+				missed++
+			}
+		case ScheduledStreamEnded, ScheduledStreamCancelled, ScheduledStreamMissed:
+			if now.Sub(stream.UpdatedAt) > m.settings.Retention {
+				m.deleteLocked(id, stream.StreamerID)
+			}
+		}
+	}
+
+	if missed > 0 {
+		m.logger.Infow("scheduled streams missed", "count", missed)
+	}
+	return missed
+}
+
+// DeleteStreamer removes all scheduled streams of a streamer, returning how many there were
+func (m *ScheduleManager) DeleteStreamer(ctx context.Context, streamerID livekit.ParticipantIdentity) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := m.streamerStreams[streamerID]
+	for _, id := range ids {
+		delete(m.streams, id)
+	}
+	delete(m.streamerStreams, streamerID)
+	return len(ids)
+}
+
+func (m *ScheduleManager) deleteLocked(id string, streamerID livekit.ParticipantIdentity) {
+	delete(m.streams, id)
+	m.streamerStreams[streamerID] = slices.DeleteFunc(m.streamerStreams[streamerID], func(other string) bool {
+		return other == id
+	})
+	if len(m.streamerStreams[streamerID]) == 0 {
+		delete(m.streamerStreams, streamerID)
+	}
+}
+
+// validateScheduledStream checks a stream about to be scheduled for the given time
+func validateScheduledStream(stream *ScheduledStream, now time.Time) error {
+	if stream.StreamerID == "" {
+		return fmt.Errorf("%w: streamer required", ErrInvalidScheduledStream)
+	}
+	if stream.StartTime.IsZero() {
+		return fmt.Errorf("%w: start_time required", ErrInvalidScheduledStream)
+	}
+	if !stream.StartTime.After(now) {
+		return fmt.Errorf("%w: start_time must be in the future", ErrInvalidScheduledStream)
+	}
+	return validateScheduledStreamFields(stream)
+}
+
+func validateScheduledStreamFields(stream *ScheduledStream) error {
+	title := strings.TrimSpace(stream.Title)
+	if title == "" {
+		return fmt.Errorf("%w: title required", ErrInvalidScheduledStream)
+	}
+	if len([]rune(title)) > maxScheduledStreamTitleLength {
+		return fmt.Errorf("%w: title longer than %d characters", ErrInvalidScheduledStream, maxScheduledStreamTitleLength)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func TestScheduleManager(t *testing.T) {
+	ctx := context.Background()
+	settings := streaming.ScheduleSettings{ReminderLead: 15 * time.Minute, LiveWindow: time.Hour, Retention: 24 * time.Hour}
+	schedule := func(t *testing.T, m *streaming.ScheduleManager, startIn time.Duration, roomName livekit.RoomName) *streaming.ScheduledStream {
+		stream, err := m.Create(ctx, &streaming.ScheduledStream{
+			StreamerID: "streamer",
+			Title:      "speedrun",
+			StartTime:  time.Now().Add(startIn),
+			RoomName:   roomName,
+		})
+		require.NoError(t, err)
+		return stream
+	}
+
+	t.Run("validates streams", func(t *testing.T) {
+		m := streaming.NewScheduleManager(settings)
+		for _, stream := range []*streaming.ScheduledStream{
+			{StreamerID: "streamer", StartTime: time.Now().Add(time.Hour)},
+			{StreamerID: "streamer", Title: "past", StartTime: time.Now().Add(-time.Minute)},
+			{StreamerID: "streamer", Title: strings.Repeat("a", 141), StartTime: time.Now().Add(time.Hour)},
+			{Title: "nobody", StartTime: time.Now().Add(time.Hour)},
+		} {
+			_, err := m.Create(ctx, stream)
+			require.ErrorIs(t, err, streaming.ErrInvalidScheduledStream)
+		}
+
+		stream := schedule(t, m, time.Hour, "")
+		require.Equal(t, streaming.ScheduledStreamUpcoming, stream.Status)
+		require.NotEmpty(t, stream.ID)
+
+		empty := ""
+		_, err := m.Update(ctx, stream.ID, streaming.ScheduledStreamUpdate{Title: &empty})
+		require.ErrorIs(t, err, streaming.ErrInvalidScheduledStream)
+		_, err = m.Update(ctx, "SS_unknown", streaming.ScheduledStreamUpdate{})
+		require.ErrorIs(t, err, streaming.ErrScheduledStreamNotFound)
+	})
+
+	t.Run("reminds once per start time", func(t *testing.T) {
+		m := streaming.NewScheduleManager(settings)
+		soon := schedule(t, m, 10*time.Minute, "")
+		schedule(t, m, time.Hour, "")
+
+		due := m.DueReminders(time.Now())
+		require.Len(t, due, 1)
+		require.Equal(t, soon.ID, due[0].ID)
+		require.Empty(t, m.DueReminders(time.Now()))
+
+		// moving the start time reminds again
+		later := time.Now().Add(12 * time.Minute)
+		_, err := m.Update(ctx, soon.ID, streaming.ScheduledStreamUpdate{StartTime: &later})
+		require.NoError(t, err)
+		require.Len(t, m.DueReminders(time.Now()), 1)
+	})
+
+	t.Run("goes live and ends", func(t *testing.T) {
+		m := streaming.NewScheduleManager(settings)
+		other := schedule(t, m, 30*time.Minute, "other-room")
+		stream := schedule(t, m, 30*time.Minute, "")
+		schedule(t, m, 3*time.Hour, "")
+
+		_, ok := m.StartLive("someone-else", "room", time.Now())
+		require.False(t, ok)
+
+		live, ok := m.StartLive("streamer", "room", time.Now())
+		require.True(t, ok)
+		require.Equal(t, stream.ID, live.ID)
+		require.Equal(t, streaming.ScheduledStreamLive, live.Status)
+		require.Equal(t, livekit.RoomName("room"), live.RoomName)
+
+		_, err := m.Cancel(ctx, stream.ID)
+		require.ErrorIs(t, err, streaming.ErrScheduledStreamNotEditable)
+
+		ended, ok := m.EndLive("streamer", "room", time.Now())
+		require.True(t, ok)
+		require.Equal(t, streaming.ScheduledStreamEnded, ended.Status)
+		require.NotNil(t, ended.EndedAt)
+
+		// streams linked to a room only go live there
+		upcoming, ok := m.Upcoming("streamer", "other-room", time.Now())
+		require.True(t, ok)
+		require.Equal(t, other.ID, upcoming.ID)
+
+		require.Len(t, m.ListByStreamer(ctx, "streamer", false), 2)
+		require.Len(t, m.ListByStreamer(ctx, "streamer", true), 3)
+	})
+
+	t.Run("sweeps missed and finished streams", func(t *testing.T) {
+		m := streaming.NewScheduleManager(settings)
+		stream := schedule(t, m, time.Minute, "")
+		cancelled := schedule(t, m, time.Hour, "")
+		_, err := m.Cancel(ctx, cancelled.ID)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, m.Sweep(ctx, time.Now().Add(2*time.Hour)))
+		missed, err := m.Get(ctx, stream.ID)
+		require.NoError(t, err)
+		require.Equal(t, streaming.ScheduledStreamMissed, missed.Status)
+
+		m.Sweep(ctx, time.Now().Add(48*time.Hour))
+		require.Empty(t, m.ListByStreamer(ctx, "streamer", true))
+	})
+}
+
+func TestNotifyStreamReminder(t *testing.T) {
+	ctx := context.Background()
+	ns := streaming.NewNotificationService(nil)
+	require.NoError(t, ns.Subscribe(ctx, "follower", "streamer", "Streamer", nil))
+	require.NoError(t, ns.Subscribe(ctx, "quiet", "streamer", "Streamer", &streaming.NotificationSubscription{}))
+
+	stream := &streaming.ScheduledStream{
+		ID:           "SS_1",
+		StreamerID:   "streamer",
+		StreamerName: "Streamer",
+		Title:        "speedrun",
+		StartTime:    time.Now().Add(10 * time.Minute),
+	}
+	require.NoError(t, ns.NotifyStreamReminder(ctx, stream))
+
+	notifications, err := ns.GetNotifications(ctx, "follower", false, 10)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	require.Equal(t, streaming.NotificationTypeStreamReminder, notifications[0].Type)
+	require.Equal(t, "speedrun", notifications[0].Body)
+	require.Equal(t, "SS_1", notifications[0].Data["schedule_id"])
+	require.Equal(t, "/examples/scheduled-stream.html?id=SS_1", notifications[0].ActionURL)
+
+	notifications, err = ns.GetNotifications(ctx, "quiet", false, 10)
+	require.NoError(t, err)
+	require.Empty(t, notifications)
+}
+
+func TestICalendarFeed(t *testing.T) {
+	start := time.Date(2026, 10, 20, 18, 30, 0, 0, time.FixedZone("ICT", 7*60*60))
+	feed := &streaming.ICalendarFeed{
+		Name:   "Streamer's streams",
+		Domain: "live.example.com",
+		Streams: []*streaming.ScheduledStream{
+			{
+				ID:           "SS_1",
+				StreamerName: "Streamer",
+				Title:        "Speedrun; any%, glitchless",
+				Description:  "Line one\nline two " + strings.Repeat("é", 60),
+				Category:     "Games",
+				StartTime:    start,
+				Status:       streaming.ScheduledStreamUpcoming,
+				UpdatedAt:    start.Add(-time.Hour),
+			},
+			{
+				ID:        "SS_2",
+				Title:     "Cancelled",
+				StartTime: start.Add(24 * time.Hour),
+				Status:    streaming.ScheduledStreamCancelled,
+			},
+		},
+		URL: func(s *streaming.ScheduledStream) string {
+			return "https://live.example.com" + streaming.ScheduledStreamPath(s.ID)
+		},
+	}
+	ics := string(feed.Encode())
+
+	require.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	require.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	require.Contains(t, ics, "UID:SS_1@live.example.com\r\n")
+	require.Contains(t, ics, "DTSTART:20261020T113000Z\r\n")
+	require.Contains(t, ics, `SUMMARY:Streamer: Speedrun\; any%\, glitchless`+"\r\n")
+	require.Contains(t, ics, "URL:https://live.example.com/examples/scheduled-stream.html?id=SS_1\r\n")
+	require.Contains(t, ics, "STATUS:CANCELLED\r\n")
+	require.Equal(t, 2, strings.Count(ics, "BEGIN:VEVENT"))
+
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), 75, line)
+		require.True(t, utf8.ValidString(line), line)
+	}
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	require.Contains(t, unfolded, `DESCRIPTION:Line one\nline two `+strings.Repeat("é", 60)+"\r\n")
+}
@@ -0,0 +1,346 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	ErrStageInviteNotFound = errors.New("no pending stage invite")
+	ErrAlreadyOnStage      = errors.New("already on stage")
+	ErrNotOnStage          = errors.New("not on stage")
+	ErrStageFull           = errors.New("stage is full")
+)
+
+// DefaultStageSettings are used for unset stage settings
+var DefaultStageSettings = StageSettings{
+	MaxGuests: 4,
+	InviteTTL: 2 * time.Minute,
+}
+
+// StageInvite asks a viewer to join the stream on stage
+type StageInvite struct {
+	RoomName  livekit.RoomName            `json:"room_name"`
+	UserID    livekit.ParticipantIdentity `json:"user_id"`
+	UserName  string                      `json:"user_name"`
+	InvitedBy livekit.ParticipantIdentity `json:"invited_by"`
+	CreatedAt time.Time                   `json:"created_at"`
+	ExpiresAt time.Time                   `json:"expires_at"`
+}
+
+// StageGuest is a viewer publishing alongside the streamer
+type StageGuest struct {
+	UserID   livekit.ParticipantIdentity `json:"user_id"`
+	UserName string                      `json:"user_name"`
+	JoinedAt time.Time                   `json:"joined_at"`
+}
+
+// RaisedHand is a viewer waiting in the queue to be invited on stage
+type RaisedHand struct {
+	UserID   livekit.ParticipantIdentity `json:"user_id"`
+	UserName string                      `json:"user_name"`
+	RaisedAt time.Time                   `json:"raised_at"`
+}
+
+// Stage is the state of the stage of a room. Guests are in the order they joined, the queue in the order hands
+// were raised.
+type Stage struct {
+	RoomName  livekit.RoomName `json:"room_name"`
+	MaxGuests int              `json:"max_guests"`
+	Guests    []*StageGuest    `json:"guests"`
+	Queue     []*RaisedHand    `json:"queue"`
+	// pending invites, only shown to the owner of the room
+	Invites []*StageInvite `json:"invites,omitempty"`
+}
+
+type roomStage struct {
+	guests  []*StageGuest
+	queue   []*RaisedHand
+	invites map[livekit.ParticipantIdentity]*StageInvite
+}
+
+func (st *roomStage) guestIndex(userID livekit.ParticipantIdentity) int {
+	return slices.IndexFunc(st.guests, func(g *StageGuest) bool { return g.UserID == userID })
+}
+
+func (st *roomStage) handIndex(userID livekit.ParticipantIdentity) int {
+	return slices.IndexFunc(st.queue, func(h *RaisedHand) bool { return h.UserID == userID })
+}
+
+func (st *roomStage) empty() bool {
+	return len(st.guests) == 0 && len(st.queue) == 0 && len(st.invites) == 0
+}
+
+// StageManager keeps who is on stage in each room, who was invited and the queue of raised hands.
+// It only keeps the state, granting and revoking the permissions to publish is up to the caller.
+type StageManager struct {
+	settings StageSettings
+
+	mu     sync.Mutex
+	stages map[livekit.RoomName]*roomStage
+}
+
+// NewStageManager creates a stage manager, unset settings take their defaults
+func NewStageManager(settings StageSettings) *StageManager {
+	if settings.MaxGuests <= 0 {
+		settings.MaxGuests = DefaultStageSettings.MaxGuests
+	}
+	if settings.InviteTTL <= 0 {
+		settings.InviteTTL = DefaultStageSettings.InviteTTL
+	}
+	return &StageManager{
+		settings: settings,
+		stages:   make(map[livekit.RoomName]*roomStage),
+	}
+}
+
+// Invite invites a viewer on stage, replacing an earlier invite of theirs
+func (m *StageManager) Invite(
+	roomName livekit.RoomName,
+	userID livekit.ParticipantIdentity,
+	userName string,
+	invitedBy livekit.ParticipantIdentity,
+	now time.Time,
+) (*StageInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.stageLocked(roomName)
+	if st.guestIndex(userID) >= 0 {
+		return nil, ErrAlreadyOnStage
+	}
+	if len(st.guests) >= m.settings.MaxGuests {
+		return nil, ErrStageFull
+	}
+
+	invite := &StageInvite{
+		RoomName:  roomName,
+		UserID:    userID,
+		UserName:  userName,
+		InvitedBy: invitedBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.settings.InviteTTL),
+	}
+	st.invites[userID] = invite
+	copied := *invite
+	return &copied, nil
+}
+
+// Accept puts an invited viewer on stage, taking them out of the queue
+func (m *StageManager) Accept(roomName livekit.RoomName, userID livekit.ParticipantIdentity, now time.Time) (*StageGuest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.stages[roomName]
+	if !ok {
+		return nil, ErrStageInviteNotFound
+	}
+	invite, ok := st.invites[userID]
+	if !ok {
+		return nil, ErrStageInviteNotFound
+	}
+	delete(st.invites, userID)
+	if !now.Before(invite.ExpiresAt) {
+		m.pruneLocked(roomName, st)
+		return nil, ErrStageInviteNotFound
+	}
+	if st.guestIndex(userID) >= 0 {
+		return nil, ErrAlreadyOnStage
+	}
+	if len(st.guests) >= m.settings.MaxGuests {
+		return nil, ErrStageFull
+	}
+
+	guest := &StageGuest{UserID: userID, UserName: invite.UserName, JoinedAt: now}
+	st.guests = append(st.guests, guest)
+	if i := st.handIndex(userID); i >= 0 {
+		st.queue = slices.Delete(st.queue, i, i+1)
+	}
+	copied := *guest
+	return &copied, nil
+}
+
+// Decline drops the pending invite of a viewer
+func (m *StageManager) Decline(roomName livekit.RoomName, userID livekit.ParticipantIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.stages[roomName]
+	if !ok || st.invites[userID] == nil {
+		return ErrStageInviteNotFound
+	}
+	delete(st.invites, userID)
+	m.pruneLocked(roomName, st)
+	return nil
+}
+
+// Remove takes a guest off stage
+func (m *StageManager) Remove(roomName livekit.RoomName, userID livekit.ParticipantIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.stages[roomName]
+	if !ok {
+		return ErrNotOnStage
+	}
+	i := st.guestIndex(userID)
+	if i < 0 {
+		return ErrNotOnStage
+	}
+	st.guests = slices.Delete(st.guests, i, i+1)
+	m.pruneLocked(roomName, st)
+	return nil
+}
+
+// RaiseHand puts a viewer at the end of the queue, returning their position in it starting at 1 and whether
+// their hand was not raised yet. Raising a hand again keeps the place in the queue.
+func (m *StageManager) RaiseHand(
+	roomName livekit.RoomName,
+	userID livekit.ParticipantIdentity,
+	userName string,
+	now time.Time,
+) (int, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.stageLocked(roomName)
+	if st.guestIndex(userID) >= 0 {
+		return 0, false, ErrAlreadyOnStage
+	}
+	if i := st.handIndex(userID); i >= 0 {
+		return i + 1, false, nil
+	}
+	st.queue = append(st.queue, &RaisedHand{UserID: userID, UserName: userName, RaisedAt: now})
+	return len(st.queue), true, nil
+}
+
+// LowerHand takes a viewer out of the queue, reporting whether they were in it
+func (m *StageManager) LowerHand(roomName livekit.RoomName, userID livekit.ParticipantIdentity) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.stages[roomName]
+	if !ok {
+		return false
+	}
+	i := st.handIndex(userID)
+	if i < 0 {
+		return false
+	}
+	st.queue = slices.Delete(st.queue, i, i+1)
+	m.pruneLocked(roomName, st)
+	return true
+}
+
+// Leave forgets a participant leaving the room: their place on stage, in the queue and their invite.
+// It reports whether the stage changed.
+func (m *StageManager) Leave(roomName livekit.RoomName, userID livekit.ParticipantIdentity) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.stages[roomName]
+	if !ok {
+		return false
+	}
+	changed := false
+	if i := st.guestIndex(userID); i >= 0 {
+		st.guests = slices.Delete(st.guests, i, i+1)
+		changed = true
+	}
+	if i := st.handIndex(userID); i >= 0 {
+		st.queue = slices.Delete(st.queue, i, i+1)
+		changed = true
+	}
+	if _, ok := st.invites[userID]; ok {
+		delete(st.invites, userID)
+		changed = true
+	}
+	m.pruneLocked(roomName, st)
+	return changed
+}
+
+// IsGuest reports whether a participant is on stage
+func (m *StageManager) IsGuest(roomName livekit.RoomName, userID livekit.ParticipantIdentity) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.stages[roomName]
+	return ok && st.guestIndex(userID) >= 0
+}
+
+// Get returns the stage of a room, dropping expired invites
+func (m *StageManager) Get(roomName livekit.RoomName, now time.Time) *Stage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stage := &Stage{
+		RoomName:  roomName,
+		MaxGuests: m.settings.MaxGuests,
+		Guests:    []*StageGuest{},
+		Queue:     []*RaisedHand{},
+	}
+	st, ok := m.stages[roomName]
+	if !ok {
+		return stage
+	}
+	for userID, invite := range st.invites {
+		if !now.Before(invite.ExpiresAt) {
+			delete(st.invites, userID)
+			continue
+		}
+		copied := *invite
+		stage.Invites = append(stage.Invites, &copied)
+	}
+	slices.SortFunc(stage.Invites, func(a, b *StageInvite) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	for _, guest := range st.guests {
+		copied := *guest
+		stage.Guests = append(stage.Guests, &copied)
+	}
+	for _, hand := range st.queue {
+		copied := *hand
+		stage.Queue = append(stage.Queue, &copied)
+	}
+	m.pruneLocked(roomName, st)
+	return stage
+}
+
+// Close forgets the stage of a room
+func (m *StageManager) Close(roomName livekit.RoomName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.stages, roomName)
+}
+
+func (m *StageManager) stageLocked(roomName livekit.RoomName) *roomStage {
+	st, ok := m.stages[roomName]
+	if !ok {
+		st = &roomStage{invites: make(map[livekit.ParticipantIdentity]*StageInvite)}
+		m.stages[roomName] = st
+	}
+	return st
+}
+
+func (m *StageManager) pruneLocked(roomName livekit.RoomName, st *roomStage) {
+	if st.empty() {
+		delete(m.stages, roomName)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func TestStageManager(t *testing.T) {
+	const room = livekit.RoomName("room")
+	now := time.Now()
+	settings := streaming.StageSettings{MaxGuests: 1, InviteTTL: time.Minute}
+
+	t.Run("invited viewers go on stage", func(t *testing.T) {
+		m := streaming.NewStageManager(settings)
+		position, raised, err := m.RaiseHand(room, "alice", "Alice", now)
+		require.NoError(t, err)
+		require.True(t, raised)
+		require.Equal(t, 1, position)
+
+		_, err = m.Accept(room, "alice", now)
+		require.ErrorIs(t, err, streaming.ErrStageInviteNotFound)
+
+		invite, err := m.Invite(room, "alice", "Alice", "host", now)
+		require.NoError(t, err)
+		require.Equal(t, now.Add(time.Minute), invite.ExpiresAt)
+
+		guest, err := m.Accept(room, "alice", now.Add(time.Second))
+		require.NoError(t, err)
+		require.Equal(t, "Alice", guest.UserName)
+		require.True(t, m.IsGuest(room, "alice"))
+
+		stage := m.Get(room, now)
+		require.Len(t, stage.Guests, 1)
+		require.Empty(t, stage.Queue)
+		require.Empty(t, stage.Invites)
+
+		_, _, err = m.RaiseHand(room, "alice", "Alice", now)
+		require.ErrorIs(t, err, streaming.ErrAlreadyOnStage)
+		_, err = m.Invite(room, "alice", "Alice", "host", now)
+		require.ErrorIs(t, err, streaming.ErrAlreadyOnStage)
+
+		require.NoError(t, m.Remove(room, "alice"))
+		require.ErrorIs(t, m.Remove(room, "alice"), streaming.ErrNotOnStage)
+		require.False(t, m.IsGuest(room, "alice"))
+	})
+
+	t.Run("the stage holds a limited number of guests", func(t *testing.T) {
+		m := streaming.NewStageManager(settings)
+		_, err := m.Invite(room, "alice", "Alice", "host", now)
+		require.NoError(t, err)
+		_, err = m.Invite(room, "bob", "Bob", "host", now)
+		require.NoError(t, err)
+
+		_, err = m.Accept(room, "alice", now)
+		require.NoError(t, err)
+		_, err = m.Accept(room, "bob", now)
+		require.ErrorIs(t, err, streaming.ErrStageFull)
+		_, err = m.Invite(room, "bob", "Bob", "host", now)
+		require.ErrorIs(t, err, streaming.ErrStageFull)
+	})
+
+	t.Run("invites expire", func(t *testing.T) {
+		m := streaming.NewStageManager(settings)
+		_, err := m.Invite(room, "alice", "Alice", "host", now)
+		require.NoError(t, err)
+		_, err = m.Invite(room, "bob", "Bob", "host", now)
+		require.NoError(t, err)
+
+		require.Len(t, m.Get(room, now).Invites, 2)
+		require.Empty(t, m.Get(room, now.Add(time.Minute)).Invites)
+
+		_, err = m.Accept(room, "alice", now.Add(time.Minute))
+		require.ErrorIs(t, err, streaming.ErrStageInviteNotFound)
+		require.ErrorIs(t, m.Decline(room, "bob"), streaming.ErrStageInviteNotFound)
+	})
+
+	t.Run("the queue keeps the order hands were raised in", func(t *testing.T) {
+		m := streaming.NewStageManager(settings)
+		for _, userID := range []livekit.ParticipantIdentity{"alice", "bob", "carol"} {
+			_, _, err := m.RaiseHand(room, userID, string(userID), now)
+			require.NoError(t, err)
+		}
+		position, raised, err := m.RaiseHand(room, "bob", "bob", now)
+		require.NoError(t, err)
+		require.False(t, raised)
+		require.Equal(t, 2, position)
+
+		require.True(t, m.LowerHand(room, "alice"))
+		require.False(t, m.LowerHand(room, "alice"))
+		queue := m.Get(room, now).Queue
+		require.Len(t, queue, 2)
+		require.Equal(t, livekit.ParticipantIdentity("bob"), queue[0].UserID)
+		require.Equal(t, livekit.ParticipantIdentity("carol"), queue[1].UserID)
+	})
+
+	t.Run("participants leaving are forgotten", func(t *testing.T) {
+		m := streaming.NewStageManager(settings)
+		_, err := m.Invite(room, "alice", "Alice", "host", now)
+		require.NoError(t, err)
+		_, err = m.Accept(room, "alice", now)
+		require.NoError(t, err)
+		_, _, err = m.RaiseHand(room, "bob", "Bob", now)
+		require.NoError(t, err)
+		_, err = m.Invite(room, "bob", "Bob", "host", now)
+		require.ErrorIs(t, err, streaming.ErrStageFull)
+
+		require.True(t, m.Leave(room, "alice"))
+		require.False(t, m.Leave(room, "alice"))
+		require.True(t, m.Leave(room, "bob"))
+
+		stage := m.Get(room, now)
+		require.Empty(t, stage.Guests)
+		require.Empty(t, stage.Queue)
+		require.Equal(t, 1, stage.MaxGuests)
+	})
+}
@@ -60,6 +60,7 @@ type StreamKeyManager struct {
 	// streamerID -> []keys for quick lookup
 	streamerKeys map[livekit.ParticipantIdentity][]string
 	logger       logger.Logger
+	metrics      *streamKeyMetrics
 }
 
 // NewStreamKeyManager creates a new stream key manager
@@ -68,6 +69,7 @@ func NewStreamKeyManager() *StreamKeyManager {
 		keys:         make(map[string]*StreamKey),
 		streamerKeys: make(map[livekit.ParticipantIdentity][]string),
 		logger:       logger.GetLogger(),
+		metrics:      newStreamKeyMetrics(),
 	}
 }
 
@@ -140,18 +142,22 @@ func (m *StreamKeyManager) ValidateStreamKey(ctx context.Context, key string) (*
 
 	streamKey, exists := m.keys[key]
 	if !exists {
+		m.metrics.validations.WithLabelValues(streamKeyNotFound).Inc()
 		return nil, fmt.Errorf("stream key not found")
 	}
 
 	if !streamKey.IsActive {
+		m.metrics.validations.WithLabelValues(streamKeyInactive).Inc()
 		return nil, fmt.Errorf("stream key is inactive")
 	}
 
 	// Check expiration
 	if streamKey.ExpiresAt != nil && time.Now().After(*streamKey.ExpiresAt) {
+		m.metrics.validations.WithLabelValues(streamKeyExpired).Inc()
 		return nil, fmt.Errorf("stream key has expired")
 	}
 
+	m.metrics.validations.WithLabelValues(streamKeyValid).Inc()
 	return streamKey, nil
 }
 
@@ -197,6 +203,53 @@ func (m *StreamKeyManager) RevokeStreamKey(ctx context.Context, key string) erro
 	return nil
 }
 
+// RevokeStreamerKeys deactivates all stream keys of a streamer, returning how many were active
+func (m *StreamKeyManager) RevokeStreamerKeys(ctx context.Context, streamerID livekit.ParticipantIdentity) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revoked := 0
+	for _, key := range m.streamerKeys[streamerID] {
+		if streamKey, ok := m.keys[key]; ok && streamKey.IsActive {
+			streamKey.IsActive = false
+			revoked++
+		}
+	}
+
+	if revoked > 0 {
+		m.logger.Infow("stream keys revoked", "streamerID", streamerID, "count", revoked)
+	}
+
+	return revoked
+}
+
+// KeyOwner returns the streamer a stream key belongs to, whether or not it is still active
+func (m *StreamKeyManager) KeyOwner(key string) (livekit.ParticipantIdentity, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	streamKey, exists := m.keys[key]
+	if !exists {
+		return "", false
+	}
+	return streamKey.StreamerID, true
+}
+
+// RoomOwner returns the streamer holding an active, unexpired key for a room
+func (m *StreamKeyManager) RoomOwner(roomName livekit.RoomName) (livekit.ParticipantIdentity, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	for _, streamKey := range m.keys {
+		if streamKey.RoomName == roomName && streamKey.IsActive &&
+			(streamKey.ExpiresAt == nil || now.Before(*streamKey.ExpiresAt)) {
+			return streamKey.StreamerID, true
+		}
+	}
+	return "", false
+}
+
 // GetStreamKeysByStreamer returns all stream keys for a specific streamer
 func (m *StreamKeyManager) GetStreamKeysByStreamer(
 	ctx context.Context,
@@ -250,6 +303,24 @@ func (m *StreamKeyManager) DeleteStreamKey(ctx context.Context, key string) erro
 	return nil
 }
 
+// DeleteStreamerKeys permanently removes all stream keys of a streamer, returning how many there were
+func (m *StreamKeyManager) DeleteStreamerKeys(ctx context.Context, streamerID livekit.ParticipantIdentity) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := m.streamerKeys[streamerID]
+	for _, key := range keys {
+		delete(m.keys, key)
+	}
+	delete(m.streamerKeys, streamerID)
+
+	if len(keys) > 0 {
+		m.logger.Infow("stream keys deleted", "streamerID", streamerID, "count", len(keys))
+	}
+
+	return len(keys)
+}
+
 // UpdateStreamKeyMetadata updates metadata for a stream key
 func (m *StreamKeyManager) UpdateStreamKeyMetadata(
 	ctx context.Context,
@@ -0,0 +1,218 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/vp8"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// largest frames decoded for thumbnails, in either orientation. Decoding allocates for the size the header
+	// claims, larger frames are not worth the memory.
+	MaxThumbnailFrameSide   = 4096
+	MaxThumbnailFramePixels = 4096 * 2304
+)
+
+var (
+	ErrThumbnailNotFound = errors.New("thumbnail not found")
+	ErrNotKeyFrame       = errors.New("not a key frame")
+	ErrFrameTooLarge     = errors.New("frame too large")
+)
+
+// DefaultThumbnailSettings are used for unset thumbnail settings
+var DefaultThumbnailSettings = ThumbnailSettings{
+	Interval: 30 * time.Second,
+	TTL:      2 * time.Minute,
+	Width:    320,
+	Quality:  75,
+}
+
+// Thumbnail is a still frame of a live stream
+type Thumbnail struct {
+	// JPEG encoded
+	Image      []byte
+	CapturedAt time.Time
+}
+
+// ThumbnailStore keeps the latest thumbnail of each live stream until it expires
+type ThumbnailStore interface {
+	Put(ctx context.Context, roomName livekit.RoomName, thumbnail *Thumbnail, ttl time.Duration) error
+	// Get returns ErrThumbnailNotFound when the room has no thumbnail or it expired
+	Get(ctx context.Context, roomName livekit.RoomName) (*Thumbnail, error)
+	// CapturedAt is Get without the image
+	CapturedAt(ctx context.Context, roomName livekit.RoomName) (time.Time, error)
+	Delete(ctx context.Context, roomName livekit.RoomName) error
+}
+
+// EncodeVP8Thumbnail decodes a VP8 key frame and encodes it as a JPEG no wider than width
+func EncodeVP8Thumbnail(frame []byte, width, quality int) ([]byte, error) {
+	d := vp8.NewDecoder()
+	d.Init(bytes.NewReader(frame), len(frame))
+	header, err := d.DecodeFrameHeader()
+	if err != nil {
+		return nil, err
+	}
+	if !header.KeyFrame {
+		return nil, ErrNotKeyFrame
+	}
+	if header.Width > MaxThumbnailFrameSide || header.Height > MaxThumbnailFrameSide ||
+		header.Width*header.Height > MaxThumbnailFramePixels {
+		return nil, ErrFrameTooLarge
+	}
+	decoded, err := d.DecodeFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	var img image.Image = decoded
+	if bounds := decoded.Bounds(); width > 0 && bounds.Dx() > width {
+		scaled := image.NewRGBA(image.Rect(0, 0, width, max(1, bounds.Dy()*width/bounds.Dx())))
+		draw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), decoded, bounds, draw.Src, nil)
+		img = scaled
+	}
+
+	var buf bytes.Buffer
+	if err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MemoryThumbnailStore keeps the thumbnails of streams on this node in memory
+type MemoryThumbnailStore struct {
+	mu         sync.Mutex
+	thumbnails map[livekit.RoomName]*storedThumbnail
+}
+
+type storedThumbnail struct {
+	*Thumbnail
+	expiresAt time.Time
+}
+
+func NewMemoryThumbnailStore() *MemoryThumbnailStore {
+	return &MemoryThumbnailStore{thumbnails: make(map[livekit.RoomName]*storedThumbnail)}
+}
+
+func (s *MemoryThumbnailStore) Put(_ context.Context, roomName livekit.RoomName, thumbnail *Thumbnail, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for name, t := range s.thumbnails {
+		if now.After(t.expiresAt) {
+			delete(s.thumbnails, name)
+		}
+	}
+	s.thumbnails[roomName] = &storedThumbnail{Thumbnail: thumbnail, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryThumbnailStore) Get(_ context.Context, roomName livekit.RoomName) (*Thumbnail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.thumbnails[roomName]
+	if !ok || time.Now().After(t.expiresAt) {
+		return nil, ErrThumbnailNotFound
+	}
+	return t.Thumbnail, nil
+}
+
+func (s *MemoryThumbnailStore) CapturedAt(ctx context.Context, roomName livekit.RoomName) (time.Time, error) {
+	t, err := s.Get(ctx, roomName)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.CapturedAt, nil
+}
+
+func (s *MemoryThumbnailStore) Delete(_ context.Context, roomName livekit.RoomName) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.thumbnails, roomName)
+	return nil
+}
+
+// RedisThumbnailStore shares thumbnails between nodes, each is a hash of the image and its capture time
+type RedisThumbnailStore struct {
+	rc     redis.UniversalClient
+	prefix string
+}
+
+func NewRedisThumbnailStore(rc redis.UniversalClient) *RedisThumbnailStore {
+	return &RedisThumbnailStore{rc: rc, prefix: "stream_thumbnails:"}
+}
+
+func (s *RedisThumbnailStore) Put(ctx context.Context, roomName livekit.RoomName, thumbnail *Thumbnail, ttl time.Duration) error {
+	key := s.prefix + string(roomName)
+	_, err := s.rc.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, key, "image", thumbnail.Image, "captured_at", thumbnail.CapturedAt.UnixMilli())
+		p.PExpire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisThumbnailStore) Get(ctx context.Context, roomName livekit.RoomName) (*Thumbnail, error) {
+	values, err := s.rc.HMGet(ctx, s.prefix+string(roomName), "image", "captured_at").Result()
+	if err != nil {
+		return nil, err
+	}
+	img, _ := values[0].(string)
+	capturedAt, err := parseCapturedAt(values[1])
+	if err != nil || img == "" {
+		return nil, ErrThumbnailNotFound
+	}
+	return &Thumbnail{Image: []byte(img), CapturedAt: capturedAt}, nil
+}
+
+func (s *RedisThumbnailStore) CapturedAt(ctx context.Context, roomName livekit.RoomName) (time.Time, error) {
+	value, err := s.rc.HGet(ctx, s.prefix+string(roomName), "captured_at").Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, ErrThumbnailNotFound
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return parseCapturedAt(value)
+}
+
+func (s *RedisThumbnailStore) Delete(ctx context.Context, roomName livekit.RoomName) error {
+	return s.rc.Del(ctx, s.prefix+string(roomName)).Err()
+}
+
+func parseCapturedAt(value any) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, ErrThumbnailNotFound
+	}
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
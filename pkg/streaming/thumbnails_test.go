@@ -0,0 +1,102 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+func TestEncodeVP8Thumbnail(t *testing.T) {
+	frame, err := os.ReadFile("testdata/keyframe.vp8")
+	require.NoError(t, err)
+
+	t.Run("keeps small frames", func(t *testing.T) {
+		encoded, err := streaming.EncodeVP8Thumbnail(frame, 320, 75)
+		require.NoError(t, err)
+		img, err := jpeg.Decode(bytes.NewReader(encoded))
+		require.NoError(t, err)
+		require.Equal(t, 150, img.Bounds().Dx())
+		require.Equal(t, 103, img.Bounds().Dy())
+	})
+
+	t.Run("scales large frames down", func(t *testing.T) {
+		encoded, err := streaming.EncodeVP8Thumbnail(frame, 75, 75)
+		require.NoError(t, err)
+		img, err := jpeg.Decode(bytes.NewReader(encoded))
+		require.NoError(t, err)
+		require.Equal(t, 75, img.Bounds().Dx())
+		require.Equal(t, 51, img.Bounds().Dy())
+	})
+
+	t.Run("rejects interframes", func(t *testing.T) {
+		interframe := append([]byte(nil), frame...)
+		// the lowest bit of the frame tag is set on interframes
+		interframe[0] |= 1
+		_, err := streaming.EncodeVP8Thumbnail(interframe, 320, 75)
+		require.ErrorIs(t, err, streaming.ErrNotKeyFrame)
+
+		_, err = streaming.EncodeVP8Thumbnail(frame[:5], 320, 75)
+		require.Error(t, err)
+	})
+
+	t.Run("rejects huge frames", func(t *testing.T) {
+		// the 14 bit width and height follow the frame tag and start code, little endian
+		huge := append([]byte(nil), frame...)
+		huge[6], huge[7] = 0xff, 0x3f
+		_, err := streaming.EncodeVP8Thumbnail(huge, 320, 75)
+		require.ErrorIs(t, err, streaming.ErrFrameTooLarge)
+
+		huge = append([]byte(nil), frame...)
+		huge[6], huge[7], huge[8], huge[9] = 0x00, 0x10, 0x00, 0x10
+		_, err = streaming.EncodeVP8Thumbnail(huge, 320, 75)
+		require.ErrorIs(t, err, streaming.ErrFrameTooLarge)
+	})
+}
+
+func TestMemoryThumbnailStore(t *testing.T) {
+	ctx := context.Background()
+	store := streaming.NewMemoryThumbnailStore()
+
+	_, err := store.Get(ctx, "room")
+	require.ErrorIs(t, err, streaming.ErrThumbnailNotFound)
+
+	capturedAt := time.Now()
+	require.NoError(t, store.Put(ctx, "room", &streaming.Thumbnail{Image: []byte("jpeg"), CapturedAt: capturedAt}, time.Hour))
+	thumbnail, err := store.Get(ctx, "room")
+	require.NoError(t, err)
+	require.Equal(t, []byte("jpeg"), thumbnail.Image)
+	at, err := store.CapturedAt(ctx, "room")
+	require.NoError(t, err)
+	require.True(t, capturedAt.Equal(at))
+
+	require.NoError(t, store.Put(ctx, "expiring", &streaming.Thumbnail{Image: []byte("jpeg")}, 10*time.Millisecond))
+	require.Eventually(t, func() bool {
+		_, err := store.Get(ctx, "expiring")
+		return err == streaming.ErrThumbnailNotFound
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, store.Delete(ctx, "room"))
+	_, err = store.CapturedAt(ctx, "room")
+	require.ErrorIs(t, err, streaming.ErrThumbnailNotFound)
+}
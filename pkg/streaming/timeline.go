@@ -0,0 +1,202 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"time"
+)
+
+// timelineSeries is a timeline of a stream along with the time span each point covers
+type timelineSeries struct {
+	points *[]TimeSeriesDataPoint
+	spans  []time.Duration
+}
+
+// append adds a point, halving the resolution of the older half of the series once it holds more than maxPoints,
+// so the whole stream stays covered while recent activity keeps its detail
+func (s *timelineSeries) append(point TimeSeriesDataPoint, span time.Duration, maxPoints int) {
+	*s.points = append(*s.points, point)
+	s.spans = append(s.spans, span)
+
+	points := *s.points
+	if len(points) <= maxPoints {
+		return
+	}
+
+	half := (len(points)/2 + 1) &^ 1
+	merged := 0
+	for i := 0; i+1 < half; i += 2 {
+		spanA, spanB := s.spans[i], s.spans[i+1]
+		points[merged] = TimeSeriesDataPoint{
+			Timestamp: points[i].Timestamp,
+			Value:     (points[i].Value*spanA.Seconds() + points[i+1].Value*spanB.Seconds()) / (spanA + spanB).Seconds(),
+		}
+		s.spans[merged] = spanA + spanB
+		merged++
+	}
+	n := copy(points[merged:], points[half:])
+	copy(s.spans[merged:], s.spans[half:])
+	*s.points = points[:merged+n]
+	s.spans = s.spans[:merged+n]
+}
+
+// streamTimeline aggregates the activity of a stream into buckets of a fixed resolution.
+// Each closed bucket adds a point to every timeline of the stream: the average number of concurrent viewers,
+// chat messages and reactions per minute, and the average bitrate when there was a sample.
+type streamTimeline struct {
+	resolution time.Duration
+	maxPoints  int
+
+	start       time.Time
+	end         time.Time
+	bucketStart time.Time
+
+	viewers      int
+	viewersSince time.Time
+	// viewer seconds within the current bucket and since the start
+	bucketViewerTime float64
+	totalViewerTime  float64
+
+	messages       int
+	reactions      int
+	bitrateSum     int
+	bitrateSamples int
+
+	viewerSeries   timelineSeries
+	chatSeries     timelineSeries
+	reactionSeries timelineSeries
+	bitrateSeries  timelineSeries
+}
+
+func newStreamTimeline(analytics *StreamAnalytics, resolution time.Duration, maxPoints int) *streamTimeline {
+	return &streamTimeline{
+		resolution:     resolution,
+		maxPoints:      maxPoints,
+		start:          analytics.StartTime,
+		bucketStart:    analytics.StartTime,
+		viewersSince:   analytics.StartTime,
+		viewerSeries:   timelineSeries{points: &analytics.ViewerTimeline},
+		chatSeries:     timelineSeries{points: &analytics.ChatTimeline},
+		reactionSeries: timelineSeries{points: &analytics.ReactionTimeline},
+		bitrateSeries:  timelineSeries{points: &analytics.BitrateTimeline},
+	}
+}
+
+func (t *streamTimeline) closed() bool {
+	return !t.end.IsZero()
+}
+
+// advance closes all buckets that ended by now
+func (t *streamTimeline) advance(now time.Time) {
+	if t.closed() {
+		return
+	}
+	for bucketEnd := t.bucketStart.Add(t.resolution); !now.Before(bucketEnd); bucketEnd = t.bucketStart.Add(t.resolution) {
+		t.closeBucket(bucketEnd)
+	}
+}
+
+func (t *streamTimeline) integrateViewers(now time.Time) {
+	if !now.After(t.viewersSince) {
+		return
+	}
+	viewerTime := float64(t.viewers) * now.Sub(t.viewersSince).Seconds()
+	t.bucketViewerTime += viewerTime
+	t.totalViewerTime += viewerTime
+	t.viewersSince = now
+}
+
+func (t *streamTimeline) closeBucket(end time.Time) {
+	t.integrateViewers(end)
+
+	span := end.Sub(t.bucketStart)
+	t.viewerSeries.append(TimeSeriesDataPoint{
+		Timestamp: t.bucketStart,
+		Value:     t.bucketViewerTime / span.Seconds(),
+	}, span, t.maxPoints)
+	t.chatSeries.append(TimeSeriesDataPoint{
+		Timestamp: t.bucketStart,
+		Value:     float64(t.messages) / span.Minutes(),
+	}, span, t.maxPoints)
+	t.reactionSeries.append(TimeSeriesDataPoint{
+		Timestamp: t.bucketStart,
+		Value:     float64(t.reactions) / span.Minutes(),
+	}, span, t.maxPoints)
+	if t.bitrateSamples > 0 {
+		t.bitrateSeries.append(TimeSeriesDataPoint{
+			Timestamp: t.bucketStart,
+			Value:     float64(t.bitrateSum) / float64(t.bitrateSamples),
+		}, span, t.maxPoints)
+	}
+
+	t.bucketStart = end
+	t.bucketViewerTime = 0
+	t.messages = 0
+	t.reactions = 0
+	t.bitrateSum = 0
+	t.bitrateSamples = 0
+}
+
+func (t *streamTimeline) setViewers(now time.Time, viewers int) {
+	t.advance(now)
+	if t.closed() {
+		return
+	}
+	t.integrateViewers(now)
+	t.viewers = viewers
+}
+
+func (t *streamTimeline) addMessage(now time.Time) {
+	t.advance(now)
+	t.messages++
+}
+
+func (t *streamTimeline) addReaction(now time.Time) {
+	t.advance(now)
+	t.reactions++
+}
+
+func (t *streamTimeline) addBitrate(now time.Time, bitrate int) {
+	t.advance(now)
+	t.bitrateSum += bitrate
+	t.bitrateSamples++
+}
+
+// close ends the timeline, the last bucket is cut short at the end of the stream
+func (t *streamTimeline) close(end time.Time) {
+	t.advance(end)
+	if t.closed() {
+		return
+	}
+	if end.After(t.bucketStart) {
+		t.closeBucket(end)
+	}
+	t.end = end
+}
+
+// averageViewers returns the number of concurrent viewers averaged over the stream so far
+func (t *streamTimeline) averageViewers(now time.Time) float64 {
+	viewerTime := t.totalViewerTime
+	if t.closed() {
+		now = t.end
+	} else if now.After(t.viewersSince) {
+		viewerTime += float64(t.viewers) * now.Sub(t.viewersSince).Seconds()
+	}
+	elapsed := now.Sub(t.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return viewerTime / elapsed
+}
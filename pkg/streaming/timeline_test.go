@@ -0,0 +1,103 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamTimeline(t *testing.T) {
+	start := time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	t.Run("buckets", func(t *testing.T) {
+		a := &StreamAnalytics{StartTime: start}
+		tl := newStreamTimeline(a, time.Minute, 100)
+
+		tl.setViewers(at(0), 2)
+		tl.addMessage(at(10 * time.Second))
+		tl.addMessage(at(20 * time.Second))
+		tl.addBitrate(at(30*time.Second), 2000)
+		tl.addBitrate(at(40*time.Second), 4000)
+		tl.setViewers(at(30*time.Second+time.Minute), 4)
+		tl.addReaction(at(70 * time.Second))
+		// a quiet minute still gets points, without a bitrate
+		tl.advance(at(3 * time.Minute))
+
+		require.Equal(t, []TimeSeriesDataPoint{
+			{Timestamp: at(0), Value: 2},
+			{Timestamp: at(time.Minute), Value: 3},
+			{Timestamp: at(2 * time.Minute), Value: 4},
+		}, a.ViewerTimeline)
+		require.Equal(t, []TimeSeriesDataPoint{
+			{Timestamp: at(0), Value: 2},
+			{Timestamp: at(time.Minute), Value: 0},
+			{Timestamp: at(2 * time.Minute), Value: 0},
+		}, a.ChatTimeline)
+		require.Equal(t, 1.0, a.ReactionTimeline[1].Value)
+		require.Equal(t, []TimeSeriesDataPoint{{Timestamp: at(0), Value: 3000}}, a.BitrateTimeline)
+
+		// (2*1.5 + 4*1.5) viewer minutes over 3 minutes
+		require.InDelta(t, 3.0, tl.averageViewers(at(3*time.Minute)), 1e-9)
+
+		// the last bucket is cut short and its rates scale up accordingly
+		tl.addMessage(at(3*time.Minute + 10*time.Second))
+		tl.close(at(3*time.Minute + 30*time.Second))
+		require.Len(t, a.ChatTimeline, 4)
+		require.Equal(t, 2.0, a.ChatTimeline[3].Value)
+		require.InDelta(t, 22.0/7, tl.averageViewers(at(time.Hour)), 1e-9)
+
+		// nothing is recorded once closed
+		tl.setViewers(at(5*time.Minute), 10)
+		tl.addMessage(at(5 * time.Minute))
+		tl.advance(at(10 * time.Minute))
+		require.Len(t, a.ViewerTimeline, 4)
+		require.Len(t, a.ChatTimeline, 4)
+	})
+
+	t.Run("downsamples older points", func(t *testing.T) {
+		a := &StreamAnalytics{StartTime: start}
+		tl := newStreamTimeline(a, time.Minute, 8)
+		for i := 0; i < 9; i++ {
+			tl.setViewers(at(time.Duration(i)*time.Minute), i)
+		}
+		tl.advance(at(9 * time.Minute))
+
+		// the first four minutes merged into two, the recent ones are untouched
+		require.Len(t, a.ViewerTimeline, 7)
+		require.Equal(t, TimeSeriesDataPoint{Timestamp: at(0), Value: 0.5}, a.ViewerTimeline[0])
+		require.Equal(t, TimeSeriesDataPoint{Timestamp: at(2 * time.Minute), Value: 2.5}, a.ViewerTimeline[1])
+		require.Equal(t, TimeSeriesDataPoint{Timestamp: at(4 * time.Minute), Value: 4}, a.ViewerTimeline[2])
+		require.Equal(t, TimeSeriesDataPoint{Timestamp: at(8 * time.Minute), Value: 8}, a.ViewerTimeline[6])
+
+		// merged points keep their weight when merged again
+		for i := 9; i < 12; i++ {
+			tl.setViewers(at(time.Duration(i)*time.Minute), i)
+		}
+		tl.advance(at(12 * time.Minute))
+		require.LessOrEqual(t, len(a.ViewerTimeline), 8)
+		require.Equal(t, TimeSeriesDataPoint{Timestamp: at(0), Value: 1.5}, a.ViewerTimeline[0])
+
+		var total float64
+		for i, span := range tl.viewerSeries.spans {
+			total += a.ViewerTimeline[i].Value * span.Minutes()
+		}
+		// sum of 0..11 viewer minutes
+		require.InDelta(t, 66.0, total, 1e-9)
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -93,6 +94,7 @@ type VODService struct {
 	playbackSessions   map[string]*VODPlaybackSession           // sessionID -> Session
 	logger             logger.Logger
 	config             *VODConfig
+	metrics            *vodMetrics
 }
 
 // VODConfig defines VOD service configuration
@@ -131,6 +133,7 @@ func NewVODService(config *VODConfig) *VODService {
 		playbackSessions:   make(map[string]*VODPlaybackSession),
 		logger:             logger.GetLogger(),
 		config:             config,
+		metrics:            newVODMetrics(),
 	}
 
 	// Restore recordings from disk
@@ -497,6 +500,7 @@ func (vs *VODService) StartPlaybackSession(
 
 	// Increment view count
 	recording.ViewCount++
+	vs.metrics.playbacks.Inc()
 
 	vs.logger.Debugw("started playback session",
 		"sessionID", sessionID,
@@ -605,6 +609,33 @@ func (vs *VODService) DeleteRecording(
 	return nil
 }
 
+// DeleteStreamerRecordings removes all recordings of a streamer along with their files,
+// returning how many there were
+func (vs *VODService) DeleteStreamerRecordings(ctx context.Context, streamerID livekit.ParticipantIdentity) int {
+	vs.mu.Lock()
+	ids := vs.streamerRecordings[streamerID]
+	for _, id := range ids {
+		delete(vs.recordings, id)
+	}
+	delete(vs.streamerRecordings, streamerID)
+	vs.mu.Unlock()
+
+	for _, id := range ids {
+		for _, ext := range []string{".mp4", ".json"} {
+			path := filepath.Join(vs.config.StoragePath, id+ext)
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				vs.logger.Warnw("could not remove recording file", err, "path", path)
+			}
+		}
+	}
+
+	if len(ids) > 0 {
+		vs.logger.Infow("deleted VOD recordings", "streamerID", streamerID, "count", len(ids))
+	}
+
+	return len(ids)
+}
+
 // CleanupExpiredRecordings removes expired recordings
 func (vs *VODService) CleanupExpiredRecordings(ctx context.Context) int {
 	vs.mu.Lock()
@@ -0,0 +1,504 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultPushTTL         = 24 * time.Hour
+	defaultPushSubject     = "mailto:admin@localhost"
+	pushRequestTimeout     = 10 * time.Second
+	vapidTokenTTL          = 12 * time.Hour
+	maxPushSubscriptions   = 10
+	pushRecordSize         = 4096
+	pushMaxMessageSize     = 4096
+	pushSaltSize           = 16
+	pushAuthSecretSize     = 16
+	p256UncompressedKeyLen = 65
+)
+
+// errPrivatePushEndpoint is returned for endpoints on this host or its private networks. Push services are public,
+// anything else would let users make the server post to internal services.
+var errPrivatePushEndpoint = errors.New("push endpoint is not a public address")
+
+// PushConfig configures Web Push delivery of notifications
+type PushConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// application server keys, base64url encoded. Generated at startup when empty,
+	// which invalidates existing subscriptions on every restart.
+	VAPIDPublicKey  string `yaml:"vapid_public_key,omitempty"`
+	VAPIDPrivateKey string `yaml:"vapid_private_key,omitempty"`
+	// contact for push services, a mailto: or https: URL
+	Subject string `yaml:"subject,omitempty"`
+	// how long push services hold messages for offline devices
+	TTL time.Duration `yaml:"ttl,omitempty"`
+}
+
+// PushSubscriptionKeys are the client keys of a browser push subscription
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// PushSubscription is a browser push subscription, as returned by PushManager.subscribe()
+type PushSubscription struct {
+	Endpoint  string               `json:"endpoint"`
+	Keys      PushSubscriptionKeys `json:"keys"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// VAPIDKeys is an application server key pair, base64url encoded
+type VAPIDKeys struct {
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+// GenerateVAPIDKeys creates a new application server key pair
+func GenerateVAPIDKeys() (*VAPIDKeys, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPIDKeys{
+		PublicKey:  base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		PrivateKey: base64.RawURLEncoding.EncodeToString(key.Bytes()),
+	}, nil
+}
+
+// pushPayload is the JSON document delivered to the service worker
+type pushPayload struct {
+	ID       string            `json:"id"`
+	Type     NotificationType  `json:"type"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body,omitempty"`
+	Icon     string            `json:"icon,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+	Priority string            `json:"priority"`
+}
+
+// PushNotifier delivers notifications to browsers through their push services
+type PushNotifier struct {
+	config     PushConfig
+	privateKey *ecdsa.PrivateKey
+	publicKey  string
+	client     *http.Client
+	logger     logger.Logger
+	// only for tests, their push services listen on the loopback address
+	allowPrivate atomic.Bool
+
+	mu            sync.RWMutex
+	subscriptions map[livekit.ParticipantIdentity][]*PushSubscription
+
+	deliveries *prometheus.CounterVec
+}
+
+// NewPushNotifier creates a push notifier from its VAPID configuration
+func NewPushNotifier(config PushConfig) (*PushNotifier, error) {
+	if config.Subject == "" {
+		config.Subject = defaultPushSubject
+	}
+	if config.TTL == 0 {
+		config.TTL = defaultPushTTL
+	}
+
+	p := &PushNotifier{
+		config:        config,
+		logger:        logger.GetLogger(),
+		subscriptions: make(map[livekit.ParticipantIdentity][]*PushSubscription),
+		deliveries:    newPushDeliveries(),
+	}
+	// endpoint hosts are checked again on every dial, they may resolve to another address than at registration
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: pushRequestTimeout, KeepAlive: 30 * time.Second, Control: p.checkDial}).DialContext
+	p.client = &http.Client{Timeout: pushRequestTimeout, Transport: transport}
+
+	if config.VAPIDPrivateKey == "" {
+		keys, err := GenerateVAPIDKeys()
+		if err != nil {
+			return nil, err
+		}
+		config.VAPIDPublicKey, config.VAPIDPrivateKey = keys.PublicKey, keys.PrivateKey
+		p.logger.Warnw("no VAPID keys configured, generated a temporary pair", nil, "publicKey", keys.PublicKey)
+	}
+
+	privateKey, publicKey, err := parseVAPIDPrivateKey(config.VAPIDPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	p.privateKey = privateKey
+	p.publicKey = base64.RawURLEncoding.EncodeToString(publicKey)
+	if config.VAPIDPublicKey != "" && strings.TrimRight(config.VAPIDPublicKey, "=") != p.publicKey {
+		return nil, fmt.Errorf("VAPID public key does not match private key")
+	}
+
+	return p, nil
+}
+
+// PublicKey returns the application server key browsers subscribe with
+func (p *PushNotifier) PublicKey() string {
+	return p.publicKey
+}
+
+// Subscribe registers a browser subscription for a user, replacing any with the same endpoint
+func (p *PushNotifier) Subscribe(userID livekit.ParticipantIdentity, sub *PushSubscription) error {
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Hostname() == "" {
+		return fmt.Errorf("invalid push endpoint")
+	}
+	if !p.allowPrivate.Load() && !isPublicHost(endpoint.Hostname()) {
+		return errPrivatePushEndpoint
+	}
+	if key, err := decodePushKey(sub.Keys.P256dh); err != nil || len(key) != p256UncompressedKeyLen {
+		return fmt.Errorf("invalid p256dh key")
+	}
+	if secret, err := decodePushKey(sub.Keys.Auth); err != nil || len(secret) != pushAuthSecretSize {
+		return fmt.Errorf("invalid auth secret")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subs := p.subscriptions[userID]
+	for i, existing := range subs {
+		if existing.Endpoint == sub.Endpoint {
+			subs = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	sub.CreatedAt = time.Now()
+	subs = append(subs, sub)
+	if len(subs) > maxPushSubscriptions {
+		subs = subs[len(subs)-maxPushSubscriptions:]
+	}
+	p.subscriptions[userID] = subs
+
+	p.logger.Debugw("push subscription added", "userID", userID, "count", len(subs))
+	return nil
+}
+
+// Unsubscribe removes a browser subscription of a user
+func (p *PushNotifier) Unsubscribe(userID livekit.ParticipantIdentity, endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.removeLocked(userID, endpoint)
+}
+
+// RemoveUser drops all browser subscriptions of a user
+func (p *PushNotifier) RemoveUser(userID livekit.ParticipantIdentity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.subscriptions, userID)
+}
+
+// GetSubscriptions returns the browser subscriptions of a user
+func (p *PushNotifier) GetSubscriptions(userID livekit.ParticipantIdentity) []*PushSubscription {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return append([]*PushSubscription{}, p.subscriptions[userID]...)
+}
+
+// Handle is the NotificationHandler for ChannelPush
+func (p *PushNotifier) Handle(notification *Notification) {
+	subs := p.GetSubscriptions(notification.UserID)
+	if len(subs) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(&pushPayload{
+		ID:       notification.ID,
+		Type:     notification.Type,
+		Title:    notification.Title,
+		Body:     notification.Body,
+		Icon:     notification.ImageURL,
+		URL:      notification.ActionURL,
+		Data:     notification.Data,
+		Priority: string(notification.Priority),
+	})
+	if err != nil {
+		p.logger.Errorw("could not encode push payload", err)
+		return
+	}
+
+	for _, sub := range subs {
+		status, err := p.send(sub, payload, notification.Priority)
+		switch {
+		case status == http.StatusNotFound || status == http.StatusGone:
+			// the browser dropped the subscription, stop sending to it
+			p.deliveries.WithLabelValues(deliveryResultExpired).Inc()
+			p.Unsubscribe(notification.UserID, sub.Endpoint)
+			p.logger.Debugw("pruned expired push subscription", "userID", notification.UserID, "status", status)
+		case err != nil:
+			p.deliveries.WithLabelValues(deliveryResultFailed).Inc()
+			p.logger.Warnw("could not deliver push notification", err,
+				"userID", notification.UserID,
+				"notificationID", notification.ID,
+			)
+		default:
+			p.deliveries.WithLabelValues(deliveryResultSent).Inc()
+		}
+	}
+}
+
+// checkDial refuses connections to private addresses, after the endpoint host was resolved
+func (p *PushNotifier) checkDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !p.allowPrivate.Load() && !isPublicAddr(addr) {
+		return errPrivatePushEndpoint
+	}
+	return nil
+}
+
+// isPublicHost reports whether an endpoint host may be public. Names are resolved only when dialing.
+func isPublicHost(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return isPublicAddr(addr)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsUnspecified() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast()
+}
+
+func (p *PushNotifier) removeLocked(userID livekit.ParticipantIdentity, endpoint string) {
+	subs := p.subscriptions[userID]
+	for i, sub := range subs {
+		if sub.Endpoint == endpoint {
+			subs = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(p.subscriptions, userID)
+	} else {
+		p.subscriptions[userID] = subs
+	}
+}
+
+// send posts an encrypted message to the subscription's push service
+func (p *PushNotifier) send(sub *PushSubscription, payload []byte, priority NotificationPriority) (int, error) {
+	body, err := encryptPushPayload(sub, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	authorization, err := p.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	urgency := "normal"
+	if priority == PriorityUrgent {
+		urgency = "high"
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(p.config.TTL.Seconds())))
+	req.Header.Set("Urgency", urgency)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("push service responded with %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// vapidAuthorization builds the RFC 8292 Authorization header for an endpoint
+func (p *PushNotifier) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenTTL).Unix(),
+		"sub": p.config.Subject,
+	}).SignedString(p.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", token, p.publicKey), nil
+}
+
+// encryptPushPayload encrypts a message for a subscription as described in RFC 8291,
+// using a single aes128gcm record (RFC 8188)
+func encryptPushPayload(sub *PushSubscription, payload []byte) ([]byte, error) {
+	uaPublicBytes, err := decodePushKey(sub.Keys.P256dh)
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := decodePushKey(sub.Keys.Auth)
+	if err != nil {
+		return nil, err
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// combine the shared secret with the subscription's auth secret
+	prkKey, err := hkdf.Extract(sha256.New, ecdhSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPublicBytes) + string(asPublicBytes)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, pushSaltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// header: salt | record size | key id length | key id (sender public key)
+	header := make([]byte, 0, pushSaltSize+4+1+len(asPublicBytes))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(asPublicBytes)))
+	header = append(header, asPublicBytes...)
+
+	// a single record, terminated by the last record delimiter
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	message := gcm.Seal(header, nonce, plaintext, nil)
+	if len(message) > pushMaxMessageSize {
+		return nil, fmt.Errorf("push payload too large")
+	}
+	return message, nil
+}
+
+func parseVAPIDPrivateKey(encoded string) (*ecdsa.PrivateKey, []byte, error) {
+	d, err := decodePushKey(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	public := key.PublicKey().Bytes()
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}, public, nil
+}
+
+// decodePushKey accepts keys in any base64 flavour, browsers use unpadded base64url
+func decodePushKey(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return base64.RawURLEncoding.DecodeString(s)
+}
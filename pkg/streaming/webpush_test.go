@@ -0,0 +1,334 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/streaming"
+)
+
+// browserStandIn plays both the browser (holding the subscription keys) and its push service
+type browserStandIn struct {
+	server     *httptest.Server
+	private    *ecdh.PrivateKey
+	authSecret []byte
+	status     atomic.Int32
+	messages   chan map[string]interface{}
+}
+
+func newBrowserStandIn(t *testing.T) *browserStandIn {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	authSecret := make([]byte, 16)
+	_, err = rand.Read(authSecret)
+	require.NoError(t, err)
+
+	b := &browserStandIn{
+		private:    private,
+		authSecret: authSecret,
+		messages:   make(chan map[string]interface{}, 16),
+	}
+	b.status.Store(http.StatusCreated)
+	b.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := b.verifyVAPID(r); err != nil {
+			t.Errorf("invalid VAPID authorization: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+			t.Errorf("missing push headers: %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		plaintext, err := b.decrypt(body)
+		if err != nil {
+			t.Errorf("could not decrypt push message: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(plaintext, &payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		w.WriteHeader(int(b.status.Load()))
+		b.messages <- payload
+	}))
+	t.Cleanup(b.server.Close)
+	return b
+}
+
+func (b *browserStandIn) subscription() *streaming.PushSubscription {
+	return &streaming.PushSubscription{
+		Endpoint: b.server.URL + "/push/abc",
+		Keys: streaming.PushSubscriptionKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(b.private.PublicKey().Bytes()),
+			Auth:   base64.RawURLEncoding.EncodeToString(b.authSecret),
+		},
+	}
+}
+
+func (b *browserStandIn) verifyVAPID(r *http.Request) error {
+	var token, key string
+	for _, part := range strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "vapid "), ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(part, "t="):
+			token = part[2:]
+		case strings.HasPrefix(part, "k="):
+			key = part[2:]
+		}
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return err
+	}
+	public := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(raw[1:33]),
+		Y:     new(big.Int).SetBytes(raw[33:]),
+	}
+	_, err = jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return public, nil
+	},
+		jwt.WithValidMethods([]string{"ES256"}),
+		jwt.WithAudience(b.server.URL),
+		jwt.WithExpirationRequired(),
+	)
+	return err
+}
+
+// decrypt reverses RFC 8291 from the user agent side
+func (b *browserStandIn) decrypt(body []byte) ([]byte, error) {
+	salt := body[:16]
+	rs := binary.BigEndian.Uint32(body[16:20])
+	idlen := int(body[20])
+	asPublicBytes := body[21 : 21+idlen]
+	ciphertext := body[21+idlen:]
+	if int(rs) < len(ciphertext) {
+		return nil, io.ErrShortBuffer
+	}
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+	ecdhSecret, err := b.private.ECDH(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	prkKey, _ := hkdf.Extract(sha256.New, ecdhSecret, b.authSecret)
+	ikm, _ := hkdf.Expand(sha256.New, prkKey, "WebPush: info\x00"+string(b.private.PublicKey().Bytes())+string(asPublicBytes), 32)
+	prk, _ := hkdf.Extract(sha256.New, ikm, salt)
+	cek, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	// strip padding and the last record delimiter
+	end := bytes.LastIndexByte(plaintext, 0x02)
+	if end < 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return plaintext[:end], nil
+}
+
+func (b *browserStandIn) receive(t *testing.T) map[string]interface{} {
+	select {
+	case msg := <-b.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no push message received")
+		return nil
+	}
+}
+
+func (b *browserStandIn) expectNone(t *testing.T) {
+	select {
+	case msg := <-b.messages:
+		t.Fatalf("unexpected push message: %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// newPushNotifier creates a notifier delivering to the browser stand-ins, which listen on the loopback address
+func newPushNotifier(t *testing.T) *streaming.PushNotifier {
+	keys, err := streaming.GenerateVAPIDKeys()
+	require.NoError(t, err)
+	p, err := streaming.NewPushNotifier(streaming.PushConfig{
+		Enabled:         true,
+		VAPIDPublicKey:  keys.PublicKey,
+		VAPIDPrivateKey: keys.PrivateKey,
+		Subject:         "mailto:admin@example.com",
+	})
+	require.NoError(t, err)
+	require.Equal(t, keys.PublicKey, p.PublicKey())
+	streaming.AllowPrivatePushEndpoints(p, true)
+	return p
+}
+
+func TestPushNotifier(t *testing.T) {
+	t.Run("rejects mismatched keys", func(t *testing.T) {
+		a, err := streaming.GenerateVAPIDKeys()
+		require.NoError(t, err)
+		b, err := streaming.GenerateVAPIDKeys()
+		require.NoError(t, err)
+		_, err = streaming.NewPushNotifier(streaming.PushConfig{VAPIDPublicKey: a.PublicKey, VAPIDPrivateKey: b.PrivateKey})
+		require.Error(t, err)
+	})
+
+	t.Run("rejects invalid subscriptions", func(t *testing.T) {
+		p := newPushNotifier(t)
+		sub := newBrowserStandIn(t).subscription()
+		sub.Keys.Auth = "short"
+		require.Error(t, p.Subscribe("alice", sub))
+
+		sub = newBrowserStandIn(t).subscription()
+		sub.Endpoint = "ftp://push.example.com"
+		require.Error(t, p.Subscribe("alice", sub))
+
+		sub.Endpoint = "http://push.example.com/abc"
+		require.Error(t, p.Subscribe("alice", sub))
+	})
+
+	t.Run("rejects private endpoints", func(t *testing.T) {
+		p := newPushNotifier(t)
+		streaming.AllowPrivatePushEndpoints(p, false)
+		sub := newBrowserStandIn(t).subscription()
+		for _, endpoint := range []string{
+			"https://127.0.0.1/push",
+			"https://localhost:8443/push",
+			"https://10.0.0.5/push",
+			"https://192.168.1.1/push",
+			"https://169.254.169.254/latest/meta-data",
+			"https://[::1]/push",
+			"https://[fe80::1]/push",
+			"https://[::ffff:127.0.0.1]/push",
+		} {
+			sub.Endpoint = endpoint
+			require.Error(t, p.Subscribe("alice", sub), endpoint)
+		}
+		sub.Endpoint = "https://push.example.com/abc"
+		require.NoError(t, p.Subscribe("alice", sub))
+	})
+
+	t.Run("checks the address when dialing", func(t *testing.T) {
+		// names are only resolved when dialing, a public looking endpoint may still point at this host
+		p := newPushNotifier(t)
+		browser := newBrowserStandIn(t)
+		require.NoError(t, p.Subscribe("alice", browser.subscription()))
+		streaming.AllowPrivatePushEndpoints(p, false)
+
+		p.Handle(&streaming.Notification{UserID: "alice", Title: "hi", Priority: streaming.PriorityUrgent})
+		browser.expectNone(t)
+		require.Len(t, p.GetSubscriptions("alice"), 1)
+	})
+
+	t.Run("delivers encrypted payload", func(t *testing.T) {
+		p := newPushNotifier(t)
+		browser := newBrowserStandIn(t)
+		require.NoError(t, p.Subscribe("alice", browser.subscription()))
+		require.NoError(t, p.Subscribe("alice", browser.subscription()))
+		require.Len(t, p.GetSubscriptions("alice"), 1)
+
+		p.Handle(&streaming.Notification{
+			ID:        "n1",
+			UserID:    "alice",
+			Type:      streaming.NotificationTypeStreamStarted,
+			Title:     "Bob is live!",
+			Body:      "Speedrun",
+			ActionURL: "/watch/room",
+			Priority:  streaming.PriorityHigh,
+		})
+
+		msg := browser.receive(t)
+		require.Equal(t, "Bob is live!", msg["title"])
+		require.Equal(t, "/watch/room", msg["url"])
+		require.Len(t, p.GetSubscriptions("alice"), 1)
+	})
+
+	t.Run("prunes expired subscriptions", func(t *testing.T) {
+		p := newPushNotifier(t)
+		browser := newBrowserStandIn(t)
+		browser.status.Store(http.StatusGone)
+		require.NoError(t, p.Subscribe("alice", browser.subscription()))
+
+		p.Handle(&streaming.Notification{UserID: "alice", Title: "hi", Priority: streaming.PriorityUrgent})
+		browser.receive(t)
+		require.Empty(t, p.GetSubscriptions("alice"))
+	})
+}
+
+func TestNotificationServicePushChannel(t *testing.T) {
+	p := newPushNotifier(t)
+	browser := newBrowserStandIn(t)
+	require.NoError(t, p.Subscribe("alice", browser.subscription()))
+
+	config := streaming.DefaultNotificationConfig
+	config.EnablePush = true
+	ns := streaming.NewNotificationService(&config)
+	ns.RegisterNotificationHandler(streaming.ChannelPush, p.Handle)
+
+	send := func(priority streaming.NotificationPriority) {
+		_, err := ns.SendNotification(context.Background(), "alice", streaming.NotificationTypeSystem, string(priority), "", priority, "", nil)
+		require.NoError(t, err)
+	}
+
+	send(streaming.PriorityMedium)
+	browser.expectNone(t)
+
+	send(streaming.PriorityHigh)
+	require.Equal(t, "high", browser.receive(t)["title"])
+
+	ns.SetUserOnlineStatus(context.Background(), "alice", true)
+	send(streaming.PriorityUrgent)
+	browser.expectNone(t)
+
+	ns.SetUserOnlineStatus(context.Background(), "alice", false)
+	send(streaming.PriorityUrgent)
+	require.Equal(t, "urgent", browser.receive(t)["title"])
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// player reported quality of experience
+var (
+	promQoEStallDuration   prometheus.Histogram
+	promQoELatency         prometheus.Histogram
+	promQoEDroppedFrames   prometheus.Histogram
+	promQoEQualityChanges  prometheus.Counter
+	promQoERejectedBeacons *prometheus.CounterVec
+)
+
+func initQoEStats(nodeID string, nodeType livekit.NodeType) {
+	promQoEStallDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "qoe",
+		Name:        "stall_duration_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+	})
+	promQoELatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "qoe",
+		Name:        "glass_to_glass_latency_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     []float64{0.1, 0.2, 0.3, 0.5, 0.75, 1, 2, 3, 5, 10, 30},
+	})
+	promQoEDroppedFrames = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "qoe",
+		Name:        "dropped_frames",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     []float64{1, 5, 10, 25, 50, 100, 250, 1000},
+	})
+	promQoEQualityChanges = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "qoe",
+		Name:        "quality_changes_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	})
+	promQoERejectedBeacons = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "qoe",
+		Name:        "rejected_beacons_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"reason"})
+
+	prometheus.MustRegister(promQoEStallDuration)
+	prometheus.MustRegister(promQoELatency)
+	prometheus.MustRegister(promQoEDroppedFrames)
+	prometheus.MustRegister(promQoEQualityChanges)
+	prometheus.MustRegister(promQoERejectedBeacons)
+}
+
+func RecordQoEStall(duration time.Duration) {
+	promQoEStallDuration.Observe(duration.Seconds())
+}
+
+func RecordQoELatency(latency time.Duration) {
+	promQoELatency.Observe(latency.Seconds())
+}
+
+func RecordQoEDroppedFrames(frames int) {
+	promQoEDroppedFrames.Observe(float64(frames))
+}
+
+func RecordQoEQualityChange() {
+	promQoEQualityChanges.Inc()
+}
+
+// RecordQoEBeaconRejected counts a beacon that was not accepted, reason is one of a fixed set
+func RecordQoEBeaconRejected(reason string) {
+	promQoERejectedBeacons.WithLabelValues(reason).Inc()
+}
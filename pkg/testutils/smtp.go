@@ -0,0 +1,177 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type SMTPMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// SMTPStandIn is a minimal in-process SMTP server that records delivered messages
+type SMTPStandIn struct {
+	listener net.Listener
+	messages chan SMTPMessage
+	// number of transactions to reject with a transient error before accepting
+	Failures atomic.Int32
+}
+
+func NewSMTPStandIn(t *testing.T) *SMTPStandIn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &SMTPStandIn{
+		listener: l,
+		messages: make(chan SMTPMessage, 16),
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// Addr returns the host and port the server listens on
+func (s *SMTPStandIn) Addr() (string, int) {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return host, p
+}
+
+func (s *SMTPStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	reply("220 localhost ESMTP stand-in")
+	var msg SMTPMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg = SMTPMessage{From: strings.Trim(line[len("MAIL FROM:"):], "<> ")}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if s.Failures.Add(-1) >= 0 {
+				reply("451 try again later")
+				continue
+			}
+			msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				dl, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dl == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dl, "."))
+			}
+			msg.Data = data.String()
+			s.messages <- msg
+			reply("250 OK")
+		case cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *SMTPStandIn) Receive(t *testing.T) SMTPMessage {
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email received")
+		return SMTPMessage{}
+	}
+}
+
+func (s *SMTPStandIn) ExpectNone(t *testing.T) {
+	select {
+	case msg := <-s.messages:
+		t.Fatalf("unexpected email: %s", msg.Data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// ParseEmail returns the subject and the text and html parts of a multipart/alternative message
+func ParseEmail(t *testing.T, data string) (string, string, string) {
+	m, err := mail.ReadMessage(strings.NewReader(data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(p)
+		require.NoError(t, err)
+		contentType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[contentType] = string(body)
+	}
+	return subject, parts["text/plain"], parts["text/html"]
+}
@@ -0,0 +1,7 @@
+ALTER TABLE users
+  DROP COLUMN IF EXISTS email_change_expires_at,
+  DROP COLUMN IF EXISTS email_change_token_hash,
+  DROP COLUMN IF EXISTS pending_email,
+  DROP COLUMN IF EXISTS channel_slug,
+  DROP COLUMN IF EXISTS bio,
+  DROP COLUMN IF EXISTS avatar_url;
\ No newline at end of file
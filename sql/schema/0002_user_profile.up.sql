@@ -0,0 +1,7 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS avatar_url TEXT,
+  ADD COLUMN IF NOT EXISTS bio TEXT,
+  ADD COLUMN IF NOT EXISTS channel_slug TEXT UNIQUE,
+  ADD COLUMN IF NOT EXISTS pending_email TEXT,
+  ADD COLUMN IF NOT EXISTS email_change_token_hash BYTEA,
+  ADD COLUMN IF NOT EXISTS email_change_expires_at TIMESTAMPTZ;
\ No newline at end of file
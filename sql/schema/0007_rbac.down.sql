@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS role_permissions;
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspension_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
\ No newline at end of file
@@ -0,0 +1,28 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role TEXT NOT NULL,
+  permission TEXT NOT NULL,
+  PRIMARY KEY (role, permission)
+);
+
+-- the defaults of storage.DefaultRolePermissions
+INSERT INTO role_permissions (role, permission) VALUES
+  ('viewer', 'chat:send'),
+  ('viewer', 'stream:publish'),
+  ('streamer', 'chat:send'),
+  ('streamer', 'stream:publish'),
+  ('moderator', 'chat:send'),
+  ('moderator', 'chat:moderate'),
+  ('moderator', 'users:read'),
+  ('moderator', 'streams:end'),
+  ('admin', 'chat:send'),
+  ('admin', 'stream:publish'),
+  ('admin', 'chat:moderate'),
+  ('admin', 'users:read'),
+  ('admin', 'users:suspend'),
+  ('admin', 'roles:assign'),
+  ('admin', 'streams:end'),
+  ('admin', 'stream_keys:revoke')
+ON CONFLICT (role, permission) DO NOTHING;
\ No newline at end of file